@@ -0,0 +1,54 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/subroll/sqetest/internal/pkg/config"
+	"github.com/subroll/sqetest/internal/pkg/otphash"
+	"github.com/subroll/sqetest/internal/service"
+)
+
+// Admin is what the operator commands work with, the user service and its
+// repository as the server builds them, minus delivery channels and tokens.
+type Admin struct {
+	User     *service.User
+	UserRepo service.UserRepository
+
+	dbs databases
+}
+
+// NewAdmin connects to the configured database. The memory driver is refused
+// since nothing a command changes would outlive it.
+func NewAdmin(ctx context.Context, cfg *config.Config) (*Admin, error) {
+	if cfg.DB.Driver == "memory" {
+		return nil, errors.New("the memory driver keeps nothing across commands, configure a database")
+	}
+
+	otpHasher, err := otphash.NewHasher(cfg.OTP.Pepper.Current, cfg.OTP.Pepper.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	userRepo, dbs, err := newUserRepository(ctx, cfg, otpHasher)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Admin{
+		User: service.NewUser(service.Dependencies{
+			User:           userRepo,
+			DefaultPurpose: cfg.OTP.DefaultPurpose,
+			NowFunc:        time.Now,
+			UUIDGenerator:  uuid.NewString,
+		}),
+		UserRepo: userRepo,
+		dbs:      dbs,
+	}, nil
+}
+
+func (a *Admin) Close() error {
+	return a.dbs.Close()
+}
@@ -0,0 +1,283 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/subroll/sqetest/internal/delivery/rest"
+	"github.com/subroll/sqetest/internal/entity"
+	"github.com/subroll/sqetest/internal/migration"
+	"github.com/subroll/sqetest/internal/pkg/config"
+	"github.com/subroll/sqetest/internal/pkg/log"
+	"github.com/subroll/sqetest/internal/pkg/otphash"
+	"github.com/subroll/sqetest/internal/repository"
+	"github.com/subroll/sqetest/internal/service"
+	"go.uber.org/zap"
+)
+
+// databases are the connections behind a SQL repository, both are nil for the
+// memory driver and replica is nil without a read replica.
+type databases struct {
+	primary *sql.DB
+	replica *sql.DB
+	dialect repository.Dialect
+}
+
+func (d databases) Close() error {
+	var errs []error
+	for _, db := range []*sql.DB{d.primary, d.replica} {
+		if db != nil {
+			errs = append(errs, db.Close())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// healthChecks pings the databases and makes sure the schema has every
+// embedded migration, there is nothing to check for the memory driver.
+func (d databases) healthChecks() ([]rest.HealthCheck, error) {
+	if d.primary == nil {
+		return nil, nil
+	}
+
+	migrator, err := migration.New(d.primary, string(d.dialect), time.Now)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := []rest.HealthCheck{
+		{Name: "db", Check: d.primary.PingContext},
+		{Name: "migrations", Check: migrator.Check},
+	}
+	if d.replica != nil {
+		checks = append(checks, rest.HealthCheck{Name: "db_replica", Check: d.replica.PingContext})
+	}
+
+	return checks, nil
+}
+
+// userRepository is implemented by the repository of every driver.
+type userRepository interface {
+	service.UserRepository
+	service.SweeperRepository
+}
+
+// newUserRepository returns the repository of config.DBDriver and the
+// databases it is connected to.
+func newUserRepository(ctx context.Context, cfg *config.Config,
+	otpHasher *otphash.Hasher) (userRepository, databases, error) {
+	deps := repository.Dependencies{
+		OTPHasher: otpHasher,
+		NowFunc:   time.Now,
+
+		LockoutBaseDuration: cfg.OTP.Lockout.BaseDuration,
+		LockoutMaxDuration:  cfg.OTP.Lockout.MaxDuration,
+	}
+
+	if cfg.DB.Driver == "memory" {
+		memory := repository.NewMemory(deps)
+		for _, uc := range cfg.DB.Memory.Users {
+			if _, err := memory.CreateUser(ctx, entity.User{
+				UUID:       uc.UUID,
+				Name:       uc.Name,
+				Email:      uc.Email,
+				Phone:      uc.Phone,
+				OTPChannel: uc.OTPChannel,
+			}); err != nil {
+				return nil, databases{}, fmt.Errorf("memory user %s: %w", uc.UUID, err)
+			}
+		}
+
+		return memory, databases{}, nil
+	}
+
+	var (
+		dbs databases
+		err error
+	)
+	if dbs.primary, dbs.dialect, err = OpenDB(ctx, cfg.DB); err != nil {
+		return nil, databases{}, err
+	}
+
+	if err := checkSchema(ctx, dbs.primary, dbs.dialect, cfg.DB.RequireCurrentSchema); err != nil {
+		dbs.Close()
+
+		return nil, databases{}, err
+	}
+
+	if dbs.replica, err = openReplica(ctx, cfg.DB); err != nil {
+		dbs.Close()
+
+		return nil, databases{}, fmt.Errorf("read replica: %w", err)
+	}
+
+	deps.DB = dbs.primary
+	deps.ReplicaDB = dbs.replica
+	deps.Dialect = dbs.dialect
+
+	return repository.NewUser(deps), dbs, nil
+}
+
+// OpenDB connects to the primary SQL database configured by cfg.Driver.
+func OpenDB(ctx context.Context, cfg config.DB) (*sql.DB, repository.Dialect, error) {
+	dialect, err := repository.ParseDialect(cfg.Driver)
+	if err != nil {
+		return nil, "", err
+	}
+
+	db, err := openDB(ctx, cfg, cfg.DSN, cfg.Address)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return db, dialect, nil
+}
+
+// openReplica connects to the read replica, it returns nil when there is none.
+func openReplica(ctx context.Context, cfg config.DB) (*sql.DB, error) {
+	if cfg.Replica.DSN == "" && cfg.Replica.Address == "" {
+		return nil, nil
+	}
+
+	return openDB(ctx, cfg, cfg.Replica.DSN, cfg.Replica.Address)
+}
+
+// openDB connects with dsn as is, or with a mysql DSN built from cfg for
+// address when dsn is empty.
+func openDB(ctx context.Context, cfg config.DB, dsn, address string) (*sql.DB, error) {
+	var db *sql.DB
+	if dsn == "" && cfg.Driver == string(repository.DialectMySQL) {
+		connector, err := mysqlConnector(cfg, address)
+		if err != nil {
+			return nil, err
+		}
+
+		db = sql.OpenDB(connector)
+	} else {
+		var err error
+		if db, err = sql.Open(cfg.Driver, dsn); err != nil {
+			return nil, err
+		}
+	}
+
+	db.SetMaxOpenConns(cfg.Pool.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Pool.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.Pool.ConnMaxIdleTime)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func mysqlConnector(cfg config.DB, address string) (driver.Connector, error) {
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	mc := mysql.NewConfig()
+	mc.User = cfg.Username
+	mc.Passwd = cfg.Password
+	mc.Net = "tcp"
+	mc.Addr = address
+	mc.DBName = cfg.Name
+	mc.ParseTime = true
+	mc.Loc = loc
+	mc.Timeout = cfg.Timeout.Dial
+	mc.ReadTimeout = cfg.Timeout.Read
+	mc.WriteTimeout = cfg.Timeout.Write
+	mc.Collation = cfg.Collation
+	if cfg.Charset != "" {
+		mc.Params = map[string]string{"charset": cfg.Charset}
+	}
+
+	switch cfg.TLS.Mode {
+	case "preferred", "skip-verify":
+		mc.TLSConfig = cfg.TLS.Mode
+	case "verify":
+		if mc.TLS, err = mysqlTLSConfig(cfg.TLS, address); err != nil {
+			return nil, err
+		}
+	}
+
+	return mysql.NewConnector(mc)
+}
+
+// mysqlTLSConfig verifies the server against the CA file, or the system roots
+// without one, and presents the client certificate when there is one.
+func mysqlTLSConfig(cfg config.DBTLS, address string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: cfg.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if tlsConfig.ServerName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+
+		tlsConfig.ServerName = host
+	}
+
+	if cfg.CAFile != "" {
+		pemData, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pemData) {
+			return nil, fmt.Errorf("no certificate found in %s", cfg.CAFile)
+		}
+	}
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// checkSchema refuses to start with a schema that is behind the embedded
+// migrations when requireCurrent is set, otherwise it only warns.
+func checkSchema(ctx context.Context, db *sql.DB, dialect repository.Dialect, requireCurrent bool) error {
+	migrator, err := migration.New(db, string(dialect), time.Now)
+	if err != nil {
+		return err
+	}
+
+	err = migrator.Check(ctx)
+	if !errors.Is(err, migration.ErrSchemaBehind) && !errors.Is(err, migration.ErrChecksumMismatch) {
+		return err
+	}
+
+	if requireCurrent {
+		return fmt.Errorf("%w, run the migrate command first", err)
+	}
+
+	log.Warn("database schema is not up to date", zap.Error(err))
+
+	return nil
+}
@@ -2,22 +2,34 @@ package app
 
 import (
 	"context"
-	"database/sql"
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-playground/validator/v10"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
-	"github.com/spf13/viper"
+	"github.com/redis/go-redis/v9"
+	"github.com/skip2/go-qrcode"
 	"github.com/subroll/sqetest/internal/delivery/rest"
 	"github.com/subroll/sqetest/internal/pkg/config"
+	"github.com/subroll/sqetest/internal/pkg/lifecycle"
 	"github.com/subroll/sqetest/internal/pkg/log"
+	"github.com/subroll/sqetest/internal/pkg/metrics"
+	"github.com/subroll/sqetest/internal/pkg/otphash"
+	"github.com/subroll/sqetest/internal/pkg/ratelimit"
+	"github.com/subroll/sqetest/internal/pkg/secretbox"
 	"github.com/subroll/sqetest/internal/pkg/stringutil"
-	"github.com/subroll/sqetest/internal/repository"
+	"github.com/subroll/sqetest/internal/pkg/token"
+	"github.com/subroll/sqetest/internal/pkg/totp"
+	"github.com/subroll/sqetest/internal/sender"
 	"github.com/subroll/sqetest/internal/service"
 	"go.uber.org/zap"
 )
@@ -28,16 +40,42 @@ type (
 	}
 
 	HTTPServer struct {
+		cfg    *config.Config
 		server *echo.Echo
 		v      *validator.Validate
-		db     *sql.DB
+		dbs    databases
+		redis  *redis.Client
 
-		pingHandler echo.HandlerFunc
+		// admin serves /metrics and the /admin endpoints apart from the API,
+		// adminAPI routes them
+		admin    *http.Server
+		adminAPI *echo.Echo
+
+		health      *rest.Health
+		jwksHandler echo.HandlerFunc
 		userHandler *rest.User
 
 		userSvc *service.User
-
-		userRepo *repository.User
+		sweeper *service.Sweeper
+
+		userRepo    service.UserRepository
+		sweeperRepo service.SweeperRepository
+
+		otpHasher       *otphash.Hasher
+		secretBox       *secretbox.Box
+		senders         map[string]service.Sender
+		rateLimiter     ratelimit.Limiter
+		otpRequestRules atomic.Pointer[[]rest.RateLimitRule]
+		tokens          *token.Issuer
+		metrics         *metrics.Metrics
+		healthChecks    []rest.HealthCheck
+
+		// deliveries counts the OTPs being sent, Stop waits for them
+		deliveries sync.WaitGroup
+		lifecycle  lifecycle.Manager
+
+		// restartRequired are the changed keys the last reload ignored
+		restartRequired []string
 	}
 )
 
@@ -49,7 +87,15 @@ func (rv *reqValidator) Validate(i interface{}) error {
 }
 
 func (hs *HTTPServer) Start() error {
-	err := hs.server.Start(viper.GetString(config.HTTPPort))
+	if hs.admin != nil {
+		go func() {
+			if err := hs.admin.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("admin server error", zap.Error(err))
+			}
+		}()
+	}
+
+	err := hs.server.Start(hs.cfg.HTTP.Port)
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
@@ -57,22 +103,165 @@ func (hs *HTTPServer) Start() error {
 	return nil
 }
 
+// Stop shuts the server down in the order of the lifecycle: /readyz fails
+// for the drain delay, the HTTP servers finish the requests in flight, the
+// sweeper stops, the deliveries still running are waited for and the
+// connections are closed last.
+// Every step is given a chance even if an earlier one fails or ctx is done.
 func (hs *HTTPServer) Stop(ctx context.Context) error {
-	if err := hs.server.Shutdown(ctx); err != nil {
-		return err
+	return hs.lifecycle.Stop(ctx)
+}
+
+func (hs *HTTPServer) addStopHooks() {
+	hs.lifecycle.Add("drain", func(ctx context.Context) error {
+		hs.health.Drain()
+
+		timer := time.NewTimer(hs.cfg.HTTP.DrainDelay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	hs.lifecycle.Add("http", func(ctx context.Context) error {
+		return shutdown(ctx, hs.server)
+	})
+	if hs.admin != nil {
+		hs.lifecycle.Add("admin", func(ctx context.Context) error {
+			return shutdown(ctx, hs.admin)
+		})
+	}
+	if hs.sweeper != nil {
+		hs.lifecycle.Go("sweeper", hs.sweep)
+	}
+	hs.lifecycle.Add("deliveries", func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			hs.deliveries.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	if hs.redis != nil {
+		hs.lifecycle.Add("redis", func(context.Context) error {
+			return hs.redis.Close()
+		})
 	}
+	hs.lifecycle.Add("db", func(context.Context) error {
+		return hs.dbs.Close()
+	})
+}
+
+// shutdowner is what *echo.Echo and *http.Server have in common.
+type shutdowner interface {
+	Shutdown(ctx context.Context) error
+	Close() error
+}
+
+// shutdown closes srv gracefully, the connections still open when ctx is done
+// are closed forcibly.
+func shutdown(ctx context.Context, srv shutdowner) error {
+	if err := srv.Shutdown(ctx); err != nil {
+		_ = srv.Close()
 
-	if err := hs.db.Close(); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// Reload applies the log level, OTP policies and OTP request rate limits of
+// next while the server keeps running. Every other changed key is ignored, it
+// needs a restart, and logged when the set of such keys changes.
+func (hs *HTTPServer) Reload(next *config.Config) {
+	if keys := hs.cfg.RestartRequired(next); !slices.Equal(keys, hs.restartRequired) {
+		if len(keys) > 0 {
+			log.Warn("config changes need a restart, ignoring them", zap.Strings("keys", keys))
+		}
+		hs.restartRequired = keys
+	}
+
+	// a reload leaves a level changed through the admin endpoint alone
+	// unless it changes the level too
+	if next.Log.Level != hs.cfg.Log.Level {
+		// config.Reload has made sure the level exists
+		_ = log.SetLevel(next.Log.Level)
+	}
+	hs.userSvc.SetPolicies(makePolicies(next.OTP.Policies), next.OTP.DefaultPurpose)
+	rules := makeOTPRequestRules(next.RateLimit.OTPRequest)
+	hs.otpRequestRules.Store(&rules)
+
+	// only reloads touch these fields after the server started, so they are
+	// updated in place
+	hs.cfg.Log.Level = next.Log.Level
+	hs.cfg.OTP.Policies = next.OTP.Policies
+	hs.cfg.OTP.DefaultPurpose = next.OTP.DefaultPurpose
+	hs.cfg.RateLimit.OTPRequest = next.RateLimit.OTPRequest
+
+	log.Info("config reloaded")
+}
+
 func (hs *HTTPServer) route() {
-	hs.server.Use(middleware.RequestID())
-	hs.server.Use(middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
+	hs.use(hs.server)
+
+	otpRequestLimit := rest.RateLimitFunc(hs.rateLimiter, func() []rest.RateLimitRule {
+		return *hs.otpRequestRules.Load()
+	})
+
+	hs.server.GET("/healthz", hs.health.Live)
+	hs.server.GET("/readyz", hs.health.Ready)
+	hs.server.POST("/otp/request", hs.userHandler.RequestOTP, otpRequestLimit)
+	hs.server.POST("/otp/resend", hs.userHandler.ResendOTP, otpRequestLimit)
+	hs.server.POST("/otp/validate", hs.userHandler.ValidateOTP)
+	requireAccessToken := rest.RequireAccessToken(hs.tokens)
+	hs.server.POST("/totp/enroll", hs.userHandler.EnrollTOTP, requireAccessToken)
+	hs.server.POST("/totp/confirm", hs.userHandler.ConfirmTOTP, requireAccessToken)
+	hs.server.POST("/token/refresh", hs.userHandler.RefreshToken)
+	hs.server.GET("/.well-known/jwks.json", hs.jwksHandler)
+
+	if hs.adminAPI == nil {
+		return
+	}
+
+	hs.use(hs.adminAPI)
+	hs.adminAPI.GET("/metrics", echo.WrapHandler(hs.metrics.Handler()))
+
+	// the admin endpoints are never reachable from the API port, the token
+	// still guards them on the admin one
+	if hs.cfg.Admin.Token != "" {
+		admin := hs.adminAPI.Group("/admin", middleware.KeyAuth(func(key string, _ echo.Context) (bool, error) {
+			return subtle.ConstantTimeCompare([]byte(key), []byte(hs.cfg.Admin.Token)) == 1, nil
+		}))
+		admin.Match([]string{http.MethodGet, http.MethodPut}, "/log/level", echo.WrapHandler(log.LevelHandler()))
+		admin.POST("/users", hs.userHandler.CreateUser)
+		admin.GET("/users", hs.userHandler.ListUsers)
+		admin.GET("/users/:user_id", hs.userHandler.GetUser)
+		admin.PUT("/users/:user_id", hs.userHandler.UpdateUser)
+		admin.POST("/users/:user_id/disable", hs.userHandler.DisableUser)
+		admin.DELETE("/users/:user_id", hs.userHandler.DeleteUser)
+	}
+}
+
+// use adds the middlewares every request to e goes through.
+func (hs *HTTPServer) use(e *echo.Echo) {
+	e.Use(middleware.RequestID())
+	e.Use(middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
+		// scrapes would drown the access log
+		Skipper: func(c echo.Context) bool {
+			return c.Path() == "/metrics"
+		},
 		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
+			hs.metrics.ObserveHTTPRequest(v.Method, v.RoutePath, v.Status, v.Latency)
+
 			var logFn func(string, ...zap.Field)
 
 			switch {
@@ -94,6 +283,7 @@ func (hs *HTTPServer) route() {
 				zap.String("uri_path", v.URIPath),
 				zap.String("route_path", v.RoutePath),
 				zap.String("request_id", v.RequestID),
+				zap.String("trace_id", log.TraceID(c.Request().Context())),
 				zap.String("referer", v.Referer),
 				zap.String("user_agent", v.UserAgent),
 				zap.Int("status", v.Status),
@@ -119,15 +309,14 @@ func (hs *HTTPServer) route() {
 		LogContentLength: true,
 		LogResponseSize:  true,
 	}))
-	hs.server.Use(middleware.Recover())
-
-	hs.server.GET("/ping", hs.pingHandler)
-	hs.server.POST("/otp/request", hs.userHandler.RequestOTP)
-	hs.server.POST("/otp/validate", hs.userHandler.ValidateOTP)
+	e.Use(middleware.Recover())
+	e.Use(rest.Trace)
+	e.Use(rest.LogContext)
 }
 
 func (hs *HTTPServer) makeHandler() {
-	hs.pingHandler = rest.Ping
+	hs.health = rest.NewHealth(hs.cfg.HTTP.ReadyTimeout, hs.healthChecks...)
+	hs.jwksHandler = rest.JWKS(hs.tokens)
 
 	deps := rest.Dependencies{
 		User: hs.userSvc,
@@ -139,59 +328,379 @@ func (hs *HTTPServer) makeHandler() {
 func (hs *HTTPServer) makeService() {
 	deps := service.Dependencies{
 		User:                hs.userRepo,
-		RandNumberGenerator: stringutil.RandomNumbers,
+		RandStringGenerator: stringutil.RandomString,
+		Policies:            makePolicies(hs.cfg.OTP.Policies),
+		DefaultPurpose:      hs.cfg.OTP.DefaultPurpose,
+		Senders:             hs.senders,
+		DefaultChannel:      hs.cfg.Delivery.DefaultChannel,
+
+		TOTP: totp.Params{
+			Digits: hs.cfg.TOTP.Digits,
+			Period: hs.cfg.TOTP.Period,
+			Skew:   hs.cfg.TOTP.Skew,
+		},
+		TOTPIssuer:          hs.cfg.TOTP.Issuer,
+		TOTPMaxAttempts:     hs.cfg.TOTP.MaxAttempts,
+		TOTPSecretGenerator: totp.NewSecret,
+		SecretBox:           hs.secretBox,
+		QRCodeEncoder: func(content string) ([]byte, error) {
+			return qrcode.Encode(content, qrcode.Medium, 256)
+		},
+		NowFunc: time.Now,
+
+		Tokens:                hs.tokens,
+		RefreshTokenGenerator: token.NewRefreshToken,
+		ChallengeIDGenerator:  token.NewChallengeID,
+		RefreshTokenTTL:       hs.cfg.Token.RefreshTTL,
+
+		UUIDGenerator: uuid.NewString,
+
+		BindFingerprint: hs.cfg.OTP.Binding.Fingerprint,
+		BindIP:          hs.cfg.OTP.Binding.IP,
+
+		OTPEvents: hs.metrics.OTPEvent,
 	}
 
 	hs.userSvc = service.NewUser(deps)
+
+	if hs.cfg.OTP.Sweeper.Enabled {
+		host, err := os.Hostname()
+		if err != nil {
+			host = "unknown"
+		}
+
+		hs.sweeper = service.NewSweeper(service.SweeperDependencies{
+			Repository: hs.sweeperRepo,
+			Holder:     leaseHolder(host),
+			// the lease outlives a missed sweep so a slow one doesn't hand it over
+			LeaseTTL:  2 * hs.cfg.OTP.Sweeper.Interval,
+			Retention: hs.cfg.OTP.Sweeper.Retention,
+			Archive:   hs.cfg.OTP.Sweeper.Archive,
+			BatchSize: hs.cfg.OTP.Sweeper.BatchSize,
+			NowFunc:   time.Now,
+		})
+	}
 }
 
-func (hs *HTTPServer) makeRepository() {
-	deps := repository.Dependencies{
-		DB:      hs.db,
-		NowFunc: time.Now,
+// maxLeaseHolderLength is the width of the leases.holder column.
+const maxLeaseHolderLength = 64
+
+// leaseHolder identifies this process on host among the replicas competing for
+// a lease. The uuid keeps it unique, so a host name too long for the column is
+// cut short.
+func leaseHolder(host string) string {
+	id := uuid.NewString()
+	if maxHost := maxLeaseHolderLength - len(id) - 1; len(host) > maxHost {
+		host = host[:maxHost]
 	}
 
-	hs.userRepo = repository.NewUser(deps)
+	return host + "/" + id
 }
 
-func NewHTTPServer() (*HTTPServer, error) {
-	ctx := context.TODO()
-	if err := config.Load(); err != nil {
-		return nil, err
+// sweep runs the sweeper every interval until ctx is done.
+func (hs *HTTPServer) sweep(ctx context.Context) {
+	ticker := time.NewTicker(hs.cfg.OTP.Sweeper.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		res, err := hs.sweeper.Sweep(ctx)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				log.Error("fail to sweep otps", zap.Error(err))
+			}
+
+			continue
+		}
+
+		if res.Expired > 0 || res.Removed > 0 {
+			log.Info("otps swept",
+				zap.Int64("expired", res.Expired),
+				zap.Int64("removed", res.Removed),
+				zap.String("latency", time.Since(start).String()))
+		}
 	}
+}
 
-	v := validator.New()
+func makePolicies(pcs map[string]config.OTPPolicy) map[string]service.Policy {
+	policies := make(map[string]service.Policy, len(pcs))
+	for purpose, pc := range pcs {
+		// config.Load has made sure the charset exists
+		charset, _ := stringutil.Charset(pc.Charset)
+
+		policies[purpose] = service.Policy{
+			Length:         pc.Length,
+			Charset:        charset,
+			TTL:            pc.TTL,
+			MaxAttempts:    pc.MaxAttempts,
+			ResendCooldown: pc.ResendCooldown,
+			MaxResends:     pc.MaxResends,
+		}
+	}
+
+	return policies
+}
+
+func (hs *HTTPServer) makeTokenIssuer() error {
+	keys := make([]token.Key, 0, len(hs.cfg.Token.Keys))
+	for kid, kc := range hs.cfg.Token.Keys {
+		var (
+			key token.Key
+			err error
+		)
+		switch kc.Algorithm {
+		case token.AlgorithmHS256:
+			key, err = token.NewHMACKey(kid, []byte(kc.Secret))
+		case token.AlgorithmRS256, token.AlgorithmEdDSA:
+			var pemData []byte
+			if pemData, err = os.ReadFile(kc.PrivateKeyFile); err != nil {
+				return err
+			}
+
+			key, err = token.ParsePrivateKey(kid, kc.Algorithm, pemData)
+		default:
+			err = fmt.Errorf("unknown algorithm %q for token key: %s", kc.Algorithm, kid)
+		}
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, key)
+	}
+
+	tokens, err := token.NewIssuer(token.Config{
+		Issuer:       hs.cfg.Token.Issuer,
+		Audience:     hs.cfg.Token.Audience,
+		TTL:          hs.cfg.Token.AccessTTL,
+		Keys:         keys,
+		CurrentKeyID: hs.cfg.Token.CurrentKey,
+		NowFunc:      time.Now,
+	})
+	if err != nil {
+		return err
+	}
+
+	hs.tokens = tokens
+
+	return nil
+}
+
+func (hs *HTTPServer) makeRateLimiter(ctx context.Context) error {
+	switch backend := hs.cfg.RateLimit.Backend; backend {
+	case "memory":
+		hs.rateLimiter = ratelimit.NewMemory(time.Now)
+	case "redis":
+		hs.redis = redis.NewClient(&redis.Options{
+			Addr:     hs.cfg.RateLimit.Redis.Address,
+			Password: hs.cfg.RateLimit.Redis.Password,
+			DB:       hs.cfg.RateLimit.Redis.DB,
+		})
+		if err := hs.redis.Ping(ctx).Err(); err != nil {
+			return err
+		}
+		hs.healthChecks = append(hs.healthChecks, rest.HealthCheck{
+			Name: "ratelimit",
+			Check: func(ctx context.Context) error {
+				return hs.redis.Ping(ctx).Err()
+			},
+		})
+
+		hs.rateLimiter = ratelimit.NewRedis(hs.redis, "sqetest:ratelimit:", time.Now)
+	default:
+		return fmt.Errorf("unknown rate limit backend: %s", backend)
+	}
+
+	return nil
+}
+
+func makeOTPRequestRules(rates config.RateLimitOTPRequest) []rest.RateLimitRule {
+	return []rest.RateLimitRule{
+		{Name: "otp_request:user", Rate: rateFromConfig(rates.User), Key: rest.UserKey},
+		{Name: "otp_request:ip", Rate: rateFromConfig(rates.IP), Key: rest.IPKey},
+		{Name: "otp_request:global", Rate: rateFromConfig(rates.Global), Key: rest.GlobalKey},
+	}
+}
+
+func rateFromConfig(rc config.Rate) ratelimit.Rate {
+	return ratelimit.Rate{
+		Limit:  rc.Limit,
+		Period: rc.Period,
+		Burst:  rc.Burst,
+	}
+}
+
+func (hs *HTTPServer) makeSenders() error {
+	delivery := hs.cfg.Delivery
+	client := &http.Client{Timeout: delivery.Timeout}
+	hs.senders = make(map[string]service.Sender)
+
+	if delivery.Email.Address != "" {
+		email, err := sender.NewEmail(sender.EmailConfig{
+			Address:  delivery.Email.Address,
+			Username: delivery.Email.Username,
+			Password: delivery.Email.Password,
+			From:     delivery.Email.From,
+			Subject:  delivery.Email.Subject,
+			Timeout:  delivery.Timeout,
+		})
+		if err != nil {
+			return err
+		}
+
+		hs.senders[service.ChannelEmail] = email
+	}
+
+	if delivery.SMS.URL != "" {
+		hs.senders[service.ChannelSMS] = sender.NewSMS(sender.SMSConfig{
+			URL:   delivery.SMS.URL,
+			Token: delivery.SMS.Token,
+			From:  delivery.SMS.From,
+		}, client)
+	}
+
+	if delivery.Webhook.URL != "" {
+		hs.senders[service.ChannelWebhook] = sender.NewWebhook(sender.WebhookConfig{
+			URL:    delivery.Webhook.URL,
+			Secret: delivery.Webhook.Secret,
+		}, client)
+	}
+
+	for channel, s := range hs.senders {
+		if p, ok := s.(pinger); ok {
+			hs.healthChecks = append(hs.healthChecks, rest.HealthCheck{Name: "sender:" + channel, Check: p.Ping})
+		}
+		hs.senders[channel] = hs.observeSender(channel, s)
+	}
+
+	return nil
+}
+
+// pinger is implemented by the senders that can tell whether their provider is
+// reachable without delivering anything.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// observeSender records how long s takes to deliver an OTP and keeps track of
+// the deliveries in flight.
+func (hs *HTTPServer) observeSender(channel string, s service.Sender) service.Sender {
+	return service.SenderFunc(func(ctx context.Context, msg service.Message) (string, error) {
+		hs.deliveries.Add(1)
+		defer hs.deliveries.Done()
+
+		start := time.Now()
+		ref, err := s.Send(ctx, msg)
+		hs.metrics.ObserveDelivery(channel, time.Since(start), err)
+
+		return ref, err
+	})
+}
+
+func (hs *HTTPServer) makeRepository(ctx context.Context) error {
+	userRepo, dbs, err := newUserRepository(ctx, hs.cfg, hs.otpHasher)
+	if err != nil {
+		return err
+	}
+
+	hs.userRepo = userRepo
+	hs.sweeperRepo = userRepo
+	hs.dbs = dbs
+
+	checks, err := dbs.healthChecks()
+	if err != nil {
+		return err
+	}
+	hs.healthChecks = append(hs.healthChecks, checks...)
+
+	if dbs.primary != nil {
+		if err := hs.metrics.RegisterDB("primary", dbs.primary); err != nil {
+			return err
+		}
+	}
+
+	if dbs.replica != nil {
+		if err := hs.metrics.RegisterDB("replica", dbs.replica); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newEcho(v *validator.Validate) *echo.Echo {
 	e := echo.New()
 	e.HideBanner = true
 	e.Validator = &reqValidator{v: v}
+	e.HTTPErrorHandler = rest.ErrorHandler
+
+	return e
+}
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true&loc=Local",
-		viper.GetString(config.DBUsername),
-		viper.GetString(config.DBPassword),
-		viper.GetString(config.DBAddress),
-		viper.GetString(config.DBName))
-	db, err := sql.Open("mysql", dsn)
+func NewHTTPServer(cfg *config.Config) (*HTTPServer, error) {
+	ctx := context.TODO()
+	otpHasher, err := otphash.NewHasher(cfg.OTP.Pepper.Current, cfg.OTP.Pepper.Keys)
 	if err != nil {
 		return nil, err
 	}
-	db.SetConnMaxLifetime(10 * time.Second)
-	db.SetMaxIdleConns(50)
-	db.SetMaxOpenConns(50)
 
-	if err := db.PingContext(ctx); err != nil {
+	secretBox, err := secretbox.New(cfg.TOTP.EncryptionKey)
+	if err != nil {
 		return nil, err
 	}
 
+	v := validator.New()
+	e := newEcho(v)
+	// only trust X-Forwarded-For from private networks, otherwise clients could
+	// dodge the per IP rate limit by sending their own header
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
+
 	hs := &HTTPServer{
-		server: e,
-		v:      v,
-		db:     db,
+		cfg:       cfg,
+		server:    e,
+		v:         v,
+		otpHasher: otpHasher,
+		secretBox: secretBox,
+		metrics:   metrics.New(),
+	}
+
+	if cfg.Admin.Address != "" {
+		hs.adminAPI = newEcho(v)
+		hs.admin = &http.Server{
+			Addr:              cfg.Admin.Address,
+			Handler:           hs.adminAPI,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+	}
+
+	if err := hs.makeRateLimiter(ctx); err != nil {
+		return nil, err
+	}
+
+	rules := makeOTPRequestRules(cfg.RateLimit.OTPRequest)
+	hs.otpRequestRules.Store(&rules)
+
+	if err := hs.makeSenders(); err != nil {
+		return nil, err
+	}
+
+	if err := hs.makeTokenIssuer(); err != nil {
+		return nil, err
+	}
+
+	if err := hs.makeRepository(ctx); err != nil {
+		return nil, err
 	}
 
-	hs.makeRepository()
 	hs.makeService()
 	hs.makeHandler()
 	hs.route()
+	hs.addStopHooks()
 
 	return hs, nil
 }
@@ -0,0 +1,21 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaseHolder(t *testing.T) {
+	t.Parallel()
+
+	holder := leaseHolder("otp-service")
+	assert.True(t, strings.HasPrefix(holder, "otp-service/"))
+	assert.NotEqual(t, holder, leaseHolder("otp-service"))
+
+	// a Kubernetes pod name is often longer than the column leaves for it
+	holder = leaseHolder("otp-service-deployment-5d8f7c9b6d-x2k4q")
+	assert.Len(t, holder, maxLeaseHolderLength)
+	assert.True(t, strings.HasPrefix(holder, "otp-service-deployment-5d8f/"))
+}
@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/subroll/sqetest/internal/app"
+	"github.com/subroll/sqetest/internal/migration"
+	"github.com/subroll/sqetest/internal/pkg/config"
+)
+
+func newMigrateCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply or revert database migrations",
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "up",
+			Short: "Apply every pending migration",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				return withMigrator(cmd.Context(), cfg, func(m *migration.Migrator) error {
+					ran, err := m.Up(cmd.Context())
+
+					return printMigrations(cmd.OutOrStdout(), m.Latest(), ran, err)
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "down",
+			Short: "Revert the last applied migration",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				return withMigrator(cmd.Context(), cfg, func(m *migration.Migrator) error {
+					ran, err := m.Down(cmd.Context())
+
+					return printMigrations(cmd.OutOrStdout(), 0, ran, err)
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "to <version>",
+			Short: "Apply or revert migrations until version is the last applied, 0 reverts everything",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				target, err := strconv.ParseUint(args[0], 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid migration version: %s", args[0])
+				}
+
+				return withMigrator(cmd.Context(), cfg, func(m *migration.Migrator) error {
+					ran, err := m.To(cmd.Context(), target)
+
+					return printMigrations(cmd.OutOrStdout(), target, ran, err)
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "List the migrations and when they were applied",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				return withMigrator(cmd.Context(), cfg, func(m *migration.Migrator) error {
+					return printMigrationStatus(cmd.Context(), m, cmd.OutOrStdout())
+				})
+			},
+		},
+	)
+
+	return cmd
+}
+
+// printMigrations prints the migrations that ran on the way to the target
+// version and passes err through, those above the target were reverted.
+func printMigrations(out io.Writer, target uint64, ran []migration.Migration, err error) error {
+	for _, m := range ran {
+		action := "reverted"
+		if m.Version <= target {
+			action = "applied"
+		}
+
+		fmt.Fprintf(out, "%s %d_%s\n", action, m.Version, m.Name)
+	}
+	if err == nil && len(ran) == 0 {
+		fmt.Fprintln(out, "nothing to migrate")
+	}
+
+	return err
+}
+
+func withMigrator(ctx context.Context, cfg *config.Config, fn func(*migration.Migrator) error) error {
+	if cfg.DB.Driver == "memory" {
+		return errors.New("the memory driver has no schema to migrate")
+	}
+
+	db, dialect, err := app.OpenDB(ctx, cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migration.New(db, string(dialect), time.Now)
+	if err != nil {
+		return err
+	}
+
+	return fn(migrator)
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migration.Migrator, out io.Writer) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "pending"
+		if s.Applied {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	// pending migrations are listed above, only a changed one is an error
+	if err := migrator.Check(ctx); !errors.Is(err, migration.ErrSchemaBehind) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/subroll/sqetest/internal/app"
+	"github.com/subroll/sqetest/internal/pkg/config"
+	"github.com/subroll/sqetest/internal/service"
+)
+
+func newOTPCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "otp",
+		Short: "Inspect and revoke a user's OTPs for support tickets",
+	}
+
+	cmd.AddCommand(
+		newOTPInspectCommand(cfg),
+		newOTPUpdateCommand(cfg, "revoke", "Invalidate the user's active OTP so the code can't be used",
+			"revoked", (*service.User).RevokeOTP),
+		newOTPUpdateCommand(cfg, "expire", "Expire the user's active OTP so a new one can be requested right away",
+			"expired", (*service.User).ExpireOTP),
+	)
+
+	return cmd
+}
+
+func newOTPInspectCommand(cfg *config.Config) *cobra.Command {
+	var limit uint
+
+	cmd := &cobra.Command{
+		Use:   "inspect <user-uuid>",
+		Short: "List the user's latest OTPs, newest first",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			admin, err := app.NewAdmin(cmd.Context(), cfg)
+			if err != nil {
+				return err
+			}
+			defer admin.Close()
+
+			otps, err := admin.User.InspectOTPs(cmd.Context(), args[0], limit)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tPURPOSE\tREQUEST ID\tSTATUS\tATTEMPTS\tRESENDS\tLAST SENT AT\tEXPIRES AT")
+			for _, otp := range otps {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d/%d\t%d\t%s\t%s\n", otp.ID, otp.Purpose, orDash(otp.RequestID),
+					otp.Status, otp.Attempts, otp.MaxAttempts, otp.ResendCount,
+					otp.LastSentAt.Format(time.RFC3339), otp.ExpiredAt.Format(time.RFC3339))
+			}
+
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().UintVar(&limit, "limit", service.DefaultListLimit,
+		fmt.Sprintf("number of OTPs to list, at most %d", service.MaxListLimit))
+
+	return cmd
+}
+
+func newOTPUpdateCommand(cfg *config.Config, use, short, done string,
+	update func(*service.User, context.Context, string, string) error) *cobra.Command {
+	var purpose string
+
+	cmd := &cobra.Command{
+		Use:   use + " <user-uuid>",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			admin, err := app.NewAdmin(cmd.Context(), cfg)
+			if err != nil {
+				return err
+			}
+			defer admin.Close()
+
+			if err := update(admin.User, cmd.Context(), args[0], purpose); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%s the active OTP of %s\n", done, args[0])
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&purpose, "purpose", "", "purpose of the OTP, the default purpose when empty")
+
+	return cmd
+}
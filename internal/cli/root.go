@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/subroll/sqetest/internal/pkg/config"
+	"github.com/subroll/sqetest/internal/pkg/log"
+)
+
+// Execute runs the command named by the arguments, the server is started when
+// none is given.
+func Execute(ctx context.Context) error {
+	// stderr can't be synced on every platform, there is nothing to do about it
+	defer func() { _ = log.Sync() }()
+
+	return newRootCommand().ExecuteContext(ctx)
+}
+
+func newRootCommand() *cobra.Command {
+	var (
+		cfg        config.Config
+		configPath string
+	)
+
+	serve := newServeCommand(&cfg)
+
+	root := &cobra.Command{
+		Use:          "sqetest",
+		Short:        "OTP authentication service",
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE:         serve.RunE,
+		PersistentPreRunE: func(*cobra.Command, []string) error {
+			loaded, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+
+			cfg = *loaded
+
+			return log.Configure(logConfig(cfg.Log))
+		},
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVarP(&configPath, "config", "c", "",
+		"config file, JSON, YAML or TOML, defaults to config.json, config.yaml or config.toml")
+	flags.String("db-driver", "", "database driver: mysql, postgres, sqlite3 or memory")
+	flags.String("db-dsn", "", "database DSN, passed to the driver as is")
+	bindFlags(root, map[string]string{
+		config.DBDriver: "db-driver",
+		config.DBDSN:    "db-dsn",
+	})
+
+	root.AddCommand(
+		serve,
+		newMigrateCommand(&cfg),
+		newSeedCommand(&cfg),
+		newUserCommand(&cfg),
+		newOTPCommand(&cfg),
+	)
+
+	return root
+}
+
+// bindFlags makes the flags override the config keys they are mapped from,
+// a flag only takes precedence once it is set.
+func bindFlags(cmd *cobra.Command, flags map[string]string) {
+	for key, name := range flags {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil {
+			flag = cmd.PersistentFlags().Lookup(name)
+		}
+
+		if err := viper.BindPFlag(key, flag); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func logConfig(lc config.Log) log.Config {
+	return log.Config{
+		Level:    lc.Level,
+		Encoding: lc.Encoding,
+		Output:   lc.Output,
+		File: log.File{
+			Path:       lc.File.Path,
+			MaxSize:    lc.File.MaxSize,
+			MaxBackups: lc.File.MaxBackups,
+			MaxAge:     lc.File.MaxAge,
+			Compress:   lc.File.Compress,
+		},
+		Sampling: log.Sampling{
+			Initial:    lc.Sampling.Initial,
+			Thereafter: lc.Sampling.Thereafter,
+		},
+	}
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/subroll/sqetest/internal/app"
+	"github.com/subroll/sqetest/internal/entity"
+	"github.com/subroll/sqetest/internal/pkg/config"
+	"github.com/subroll/sqetest/internal/repository"
+)
+
+// seedUser is an entry of a seed file, it has the same fields as the users
+// of the memory driver.
+type seedUser struct {
+	UUID       string `json:"uuid"`
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+	Phone      string `json:"phone"`
+	OTPChannel string `json:"otp_channel"`
+}
+
+// fixtureUsers are the development users of the former sql/sqetest.sql dump.
+var fixtureUsers = []seedUser{
+	{UUID: "ac304b86-1437-43bc-a7a9-239c262c2e17", Name: "Robert", Email: "robert@example.com",
+		Phone: "+15550000001"},
+	{UUID: "ead5f356-ad40-4b71-bc1f-f015cf2dbf26", Name: "Jhon", Email: "jhon@example.com",
+		OTPChannel: "email"},
+	{UUID: "0ea89b50-828d-495f-a48a-3d1d97f8c3cd", Name: "George", Phone: "+15550000003",
+		OTPChannel: "sms"},
+}
+
+func newSeedCommand(cfg *config.Config) *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Create the development users, users that already exist are skipped",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			users := fixtureUsers
+			if file != "" {
+				content, err := os.ReadFile(file)
+				if err != nil {
+					return err
+				}
+
+				users = nil
+				if err := json.Unmarshal(content, &users); err != nil {
+					return fmt.Errorf("invalid seed file: %w", err)
+				}
+			}
+
+			admin, err := app.NewAdmin(cmd.Context(), cfg)
+			if err != nil {
+				return err
+			}
+			defer admin.Close()
+
+			out := cmd.OutOrStdout()
+			for _, u := range users {
+				_, err := admin.UserRepo.CreateUser(cmd.Context(), entity.User{
+					UUID:       u.UUID,
+					Name:       u.Name,
+					Email:      u.Email,
+					Phone:      u.Phone,
+					OTPChannel: u.OTPChannel,
+				})
+				switch {
+				case errors.Is(err, repository.ErrUserExist):
+					fmt.Fprintf(out, "skipped %s %s, it already exists\n", u.UUID, u.Name)
+				case err != nil:
+					return fmt.Errorf("seed user %s: %w", u.UUID, err)
+				default:
+					fmt.Fprintf(out, "created %s %s\n", u.UUID, u.Name)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "",
+		"JSON file with a list of uuid, name, email, phone and otp_channel, instead of the development users")
+
+	return cmd
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/subroll/sqetest/internal/app"
+	"github.com/subroll/sqetest/internal/pkg/config"
+	"github.com/subroll/sqetest/internal/pkg/log"
+	"github.com/subroll/sqetest/internal/pkg/tracing"
+	"go.uber.org/zap"
+)
+
+func newServeCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Start the HTTP server",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return serve(cmd.Context(), cfg)
+		},
+	}
+
+	cmd.Flags().String("port", "", "address to listen on, e.g. :8080")
+	bindFlags(cmd, map[string]string{
+		config.HTTPPort: "port",
+	})
+
+	return cmd
+}
+
+// serve runs the server until it receives SIGINT or SIGTERM, then gives it
+// config.ShutdownGracePeriod to stop. A second signal kills the process right
+// away. An error is returned when the server fails to start or to stop
+// cleanly, so the process exits with a non-zero code.
+func serve(ctx context.Context, cfg *config.Config) error {
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		ServiceName: cfg.Tracing.ServiceName,
+	})
+	if err != nil {
+		return err
+	}
+
+	server, err := app.NewHTTPServer(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, stopSignals := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	go reloadConfig(ctx, server)
+
+	started := make(chan error, 1)
+	go func() {
+		started <- server.Start()
+	}()
+
+	var startErr error
+	select {
+	case startErr = <-started:
+	case <-ctx.Done():
+		stopSignals()
+		log.Info("shutting down", zap.String("grace_period", cfg.Shutdown.GracePeriod.String()))
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.GracePeriod)
+	defer cancel()
+
+	return errors.Join(startErr, server.Stop(stopCtx), shutdownTracing(stopCtx))
+}
+
+// reloadConfig hands the reloaded configuration to the server whenever the
+// config file changes or the process receives SIGHUP. An invalid configuration
+// is logged and the server keeps running with the one it has.
+func reloadConfig(ctx context.Context, server *app.HTTPServer) {
+	changed := make(chan struct{}, 1)
+	err := config.Watch(ctx, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		log.Error("fail to watch config file, only SIGHUP reloads it", zap.Error(err))
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	for {
+		select {
+		case <-changed:
+		case <-sighup:
+		case <-ctx.Done():
+			signal.Stop(sighup)
+
+			return
+		}
+
+		next, err := config.Reload()
+		if err != nil {
+			log.Error("fail to reload config", zap.Error(err))
+
+			continue
+		}
+
+		server.Reload(next)
+	}
+}
@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/subroll/sqetest/internal/app"
+	"github.com/subroll/sqetest/internal/pkg/config"
+	"github.com/subroll/sqetest/internal/service"
+)
+
+func newUserCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "user",
+		Short: "Manage users",
+	}
+
+	cmd.AddCommand(newUserCreateCommand(cfg), newUserListCommand(cfg), newUserDisableCommand(cfg))
+
+	return cmd
+}
+
+func newUserCreateCommand(cfg *config.Config) *cobra.Command {
+	var params service.CreateUserParams
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a user and print its UUID",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			admin, err := app.NewAdmin(cmd.Context(), cfg)
+			if err != nil {
+				return err
+			}
+			defer admin.Close()
+
+			user, err := admin.User.CreateUser(cmd.Context(), params)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), user.UUID)
+
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&params.Name, "name", "", "name of the user")
+	flags.StringVar(&params.Email, "email", "", "email address OTPs are sent to")
+	flags.StringVar(&params.Phone, "phone", "", "phone number OTPs are sent to")
+	flags.StringVar(&params.OTPChannel, "channel", "", "preferred OTP channel: email, sms or webhook")
+	if err := cmd.MarkFlagRequired("name"); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func newUserListCommand(cfg *config.Config) *cobra.Command {
+	var params service.ListUsersParams
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List users ordered by ID",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			admin, err := app.NewAdmin(cmd.Context(), cfg)
+			if err != nil {
+				return err
+			}
+			defer admin.Close()
+
+			users, err := admin.User.ListUsers(cmd.Context(), params)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tUUID\tNAME\tEMAIL\tPHONE\tCHANNEL\tSTATUS")
+			for _, u := range users {
+				status := "active"
+				if u.Disabled {
+					status = "disabled"
+				}
+
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", u.ID, u.UUID, u.Name, orDash(u.Email),
+					orDash(u.Phone), orDash(u.OTPChannel), status)
+			}
+
+			return w.Flush()
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.UintVar(&params.Limit, "limit", service.DefaultListLimit,
+		fmt.Sprintf("number of users to list, at most %d", service.MaxListLimit))
+	flags.UintVar(&params.Offset, "offset", 0, "number of users to skip")
+
+	return cmd
+}
+
+func newUserDisableCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "disable <uuid>",
+		Short: "Disable a user and revoke its OTPs and refresh tokens",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			admin, err := app.NewAdmin(cmd.Context(), cfg)
+			if err != nil {
+				return err
+			}
+			defer admin.Close()
+
+			if err := admin.User.DisableUser(cmd.Context(), args[0]); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "disabled %s\n", args[0])
+
+			return nil
+		},
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
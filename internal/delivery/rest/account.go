@@ -0,0 +1,225 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/subroll/sqetest/internal/entity"
+	"github.com/subroll/sqetest/internal/pkg/log"
+	"github.com/subroll/sqetest/internal/service"
+	"go.uber.org/zap"
+)
+
+type (
+	CreateUserRequest struct {
+		Name       string `json:"name" validate:"required,max=50"`
+		Email      string `json:"email" validate:"omitempty,email,max=255"`
+		Phone      string `json:"phone" validate:"omitempty,e164"`
+		OTPChannel string `json:"otp_channel" validate:"omitempty,oneof=email sms webhook"`
+	}
+
+	// UpdateUserRequest replaces the name and contact info of the user, the
+	// fields left out are cleared.
+	UpdateUserRequest struct {
+		UserID     string `param:"user_id" json:"-" validate:"required,uuid4"`
+		Name       string `json:"name" validate:"required,max=50"`
+		Email      string `json:"email" validate:"omitempty,email,max=255"`
+		Phone      string `json:"phone" validate:"omitempty,e164"`
+		OTPChannel string `json:"otp_channel" validate:"omitempty,oneof=email sms webhook"`
+	}
+
+	UserRequest struct {
+		UserID string `param:"user_id" validate:"required,uuid4"`
+	}
+
+	// ListUsersRequest pages through users ordered by creation, Limit defaults
+	// to service.DefaultListLimit.
+	ListUsersRequest struct {
+		Limit  uint `query:"limit" validate:"max=100"`
+		Offset uint `query:"offset"`
+	}
+
+	UserResponse struct {
+		UserID     string `json:"user_id"`
+		Name       string `json:"name"`
+		Email      string `json:"email,omitempty"`
+		Phone      string `json:"phone,omitempty"`
+		OTPChannel string `json:"otp_channel,omitempty"`
+		Disabled   bool   `json:"disabled"`
+	}
+
+	ListUsersResponse struct {
+		Users  []UserResponse `json:"users"`
+		Limit  uint           `json:"limit"`
+		Offset uint           `json:"offset"`
+	}
+
+	UserMessageResponse struct {
+		UserID  string `json:"user_id"`
+		Message string `json:"message"`
+	}
+)
+
+func (u *User) CreateUser(c echo.Context) error {
+	ctx := c.Request().Context()
+	var createReq CreateUserRequest
+	if err := bindAndValidate(c, &createReq); err != nil {
+		return err
+	}
+
+	user, err := u.userSvc.CreateUser(ctx, service.CreateUserParams{
+		Name:       createReq.Name,
+		Email:      createReq.Email,
+		Phone:      createReq.Phone,
+		OTPChannel: createReq.OTPChannel,
+	})
+	if err != nil {
+		log.ErrorCtx(ctx, "fail to create user", zap.Error(err))
+
+		return newHTTPError(err)
+	}
+
+	return c.JSON(http.StatusCreated, newUserResponse(user))
+}
+
+func (u *User) ListUsers(c echo.Context) error {
+	ctx := c.Request().Context()
+	var listReq ListUsersRequest
+	if err := bindAndValidate(c, &listReq); err != nil {
+		return err
+	}
+
+	if listReq.Limit == 0 {
+		listReq.Limit = service.DefaultListLimit
+	}
+
+	users, err := u.userSvc.ListUsers(ctx, service.ListUsersParams{
+		Limit:  listReq.Limit,
+		Offset: listReq.Offset,
+	})
+	if err != nil {
+		log.ErrorCtx(ctx, "fail to list users", zap.Error(err))
+
+		return newHTTPError(err)
+	}
+
+	res := ListUsersResponse{
+		Users:  make([]UserResponse, 0, len(users)),
+		Limit:  listReq.Limit,
+		Offset: listReq.Offset,
+	}
+	for _, user := range users {
+		res.Users = append(res.Users, newUserResponse(user))
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+func (u *User) GetUser(c echo.Context) error {
+	ctx := c.Request().Context()
+	var userReq UserRequest
+	if err := bindAndValidate(c, &userReq); err != nil {
+		return err
+	}
+
+	user, err := u.userSvc.GetUser(ctx, userReq.UserID)
+	if err != nil {
+		log.ErrorCtx(ctx, "fail to get user", zap.Error(err))
+
+		return newHTTPError(err)
+	}
+
+	return c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+func (u *User) UpdateUser(c echo.Context) error {
+	ctx := c.Request().Context()
+	var updateReq UpdateUserRequest
+	if err := bindAndValidate(c, &updateReq); err != nil {
+		return err
+	}
+
+	user, err := u.userSvc.UpdateUser(ctx, service.UpdateUserParams{
+		UserUUID:   updateReq.UserID,
+		Name:       updateReq.Name,
+		Email:      updateReq.Email,
+		Phone:      updateReq.Phone,
+		OTPChannel: updateReq.OTPChannel,
+	})
+	if err != nil {
+		log.ErrorCtx(ctx, "fail to update user", zap.Error(err))
+
+		return newHTTPError(err)
+	}
+
+	return c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+func (u *User) DisableUser(c echo.Context) error {
+	ctx := c.Request().Context()
+	var userReq UserRequest
+	if err := bindAndValidate(c, &userReq); err != nil {
+		return err
+	}
+
+	if err := u.userSvc.DisableUser(ctx, userReq.UserID); err != nil {
+		log.ErrorCtx(ctx, "fail to disable user", zap.Error(err))
+
+		return newHTTPError(err)
+	}
+
+	return c.JSON(http.StatusOK, UserMessageResponse{
+		UserID:  userReq.UserID,
+		Message: "User disabled successfully.",
+	})
+}
+
+// DeleteUser anonymizes the user, see service.User.AnonymizeUser.
+func (u *User) DeleteUser(c echo.Context) error {
+	ctx := c.Request().Context()
+	var userReq UserRequest
+	if err := bindAndValidate(c, &userReq); err != nil {
+		return err
+	}
+
+	if err := u.userSvc.AnonymizeUser(ctx, userReq.UserID); err != nil {
+		log.ErrorCtx(ctx, "fail to delete user", zap.Error(err))
+
+		return newHTTPError(err)
+	}
+
+	return c.JSON(http.StatusOK, UserMessageResponse{
+		UserID:  userReq.UserID,
+		Message: "User deleted successfully.",
+	})
+}
+
+// bindAndValidate binds the request to req and validates it with the echo
+// validator, the returned error is the HTTP error to respond with.
+func bindAndValidate(c echo.Context, req interface{}) error {
+	ctx := c.Request().Context()
+	if err := c.Bind(req); err != nil {
+		log.WarnCtx(ctx, "fail to bind request", zap.Error(err))
+
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad Request")
+	}
+
+	if err := c.Validate(req); err != nil {
+		log.WarnCtx(ctx, "fail to validate request", zap.Error(err))
+
+		return err
+	}
+
+	return nil
+}
+
+func newUserResponse(user entity.User) UserResponse {
+	return UserResponse{
+		UserID:     user.UUID,
+		Name:       user.Name,
+		Email:      user.Email,
+		Phone:      user.Phone,
+		OTPChannel: user.OTPChannel,
+		Disabled:   user.Disabled,
+	}
+}
@@ -0,0 +1,578 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/subroll/sqetest/internal/entity"
+	mocksvc "github.com/subroll/sqetest/internal/mocks/delivery/rest"
+	"github.com/subroll/sqetest/internal/service"
+)
+
+const testUserUUID = "0b7a0f5e-5a7e-4c56-9d0e-3f1a2b3c4d5e"
+
+// testValidator validates requests the way the server's validator does.
+type testValidator struct {
+	v *validator.Validate
+}
+
+func (tv *testValidator) Validate(i interface{}) error {
+	if err := tv.v.Struct(i); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return nil
+}
+
+func newAccountContext(method, target, body, userID string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	e.Validator = &testValidator{v: validator.New()}
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if userID != "" {
+		c.SetParamNames("user_id")
+		c.SetParamValues(userID)
+	}
+
+	return c, rec
+}
+
+func TestUser_CreateUser(t *testing.T) {
+	t.Parallel()
+
+	type expectaion struct {
+		httpStatus int
+		response   interface{}
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion)
+	}{
+		{
+			desc: "ErrorBindingRequest",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				user := NewUser(Dependencies{})
+				c, rec := newAccountContext(http.MethodPost, "/admin/users", " ", "")
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusBadRequest,
+					response:   "Bad Request",
+				}
+			},
+		},
+		{
+			desc: "ErrorValidatingRequest",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				user := NewUser(Dependencies{})
+				c, rec := newAccountContext(http.MethodPost, "/admin/users", `{"name":"John Doe","otp_channel":"fax"}`, "")
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusBadRequest,
+					response:   "Key: 'CreateUserRequest.OTPChannel' Error:Field validation for 'OTPChannel' failed on the 'oneof' tag",
+				}
+			},
+		},
+		{
+			desc: "ErrorUserExist",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+				c, rec := newAccountContext(http.MethodPost, "/admin/users", `{"name":"John Doe"}`, "")
+				ctx := c.Request().Context()
+
+				userSvc.On("CreateUser", ctx, service.CreateUserParams{Name: "John Doe"}).Return(entity.User{}, service.ErrUserExist)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusConflict,
+					response: ErrorResponse{
+						Code:    "user_exist",
+						Message: "User already exists.",
+					},
+				}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+				c, rec := newAccountContext(http.MethodPost, "/admin/users",
+					`{"name":"John Doe","phone":"+15550000001","otp_channel":"sms"}`, "")
+				ctx := c.Request().Context()
+
+				userSvc.
+					On("CreateUser", ctx, service.CreateUserParams{Name: "John Doe", Phone: "+15550000001", OTPChannel: "sms"}).
+					Return(entity.User{ID: 4, UUID: testUserUUID, Name: "John Doe", Phone: "+15550000001", OTPChannel: "sms"}, nil)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusCreated,
+					response: `{"user_id":"0b7a0f5e-5a7e-4c56-9d0e-3f1a2b3c4d5e","name":"John Doe","phone":"+15550000001",` +
+						`"otp_channel":"sms","disabled":false}
+`,
+				}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, c, rec, exp := tC.mockFn(t)
+			err := u.CreateUser(c)
+			if err != nil {
+				echoError := err.(*echo.HTTPError)
+
+				assert.Equal(t, exp.httpStatus, echoError.Code)
+				assert.Equal(t, exp.response, echoError.Message)
+			} else {
+				assert.Equal(t, exp.httpStatus, rec.Code)
+				assert.Equal(t, exp.response, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestUser_ListUsers(t *testing.T) {
+	t.Parallel()
+
+	type expectaion struct {
+		httpStatus int
+		response   interface{}
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion)
+	}{
+		{
+			desc: "ErrorLimitTooHigh",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				user := NewUser(Dependencies{})
+				c, rec := newAccountContext(http.MethodGet, "/admin/users?limit=101", "", "")
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusBadRequest,
+					response:   "Key: 'ListUsersRequest.Limit' Error:Field validation for 'Limit' failed on the 'max' tag",
+				}
+			},
+		},
+		{
+			desc: "SuccessDefaultLimit",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+				c, rec := newAccountContext(http.MethodGet, "/admin/users?offset=20", "", "")
+				ctx := c.Request().Context()
+
+				userSvc.
+					On("ListUsers", ctx, service.ListUsersParams{Limit: service.DefaultListLimit, Offset: 20}).
+					Return([]entity.User{}, nil)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusOK,
+					response: `{"users":[],"limit":20,"offset":20}
+`,
+				}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+				c, rec := newAccountContext(http.MethodGet, "/admin/users?limit=2", "", "")
+				ctx := c.Request().Context()
+
+				userSvc.
+					On("ListUsers", ctx, service.ListUsersParams{Limit: 2}).
+					Return([]entity.User{
+						{ID: 1, UUID: testUserUUID, Name: "John Doe", Email: "john@example.com"},
+						{ID: 2, UUID: "fake-uuid", Name: "Jane Doe", Disabled: true},
+					}, nil)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusOK,
+					response: `{"users":[{"user_id":"0b7a0f5e-5a7e-4c56-9d0e-3f1a2b3c4d5e","name":"John Doe",` +
+						`"email":"john@example.com","disabled":false},{"user_id":"fake-uuid","name":"Jane Doe","disabled":true}],` +
+						`"limit":2,"offset":0}
+`,
+				}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, c, rec, exp := tC.mockFn(t)
+			err := u.ListUsers(c)
+			if err != nil {
+				echoError := err.(*echo.HTTPError)
+
+				assert.Equal(t, exp.httpStatus, echoError.Code)
+				assert.Equal(t, exp.response, echoError.Message)
+			} else {
+				assert.Equal(t, exp.httpStatus, rec.Code)
+				assert.Equal(t, exp.response, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestUser_GetUser(t *testing.T) {
+	t.Parallel()
+
+	type expectaion struct {
+		httpStatus int
+		response   interface{}
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion)
+	}{
+		{
+			desc: "ErrorInvalidUserID",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				user := NewUser(Dependencies{})
+				c, rec := newAccountContext(http.MethodGet, "/admin/users/fake-uuid", "", "fake-uuid")
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusBadRequest,
+					response:   "Key: 'UserRequest.UserID' Error:Field validation for 'UserID' failed on the 'uuid4' tag",
+				}
+			},
+		},
+		{
+			desc: "ErrorUserNotFound",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+				c, rec := newAccountContext(http.MethodGet, "/admin/users/"+testUserUUID, "", testUserUUID)
+				ctx := c.Request().Context()
+
+				userSvc.On("GetUser", ctx, testUserUUID).Return(entity.User{}, service.ErrUserNotFound)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusNotFound,
+					response: ErrorResponse{
+						Code:    "user_not_found",
+						Message: "User not found.",
+					},
+				}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+				c, rec := newAccountContext(http.MethodGet, "/admin/users/"+testUserUUID, "", testUserUUID)
+				ctx := c.Request().Context()
+
+				userSvc.
+					On("GetUser", ctx, testUserUUID).
+					Return(entity.User{ID: 1, UUID: testUserUUID, Name: "John Doe", Email: "john@example.com", Disabled: true}, nil)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusOK,
+					response: `{"user_id":"0b7a0f5e-5a7e-4c56-9d0e-3f1a2b3c4d5e","name":"John Doe","email":"john@example.com",` +
+						`"disabled":true}
+`,
+				}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, c, rec, exp := tC.mockFn(t)
+			err := u.GetUser(c)
+			if err != nil {
+				echoError := err.(*echo.HTTPError)
+
+				assert.Equal(t, exp.httpStatus, echoError.Code)
+				assert.Equal(t, exp.response, echoError.Message)
+			} else {
+				assert.Equal(t, exp.httpStatus, rec.Code)
+				assert.Equal(t, exp.response, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestUser_UpdateUser(t *testing.T) {
+	t.Parallel()
+
+	type expectaion struct {
+		httpStatus int
+		response   interface{}
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion)
+	}{
+		{
+			desc: "ErrorValidatingRequest",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				user := NewUser(Dependencies{})
+				c, rec := newAccountContext(http.MethodPut, "/admin/users/"+testUserUUID,
+					`{"name":"John Doe","email":"john"}`, testUserUUID)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusBadRequest,
+					response:   "Key: 'UpdateUserRequest.Email' Error:Field validation for 'Email' failed on the 'email' tag",
+				}
+			},
+		},
+		{
+			desc: "ErrorUserNotFound",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+				c, rec := newAccountContext(http.MethodPut, "/admin/users/"+testUserUUID, `{"name":"John Doe"}`, testUserUUID)
+				ctx := c.Request().Context()
+
+				userSvc.
+					On("UpdateUser", ctx, service.UpdateUserParams{UserUUID: testUserUUID, Name: "John Doe"}).
+					Return(entity.User{}, service.ErrUserNotFound)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusNotFound,
+					response: ErrorResponse{
+						Code:    "user_not_found",
+						Message: "User not found.",
+					},
+				}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+				c, rec := newAccountContext(http.MethodPut, "/admin/users/"+testUserUUID,
+					`{"user_id":"fake-uuid","name":"John Doe","email":"john@example.com","otp_channel":"email"}`, testUserUUID)
+				ctx := c.Request().Context()
+
+				userSvc.
+					On("UpdateUser", ctx, service.UpdateUserParams{
+						UserUUID:   testUserUUID,
+						Name:       "John Doe",
+						Email:      "john@example.com",
+						OTPChannel: "email",
+					}).
+					Return(entity.User{ID: 1, UUID: testUserUUID, Name: "John Doe", Email: "john@example.com", OTPChannel: "email"}, nil)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusOK,
+					response: `{"user_id":"0b7a0f5e-5a7e-4c56-9d0e-3f1a2b3c4d5e","name":"John Doe","email":"john@example.com",` +
+						`"otp_channel":"email","disabled":false}
+`,
+				}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, c, rec, exp := tC.mockFn(t)
+			err := u.UpdateUser(c)
+			if err != nil {
+				echoError := err.(*echo.HTTPError)
+
+				assert.Equal(t, exp.httpStatus, echoError.Code)
+				assert.Equal(t, exp.response, echoError.Message)
+			} else {
+				assert.Equal(t, exp.httpStatus, rec.Code)
+				assert.Equal(t, exp.response, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestUser_DisableUser(t *testing.T) {
+	t.Parallel()
+
+	type expectaion struct {
+		httpStatus int
+		response   interface{}
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion)
+	}{
+		{
+			desc: "ErrorUserNotFound",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+				c, rec := newAccountContext(http.MethodPost, "/admin/users/"+testUserUUID+"/disable", "", testUserUUID)
+				ctx := c.Request().Context()
+
+				userSvc.On("DisableUser", ctx, testUserUUID).Return(service.ErrUserNotFound)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusNotFound,
+					response: ErrorResponse{
+						Code:    "user_not_found",
+						Message: "User not found.",
+					},
+				}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+				c, rec := newAccountContext(http.MethodPost, "/admin/users/"+testUserUUID+"/disable", "", testUserUUID)
+				ctx := c.Request().Context()
+
+				userSvc.On("DisableUser", ctx, testUserUUID).Return(nil)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusOK,
+					response: `{"user_id":"0b7a0f5e-5a7e-4c56-9d0e-3f1a2b3c4d5e","message":"User disabled successfully."}
+`,
+				}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, c, rec, exp := tC.mockFn(t)
+			err := u.DisableUser(c)
+			if err != nil {
+				echoError := err.(*echo.HTTPError)
+
+				assert.Equal(t, exp.httpStatus, echoError.Code)
+				assert.Equal(t, exp.response, echoError.Message)
+			} else {
+				assert.Equal(t, exp.httpStatus, rec.Code)
+				assert.Equal(t, exp.response, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestUser_DeleteUser(t *testing.T) {
+	t.Parallel()
+
+	type expectaion struct {
+		httpStatus int
+		response   interface{}
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion)
+	}{
+		{
+			desc: "ErrorUserNotFound",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+				c, rec := newAccountContext(http.MethodDelete, "/admin/users/"+testUserUUID, "", testUserUUID)
+				ctx := c.Request().Context()
+
+				userSvc.On("AnonymizeUser", ctx, testUserUUID).Return(service.ErrUserNotFound)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusNotFound,
+					response: ErrorResponse{
+						Code:    "user_not_found",
+						Message: "User not found.",
+					},
+				}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+				c, rec := newAccountContext(http.MethodDelete, "/admin/users/"+testUserUUID, "", testUserUUID)
+				ctx := c.Request().Context()
+
+				userSvc.On("AnonymizeUser", ctx, testUserUUID).Return(nil)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusOK,
+					response: `{"user_id":"0b7a0f5e-5a7e-4c56-9d0e-3f1a2b3c4d5e","message":"User deleted successfully."}
+`,
+				}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, c, rec, exp := tC.mockFn(t)
+			err := u.DeleteUser(c)
+			if err != nil {
+				echoError := err.(*echo.HTTPError)
+
+				assert.Equal(t, exp.httpStatus, echoError.Code)
+				assert.Equal(t, exp.response, echoError.Message)
+			} else {
+				assert.Equal(t, exp.httpStatus, rec.Code)
+				assert.Equal(t, exp.response, rec.Body.String())
+			}
+		})
+	}
+}
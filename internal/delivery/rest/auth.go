@@ -0,0 +1,63 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/subroll/sqetest/internal/pkg/log"
+	"go.uber.org/zap"
+)
+
+// authenticatedUserKey holds the UUID of the user the access token of the
+// request was issued to.
+const authenticatedUserKey = "authenticated_user"
+
+// RequireAccessToken only lets requests carrying a valid bearer access token
+// through, handlers find the user it was issued to under authenticatedUserKey.
+func RequireAccessToken(verifier TokenVerifier) echo.MiddlewareFunc {
+	return middleware.KeyAuthWithConfig(middleware.KeyAuthConfig{
+		AuthScheme: tokenTypeBearer,
+		Validator: func(key string, c echo.Context) (bool, error) {
+			subject, err := verifier.Verify(key)
+			if err != nil {
+				return false, err
+			}
+
+			c.Set(authenticatedUserKey, subject)
+
+			return true, nil
+		},
+		ErrorHandler: func(err error, c echo.Context) error {
+			log.WarnCtx(c.Request().Context(), "fail to authenticate request", zap.Error(err))
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, tokenTypeBearer)
+
+			return &echo.HTTPError{
+				Code: http.StatusUnauthorized,
+				Message: ErrorResponse{
+					Code:    "invalid_access_token",
+					Message: "A valid access token is required.",
+				},
+				Internal: err,
+			}
+		},
+	})
+}
+
+// authorizeUser makes sure the access token of the request was issued to the
+// user userUUID, so that nobody acts on behalf of another user by naming it.
+func authorizeUser(c echo.Context, userUUID string) error {
+	if subject, _ := c.Get(authenticatedUserKey).(string); subject == "" || subject != userUUID {
+		log.WarnCtx(c.Request().Context(), "access token issued to another user")
+
+		return &echo.HTTPError{
+			Code: http.StatusForbidden,
+			Message: ErrorResponse{
+				Code:    "forbidden",
+				Message: "The access token was issued to another user.",
+			},
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type verifierFunc func(token string) (string, error)
+
+func (f verifierFunc) Verify(token string) (string, error) {
+	return f(token)
+}
+
+func TestRequireAccessToken(t *testing.T) {
+	t.Parallel()
+
+	type expectaion struct {
+		httpStatus      int
+		wwwAuthenticate string
+		body            string
+	}
+
+	verifier := verifierFunc(func(token string) (string, error) {
+		if token != "fake-token" {
+			return "", errors.New("fake error")
+		}
+
+		return "fake-uuid", nil
+	})
+
+	testCases := []struct {
+		desc          string
+		authorization string
+		exp           expectaion
+	}{
+		{
+			desc: "ErrorMissingToken",
+			exp: expectaion{
+				httpStatus:      http.StatusUnauthorized,
+				wwwAuthenticate: "Bearer",
+				body:            `{"code":"invalid_access_token","message":"A valid access token is required."}`,
+			},
+		},
+		{
+			desc:          "ErrorInvalidToken",
+			authorization: "Bearer another-token",
+			exp: expectaion{
+				httpStatus:      http.StatusUnauthorized,
+				wwwAuthenticate: "Bearer",
+				body:            `{"code":"invalid_access_token","message":"A valid access token is required."}`,
+			},
+		},
+		{
+			desc:          "Success",
+			authorization: "Bearer fake-token",
+			exp: expectaion{
+				httpStatus: http.StatusOK,
+				body:       `{"user_id":"fake-uuid"}`,
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			e := echo.New()
+			e.HTTPErrorHandler = ErrorHandler
+			e.GET("/me", func(c echo.Context) error {
+				return c.JSON(http.StatusOK, map[string]interface{}{"user_id": c.Get(authenticatedUserKey)})
+			}, RequireAccessToken(verifier))
+
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tC.authorization != "" {
+				req.Header.Set(echo.HeaderAuthorization, tC.authorization)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tC.exp.httpStatus, rec.Code)
+			assert.Equal(t, tC.exp.wwwAuthenticate, rec.Header().Get(echo.HeaderWWWAuthenticate))
+			assert.JSONEq(t, tC.exp.body, rec.Body.String())
+		})
+	}
+}
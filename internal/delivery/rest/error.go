@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/subroll/sqetest/internal/pkg/log"
+	"github.com/subroll/sqetest/internal/service"
+	"go.uber.org/zap"
+)
+
+const internalErrorCode = "internal_error"
+
+var errorKindStatus = map[service.ErrorKind]int{
+	service.KindNotFound:        http.StatusNotFound,
+	service.KindConflict:        http.StatusConflict,
+	service.KindExpired:         http.StatusGone,
+	service.KindUnauthorized:    http.StatusUnauthorized,
+	service.KindTooManyRequests: http.StatusTooManyRequests,
+	service.KindLocked:          http.StatusLocked,
+	service.KindInvalid:         http.StatusBadRequest,
+	service.KindUnprocessable:   http.StatusUnprocessableEntity,
+	service.KindUnavailable:     http.StatusServiceUnavailable,
+}
+
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// newHTTPError translates a service error into an echo HTTP error carrying an
+// ErrorResponse, unknown errors become a generic internal server error.
+func newHTTPError(err error) *echo.HTTPError {
+	var svcErr *service.Error
+	if errors.As(err, &svcErr) {
+		if status, ok := errorKindStatus[svcErr.Kind]; ok {
+			return &echo.HTTPError{
+				Code:     status,
+				Message:  ErrorResponse{Code: svcErr.Code, Message: svcErr.Message},
+				Internal: err,
+			}
+		}
+	}
+
+	return &echo.HTTPError{
+		Code:     http.StatusInternalServerError,
+		Message:  ErrorResponse{Code: internalErrorCode, Message: http.StatusText(http.StatusInternalServerError)},
+		Internal: err,
+	}
+}
+
+// ErrorHandler is an echo.HTTPErrorHandler that always responds with an
+// ErrorResponse JSON envelope, a Retry-After header is added when the underlying
+// service error asks the client to back off.
+func ErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	var he *echo.HTTPError
+	if !errors.As(err, &he) {
+		he = newHTTPError(err)
+	}
+
+	res, ok := he.Message.(ErrorResponse)
+	if !ok {
+		res = ErrorResponse{
+			Code:    statusCode(he.Code),
+			Message: fmt.Sprint(he.Message),
+		}
+	}
+
+	var svcErr *service.Error
+	if errors.As(he.Internal, &svcErr) && svcErr.RetryAfter > 0 {
+		c.Response().Header().Set(echo.HeaderRetryAfter, strconv.FormatInt(seconds(svcErr.RetryAfter), 10))
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(he.Code)
+	} else {
+		err = c.JSON(he.Code, res)
+	}
+
+	if err != nil {
+		log.ErrorCtx(c.Request().Context(), "fail to write error response", zap.Error(err))
+	}
+}
+
+func statusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" || status == http.StatusInternalServerError {
+		return internalErrorCode
+	}
+
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
+
+// seconds rounds d up to whole seconds so that clients never retry too early.
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/subroll/sqetest/internal/service"
+)
+
+func TestErrorHandler(t *testing.T) {
+	t.Parallel()
+
+	type expectaion struct {
+		httpStatus int
+		retryAfter string
+		response   string
+	}
+
+	testCases := []struct {
+		desc string
+		err  error
+		exp  expectaion
+	}{
+		{
+			desc: "UnknownError",
+			err:  errors.New("fake error"),
+			exp: expectaion{
+				httpStatus: http.StatusInternalServerError,
+				response: `{"code":"internal_error","message":"Internal Server Error"}
+`,
+			},
+		},
+		{
+			desc: "EchoHTTPError",
+			err:  echo.NewHTTPError(http.StatusBadRequest, "Bad Request"),
+			exp: expectaion{
+				httpStatus: http.StatusBadRequest,
+				response: `{"code":"bad_request","message":"Bad Request"}
+`,
+			},
+		},
+		{
+			desc: "EchoRouteNotFound",
+			err:  echo.ErrNotFound,
+			exp: expectaion{
+				httpStatus: http.StatusNotFound,
+				response: `{"code":"not_found","message":"Not Found"}
+`,
+			},
+		},
+		{
+			desc: "ServiceError",
+			err:  service.ErrInvalidOTP,
+			exp: expectaion{
+				httpStatus: http.StatusUnauthorized,
+				response: `{"code":"invalid_otp","message":"Invalid OTP."}
+`,
+			},
+		},
+		{
+			desc: "ServiceErrorWithRetryAfter",
+			err: &service.Error{
+				Kind:       service.KindLocked,
+				Code:       "user_locked",
+				Message:    "User is temporarily locked out.",
+				RetryAfter: 1500 * time.Millisecond,
+			},
+			exp: expectaion{
+				httpStatus: http.StatusLocked,
+				retryAfter: "2",
+				response: `{"code":"user_locked","message":"User is temporarily locked out."}
+`,
+			},
+		},
+		{
+			desc: "TranslatedHTTPError",
+			err:  newHTTPError(service.ErrOTPExpired),
+			exp: expectaion{
+				httpStatus: http.StatusGone,
+				response: `{"code":"otp_expired","message":"OTP has expired."}
+`,
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			ErrorHandler(tC.err, c)
+			assert.Equal(t, tC.exp.httpStatus, rec.Code)
+			assert.Equal(t, tC.exp.retryAfter, rec.Header().Get(echo.HeaderRetryAfter))
+			assert.Equal(t, tC.exp.response, rec.Body.String())
+		})
+	}
+}
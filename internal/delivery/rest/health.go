@@ -0,0 +1,110 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HealthStatusOK       = "ok"
+	HealthStatusFailing  = "failing"
+	HealthStatusDraining = "draining"
+)
+
+type (
+	// HealthCheck tells whether a dependency needed to serve requests is
+	// usable, Check must give up once ctx is done.
+	HealthCheck struct {
+		Name  string
+		Check func(ctx context.Context) error
+	}
+
+	HealthResponse struct {
+		Status string                 `json:"status"`
+		Checks map[string]CheckResult `json:"checks,omitempty"`
+	}
+
+	CheckResult struct {
+		Status   string `json:"status"`
+		Duration string `json:"duration"`
+		Error    string `json:"error,omitempty"`
+	}
+
+	// Health serves the liveness and readiness probes. Once Drain is called
+	// the server reports itself not ready so load balancers stop routing to it
+	// while it shuts down.
+	Health struct {
+		checks   []HealthCheck
+		timeout  time.Duration
+		draining atomic.Bool
+	}
+)
+
+// NewHealth returns the probes of a server that is ready when every check
+// passes within timeout.
+func NewHealth(timeout time.Duration, checks ...HealthCheck) *Health {
+	return &Health{
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+// Drain makes Ready fail from now on.
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Live responds as long as the process is able to serve HTTP at all.
+func (h *Health) Live(c echo.Context) error {
+	return c.JSON(http.StatusOK, HealthResponse{Status: HealthStatusOK})
+}
+
+// Ready runs every check concurrently and responds with the outcome of each,
+// the status is 503 when one of them fails or the server is draining.
+func (h *Health) Ready(c echo.Context) error {
+	if h.draining.Load() {
+		return c.JSON(http.StatusServiceUnavailable, HealthResponse{Status: HealthStatusDraining})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), h.timeout)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		results = make([]CheckResult, len(h.checks))
+	)
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := check.Check(ctx)
+			results[i] = CheckResult{Status: HealthStatusOK, Duration: time.Since(start).String()}
+			if err != nil {
+				results[i].Status = HealthStatusFailing
+				results[i].Error = err.Error()
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	res := HealthResponse{Status: HealthStatusOK, Checks: make(map[string]CheckResult, len(h.checks))}
+	for i, check := range h.checks {
+		res.Checks[check.Name] = results[i]
+		if results[i].Status != HealthStatusOK {
+			res.Status = HealthStatusFailing
+		}
+	}
+
+	if res.Status != HealthStatusOK {
+		return c.JSON(http.StatusServiceUnavailable, res)
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
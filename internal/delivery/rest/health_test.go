@@ -0,0 +1,149 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth_Live(t *testing.T) {
+	t.Parallel()
+
+	health := NewHealth(time.Second, HealthCheck{
+		Name: "db",
+		Check: func(context.Context) error {
+			return errors.New("fake error")
+		},
+	})
+	health.Drain()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	rec := httptest.NewRecorder()
+
+	assert.NoError(t, health.Live(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+}
+
+func TestHealth_Ready(t *testing.T) {
+	t.Parallel()
+
+	type expectaion struct {
+		httpStatus int
+		response   HealthResponse
+	}
+
+	passing := HealthCheck{
+		Name: "db",
+		Check: func(context.Context) error {
+			return nil
+		},
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*Health, expectaion)
+	}{
+		{
+			desc: "Ready",
+			mockFn: func(*testing.T) (*Health, expectaion) {
+				return NewHealth(time.Second, passing), expectaion{
+					httpStatus: http.StatusOK,
+					response: HealthResponse{
+						Status: HealthStatusOK,
+						Checks: map[string]CheckResult{
+							"db": {Status: HealthStatusOK},
+						},
+					},
+				}
+			},
+		},
+		{
+			desc: "ErrorCheckFailing",
+			mockFn: func(*testing.T) (*Health, expectaion) {
+				return NewHealth(time.Second, passing, HealthCheck{
+						Name: "sender:sms",
+						Check: func(context.Context) error {
+							return errors.New("connection refused")
+						},
+					}), expectaion{
+						httpStatus: http.StatusServiceUnavailable,
+						response: HealthResponse{
+							Status: HealthStatusFailing,
+							Checks: map[string]CheckResult{
+								"db":         {Status: HealthStatusOK},
+								"sender:sms": {Status: HealthStatusFailing, Error: "connection refused"},
+							},
+						},
+					}
+			},
+		},
+		{
+			desc: "ErrorCheckTimeout",
+			mockFn: func(*testing.T) (*Health, expectaion) {
+				return NewHealth(10*time.Millisecond, HealthCheck{
+						Name: "db",
+						Check: func(ctx context.Context) error {
+							<-ctx.Done()
+
+							return ctx.Err()
+						},
+					}), expectaion{
+						httpStatus: http.StatusServiceUnavailable,
+						response: HealthResponse{
+							Status: HealthStatusFailing,
+							Checks: map[string]CheckResult{
+								"db": {Status: HealthStatusFailing, Error: context.DeadlineExceeded.Error()},
+							},
+						},
+					}
+			},
+		},
+		{
+			desc: "ErrorDraining",
+			mockFn: func(*testing.T) (*Health, expectaion) {
+				health := NewHealth(time.Second, passing)
+				health.Drain()
+
+				return health, expectaion{
+					httpStatus: http.StatusServiceUnavailable,
+					response:   HealthResponse{Status: HealthStatusDraining},
+				}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			health, exp := tC.mockFn(t)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			rec := httptest.NewRecorder()
+
+			assert.NoError(t, health.Ready(e.NewContext(req, rec)))
+			assert.Equal(t, exp.httpStatus, rec.Code)
+
+			var res HealthResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			for name, result := range res.Checks {
+				assert.NotEmpty(t, result.Duration)
+				result.Duration = ""
+				res.Checks[name] = result
+			}
+			assert.Equal(t, exp.response, res)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package rest
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/subroll/sqetest/internal/pkg/log"
+	"github.com/subroll/sqetest/internal/pkg/requestid"
+	"go.uber.org/zap"
+)
+
+// LogContext puts the request ID, the route and the user_id of a JSON request
+// body in the request context, so that every line logged with it can be
+// correlated with the access log. It must run after the request ID middleware.
+func LogContext(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		fields := []zap.Field{zap.String("route", c.Path())}
+		if userUUID := UserKey(c); userUUID != "" {
+			fields = append(fields, zap.String("user_uuid", userUUID))
+		}
+
+		req := c.Request()
+		ctx := requestid.InjectToCtx(c.Response().Header(), req.Context())
+		c.SetRequest(req.WithContext(log.WithFields(ctx, fields...)))
+
+		return next(c)
+	}
+}
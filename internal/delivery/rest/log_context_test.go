@@ -0,0 +1,40 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/subroll/sqetest/internal/pkg/requestid"
+)
+
+func TestLogContext(t *testing.T) {
+	t.Parallel()
+
+	var requestID string
+
+	e := echo.New()
+	e.Use(middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		Generator: func() string {
+			return "fake-request-id"
+		},
+	}))
+	e.Use(LogContext)
+	e.POST("/otp/request", func(c echo.Context) error {
+		requestID = requestid.ExtractFromCtx(c.Request().Context())
+
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/otp/request", strings.NewReader(`{"user_id":"fake-uuid"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "fake-request-id", requestID)
+}
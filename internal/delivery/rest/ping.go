@@ -1,11 +0,0 @@
-package rest
-
-import (
-	"net/http"
-
-	"github.com/labstack/echo/v4"
-)
-
-func Ping(c echo.Context) error {
-	return c.String(http.StatusOK, "pong")
-}
@@ -0,0 +1,97 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/labstack/echo/v4"
+	"github.com/subroll/sqetest/internal/pkg/log"
+	"github.com/subroll/sqetest/internal/pkg/ratelimit"
+	"github.com/subroll/sqetest/internal/service"
+	"go.uber.org/zap"
+)
+
+const maxPeekBodySize = 1 << 20
+
+// RateLimitRule limits the requests sharing the key returned by Key, requests
+// for which Key returns an empty key aren't limited by the rule.
+type RateLimitRule struct {
+	Name string
+	Rate ratelimit.Rate
+	Key  func(c echo.Context) string
+}
+
+// RateLimit rejects a request with 429 and a Retry-After header as soon as one
+// of the rules is exceeded. Limiter failures are logged and let the request
+// through, so a broken backend doesn't take the API down with it.
+func RateLimit(limiter ratelimit.Limiter, rules ...RateLimitRule) echo.MiddlewareFunc {
+	return RateLimitFunc(limiter, func() []RateLimitRule {
+		return rules
+	})
+}
+
+// RateLimitFunc is RateLimit with the rules returned by rules on every
+// request, so they can be replaced while the server runs.
+func RateLimitFunc(limiter ratelimit.Limiter, rules func() []RateLimitRule) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+
+			for _, rule := range rules() {
+				key := rule.Key(c)
+				if key == "" {
+					continue
+				}
+
+				res, err := limiter.Allow(ctx, rule.Name+":"+key, rule.Rate)
+				if err != nil {
+					log.ErrorCtx(ctx, "fail to check rate limit", zap.String("rule", rule.Name), zap.Error(err))
+
+					continue
+				}
+
+				if !res.Allowed {
+					log.WarnCtx(ctx, "rate limit exceeded", zap.String("rule", rule.Name), zap.String("key", key))
+
+					return newHTTPError(service.ErrRateLimited.WithRetryAfter(res.RetryAfter))
+				}
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// GlobalKey puts every request in the same bucket.
+func GlobalKey(echo.Context) string {
+	return "global"
+}
+
+func IPKey(c echo.Context) string {
+	return c.RealIP()
+}
+
+// UserKey returns the user_id of a JSON request body, the body is restored so
+// the handler can still bind it.
+func UserKey(c echo.Context) string {
+	req := c.Request()
+	if req.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxPeekBodySize))
+	if err != nil {
+		return ""
+	}
+	req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+
+	var payload struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+
+	return payload.UserID
+}
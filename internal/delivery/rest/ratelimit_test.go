@@ -0,0 +1,168 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/subroll/sqetest/internal/pkg/ratelimit"
+)
+
+type limiterFunc func(ctx context.Context, key string, rate ratelimit.Rate) (ratelimit.Result, error)
+
+func (f limiterFunc) Allow(ctx context.Context, key string, rate ratelimit.Rate) (ratelimit.Result, error) {
+	return f(ctx, key, rate)
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	type expectaion struct {
+		httpStatus int
+		retryAfter string
+		keys       []string
+	}
+
+	rules := []RateLimitRule{
+		{Name: "user", Rate: ratelimit.Rate{Limit: 1, Period: time.Minute}, Key: UserKey},
+		{Name: "ip", Rate: ratelimit.Rate{Limit: 1, Period: time.Minute}, Key: IPKey},
+		{Name: "global", Rate: ratelimit.Rate{Limit: 1, Period: time.Minute}, Key: GlobalKey},
+	}
+
+	testCases := []struct {
+		desc    string
+		body    string
+		limiter func(keys *[]string) ratelimit.Limiter
+		exp     expectaion
+	}{
+		{
+			desc: "Allowed",
+			body: `{"user_id":"fake-uuid"}`,
+			limiter: func(keys *[]string) ratelimit.Limiter {
+				return limiterFunc(func(_ context.Context, key string, _ ratelimit.Rate) (ratelimit.Result, error) {
+					*keys = append(*keys, key)
+
+					return ratelimit.Result{Allowed: true}, nil
+				})
+			},
+			exp: expectaion{
+				httpStatus: http.StatusOK,
+				keys:       []string{"user:fake-uuid", "ip:192.0.2.1", "global:global"},
+			},
+		},
+		{
+			desc: "SkipRuleWithoutKey",
+			body: ` `,
+			limiter: func(keys *[]string) ratelimit.Limiter {
+				return limiterFunc(func(_ context.Context, key string, _ ratelimit.Rate) (ratelimit.Result, error) {
+					*keys = append(*keys, key)
+
+					return ratelimit.Result{Allowed: true}, nil
+				})
+			},
+			exp: expectaion{
+				httpStatus: http.StatusOK,
+				keys:       []string{"ip:192.0.2.1", "global:global"},
+			},
+		},
+		{
+			desc: "ErrorLimiterFailsOpen",
+			body: `{"user_id":"fake-uuid"}`,
+			limiter: func(keys *[]string) ratelimit.Limiter {
+				return limiterFunc(func(_ context.Context, key string, _ ratelimit.Rate) (ratelimit.Result, error) {
+					*keys = append(*keys, key)
+
+					return ratelimit.Result{}, errors.New("fake error")
+				})
+			},
+			exp: expectaion{
+				httpStatus: http.StatusOK,
+				keys:       []string{"user:fake-uuid", "ip:192.0.2.1", "global:global"},
+			},
+		},
+		{
+			desc: "ErrorRateLimited",
+			body: `{"user_id":"fake-uuid"}`,
+			limiter: func(keys *[]string) ratelimit.Limiter {
+				return limiterFunc(func(_ context.Context, key string, _ ratelimit.Rate) (ratelimit.Result, error) {
+					*keys = append(*keys, key)
+					if strings.HasPrefix(key, "ip:") {
+						return ratelimit.Result{RetryAfter: 1500 * time.Millisecond}, nil
+					}
+
+					return ratelimit.Result{Allowed: true}, nil
+				})
+			},
+			exp: expectaion{
+				httpStatus: http.StatusTooManyRequests,
+				retryAfter: "2",
+				keys:       []string{"user:fake-uuid", "ip:192.0.2.1"},
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var keys []string
+
+			e := echo.New()
+			e.HTTPErrorHandler = ErrorHandler
+			e.POST("/otp/request", func(c echo.Context) error {
+				// the handler must still see the whole body
+				body, err := io.ReadAll(c.Request().Body)
+				assert.NoError(t, err)
+				assert.Equal(t, tC.body, string(body))
+
+				return c.NoContent(http.StatusOK)
+			}, RateLimit(tC.limiter(&keys), rules...))
+
+			req := httptest.NewRequest(http.MethodPost, "/otp/request", strings.NewReader(tC.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tC.exp.httpStatus, rec.Code)
+			assert.Equal(t, tC.exp.retryAfter, rec.Header().Get(echo.HeaderRetryAfter))
+			assert.Equal(t, tC.exp.keys, keys)
+		})
+	}
+}
+
+func TestRateLimitFunc(t *testing.T) {
+	t.Parallel()
+
+	var rates []ratelimit.Rate
+	limiter := limiterFunc(func(_ context.Context, _ string, rate ratelimit.Rate) (ratelimit.Result, error) {
+		rates = append(rates, rate)
+
+		return ratelimit.Result{Allowed: true}, nil
+	})
+
+	rule := RateLimitRule{Name: "global", Rate: ratelimit.Rate{Limit: 1, Period: time.Minute}, Key: GlobalKey}
+
+	e := echo.New()
+	e.GET("/ping", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, RateLimitFunc(limiter, func() []RateLimitRule {
+		return []RateLimitRule{rule}
+	}))
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ping", nil))
+	rule.Rate = ratelimit.Rate{Limit: 2, Period: time.Second}
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	assert.Equal(t, []ratelimit.Rate{
+		{Limit: 1, Period: time.Minute},
+		{Limit: 2, Period: time.Second},
+	}, rates)
+}
@@ -2,13 +2,37 @@ package rest
 
 import (
 	"context"
+
+	"github.com/subroll/sqetest/internal/entity"
+	"github.com/subroll/sqetest/internal/pkg/token"
+	"github.com/subroll/sqetest/internal/service"
 )
 
 type Dependencies struct {
 	User UserService
 }
 
+// KeySet publishes the public keys that verify access tokens.
+type KeySet interface {
+	JWKS() token.JWKS
+}
+
+// TokenVerifier checks an access token and returns the user it was issued to.
+type TokenVerifier interface {
+	Verify(token string) (string, error)
+}
+
 type UserService interface {
-	GenerateOTP(ctx context.Context, userUUID, requestID string) (string, error)
-	ValidateOTP(ctx context.Context, userID, otp, requestID string) error
+	GenerateOTP(ctx context.Context, params service.GenerateOTPParams) (service.Delivery, error)
+	ResendOTP(ctx context.Context, params service.ResendOTPParams) (service.Delivery, error)
+	ValidateOTP(ctx context.Context, params service.ValidateOTPParams) (service.Session, error)
+	RefreshSession(ctx context.Context, refreshToken string) (service.Session, error)
+	EnrollTOTP(ctx context.Context, userUUID string) (service.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userUUID, code string) error
+	CreateUser(ctx context.Context, params service.CreateUserParams) (entity.User, error)
+	ListUsers(ctx context.Context, params service.ListUsersParams) ([]entity.User, error)
+	GetUser(ctx context.Context, userUUID string) (entity.User, error)
+	UpdateUser(ctx context.Context, params service.UpdateUserParams) (entity.User, error)
+	DisableUser(ctx context.Context, userUUID string) error
+	AnonymizeUser(ctx context.Context, userUUID string) error
 }
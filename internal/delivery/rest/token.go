@@ -0,0 +1,63 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/subroll/sqetest/internal/pkg/log"
+	"github.com/subroll/sqetest/internal/service"
+	"go.uber.org/zap"
+)
+
+const tokenTypeBearer = "Bearer"
+
+type (
+	RefreshTokenRequest struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
+
+	// TokenResponse follows the OAuth 2.0 token response, ExpiresIn is the
+	// number of seconds the access token is valid for.
+	TokenResponse struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int64  `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+	}
+)
+
+func (u *User) RefreshToken(c echo.Context) error {
+	ctx := c.Request().Context()
+	var refreshReq RefreshTokenRequest
+	if err := bindAndValidate(c, &refreshReq); err != nil {
+		return err
+	}
+
+	session, err := u.userSvc.RefreshSession(ctx, refreshReq.RefreshToken)
+	if err != nil {
+		log.ErrorCtx(ctx, "fail to refresh session", zap.Error(err))
+
+		return newHTTPError(err)
+	}
+
+	return c.JSON(http.StatusOK, newTokenResponse(session))
+}
+
+// JWKS serves the public keys of keys so that other services can verify access
+// tokens offline.
+func JWKS(keys KeySet) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=300")
+
+		return c.JSON(http.StatusOK, keys.JWKS())
+	}
+}
+
+func newTokenResponse(session service.Session) TokenResponse {
+	return TokenResponse{
+		AccessToken:  session.AccessToken,
+		TokenType:    tokenTypeBearer,
+		ExpiresIn:    seconds(session.ExpiresIn),
+		RefreshToken: session.RefreshToken,
+	}
+}
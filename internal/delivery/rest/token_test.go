@@ -0,0 +1,196 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	mocksvc "github.com/subroll/sqetest/internal/mocks/delivery/rest"
+	"github.com/subroll/sqetest/internal/pkg/token"
+	"github.com/subroll/sqetest/internal/service"
+)
+
+type fakeKeySet token.JWKS
+
+func (f fakeKeySet) JWKS() token.JWKS {
+	return token.JWKS(f)
+}
+
+func TestUser_RefreshToken(t *testing.T) {
+	t.Parallel()
+
+	type expectaion struct {
+		httpStatus int
+		response   interface{}
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion)
+	}{
+		{
+			desc: "ErrorBindingRequest",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				user := NewUser(Dependencies{})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/token/refresh", strings.NewReader(" "))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusBadRequest,
+					response:   "Bad Request",
+				}
+			},
+		},
+		{
+			desc: "ErrorValidatingRequest",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				user := NewUser(Dependencies{})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/token/refresh", strings.NewReader(`{"refresh_token":""}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusBadRequest,
+					response:   "Key: 'RefreshTokenRequest.RefreshToken' Error:Field validation for 'RefreshToken' failed on the 'required' tag",
+				}
+			},
+		},
+		{
+			desc: "ErrorRefreshSession",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/token/refresh", strings.NewReader(`{"refresh_token":"old-refresh-token"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				ctx := c.Request().Context()
+
+				userSvc.On("RefreshSession", ctx, "old-refresh-token").Return(service.Session{}, errors.New("fake error"))
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusInternalServerError,
+					response:   ErrorResponse{Code: "internal_error", Message: "Internal Server Error"},
+				}
+			},
+		},
+		{
+			desc: "ErrorInvalidRefreshToken",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/token/refresh", strings.NewReader(`{"refresh_token":"old-refresh-token"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				ctx := c.Request().Context()
+
+				userSvc.On("RefreshSession", ctx, "old-refresh-token").Return(service.Session{}, service.ErrInvalidRefreshToken)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusUnauthorized,
+					response:   ErrorResponse{Code: "invalid_refresh_token", Message: "Invalid refresh token."},
+				}
+			},
+		},
+		{
+			desc: "SuccessRefreshToken",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/token/refresh", strings.NewReader(`{"refresh_token":"old-refresh-token"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				ctx := c.Request().Context()
+
+				userSvc.On("RefreshSession", ctx, "old-refresh-token").Return(service.Session{
+					AccessToken:  "fake-access-token",
+					ExpiresIn:    5 * time.Minute,
+					RefreshToken: "fake-refresh-token",
+				}, nil)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusOK,
+					response:   `{"access_token":"fake-access-token","token_type":"Bearer","expires_in":300,` +
+						`"refresh_token":"fake-refresh-token"}
+`,
+				}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, c, rec, exp := tC.mockFn(t)
+			err := u.RefreshToken(c)
+			if err != nil {
+				echoError := err.(*echo.HTTPError)
+
+				assert.Equal(t, exp.httpStatus, echoError.Code)
+				assert.Equal(t, exp.response, echoError.Message)
+			} else {
+				assert.Equal(t, exp.httpStatus, rec.Code)
+				assert.Equal(t, exp.response, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	t.Parallel()
+
+	keys := fakeKeySet{Keys: []token.JWK{{
+		KeyType:   "OKP",
+		Use:       "sig",
+		KeyID:     "fake-kid",
+		Algorithm: token.AlgorithmEdDSA,
+		Curve:     "Ed25519",
+		X:         "fake-x",
+	}}}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := JWKS(keys)(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "public, max-age=300", rec.Header().Get(echo.HeaderCacheControl))
+	assert.Equal(t, `{"keys":[{"kty":"OKP","use":"sig","kid":"fake-kid","alg":"EdDSA","crv":"Ed25519","x":"fake-x"}]}
+`, rec.Body.String())
+}
@@ -0,0 +1,93 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/subroll/sqetest/internal/pkg/log"
+	"go.uber.org/zap"
+)
+
+const mimeImagePNG = "image/png"
+
+type (
+	// TOTPEnrollRequest and TOTPConfirmRequest need the bearer access token of
+	// the user, an authenticator app is only enrolled by the user it generates
+	// codes for.
+	TOTPEnrollRequest struct {
+		UserID string `json:"user_id" validate:"required,uuid4"`
+	}
+
+	// TOTPEnrollResponse carries the secret both as an otpauth:// URI and as a
+	// base64 encoded QR code PNG, clients that only want the image can ask for
+	// it with "Accept: image/png".
+	TOTPEnrollResponse struct {
+		UserID string `json:"user_id"`
+		Secret string `json:"secret"`
+		URI    string `json:"uri"`
+		QRCode []byte `json:"qr_code"`
+	}
+
+	TOTPConfirmRequest struct {
+		UserID string `json:"user_id" validate:"required,uuid4"`
+		OTP    string `json:"otp" validate:"required"`
+	}
+
+	TOTPConfirmResponse struct {
+		UserID  string `json:"user_id"`
+		Message string `json:"message"`
+	}
+)
+
+func (u *User) EnrollTOTP(c echo.Context) error {
+	ctx := c.Request().Context()
+	var enrollReq TOTPEnrollRequest
+	if err := bindAndValidate(c, &enrollReq); err != nil {
+		return err
+	}
+
+	if err := authorizeUser(c, enrollReq.UserID); err != nil {
+		return err
+	}
+
+	enrollment, err := u.userSvc.EnrollTOTP(ctx, enrollReq.UserID)
+	if err != nil {
+		log.ErrorCtx(ctx, "fail to enroll totp", zap.Error(err))
+
+		return newHTTPError(err)
+	}
+
+	if c.Request().Header.Get(echo.HeaderAccept) == mimeImagePNG {
+		return c.Blob(http.StatusOK, mimeImagePNG, enrollment.QRCode)
+	}
+
+	return c.JSON(http.StatusOK, TOTPEnrollResponse{
+		UserID: enrollReq.UserID,
+		Secret: enrollment.Secret,
+		URI:    enrollment.URI,
+		QRCode: enrollment.QRCode,
+	})
+}
+
+func (u *User) ConfirmTOTP(c echo.Context) error {
+	ctx := c.Request().Context()
+	var confirmReq TOTPConfirmRequest
+	if err := bindAndValidate(c, &confirmReq); err != nil {
+		return err
+	}
+
+	if err := authorizeUser(c, confirmReq.UserID); err != nil {
+		return err
+	}
+
+	if err := u.userSvc.ConfirmTOTP(ctx, confirmReq.UserID, confirmReq.OTP); err != nil {
+		log.ErrorCtx(ctx, "fail to confirm totp", zap.Error(err))
+
+		return newHTTPError(err)
+	}
+
+	return c.JSON(http.StatusOK, TOTPConfirmResponse{
+		UserID:  confirmReq.UserID,
+		Message: "Authenticator app enrolled successfully.",
+	})
+}
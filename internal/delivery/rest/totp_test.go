@@ -0,0 +1,367 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	mocksvc "github.com/subroll/sqetest/internal/mocks/delivery/rest"
+	"github.com/subroll/sqetest/internal/service"
+)
+
+func TestUser_EnrollTOTP(t *testing.T) {
+	t.Parallel()
+
+	type expectaion struct {
+		httpStatus int
+		response   interface{}
+	}
+
+	enrollment := service.TOTPEnrollment{
+		Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		URI:    "otpauth://totp/sqetest:0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		QRCode: []byte("fake-png"),
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion)
+	}{
+		{
+			desc: "ErrorBindingRequest",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				user := NewUser(Dependencies{})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/totp/enroll", strings.NewReader(" "))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusBadRequest,
+					response:   "Bad Request",
+				}
+			},
+		},
+		{
+			desc: "ErrorValidatingRequest",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				user := NewUser(Dependencies{})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/totp/enroll", strings.NewReader(`{}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				c.Set(authenticatedUserKey, "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24")
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusBadRequest,
+					response:   "Key: 'TOTPEnrollRequest.UserID' Error:Field validation for 'UserID' failed on the 'required' tag",
+				}
+			},
+		},
+		{
+			desc: "ErrorAnotherUser",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				user := NewUser(Dependencies{})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/totp/enroll", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				c.Set(authenticatedUserKey, "other-uuid")
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusForbidden,
+					response: ErrorResponse{
+						Code:    "forbidden",
+						Message: "The access token was issued to another user.",
+					},
+				}
+			},
+		},
+		{
+			desc: "ErrorAlreadyEnrolled",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/totp/enroll", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				c.Set(authenticatedUserKey, "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24")
+				ctx := c.Request().Context()
+
+				userSvc.On("EnrollTOTP", ctx, "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24").Return(service.TOTPEnrollment{}, service.ErrTOTPAlreadyEnrolled)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusConflict,
+					response: ErrorResponse{
+						Code:    "totp_already_enrolled",
+						Message: "An authenticator app is already enrolled.",
+					},
+				}
+			},
+		},
+		{
+			desc: "SuccessJSON",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/totp/enroll", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				c.Set(authenticatedUserKey, "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24")
+				ctx := c.Request().Context()
+
+				userSvc.On("EnrollTOTP", ctx, "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24").Return(enrollment, nil)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusOK,
+					response: `{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","secret":"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",` +
+						`"uri":"otpauth://totp/sqetest:0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",` +
+						`"qr_code":"ZmFrZS1wbmc="}
+`,
+				}
+			},
+		},
+		{
+			desc: "SuccessPNG",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/totp/enroll", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				req.Header.Set(echo.HeaderAccept, "image/png")
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				c.Set(authenticatedUserKey, "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24")
+				ctx := c.Request().Context()
+
+				userSvc.On("EnrollTOTP", ctx, "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24").Return(enrollment, nil)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusOK,
+					response:   "fake-png",
+				}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, c, rec, exp := tC.mockFn(t)
+			err := u.EnrollTOTP(c)
+			if err != nil {
+				echoError := err.(*echo.HTTPError)
+
+				assert.Equal(t, exp.httpStatus, echoError.Code)
+				assert.Equal(t, exp.response, echoError.Message)
+			} else {
+				assert.Equal(t, exp.httpStatus, rec.Code)
+				assert.Equal(t, exp.response, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestUser_ConfirmTOTP(t *testing.T) {
+	t.Parallel()
+
+	type expectaion struct {
+		httpStatus int
+		response   interface{}
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion)
+	}{
+		{
+			desc: "ErrorBindingRequest",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				user := NewUser(Dependencies{})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/totp/confirm", strings.NewReader(" "))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusBadRequest,
+					response:   "Bad Request",
+				}
+			},
+		},
+		{
+			desc: "ErrorValidatingRequest",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				user := NewUser(Dependencies{})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/totp/confirm", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				c.Set(authenticatedUserKey, "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24")
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusBadRequest,
+					response:   "Key: 'TOTPConfirmRequest.OTP' Error:Field validation for 'OTP' failed on the 'required' tag",
+				}
+			},
+		},
+		{
+			desc: "ErrorAnotherUser",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				user := NewUser(Dependencies{})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/totp/confirm", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","otp":"287082"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				c.Set(authenticatedUserKey, "other-uuid")
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusForbidden,
+					response: ErrorResponse{
+						Code:    "forbidden",
+						Message: "The access token was issued to another user.",
+					},
+				}
+			},
+		},
+		{
+			desc: "ErrorNotEnrolled",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/totp/confirm", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","otp":"287082"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				c.Set(authenticatedUserKey, "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24")
+				ctx := c.Request().Context()
+
+				userSvc.On("ConfirmTOTP", ctx, "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24", "287082").Return(service.ErrTOTPNotEnrolled)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusNotFound,
+					response: ErrorResponse{
+						Code:    "totp_not_enrolled",
+						Message: "No authenticator app enrollment found.",
+					},
+				}
+			},
+		},
+		{
+			desc: "ErrorInvalidOTP",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/totp/confirm", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","otp":"287082"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				c.Set(authenticatedUserKey, "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24")
+				ctx := c.Request().Context()
+
+				userSvc.On("ConfirmTOTP", ctx, "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24", "287082").Return(service.ErrInvalidOTP)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusUnauthorized,
+					response:   ErrorResponse{Code: "invalid_otp", Message: "Invalid OTP."},
+				}
+			},
+		},
+		{
+			desc: "SuccessConfirmTOTP",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/totp/confirm", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","otp":"287082"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				c.Set(authenticatedUserKey, "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24")
+				ctx := c.Request().Context()
+
+				userSvc.On("ConfirmTOTP", ctx, "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24", "287082").Return(nil)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusOK,
+					response: `{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","message":"Authenticator app enrolled successfully."}
+`,
+				}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, c, rec, exp := tC.mockFn(t)
+			err := u.ConfirmTOTP(c)
+			if err != nil {
+				echoError := err.(*echo.HTTPError)
+
+				assert.Equal(t, exp.httpStatus, echoError.Code)
+				assert.Equal(t, exp.response, echoError.Message)
+			} else {
+				assert.Equal(t, exp.httpStatus, rec.Code)
+				assert.Equal(t, exp.response, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevTracer := tracer
+	tracer = provider.Tracer("github.com/subroll/sqetest/internal/delivery/rest")
+	prevPropagator := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		tracer = prevTracer
+		otel.SetTextMapPropagator(prevPropagator)
+		_ = provider.Shutdown(context.Background())
+	})
+
+	var handlerTraceID string
+
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler
+	e.Use(Trace)
+	e.POST("/otp/validate", func(c echo.Context) error {
+		handlerTraceID = trace.SpanContextFromContext(c.Request().Context()).TraceID().String()
+
+		return errors.New("fake-error")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/otp/validate", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", handlerTraceID)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "POST /otp/validate", spans[0].Name())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Contains(t, spans[0].Attributes(), semconv.HTTPRoute("/otp/validate"))
+	assert.Contains(t, spans[0].Attributes(), semconv.HTTPStatusCode(http.StatusInternalServerError))
+}
@@ -2,35 +2,60 @@ package rest
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/subroll/sqetest/internal/pkg/log"
+	"github.com/subroll/sqetest/internal/service"
 	"go.uber.org/zap"
 )
 
+// HeaderDeviceFingerprint carries the fingerprint of the client's device, an
+// OTP can be bound to it so that only the device requesting it can validate it.
+const HeaderDeviceFingerprint = "X-Device-Fingerprint"
+
 type (
 	User struct {
 		userSvc UserService
 	}
 
 	OTPRequest struct {
-		UserID string `json:"user_id" validate:"required,uuid4"`
+		UserID  string `json:"user_id" validate:"required,uuid4"`
+		Purpose string `json:"purpose"`
+		Channel string `json:"channel" validate:"omitempty,oneof=email sms webhook"`
+	}
+
+	ResendOTPRequest struct {
+		UserID  string `json:"user_id" validate:"required,uuid4"`
+		Purpose string `json:"purpose"`
+		Channel string `json:"channel" validate:"omitempty,oneof=email sms webhook"`
 	}
 
+	// OTPResponse tells the client where the OTP was sent to, ChallengeID has
+	// to be sent along with the OTP to validate it before ExpiresAt.
+	// ResendAfter is the number of seconds before the OTP can be resent.
 	OTPResponse struct {
-		UserID string `json:"user_id"`
-		OTP    string `json:"otp"`
+		UserID      string    `json:"user_id"`
+		ChallengeID string    `json:"challenge_id"`
+		ExpiresAt   time.Time `json:"expires_at"`
+		Channel     string    `json:"channel"`
+		DeliveryRef string    `json:"delivery_ref"`
+		ResendAfter int64     `json:"resend_after"`
+		ResendsLeft uint8     `json:"resends_left"`
 	}
 
 	ValidateOTPRequest struct {
-		UserID string `json:"user_id" validate:"required,uuid4"`
-		OTP    string `json:"otp" validate:"required"`
-		ReqID  string `json:"request_id" validate:"required"`
+		UserID      string `json:"user_id" validate:"required,uuid4"`
+		OTP         string `json:"otp" validate:"required"`
+		Method      string `json:"method" validate:"omitempty,oneof=otp totp"`
+		Purpose     string `json:"purpose"`
+		ChallengeID string `json:"challenge_id" validate:"required_unless=Method totp"`
 	}
 
 	ValidateOTPResponse struct {
 		UserID  string `json:"user_id"`
 		Message string `json:"message"`
+		TokenResponse
 	}
 )
 
@@ -41,46 +66,92 @@ func NewUser(deps Dependencies) *User {
 }
 
 func (u *User) RequestOTP(c echo.Context) error {
+	ctx := c.Request().Context()
 	var otpReq OTPRequest
-	if err := c.Bind(&otpReq); err != nil {
-		log.Warn("fail to bind request", zap.Error(err))
+	if err := bindAndValidate(c, &otpReq); err != nil {
+		return err
+	}
 
-		return echo.NewHTTPError(http.StatusBadRequest, "Bad Request")
+	delivery, err := u.userSvc.GenerateOTP(ctx, service.GenerateOTPParams{
+		UserUUID:  otpReq.UserID,
+		Purpose:   otpReq.Purpose,
+		Channel:   otpReq.Channel,
+		RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+		Client:    client(c),
+	})
+	if err != nil {
+		log.ErrorCtx(ctx, "fail to generate otp", zap.Error(err))
+
+		return newHTTPError(err)
 	}
+
+	return c.JSON(http.StatusOK, newOTPResponse(otpReq.UserID, delivery))
+}
+
+func (u *User) ResendOTP(c echo.Context) error {
 	ctx := c.Request().Context()
+	var resendReq ResendOTPRequest
+	if err := bindAndValidate(c, &resendReq); err != nil {
+		return err
+	}
 
-	otp, err := u.userSvc.GenerateOTP(ctx, otpReq.UserID,
-		c.Response().Header().Get(echo.HeaderXRequestID))
+	delivery, err := u.userSvc.ResendOTP(ctx, service.ResendOTPParams{
+		UserUUID: resendReq.UserID,
+		Purpose:  resendReq.Purpose,
+		Channel:  resendReq.Channel,
+	})
 	if err != nil {
-		log.Error("fail to generate otp", zap.Error(err))
+		log.ErrorCtx(ctx, "fail to resend otp", zap.Error(err))
 
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+		return newHTTPError(err)
 	}
 
-	return c.JSON(http.StatusOK, OTPResponse{
-		UserID: otpReq.UserID,
-		OTP:    otp,
-	})
+	return c.JSON(http.StatusOK, newOTPResponse(resendReq.UserID, delivery))
 }
 
 func (u *User) ValidateOTP(c echo.Context) error {
+	ctx := c.Request().Context()
 	var validateOTPReq ValidateOTPRequest
-	if err := c.Bind(&validateOTPReq); err != nil {
-		log.Warn("fail to bind request", zap.Error(err))
-
-		return echo.NewHTTPError(http.StatusBadRequest, "Bad Request")
+	if err := bindAndValidate(c, &validateOTPReq); err != nil {
+		return err
 	}
-	ctx := c.Request().Context()
 
-	if err := u.userSvc.ValidateOTP(ctx, validateOTPReq.UserID, validateOTPReq.OTP,
-		validateOTPReq.ReqID); err != nil {
-		log.Error("fail to validate otp", zap.Error(err))
+	session, err := u.userSvc.ValidateOTP(ctx, service.ValidateOTPParams{
+		UserUUID:    validateOTPReq.UserID,
+		OTP:         validateOTPReq.OTP,
+		Method:      validateOTPReq.Method,
+		Purpose:     validateOTPReq.Purpose,
+		ChallengeID: validateOTPReq.ChallengeID,
+		Client:      client(c),
+	})
+	if err != nil {
+		log.ErrorCtx(ctx, "fail to validate otp", zap.Error(err))
 
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+		return newHTTPError(err)
 	}
 
 	return c.JSON(http.StatusOK, ValidateOTPResponse{
-		UserID:  validateOTPReq.UserID,
-		Message: "OTP validated successfully.",
+		UserID:        validateOTPReq.UserID,
+		Message:       "OTP validated successfully.",
+		TokenResponse: newTokenResponse(session),
 	})
 }
+
+func newOTPResponse(userID string, delivery service.Delivery) OTPResponse {
+	return OTPResponse{
+		UserID:      userID,
+		ChallengeID: delivery.ChallengeID,
+		ExpiresAt:   delivery.ExpiresAt,
+		Channel:     delivery.Channel,
+		DeliveryRef: delivery.Reference,
+		ResendAfter: seconds(delivery.ResendAfter),
+		ResendsLeft: delivery.ResendsLeft,
+	}
+}
+
+func client(c echo.Context) service.Client {
+	return service.Client{
+		Fingerprint: c.Request().Header.Get(HeaderDeviceFingerprint),
+		IP:          c.RealIP(),
+	}
+}
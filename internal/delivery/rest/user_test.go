@@ -2,15 +2,17 @@ package rest
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	mocksvc "github.com/subroll/sqetest/internal/mocks/delivery/rest"
+	"github.com/subroll/sqetest/internal/service"
 )
 
 func TestUser_RequestOTP(t *testing.T) {
@@ -18,7 +20,7 @@ func TestUser_RequestOTP(t *testing.T) {
 
 	type expectaion struct {
 		httpStatus int
-		response   string
+		response   interface{}
 	}
 
 	testCases := []struct {
@@ -31,6 +33,7 @@ func TestUser_RequestOTP(t *testing.T) {
 				user := NewUser(Dependencies{})
 
 				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
 				req := httptest.NewRequest(http.MethodPost, "/otp/request", strings.NewReader(" "))
 				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 				rec := httptest.NewRecorder()
@@ -42,6 +45,24 @@ func TestUser_RequestOTP(t *testing.T) {
 				}
 			},
 		},
+		{
+			desc: "ErrorValidatingRequest",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				user := NewUser(Dependencies{})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/otp/request", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","channel":"pigeon"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusBadRequest,
+					response:   "Key: 'OTPRequest.Channel' Error:Field validation for 'Channel' failed on the 'oneof' tag",
+				}
+			},
+		},
 		{
 			desc: "ErrorGenerateOTP",
 			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
@@ -51,18 +72,69 @@ func TestUser_RequestOTP(t *testing.T) {
 				})
 
 				e := echo.New()
-				req := httptest.NewRequest(http.MethodPost, "/otp/request", strings.NewReader(`{"user_id":"fake-uuid"}`))
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/otp/request", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24"}`))
 				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 				rec := httptest.NewRecorder()
 				rec.Header().Set(echo.HeaderXRequestID, "fake-request-id")
 				c := e.NewContext(req, rec)
 				ctx := c.Request().Context()
 
-				userSvc.On("GenerateOTP", ctx, "fake-uuid", "fake-request-id").Return("", errors.New("fake error"))
+				userSvc.On("GenerateOTP", ctx, service.GenerateOTPParams{UserUUID: "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24", RequestID: "fake-request-id", Client: service.Client{IP: "192.0.2.1"}}).Return(service.Delivery{}, errors.New("fake error"))
 
 				return user, c, rec, expectaion{
 					httpStatus: http.StatusInternalServerError,
-					response:   "Internal Server Error",
+					response:   ErrorResponse{Code: "internal_error", Message: "Internal Server Error"},
+				}
+			},
+		},
+		{
+			desc: "ErrorUserNotFound",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/otp/request", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				rec.Header().Set(echo.HeaderXRequestID, "fake-request-id")
+				c := e.NewContext(req, rec)
+				ctx := c.Request().Context()
+
+				userSvc.On("GenerateOTP", ctx, service.GenerateOTPParams{UserUUID: "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24", RequestID: "fake-request-id", Client: service.Client{IP: "192.0.2.1"}}).Return(service.Delivery{}, service.ErrUserNotFound)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusNotFound,
+					response:   ErrorResponse{Code: "user_not_found", Message: "User not found."},
+				}
+			},
+		},
+		{
+			desc: "ErrorOTPExist",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/otp/request", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				rec.Header().Set(echo.HeaderXRequestID, "fake-request-id")
+				c := e.NewContext(req, rec)
+				ctx := c.Request().Context()
+
+				userSvc.On("GenerateOTP", ctx, service.GenerateOTPParams{UserUUID: "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24", RequestID: "fake-request-id", Client: service.Client{IP: "192.0.2.1"}}).Return(service.Delivery{}, service.ErrOTPExist)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusConflict,
+					response:   ErrorResponse{Code: "otp_exist", Message: "There is still an active OTP."},
 				}
 			},
 		},
@@ -75,18 +147,33 @@ func TestUser_RequestOTP(t *testing.T) {
 				})
 
 				e := echo.New()
-				req := httptest.NewRequest(http.MethodPost, "/otp/request", strings.NewReader(`{"user_id":"fake-uuid"}`))
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/otp/request", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","purpose":"login","channel":"sms"}`))
 				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				req.Header.Set(HeaderDeviceFingerprint, "fake-fingerprint")
 				rec := httptest.NewRecorder()
 				rec.Header().Set(echo.HeaderXRequestID, "fake-request-id")
 				c := e.NewContext(req, rec)
 				ctx := c.Request().Context()
 
-				userSvc.On("GenerateOTP", ctx, "fake-uuid", "fake-request-id").Return("12345", nil)
+				userSvc.On("GenerateOTP", ctx, service.GenerateOTPParams{
+					UserUUID:  "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24",
+					Purpose:   "login",
+					Channel:   "sms",
+					RequestID: "fake-request-id",
+					Client:    service.Client{Fingerprint: "fake-fingerprint", IP: "192.0.2.1"},
+				}).
+					Return(service.Delivery{
+						Channel:     "sms",
+						Reference:   "fake-ref",
+						ChallengeID: "fake-challenge-id",
+						ExpiresAt:   time.Date(2024, time.January, 1, 0, 5, 0, 0, time.UTC),
+					}, nil)
 
 				return user, c, rec, expectaion{
 					httpStatus: http.StatusOK,
-					response: `{"user_id":"fake-uuid","otp":"12345"}
+					response: `{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","challenge_id":"fake-challenge-id","expires_at":"2024-01-01T00:05:00Z",` +
+						`"channel":"sms","delivery_ref":"fake-ref","resend_after":0,"resends_left":0}
 `,
 				}
 			},
@@ -103,7 +190,183 @@ func TestUser_RequestOTP(t *testing.T) {
 			if err != nil {
 				echoError := err.(*echo.HTTPError)
 
-				assert.Equal(t, fmt.Sprintf("code=%d, message=%v", exp.httpStatus, exp.response), echoError.Error())
+				assert.Equal(t, exp.httpStatus, echoError.Code)
+				assert.Equal(t, exp.response, echoError.Message)
+			} else {
+				assert.Equal(t, exp.httpStatus, rec.Code)
+				assert.Equal(t, exp.response, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestUser_ResendOTP(t *testing.T) {
+	t.Parallel()
+
+	type expectaion struct {
+		httpStatus int
+		response   interface{}
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion)
+	}{
+		{
+			desc: "ErrorBindingRequest",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				user := NewUser(Dependencies{})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/otp/resend", strings.NewReader(" "))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusBadRequest,
+					response:   "Bad Request",
+				}
+			},
+		},
+		{
+			desc: "ErrorValidatingRequest",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				user := NewUser(Dependencies{})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/otp/resend", strings.NewReader(`{"user_id":"fake-uuid","purpose":"login"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusBadRequest,
+					response:   "Key: 'ResendOTPRequest.UserID' Error:Field validation for 'UserID' failed on the 'uuid4' tag",
+				}
+			},
+		},
+		{
+			desc: "ErrorResendOTP",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/otp/resend", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","purpose":"login"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				ctx := c.Request().Context()
+
+				userSvc.On("ResendOTP", ctx, service.ResendOTPParams{UserUUID: "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24", Purpose: "login"}).Return(service.Delivery{}, errors.New("fake error"))
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusInternalServerError,
+					response:   ErrorResponse{Code: "internal_error", Message: "Internal Server Error"},
+				}
+			},
+		},
+		{
+			desc: "ErrorNoActiveOTP",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/otp/resend", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","purpose":"login"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				ctx := c.Request().Context()
+
+				userSvc.On("ResendOTP", ctx, service.ResendOTPParams{UserUUID: "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24", Purpose: "login"}).Return(service.Delivery{}, service.ErrNoActiveOTP)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusNotFound,
+					response:   ErrorResponse{Code: "otp_not_found", Message: "There is no active OTP to resend."},
+				}
+			},
+		},
+		{
+			desc: "ErrorResendCooldown",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/otp/resend", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","purpose":"login"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				ctx := c.Request().Context()
+
+				userSvc.On("ResendOTP", ctx, service.ResendOTPParams{UserUUID: "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24", Purpose: "login"}).Return(service.Delivery{}, service.ErrResendCooldown)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusTooManyRequests,
+					response:   ErrorResponse{Code: "resend_cooldown", Message: "Please wait before requesting another resend."},
+				}
+			},
+		},
+		{
+			desc: "SuccessResendOTP",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/otp/resend", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","purpose":"login"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				ctx := c.Request().Context()
+
+				userSvc.On("ResendOTP", ctx, service.ResendOTPParams{UserUUID: "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24", Purpose: "login"}).Return(service.Delivery{
+					Channel:     "sms",
+					Reference:   "fake-ref",
+					ChallengeID: "fake-challenge-id",
+					ExpiresAt:   time.Date(2024, time.January, 1, 0, 10, 0, 0, time.UTC),
+					ResendAfter: 1500 * time.Millisecond,
+					ResendsLeft: 2,
+				}, nil)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusOK,
+					response:   `{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","challenge_id":"fake-challenge-id","expires_at":"2024-01-01T00:10:00Z",` +
+						`"channel":"sms","delivery_ref":"fake-ref","resend_after":2,"resends_left":2}
+`,
+				}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, c, rec, exp := tC.mockFn(t)
+			err := u.ResendOTP(c)
+			if err != nil {
+				echoError := err.(*echo.HTTPError)
+
+				assert.Equal(t, exp.httpStatus, echoError.Code)
+				assert.Equal(t, exp.response, echoError.Message)
 			} else {
 				assert.Equal(t, exp.httpStatus, rec.Code)
 				assert.Equal(t, exp.response, rec.Body.String())
@@ -117,7 +380,7 @@ func TestUser_ValidateOTP(t *testing.T) {
 
 	type expectaion struct {
 		httpStatus int
-		response   string
+		response   interface{}
 	}
 
 	testCases := []struct {
@@ -130,6 +393,7 @@ func TestUser_ValidateOTP(t *testing.T) {
 				user := NewUser(Dependencies{})
 
 				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
 				req := httptest.NewRequest(http.MethodPost, "/otp/validate", strings.NewReader(" "))
 				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 				rec := httptest.NewRecorder()
@@ -141,6 +405,24 @@ func TestUser_ValidateOTP(t *testing.T) {
 				}
 			},
 		},
+		{
+			desc: "ErrorValidatingRequest",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				user := NewUser(Dependencies{})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/otp/validate", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","otp":"12345"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusBadRequest,
+					response:   "Key: 'ValidateOTPRequest.ChallengeID' Error:Field validation for 'ChallengeID' failed on the 'required_unless' tag",
+				}
+			},
+		},
 		{
 			desc: "ErrorValidateOTP",
 			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
@@ -150,17 +432,91 @@ func TestUser_ValidateOTP(t *testing.T) {
 				})
 
 				e := echo.New()
-				req := httptest.NewRequest(http.MethodPost, "/otp/validate", strings.NewReader(`{"user_id":"fake-uuid","otp":"12345","request_id":"fake-request-id"}`))
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/otp/validate", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","otp":"12345","challenge_id":"fake-challenge-id"}`))
 				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 				rec := httptest.NewRecorder()
 				c := e.NewContext(req, rec)
 				ctx := c.Request().Context()
 
-				userSvc.On("ValidateOTP", ctx, "fake-uuid", "12345", "fake-request-id").Return(errors.New("fake error"))
+				userSvc.On("ValidateOTP", ctx, service.ValidateOTPParams{UserUUID: "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24", OTP: "12345", ChallengeID: "fake-challenge-id", Client: service.Client{IP: "192.0.2.1"}}).Return(service.Session{}, errors.New("fake error"))
 
 				return user, c, rec, expectaion{
 					httpStatus: http.StatusInternalServerError,
-					response:   "Internal Server Error",
+					response:   ErrorResponse{Code: "internal_error", Message: "Internal Server Error"},
+				}
+			},
+		},
+		{
+			desc: "ErrorInvalidOTP",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/otp/validate", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","otp":"12345","challenge_id":"fake-challenge-id"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				ctx := c.Request().Context()
+
+				userSvc.On("ValidateOTP", ctx, service.ValidateOTPParams{UserUUID: "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24", OTP: "12345", ChallengeID: "fake-challenge-id", Client: service.Client{IP: "192.0.2.1"}}).Return(service.Session{}, service.ErrInvalidOTP)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusUnauthorized,
+					response:   ErrorResponse{Code: "invalid_otp", Message: "Invalid OTP."},
+				}
+			},
+		},
+		{
+			desc: "ErrorClientMismatch",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/otp/validate", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","otp":"12345","challenge_id":"fake-challenge-id"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				req.Header.Set(HeaderDeviceFingerprint, "other-fingerprint")
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				ctx := c.Request().Context()
+
+				userSvc.On("ValidateOTP", ctx, service.ValidateOTPParams{UserUUID: "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24", OTP: "12345", ChallengeID: "fake-challenge-id", Client: service.Client{Fingerprint: "other-fingerprint", IP: "192.0.2.1"}}).Return(service.Session{}, service.ErrOTPClientMismatch)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusUnauthorized,
+					response:   ErrorResponse{Code: "otp_client_mismatch", Message: "OTP was issued to another client."},
+				}
+			},
+		},
+		{
+			desc: "ErrorOTPExpired",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/otp/validate", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","otp":"12345","challenge_id":"fake-challenge-id"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				ctx := c.Request().Context()
+
+				userSvc.On("ValidateOTP", ctx, service.ValidateOTPParams{UserUUID: "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24", OTP: "12345", ChallengeID: "fake-challenge-id", Client: service.Client{IP: "192.0.2.1"}}).Return(service.Session{}, service.ErrOTPExpired)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusGone,
+					response:   ErrorResponse{Code: "otp_expired", Message: "OTP has expired."},
 				}
 			},
 		},
@@ -173,18 +529,54 @@ func TestUser_ValidateOTP(t *testing.T) {
 				})
 
 				e := echo.New()
-				req := httptest.NewRequest(http.MethodPost, "/otp/validate", strings.NewReader(`{"user_id":"fake-uuid","otp":"12345","request_id":"fake-request-id"}`))
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/otp/validate", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","otp":"12345","challenge_id":"fake-challenge-id"}`))
 				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 				rec := httptest.NewRecorder()
 				rec.Header().Set(echo.HeaderXRequestID, "fake-request-id")
 				c := e.NewContext(req, rec)
 				ctx := c.Request().Context()
 
-				userSvc.On("ValidateOTP", ctx, "fake-uuid", "12345", "fake-request-id").Return(nil)
+				userSvc.On("ValidateOTP", ctx, service.ValidateOTPParams{UserUUID: "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24", OTP: "12345", ChallengeID: "fake-challenge-id", Client: service.Client{IP: "192.0.2.1"}}).Return(service.Session{
+					AccessToken:  "fake-access-token",
+					ExpiresIn:    5 * time.Minute,
+					RefreshToken: "fake-refresh-token",
+				}, nil)
+
+				return user, c, rec, expectaion{
+					httpStatus: http.StatusOK,
+					response: `{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","message":"OTP validated successfully.","access_token":"fake-access-token",` +
+						`"token_type":"Bearer","expires_in":300,"refresh_token":"fake-refresh-token"}
+`,
+				}
+			},
+		},
+		{
+			desc: "SuccessValidateTOTP",
+			mockFn: func(*testing.T) (*User, echo.Context, *httptest.ResponseRecorder, expectaion) {
+				userSvc := mocksvc.NewUserService(t)
+				user := NewUser(Dependencies{
+					User: userSvc,
+				})
+
+				e := echo.New()
+				e.Validator = &testValidator{v: validator.New()}
+				req := httptest.NewRequest(http.MethodPost, "/otp/validate", strings.NewReader(`{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","otp":"287082","method":"totp"}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				ctx := c.Request().Context()
+
+				userSvc.On("ValidateOTP", ctx, service.ValidateOTPParams{UserUUID: "0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24", OTP: "287082", Method: "totp", Client: service.Client{IP: "192.0.2.1"}}).Return(service.Session{
+					AccessToken:  "fake-access-token",
+					ExpiresIn:    5 * time.Minute,
+					RefreshToken: "fake-refresh-token",
+				}, nil)
 
 				return user, c, rec, expectaion{
 					httpStatus: http.StatusOK,
-					response: `{"user_id":"fake-uuid","message":"OTP validated successfully."}
+					response: `{"user_id":"0b7c3f4e-2a9d-4c1e-8f6b-5d3a9e7c1b24","message":"OTP validated successfully.","access_token":"fake-access-token",` +
+						`"token_type":"Bearer","expires_in":300,"refresh_token":"fake-refresh-token"}
 `,
 				}
 			},
@@ -201,7 +593,8 @@ func TestUser_ValidateOTP(t *testing.T) {
 			if err != nil {
 				echoError := err.(*echo.HTTPError)
 
-				assert.Equal(t, fmt.Sprintf("code=%d, message=%v", exp.httpStatus, exp.response), echoError.Error())
+				assert.Equal(t, exp.httpStatus, echoError.Code)
+				assert.Equal(t, exp.response, echoError.Message)
 			} else {
 				assert.Equal(t, exp.httpStatus, rec.Code)
 				assert.Equal(t, exp.response, rec.Body.String())
@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"time"
+)
+
+// The status of a stored OTP, an active OTP may still be past its expiry until
+// a new one is requested for the same purpose.
+const (
+	OTPStatusActive      = "active"
+	OTPStatusUsed        = "used"
+	OTPStatusExpired     = "expired"
+	OTPStatusInvalidated = "invalidated"
+)
+
+type OTP struct {
+	UserID    uint64
+	Code      string
+	Purpose   string
+	RequestID string
+
+	// ChallengeID is handed to the client to validate the OTP with, RequestID
+	// only ties the OTP to the request that issued it for tracing.
+	ChallengeID string
+
+	// Binding is a digest of the client the OTP was requested from, only that
+	// client can validate it. It is empty when the OTP isn't bound.
+	Binding string
+
+	// TTL and MaxAttempts come from the purpose's policy at creation time and
+	// are stored with the OTP, so policy changes only affect new OTPs.
+	TTL         time.Duration
+	MaxAttempts uint8
+
+	// ResendCooldown and MaxResends limit how often the code can be resent,
+	// they're read from the current policy on every resend.
+	ResendCooldown time.Duration
+	MaxResends     uint8
+	ResendCount    uint8
+
+	// ID, Status, Attempts, LastSentAt and ExpiredAt are only set when stored
+	// OTPs are listed for inspection, Code is never readable once stored.
+	ID         uint64
+	Status     string
+	Attempts   uint8
+	LastSentAt time.Time
+	ExpiredAt  time.Time
+}
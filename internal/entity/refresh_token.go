@@ -0,0 +1,14 @@
+package entity
+
+import (
+	"time"
+)
+
+// RefreshToken lets a user obtain new access tokens without validating another
+// OTP, Token is only known in plain text when it is issued.
+type RefreshToken struct {
+	UserID    uint64
+	UserUUID  string
+	Token     string
+	ExpiresAt time.Time
+}
@@ -0,0 +1,10 @@
+package entity
+
+// TOTP is a user's authenticator app enrollment, Secret is encrypted at rest
+// and LastCounter is the last accepted time step, used to reject replays.
+type TOTP struct {
+	UserID      uint64
+	Secret      string
+	Confirmed   bool
+	LastCounter uint64
+}
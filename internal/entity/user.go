@@ -0,0 +1,16 @@
+package entity
+
+type User struct {
+	ID    uint64
+	UUID  string
+	Name  string
+	Email string
+	Phone string
+
+	// OTPChannel is the user's preferred OTP delivery channel, empty means the
+	// configured default channel is used.
+	OTPChannel string
+
+	// Disabled users can't authenticate, they're only visible to operators.
+	Disabled bool
+}
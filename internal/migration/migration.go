@@ -0,0 +1,419 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed mysql/*.sql postgres/*.sql sqlite3/*.sql
+var files embed.FS
+
+var (
+	ErrSchemaBehind     = errors.New("database schema is behind")
+	ErrChecksumMismatch = errors.New("applied migration has changed")
+	ErrUnknownVersion   = errors.New("unknown migration version")
+)
+
+type (
+	// Migration is a pair of <version>_<name>.up.sql and .down.sql scripts,
+	// Checksum is the SHA-256 of the up script and is recorded when the
+	// migration is applied.
+	Migration struct {
+		Version  uint64
+		Name     string
+		Checksum string
+
+		up   string
+		down string
+	}
+
+	Status struct {
+		Migration
+		Applied   bool
+		AppliedAt time.Time
+	}
+
+	// Migrator applies the migrations embedded for a database driver and
+	// records them in the schema_migrations table. Each migration runs in a
+	// transaction, MySQL commits DDL statements implicitly though, so a failed
+	// MySQL migration may have to be cleaned up by hand before it is retried.
+	Migrator struct {
+		db         *sql.DB
+		driver     string
+		migrations []Migration
+		nowFunc    func() time.Time
+	}
+
+	applied struct {
+		checksum  string
+		appliedAt time.Time
+	}
+)
+
+// New returns a Migrator for driver, which is mysql, postgres or sqlite3.
+func New(db *sql.DB, driver string, nowFunc func() time.Time) (*Migrator, error) {
+	migrations, err := load(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		driver:     driver,
+		migrations: migrations,
+		nowFunc:    nowFunc,
+	}, nil
+}
+
+// Latest returns the version of the newest embedded migration.
+func (m *Migrator) Latest() uint64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status returns every embedded migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		a, ok := done[migration.Version]
+		statuses = append(statuses, Status{Migration: migration, Applied: ok, AppliedAt: a.appliedAt})
+	}
+
+	return statuses, nil
+}
+
+// Check returns ErrSchemaBehind when an embedded migration hasn't been applied
+// and ErrChecksumMismatch when an applied migration has changed since.
+func (m *Migrator) Check(ctx context.Context) error {
+	done, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := m.verify(done); err != nil {
+		return err
+	}
+
+	for _, migration := range m.migrations {
+		if _, ok := done[migration.Version]; !ok {
+			return fmt.Errorf("%w: migration %d_%s is pending", ErrSchemaBehind, migration.Version, migration.Name)
+		}
+	}
+
+	return nil
+}
+
+// Up applies every pending migration and returns them.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.To(ctx, m.Latest())
+}
+
+// Down reverts the last applied migration and returns it, nothing is reverted
+// when no migration has been applied.
+func (m *Migrator) Down(ctx context.Context) ([]Migration, error) {
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var last uint64
+	for version := range done {
+		if version > last {
+			last = version
+		}
+	}
+	if last == 0 {
+		return nil, nil
+	}
+
+	var target uint64
+	for _, migration := range m.migrations {
+		if migration.Version < last {
+			target = migration.Version
+		}
+	}
+
+	return m.To(ctx, target)
+}
+
+// To applies or reverts migrations until version is the last one applied,
+// version 0 reverts every migration. It returns the migrations it ran in the
+// order they ran.
+func (m *Migrator) To(ctx context.Context, version uint64) ([]Migration, error) {
+	if version != 0 && m.find(version) < 0 {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.verify(done); err != nil {
+		return nil, err
+	}
+
+	for v := range done {
+		if v > version && m.find(v) < 0 {
+			return nil, fmt.Errorf("%w: %d is applied but not embedded", ErrUnknownVersion, v)
+		}
+	}
+
+	var ran []Migration
+	for _, migration := range m.migrations {
+		if _, ok := done[migration.Version]; ok || migration.Version > version {
+			continue
+		}
+
+		if err := m.run(ctx, migration, true); err != nil {
+			return ran, err
+		}
+		ran = append(ran, migration)
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := done[migration.Version]; !ok || migration.Version <= version {
+			continue
+		}
+
+		if err := m.run(ctx, migration, false); err != nil {
+			return ran, err
+		}
+		ran = append(ran, migration)
+	}
+
+	return ran, nil
+}
+
+func (m *Migrator) run(ctx context.Context, migration Migration, up bool) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	script := migration.down
+	if up {
+		script = migration.up
+	}
+
+	for _, statement := range statements(script) {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, m.rebind(`INSERT INTO schema_migrations (version, name, checksum, applied_at) `+
+			`VALUES (?, ?, ?, ?);`), migration.Version, migration.Name, migration.Checksum, m.nowFunc().UTC())
+	} else {
+		_, err = tx.ExecContext(ctx, m.rebind(`DELETE FROM schema_migrations WHERE version = ?;`), migration.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// applied creates the schema_migrations table when it is missing and returns
+// the migrations it records by version.
+func (m *Migrator) applied(ctx context.Context) (map[uint64]applied, error) {
+	if _, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (`+
+		`version BIGINT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, checksum CHAR(64) NOT NULL, `+
+		`applied_at TIMESTAMP NOT NULL);`); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, `SELECT version, checksum, applied_at FROM schema_migrations;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := make(map[uint64]applied)
+	for rows.Next() {
+		var (
+			version uint64
+			a       applied
+		)
+		if err := rows.Scan(&version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+
+		done[version] = a
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return done, nil
+}
+
+func (m *Migrator) verify(done map[uint64]applied) error {
+	for _, migration := range m.migrations {
+		if a, ok := done[migration.Version]; ok && a.checksum != migration.Checksum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) find(version uint64) int {
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (m *Migrator) rebind(query string) string {
+	if m.driver != "postgres" {
+		return query
+	}
+
+	var (
+		b strings.Builder
+		n int
+	)
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+
+			continue
+		}
+
+		n++
+		b.WriteByte('$')
+		b.WriteString(strconv.Itoa(n))
+	}
+
+	return b.String()
+}
+
+// load reads the migrations embedded for driver, every version needs both an
+// up and a down script.
+func load(driver string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, driver)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for driver: %s", driver)
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+
+		var up bool
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			up = true
+		case strings.HasSuffix(name, ".down.sql"):
+		default:
+			return nil, fmt.Errorf("unexpected migration file: %s", name)
+		}
+
+		base := strings.TrimSuffix(strings.TrimSuffix(name, ".up.sql"), ".down.sql")
+		prefix, title, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration file must be named <version>_<name>: %s", name)
+		}
+
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid migration version: %s", name)
+		}
+
+		content, err := files.ReadFile(path.Join(driver, name))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: title}
+			byVersion[version] = migration
+		}
+		if migration.Name != title {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, title)
+		}
+
+		if up {
+			sum := sha256.Sum256(content)
+			migration.up = string(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == "" || migration.down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs an up and a down script", migration.Version, migration.Name)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(a, b int) bool {
+		return migrations[a].Version < migrations[b].Version
+	})
+
+	return migrations, nil
+}
+
+// statements splits a script on the semicolons ending a line, the scripts
+// don't define routines so no statement has one inside it.
+func statements(script string) []string {
+	var (
+		result  []string
+		current strings.Builder
+	)
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteByte('\n')
+
+		if strings.HasSuffix(trimmed, ";") {
+			result = append(result, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		result = append(result, rest)
+	}
+
+	return result
+}
@@ -0,0 +1,296 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestMigrator(t *testing.T, migrations ...Migration) (*Migrator, *sql.DB) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "migration.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	m, err := New(db, "sqlite3", func() time.Time {
+		return testNow
+	})
+	require.NoError(t, err)
+
+	if len(migrations) > 0 {
+		m.migrations = migrations
+	}
+
+	return m, db
+}
+
+func testMigrations() []Migration {
+	return []Migration{
+		{Version: 1, Name: "create_a", Checksum: "sum-1", up: "CREATE TABLE a (id INTEGER);", down: "DROP TABLE a;"},
+		{Version: 2, Name: "create_b", Checksum: "sum-2", up: "CREATE TABLE b (id INTEGER);", down: "DROP TABLE b;"},
+		{Version: 5, Name: "create_c", Checksum: "sum-5", up: "-- c is the last table\nCREATE TABLE c (\n  id INTEGER\n);",
+			down: "DROP TABLE c;"},
+	}
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	var n int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?;`, name).
+		Scan(&n))
+
+	return n == 1
+}
+
+func versions(migrations []Migration) []uint64 {
+	result := make([]uint64, 0, len(migrations))
+	for _, m := range migrations {
+		result = append(result, m.Version)
+	}
+
+	return result
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	var expected []uint64
+	for _, driver := range []string{"mysql", "postgres", "sqlite3"} {
+		migrations, err := load(driver)
+		require.NoError(t, err)
+		require.NotEmpty(t, migrations)
+
+		if expected == nil {
+			expected = versions(migrations)
+		}
+		assert.Equal(t, expected, versions(migrations), "every driver needs the same migrations")
+
+		for _, m := range migrations {
+			assert.Len(t, m.Checksum, 64)
+			assert.NotEmpty(t, m.up)
+			assert.NotEmpty(t, m.down)
+		}
+	}
+
+	_, err := load("oracle")
+	assert.Error(t, err)
+}
+
+func TestMigrator_Embedded(t *testing.T) {
+	t.Parallel()
+
+	m, db := newTestMigrator(t)
+	ctx := context.Background()
+
+	ran, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, m.migrations, ran)
+	assert.True(t, tableExists(t, db, "users"))
+	assert.NoError(t, m.Check(ctx))
+
+	ran, err = m.To(ctx, 0)
+	require.NoError(t, err)
+	assert.Len(t, ran, len(m.migrations))
+	assert.False(t, tableExists(t, db, "users"))
+	assert.True(t, errors.Is(m.Check(ctx), ErrSchemaBehind))
+}
+
+func TestMigrator_EmbeddedUpgradesBaseline(t *testing.T) {
+	t.Parallel()
+
+	m, db := newTestMigrator(t)
+	ctx := context.Background()
+
+	// a database built before migrations were versioned, with plaintext codes
+	for _, statement := range []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, uuid VARCHAR(36) NOT NULL UNIQUE, ` +
+			`name VARCHAR(50) NOT NULL);`,
+		`CREATE TABLE otps (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL REFERENCES users (id), ` +
+			`otp VARCHAR(5) NOT NULL, request_id VARCHAR(36) NOT NULL, status INTEGER NOT NULL DEFAULT 0, ` +
+			`expired_at TIMESTAMP NOT NULL);`,
+		`INSERT INTO users (uuid, name) VALUES ('ead5f356-ad40-4b71-bc1f-f015cf2dbf26', 'Jhon');`,
+		`INSERT INTO otps (user_id, otp, request_id, status, expired_at) VALUES ` +
+			`(1, '12345', 'fake-request-id', 0, '2024-01-01 00:00:00'), ` +
+			`(1, '54321', 'fake-request-id', 1, '2024-01-01 00:00:00');`,
+	} {
+		_, err := db.Exec(statement)
+		require.NoError(t, err)
+	}
+
+	_, err := m.Up(ctx)
+	require.NoError(t, err)
+
+	rows, err := db.Query(`SELECT otp, otp_key_id, purpose, status, attempts, max_attempts, resend_count, challenge_id ` +
+		`FROM otps ORDER BY id;`)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var got []string
+	for rows.Next() {
+		var (
+			otp, keyID, purpose, challengeID           string
+			status, attempts, maxAttempts, resendCount int
+		)
+		require.NoError(t, rows.Scan(&otp, &keyID, &purpose, &status, &attempts, &maxAttempts, &resendCount,
+			&challengeID))
+		got = append(got, fmt.Sprintf("%q %q %s %d %d %d %d %q", otp, keyID, purpose, status, attempts, maxAttempts,
+			resendCount, challengeID))
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{
+		`"" "" login 2 0 5 0 ""`,
+		`"" "" login 1 0 5 0 ""`,
+	}, got, "plaintext codes are scrubbed and the unused one expired")
+
+	var email sql.NullString
+	require.NoError(t, db.QueryRow(`SELECT email FROM users WHERE id = 1;`).Scan(&email))
+	assert.False(t, email.Valid)
+	assert.True(t, tableExists(t, db, "user_totps"))
+
+	_, err = m.To(ctx, 0)
+	require.NoError(t, err)
+	assert.False(t, tableExists(t, db, "otps"))
+
+	_, err = m.Up(ctx)
+	assert.NoError(t, err)
+}
+
+func TestMigrator_Up(t *testing.T) {
+	t.Parallel()
+
+	m, db := newTestMigrator(t, testMigrations()...)
+	ctx := context.Background()
+
+	assert.True(t, errors.Is(m.Check(ctx), ErrSchemaBehind))
+
+	ran, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 5}, versions(ran))
+	assert.True(t, tableExists(t, db, "c"))
+	assert.NoError(t, m.Check(ctx))
+
+	ran, err = m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, ran)
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	for _, s := range statuses {
+		assert.True(t, s.Applied)
+		assert.True(t, testNow.Equal(s.AppliedAt))
+	}
+}
+
+func TestMigrator_Down(t *testing.T) {
+	t.Parallel()
+
+	m, db := newTestMigrator(t, testMigrations()...)
+	ctx := context.Background()
+
+	ran, err := m.Down(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, ran)
+
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+
+	ran, err = m.Down(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{5}, versions(ran))
+	assert.False(t, tableExists(t, db, "c"))
+	assert.True(t, tableExists(t, db, "b"))
+	assert.True(t, errors.Is(m.Check(ctx), ErrSchemaBehind))
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, statuses[1].Applied)
+	assert.False(t, statuses[2].Applied)
+	assert.True(t, statuses[2].AppliedAt.IsZero())
+}
+
+func TestMigrator_To(t *testing.T) {
+	t.Parallel()
+
+	m, db := newTestMigrator(t, testMigrations()...)
+	ctx := context.Background()
+
+	ran, err := m.To(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2}, versions(ran))
+	assert.False(t, tableExists(t, db, "c"))
+
+	ran, err = m.To(ctx, 5)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{5}, versions(ran))
+
+	ran, err = m.To(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{5, 2}, versions(ran))
+	assert.True(t, tableExists(t, db, "a"))
+	assert.False(t, tableExists(t, db, "b"))
+
+	_, err = m.To(ctx, 3)
+	assert.True(t, errors.Is(err, ErrUnknownVersion))
+}
+
+func TestMigrator_ChecksumMismatch(t *testing.T) {
+	t.Parallel()
+
+	m, db := newTestMigrator(t, testMigrations()...)
+	ctx := context.Background()
+
+	_, err := m.To(ctx, 2)
+	require.NoError(t, err)
+
+	_, err = db.Exec(`UPDATE schema_migrations SET checksum = 'changed' WHERE version = 1;`)
+	require.NoError(t, err)
+
+	assert.True(t, errors.Is(m.Check(ctx), ErrChecksumMismatch))
+
+	ran, err := m.Up(ctx)
+	assert.True(t, errors.Is(err, ErrChecksumMismatch))
+	assert.Empty(t, ran)
+	assert.False(t, tableExists(t, db, "c"))
+}
+
+func TestMigrator_FailedMigration(t *testing.T) {
+	t.Parallel()
+
+	migrations := testMigrations()
+	migrations[1].up = "CREATE TABLE b (id INTEGER);\nCREATE TABLE a (id INTEGER);"
+	m, db := newTestMigrator(t, migrations...)
+	ctx := context.Background()
+
+	ran, err := m.Up(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, []uint64{1}, versions(ran))
+	assert.False(t, tableExists(t, db, "b"), "the failed migration is rolled back")
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[1].Applied)
+}
+
+func TestStatements(t *testing.T) {
+	t.Parallel()
+
+	script := "-- header\n\nCREATE TABLE a (\n  id INTEGER\n);\n-- between\nDROP TABLE b;\nSELECT 1"
+
+	assert.Equal(t, []string{
+		"CREATE TABLE a (\n  id INTEGER\n);",
+		"DROP TABLE b;",
+		"SELECT 1",
+	}, statements(script))
+}
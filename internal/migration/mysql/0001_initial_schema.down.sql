@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `otps`;
+DROP TABLE IF EXISTS `users`;
@@ -0,0 +1,25 @@
+-- Creates the schema the application had before migrations were versioned,
+-- exactly as the former sql/sqetest.sql dump did. Tables are only created when
+-- missing, so a database built from the dump adopts migrations by running up
+-- and every later change is applied to it by the migrations that follow.
+
+CREATE TABLE IF NOT EXISTS `users` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `uuid` varchar(36) NOT NULL,
+  `name` varchar(50) NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `users_pk_2` (`uuid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE IF NOT EXISTS `otps` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` bigint NOT NULL,
+  `otp` varchar(5) NOT NULL,
+  `request_id` varchar(36) NOT NULL,
+  `status` tinyint NOT NULL DEFAULT '0',
+  `expired_at` timestamp NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `otps_users_id_fk` (`user_id`),
+  KEY `otps_otp_index` (`otp`),
+  CONSTRAINT `otps_users_id_fk` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
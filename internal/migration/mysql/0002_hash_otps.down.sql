@@ -0,0 +1,10 @@
+-- Digests can't be turned back into codes, the OTPs stored as digests are
+-- deleted so the codes fit the former column again.
+
+DELETE FROM `otps` WHERE `otp_key_id` <> '';
+
+ALTER TABLE `otps`
+  DROP INDEX `otps_user_id_status_index`,
+  ADD INDEX `otps_otp_index` (`otp`),
+  DROP COLUMN `otp_key_id`,
+  MODIFY `otp` varchar(5) NOT NULL;
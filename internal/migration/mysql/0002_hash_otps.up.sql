@@ -0,0 +1,10 @@
+-- Stores OTPs as keyed HMAC digests, otp_key_id names the pepper a digest was
+-- keyed with and is empty for the plaintext codes stored so far.
+
+ALTER TABLE `otps`
+  MODIFY `otp` varchar(64) NOT NULL,
+  ADD COLUMN `otp_key_id` varchar(16) NOT NULL DEFAULT '' AFTER `otp`,
+  DROP INDEX `otps_otp_index`,
+  ADD INDEX `otps_user_id_status_index` (`user_id`, `status`);
+
+ALTER TABLE `otps` ALTER COLUMN `otp_key_id` DROP DEFAULT;
@@ -0,0 +1,6 @@
+-- Plaintext codes can't be re-hashed without trusting their value, so every
+-- unused plaintext OTP is expired (users simply request a new one) and the
+-- plaintext of every legacy row is scrubbed.
+
+UPDATE `otps` SET `status` = 2 WHERE `otp_key_id` = '' AND `status` = 0;
+UPDATE `otps` SET `otp` = '' WHERE `otp_key_id` = '';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `user_lockouts`;
+ALTER TABLE `otps` DROP COLUMN `attempts`;
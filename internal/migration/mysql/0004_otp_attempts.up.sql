@@ -0,0 +1,12 @@
+-- Adds per-OTP attempt counters and per-user lockouts.
+
+ALTER TABLE `otps`
+  ADD COLUMN `attempts` tinyint unsigned NOT NULL DEFAULT '0' AFTER `status`;
+
+CREATE TABLE `user_lockouts` (
+  `user_id` bigint NOT NULL,
+  `lockouts` int unsigned NOT NULL DEFAULT '0',
+  `locked_until` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`user_id`),
+  CONSTRAINT `user_lockouts_users_id_fk` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,4 @@
+ALTER TABLE `users`
+  DROP COLUMN `otp_channel`,
+  DROP COLUMN `phone`,
+  DROP COLUMN `email`;
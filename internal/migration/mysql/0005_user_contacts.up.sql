@@ -0,0 +1,6 @@
+-- Adds contact details and the preferred OTP delivery channel to users.
+
+ALTER TABLE `users`
+  ADD COLUMN `email` varchar(255) DEFAULT NULL AFTER `name`,
+  ADD COLUMN `phone` varchar(20) DEFAULT NULL AFTER `email`,
+  ADD COLUMN `otp_channel` varchar(16) DEFAULT NULL AFTER `phone`;
@@ -0,0 +1,5 @@
+ALTER TABLE `otps`
+  DROP INDEX `otps_user_id_purpose_status_index`,
+  ADD INDEX `otps_user_id_status_index` (`user_id`, `status`),
+  DROP COLUMN `max_attempts`,
+  DROP COLUMN `purpose`;
@@ -0,0 +1,8 @@
+-- Adds the OTP purpose and the per-OTP attempt limit taken from its policy.
+-- Existing rows were all issued for logins with the former limit of 5.
+
+ALTER TABLE `otps`
+  ADD COLUMN `purpose` varchar(32) NOT NULL DEFAULT 'login' AFTER `otp_key_id`,
+  ADD COLUMN `max_attempts` tinyint unsigned NOT NULL DEFAULT '5' AFTER `attempts`,
+  DROP INDEX `otps_user_id_status_index`,
+  ADD INDEX `otps_user_id_purpose_status_index` (`user_id`, `purpose`, `status`);
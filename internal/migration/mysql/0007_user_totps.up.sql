@@ -0,0 +1,12 @@
+-- Adds authenticator app (TOTP) enrollments, the secret is AES-GCM sealed by
+-- the application and last_counter is the last accepted time step.
+
+CREATE TABLE `user_totps` (
+  `user_id` bigint NOT NULL,
+  `secret` varchar(255) NOT NULL,
+  `last_counter` bigint unsigned NOT NULL DEFAULT '0',
+  `confirmed_at` timestamp NULL DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`user_id`),
+  CONSTRAINT `user_totps_users_id_fk` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
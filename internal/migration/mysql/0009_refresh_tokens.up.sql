@@ -0,0 +1,15 @@
+-- Stores the refresh tokens issued with every session, tokens are kept as
+-- SHA-256 digests and revoked once they are exchanged for a new session.
+
+CREATE TABLE `refresh_tokens` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `user_id` bigint NOT NULL,
+  `token_hash` char(64) NOT NULL,
+  `expires_at` timestamp NOT NULL,
+  `revoked_at` timestamp NULL DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `refresh_tokens_token_hash_uindex` (`token_hash`),
+  KEY `refresh_tokens_users_id_fk` (`user_id`),
+  CONSTRAINT `refresh_tokens_users_id_fk` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,3 @@
+-- Lets operators disable users, a disabled user can no longer authenticate.
+
+ALTER TABLE `users` ADD COLUMN `disabled_at` timestamp NULL DEFAULT NULL AFTER `otp_channel`;
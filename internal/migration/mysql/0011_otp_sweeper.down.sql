@@ -0,0 +1,3 @@
+DROP INDEX `otps_expired_at_index` ON `otps`;
+DROP TABLE IF EXISTS `leases`;
+DROP TABLE IF EXISTS `otps_archive`;
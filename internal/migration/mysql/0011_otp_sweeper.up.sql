@@ -0,0 +1,27 @@
+-- Backs the OTP sweeper: swept OTPs can be archived into otps_archive and the
+-- leases table elects the replica that sweeps.
+
+CREATE TABLE IF NOT EXISTS `otps_archive` (
+  `id` int NOT NULL,
+  `user_id` bigint NOT NULL,
+  `purpose` varchar(32) NOT NULL,
+  `request_id` varchar(36) NOT NULL,
+  `status` tinyint NOT NULL,
+  `attempts` tinyint unsigned NOT NULL,
+  `max_attempts` tinyint unsigned NOT NULL,
+  `resend_count` tinyint unsigned NOT NULL,
+  `last_sent_at` timestamp NOT NULL,
+  `expired_at` timestamp NOT NULL,
+  `archived_at` timestamp NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `otps_archive_user_id_index` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE IF NOT EXISTS `leases` (
+  `name` varchar(64) NOT NULL,
+  `holder` varchar(64) NOT NULL,
+  `expires_at` timestamp NOT NULL,
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE INDEX `otps_expired_at_index` ON `otps` (`expired_at`);
@@ -0,0 +1 @@
+ALTER TABLE `otps` DROP COLUMN `challenge_id`;
@@ -0,0 +1,4 @@
+-- Lets operators delete users, an anonymized user keeps its row for the OTPs and
+-- tokens referencing it but loses its personal data and stops being listed.
+
+ALTER TABLE `users` ADD COLUMN `anonymized_at` timestamp NULL DEFAULT NULL AFTER `disabled_at`;
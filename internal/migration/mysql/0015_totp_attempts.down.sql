@@ -0,0 +1 @@
+ALTER TABLE `user_totps` DROP COLUMN `attempts`;
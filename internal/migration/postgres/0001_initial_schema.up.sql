@@ -0,0 +1,19 @@
+-- Creates the schema the application had before migrations were versioned,
+-- the same tables the MySQL dump had so every driver runs the same migrations.
+
+CREATE TABLE IF NOT EXISTS users (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  uuid VARCHAR(36) NOT NULL UNIQUE,
+  name VARCHAR(50) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS otps (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users (id),
+  otp VARCHAR(5) NOT NULL,
+  request_id VARCHAR(36) NOT NULL,
+  status SMALLINT NOT NULL DEFAULT 0,
+  expired_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS otps_otp_index ON otps (otp);
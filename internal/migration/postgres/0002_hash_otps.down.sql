@@ -0,0 +1,11 @@
+-- Digests can't be turned back into codes, the OTPs stored as digests are
+-- deleted so the codes fit the former column again.
+
+DELETE FROM otps WHERE otp_key_id <> '';
+
+DROP INDEX IF EXISTS otps_user_id_status_index;
+CREATE INDEX otps_otp_index ON otps (otp);
+
+ALTER TABLE otps
+  DROP COLUMN otp_key_id,
+  ALTER COLUMN otp TYPE VARCHAR(5);
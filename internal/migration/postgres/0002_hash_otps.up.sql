@@ -0,0 +1,11 @@
+-- Stores OTPs as keyed HMAC digests, otp_key_id names the pepper a digest was
+-- keyed with and is empty for the plaintext codes stored so far.
+
+ALTER TABLE otps
+  ALTER COLUMN otp TYPE VARCHAR(64),
+  ADD COLUMN otp_key_id VARCHAR(16) NOT NULL DEFAULT '';
+
+DROP INDEX IF EXISTS otps_otp_index;
+CREATE INDEX otps_user_id_status_index ON otps (user_id, status);
+
+ALTER TABLE otps ALTER COLUMN otp_key_id DROP DEFAULT;
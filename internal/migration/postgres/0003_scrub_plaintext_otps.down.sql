@@ -0,0 +1 @@
+-- The scrubbed codes are gone for good, there is nothing to revert.
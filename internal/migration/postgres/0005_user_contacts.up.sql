@@ -0,0 +1,6 @@
+-- Adds contact details and the preferred OTP delivery channel to users.
+
+ALTER TABLE users
+  ADD COLUMN email VARCHAR(255) DEFAULT NULL,
+  ADD COLUMN phone VARCHAR(20) DEFAULT NULL,
+  ADD COLUMN otp_channel VARCHAR(16) DEFAULT NULL;
@@ -0,0 +1,10 @@
+-- Adds authenticator app (TOTP) enrollments, the secret is AES-GCM sealed by
+-- the application and last_counter is the last accepted time step.
+
+CREATE TABLE user_totps (
+  user_id BIGINT PRIMARY KEY REFERENCES users (id),
+  secret VARCHAR(255) NOT NULL,
+  last_counter BIGINT NOT NULL DEFAULT 0,
+  confirmed_at TIMESTAMPTZ DEFAULT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
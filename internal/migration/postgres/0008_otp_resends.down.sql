@@ -0,0 +1,3 @@
+ALTER TABLE otps
+  DROP COLUMN last_sent_at,
+  DROP COLUMN resend_count;
@@ -0,0 +1,6 @@
+-- Tracks how many times an OTP has been resent and when it was last sent so
+-- that resends can be limited and throttled.
+
+ALTER TABLE otps
+  ADD COLUMN resend_count SMALLINT NOT NULL DEFAULT 0,
+  ADD COLUMN last_sent_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
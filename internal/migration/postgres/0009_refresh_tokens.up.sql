@@ -0,0 +1,13 @@
+-- Stores the refresh tokens issued with every session, tokens are kept as
+-- SHA-256 digests and revoked once they are exchanged for a new session.
+
+CREATE TABLE refresh_tokens (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users (id),
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  expires_at TIMESTAMPTZ NOT NULL,
+  revoked_at TIMESTAMPTZ DEFAULT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX refresh_tokens_user_id_index ON refresh_tokens (user_id);
@@ -0,0 +1,4 @@
+-- Stores the challenge ID handed to the client to validate an OTP with, it
+-- replaces the request ID which is only kept for tracing.
+
+ALTER TABLE otps ADD COLUMN challenge_id VARCHAR(64) NOT NULL DEFAULT '';
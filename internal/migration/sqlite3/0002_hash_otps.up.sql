@@ -0,0 +1,9 @@
+-- Stores OTPs as keyed HMAC digests, otp_key_id names the pepper a digest was
+-- keyed with and is empty for the plaintext codes stored so far. SQLite doesn't
+-- enforce the length of otp nor can it drop the default of otp_key_id, the
+-- application always sets it.
+
+ALTER TABLE otps ADD COLUMN otp_key_id VARCHAR(16) NOT NULL DEFAULT '';
+
+DROP INDEX IF EXISTS otps_otp_index;
+CREATE INDEX otps_user_id_status_index ON otps (user_id, status);
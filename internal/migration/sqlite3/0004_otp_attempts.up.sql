@@ -0,0 +1,9 @@
+-- Adds per-OTP attempt counters and per-user lockouts.
+
+ALTER TABLE otps ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE user_lockouts (
+  user_id INTEGER PRIMARY KEY REFERENCES users (id),
+  lockouts INTEGER NOT NULL DEFAULT 0,
+  locked_until TIMESTAMP DEFAULT NULL
+);
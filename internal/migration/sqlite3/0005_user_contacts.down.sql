@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN otp_channel;
+ALTER TABLE users DROP COLUMN phone;
+ALTER TABLE users DROP COLUMN email;
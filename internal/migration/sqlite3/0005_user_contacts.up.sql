@@ -0,0 +1,5 @@
+-- Adds contact details and the preferred OTP delivery channel to users.
+
+ALTER TABLE users ADD COLUMN email VARCHAR(255) DEFAULT NULL;
+ALTER TABLE users ADD COLUMN phone VARCHAR(20) DEFAULT NULL;
+ALTER TABLE users ADD COLUMN otp_channel VARCHAR(16) DEFAULT NULL;
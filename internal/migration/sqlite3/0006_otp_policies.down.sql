@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS otps_user_id_purpose_status_index;
+CREATE INDEX otps_user_id_status_index ON otps (user_id, status);
+
+ALTER TABLE otps DROP COLUMN max_attempts;
+ALTER TABLE otps DROP COLUMN purpose;
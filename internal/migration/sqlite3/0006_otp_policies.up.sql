@@ -0,0 +1,8 @@
+-- Adds the OTP purpose and the per-OTP attempt limit taken from its policy.
+-- Existing rows were all issued for logins with the former limit of 5.
+
+ALTER TABLE otps ADD COLUMN purpose VARCHAR(32) NOT NULL DEFAULT 'login';
+ALTER TABLE otps ADD COLUMN max_attempts INTEGER NOT NULL DEFAULT 5;
+
+DROP INDEX IF EXISTS otps_user_id_status_index;
+CREATE INDEX otps_user_id_purpose_status_index ON otps (user_id, purpose, status);
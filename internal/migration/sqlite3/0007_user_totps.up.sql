@@ -0,0 +1,10 @@
+-- Adds authenticator app (TOTP) enrollments, the secret is AES-GCM sealed by
+-- the application and last_counter is the last accepted time step.
+
+CREATE TABLE user_totps (
+  user_id INTEGER PRIMARY KEY REFERENCES users (id),
+  secret VARCHAR(255) NOT NULL,
+  last_counter INTEGER NOT NULL DEFAULT 0,
+  confirmed_at TIMESTAMP DEFAULT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,9 @@
+-- Tracks how many times an OTP has been resent and when it was last sent so
+-- that resends can be limited and throttled. SQLite can't add a column
+-- defaulting to CURRENT_TIMESTAMP, existing rows are set to it instead and
+-- the application always sets last_sent_at.
+
+ALTER TABLE otps ADD COLUMN resend_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE otps ADD COLUMN last_sent_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+
+UPDATE otps SET last_sent_at = CURRENT_TIMESTAMP;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS otps_expired_at_index;
+DROP TABLE IF EXISTS leases;
+DROP TABLE IF EXISTS otps_archive;
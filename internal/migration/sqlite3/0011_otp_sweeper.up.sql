@@ -0,0 +1,26 @@
+-- Backs the OTP sweeper: swept OTPs can be archived into otps_archive and the
+-- leases table elects the replica that sweeps.
+
+CREATE TABLE IF NOT EXISTS otps_archive (
+  id INTEGER PRIMARY KEY,
+  user_id INTEGER NOT NULL,
+  purpose VARCHAR(32) NOT NULL,
+  request_id VARCHAR(36) NOT NULL,
+  status INTEGER NOT NULL,
+  attempts INTEGER NOT NULL,
+  max_attempts INTEGER NOT NULL,
+  resend_count INTEGER NOT NULL,
+  last_sent_at TIMESTAMP NOT NULL,
+  expired_at TIMESTAMP NOT NULL,
+  archived_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS otps_archive_user_id_index ON otps_archive (user_id);
+
+CREATE TABLE IF NOT EXISTS leases (
+  name VARCHAR(64) PRIMARY KEY,
+  holder VARCHAR(64) NOT NULL,
+  expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS otps_expired_at_index ON otps (expired_at);
@@ -0,0 +1,4 @@
+-- Binds an OTP to the client that requested it, binding is a digest of the
+-- client's fingerprint and/or IP and is empty for an OTP that isn't bound.
+
+ALTER TABLE otps ADD COLUMN binding VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+-- Counts invalid authenticator app codes so they lock the user out like invalid
+-- OTPs do, the count starts over after a valid code.
+
+ALTER TABLE user_totps ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
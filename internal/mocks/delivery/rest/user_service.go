@@ -5,7 +5,11 @@ package mocks
 import (
 	context "context"
 
+	entity "github.com/subroll/sqetest/internal/entity"
+
 	mock "github.com/stretchr/testify/mock"
+
+	service "github.com/subroll/sqetest/internal/service"
 )
 
 // UserService is an autogenerated mock type for the UserService type
@@ -13,27 +17,63 @@ type UserService struct {
 	mock.Mock
 }
 
-// GenerateOTP provides a mock function with given fields: ctx, userUUID, requestID
-func (_m *UserService) GenerateOTP(ctx context.Context, userUUID string, requestID string) (string, error) {
-	ret := _m.Called(ctx, userUUID, requestID)
+// AnonymizeUser provides a mock function with given fields: ctx, userUUID
+func (_m *UserService) AnonymizeUser(ctx context.Context, userUUID string) error {
+	ret := _m.Called(ctx, userUUID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GenerateOTP")
+		panic("no return value specified for AnonymizeUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, userUUID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ConfirmTOTP provides a mock function with given fields: ctx, userUUID, code
+func (_m *UserService) ConfirmTOTP(ctx context.Context, userUUID string, code string) error {
+	ret := _m.Called(ctx, userUUID, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfirmTOTP")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, userUUID, code)
+	} else {
+		r0 = ret.Error(0)
 	}
 
-	var r0 string
+	return r0
+}
+
+// CreateUser provides a mock function with given fields: ctx, params
+func (_m *UserService) CreateUser(ctx context.Context, params service.CreateUserParams) (entity.User, error) {
+	ret := _m.Called(ctx, params)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateUser")
+	}
+
+	var r0 entity.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
-		return rf(ctx, userUUID, requestID)
+	if rf, ok := ret.Get(0).(func(context.Context, service.CreateUserParams) (entity.User, error)); ok {
+		return rf(ctx, params)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
-		r0 = rf(ctx, userUUID, requestID)
+	if rf, ok := ret.Get(0).(func(context.Context, service.CreateUserParams) entity.User); ok {
+		r0 = rf(ctx, params)
 	} else {
-		r0 = ret.Get(0).(string)
+		r0 = ret.Get(0).(entity.User)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
-		r1 = rf(ctx, userUUID, requestID)
+	if rf, ok := ret.Get(1).(func(context.Context, service.CreateUserParams) error); ok {
+		r1 = rf(ctx, params)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -41,17 +81,17 @@ func (_m *UserService) GenerateOTP(ctx context.Context, userUUID string, request
 	return r0, r1
 }
 
-// ValidateOTP provides a mock function with given fields: ctx, userID, otp, requestID
-func (_m *UserService) ValidateOTP(ctx context.Context, userID string, otp string, requestID string) error {
-	ret := _m.Called(ctx, userID, otp, requestID)
+// DisableUser provides a mock function with given fields: ctx, userUUID
+func (_m *UserService) DisableUser(ctx context.Context, userUUID string) error {
+	ret := _m.Called(ctx, userUUID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for ValidateOTP")
+		panic("no return value specified for DisableUser")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
-		r0 = rf(ctx, userID, otp, requestID)
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, userUUID)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -59,6 +99,232 @@ func (_m *UserService) ValidateOTP(ctx context.Context, userID string, otp strin
 	return r0
 }
 
+// EnrollTOTP provides a mock function with given fields: ctx, userUUID
+func (_m *UserService) EnrollTOTP(ctx context.Context, userUUID string) (service.TOTPEnrollment, error) {
+	ret := _m.Called(ctx, userUUID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnrollTOTP")
+	}
+
+	var r0 service.TOTPEnrollment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (service.TOTPEnrollment, error)); ok {
+		return rf(ctx, userUUID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) service.TOTPEnrollment); ok {
+		r0 = rf(ctx, userUUID)
+	} else {
+		r0 = ret.Get(0).(service.TOTPEnrollment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userUUID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GenerateOTP provides a mock function with given fields: ctx, params
+func (_m *UserService) GenerateOTP(ctx context.Context, params service.GenerateOTPParams) (service.Delivery, error) {
+	ret := _m.Called(ctx, params)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GenerateOTP")
+	}
+
+	var r0 service.Delivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.GenerateOTPParams) (service.Delivery, error)); ok {
+		return rf(ctx, params)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.GenerateOTPParams) service.Delivery); ok {
+		r0 = rf(ctx, params)
+	} else {
+		r0 = ret.Get(0).(service.Delivery)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.GenerateOTPParams) error); ok {
+		r1 = rf(ctx, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUser provides a mock function with given fields: ctx, userUUID
+func (_m *UserService) GetUser(ctx context.Context, userUUID string) (entity.User, error) {
+	ret := _m.Called(ctx, userUUID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUser")
+	}
+
+	var r0 entity.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (entity.User, error)); ok {
+		return rf(ctx, userUUID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) entity.User); ok {
+		r0 = rf(ctx, userUUID)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userUUID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListUsers provides a mock function with given fields: ctx, params
+func (_m *UserService) ListUsers(ctx context.Context, params service.ListUsersParams) ([]entity.User, error) {
+	ret := _m.Called(ctx, params)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListUsers")
+	}
+
+	var r0 []entity.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.ListUsersParams) ([]entity.User, error)); ok {
+		return rf(ctx, params)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.ListUsersParams) []entity.User); ok {
+		r0 = rf(ctx, params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.ListUsersParams) error); ok {
+		r1 = rf(ctx, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RefreshSession provides a mock function with given fields: ctx, refreshToken
+func (_m *UserService) RefreshSession(ctx context.Context, refreshToken string) (service.Session, error) {
+	ret := _m.Called(ctx, refreshToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RefreshSession")
+	}
+
+	var r0 service.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (service.Session, error)); ok {
+		return rf(ctx, refreshToken)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) service.Session); ok {
+		r0 = rf(ctx, refreshToken)
+	} else {
+		r0 = ret.Get(0).(service.Session)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, refreshToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ResendOTP provides a mock function with given fields: ctx, params
+func (_m *UserService) ResendOTP(ctx context.Context, params service.ResendOTPParams) (service.Delivery, error) {
+	ret := _m.Called(ctx, params)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResendOTP")
+	}
+
+	var r0 service.Delivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.ResendOTPParams) (service.Delivery, error)); ok {
+		return rf(ctx, params)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.ResendOTPParams) service.Delivery); ok {
+		r0 = rf(ctx, params)
+	} else {
+		r0 = ret.Get(0).(service.Delivery)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.ResendOTPParams) error); ok {
+		r1 = rf(ctx, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateUser provides a mock function with given fields: ctx, params
+func (_m *UserService) UpdateUser(ctx context.Context, params service.UpdateUserParams) (entity.User, error) {
+	ret := _m.Called(ctx, params)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateUser")
+	}
+
+	var r0 entity.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.UpdateUserParams) (entity.User, error)); ok {
+		return rf(ctx, params)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.UpdateUserParams) entity.User); ok {
+		r0 = rf(ctx, params)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.UpdateUserParams) error); ok {
+		r1 = rf(ctx, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ValidateOTP provides a mock function with given fields: ctx, params
+func (_m *UserService) ValidateOTP(ctx context.Context, params service.ValidateOTPParams) (service.Session, error) {
+	ret := _m.Called(ctx, params)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ValidateOTP")
+	}
+
+	var r0 service.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.ValidateOTPParams) (service.Session, error)); ok {
+		return rf(ctx, params)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.ValidateOTPParams) service.Session); ok {
+		r0 = rf(ctx, params)
+	} else {
+		r0 = ret.Get(0).(service.Session)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.ValidateOTPParams) error); ok {
+		r1 = rf(ctx, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewUserService creates a new instance of UserService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewUserService(t interface {
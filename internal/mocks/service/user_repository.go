@@ -5,6 +5,8 @@ package mocks
 import (
 	context "context"
 
+	entity "github.com/subroll/sqetest/internal/entity"
+
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -13,6 +15,226 @@ type UserRepository struct {
 	mock.Mock
 }
 
+// AnonymizeUser provides a mock function with given fields: ctx, uuid
+func (_m *UserRepository) AnonymizeUser(ctx context.Context, uuid string) error {
+	ret := _m.Called(ctx, uuid)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AnonymizeUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, uuid)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CheckLockout provides a mock function with given fields: ctx, userID
+func (_m *UserRepository) CheckLockout(ctx context.Context, userID uint64) error {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CheckLockout")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ConfirmTOTP provides a mock function with given fields: ctx, userID, counter
+func (_m *UserRepository) ConfirmTOTP(ctx context.Context, userID uint64, counter uint64) error {
+	ret := _m.Called(ctx, userID, counter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfirmTOTP")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) error); ok {
+		r0 = rf(ctx, userID, counter)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateUser provides a mock function with given fields: ctx, user
+func (_m *UserRepository) CreateUser(ctx context.Context, user entity.User) (entity.User, error) {
+	ret := _m.Called(ctx, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateUser")
+	}
+
+	var r0 entity.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) (entity.User, error)); ok {
+		return rf(ctx, user)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) entity.User); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User) error); ok {
+		r1 = rf(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DisableUser provides a mock function with given fields: ctx, uuid
+func (_m *UserRepository) DisableUser(ctx context.Context, uuid string) error {
+	ret := _m.Called(ctx, uuid)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DisableUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, uuid)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ExpireOTP provides a mock function with given fields: ctx, userID, purpose
+func (_m *UserRepository) ExpireOTP(ctx context.Context, userID uint64, purpose string) error {
+	ret := _m.Called(ctx, userID, purpose)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExpireOTP")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, string) error); ok {
+		r0 = rf(ctx, userID, purpose)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FailTOTPAttempt provides a mock function with given fields: ctx, userID, maxAttempts
+func (_m *UserRepository) FailTOTPAttempt(ctx context.Context, userID uint64, maxAttempts uint8) error {
+	ret := _m.Called(ctx, userID, maxAttempts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FailTOTPAttempt")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint8) error); ok {
+		r0 = rf(ctx, userID, maxAttempts)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetTOTP provides a mock function with given fields: ctx, userID
+func (_m *UserRepository) GetTOTP(ctx context.Context, userID uint64) (entity.TOTP, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTOTP")
+	}
+
+	var r0 entity.TOTP
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) (entity.TOTP, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) entity.TOTP); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Get(0).(entity.TOTP)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUser provides a mock function with given fields: ctx, uuid
+func (_m *UserRepository) GetUser(ctx context.Context, uuid string) (entity.User, error) {
+	ret := _m.Called(ctx, uuid)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUser")
+	}
+
+	var r0 entity.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (entity.User, error)); ok {
+		return rf(ctx, uuid)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) entity.User); ok {
+		r0 = rf(ctx, uuid)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, uuid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserByUUID provides a mock function with given fields: ctx, uuid
+func (_m *UserRepository) GetUserByUUID(ctx context.Context, uuid string) (entity.User, error) {
+	ret := _m.Called(ctx, uuid)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserByUUID")
+	}
+
+	var r0 entity.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (entity.User, error)); ok {
+		return rf(ctx, uuid)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) entity.User); ok {
+		r0 = rf(ctx, uuid)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, uuid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUserIDByUUID provides a mock function with given fields: ctx, uuid
 func (_m *UserRepository) GetUserIDByUUID(ctx context.Context, uuid string) (uint64, error) {
 	ret := _m.Called(ctx, uuid)
@@ -41,17 +263,151 @@ func (_m *UserRepository) GetUserIDByUUID(ctx context.Context, uuid string) (uin
 	return r0, r1
 }
 
-// StoreOTP provides a mock function with given fields: ctx, userID, otp, requestID
-func (_m *UserRepository) StoreOTP(ctx context.Context, userID uint64, otp string, requestID string) error {
-	ret := _m.Called(ctx, userID, otp, requestID)
+// ListOTPs provides a mock function with given fields: ctx, userID, limit
+func (_m *UserRepository) ListOTPs(ctx context.Context, userID uint64, limit uint) ([]entity.OTP, error) {
+	ret := _m.Called(ctx, userID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListOTPs")
+	}
+
+	var r0 []entity.OTP
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint) ([]entity.OTP, error)); ok {
+		return rf(ctx, userID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint) []entity.OTP); ok {
+		r0 = rf(ctx, userID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.OTP)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, uint) error); ok {
+		r1 = rf(ctx, userID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListUsers provides a mock function with given fields: ctx, limit, offset
+func (_m *UserRepository) ListUsers(ctx context.Context, limit uint, offset uint) ([]entity.User, error) {
+	ret := _m.Called(ctx, limit, offset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListUsers")
+	}
+
+	var r0 []entity.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint, uint) ([]entity.User, error)); ok {
+		return rf(ctx, limit, offset)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint, uint) []entity.User); ok {
+		r0 = rf(ctx, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint, uint) error); ok {
+		r1 = rf(ctx, limit, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ResendOTP provides a mock function with given fields: ctx, otp
+func (_m *UserRepository) ResendOTP(ctx context.Context, otp entity.OTP) (entity.OTP, error) {
+	ret := _m.Called(ctx, otp)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResendOTP")
+	}
+
+	var r0 entity.OTP
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.OTP) (entity.OTP, error)); ok {
+		return rf(ctx, otp)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.OTP) entity.OTP); ok {
+		r0 = rf(ctx, otp)
+	} else {
+		r0 = ret.Get(0).(entity.OTP)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.OTP) error); ok {
+		r1 = rf(ctx, otp)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeOTP provides a mock function with given fields: ctx, userID, purpose
+func (_m *UserRepository) RevokeOTP(ctx context.Context, userID uint64, purpose string) error {
+	ret := _m.Called(ctx, userID, purpose)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeOTP")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, string) error); ok {
+		r0 = rf(ctx, userID, purpose)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RotateRefreshToken provides a mock function with given fields: ctx, oldToken, newToken
+func (_m *UserRepository) RotateRefreshToken(ctx context.Context, oldToken string, newToken entity.RefreshToken) (entity.RefreshToken, error) {
+	ret := _m.Called(ctx, oldToken, newToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RotateRefreshToken")
+	}
+
+	var r0 entity.RefreshToken
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, entity.RefreshToken) (entity.RefreshToken, error)); ok {
+		return rf(ctx, oldToken, newToken)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, entity.RefreshToken) entity.RefreshToken); ok {
+		r0 = rf(ctx, oldToken, newToken)
+	} else {
+		r0 = ret.Get(0).(entity.RefreshToken)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, entity.RefreshToken) error); ok {
+		r1 = rf(ctx, oldToken, newToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// StoreOTP provides a mock function with given fields: ctx, otp
+func (_m *UserRepository) StoreOTP(ctx context.Context, otp entity.OTP) error {
+	ret := _m.Called(ctx, otp)
 
 	if len(ret) == 0 {
 		panic("no return value specified for StoreOTP")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, uint64, string, string) error); ok {
-		r0 = rf(ctx, userID, otp, requestID)
+	if rf, ok := ret.Get(0).(func(context.Context, entity.OTP) error); ok {
+		r0 = rf(ctx, otp)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -59,17 +415,99 @@ func (_m *UserRepository) StoreOTP(ctx context.Context, userID uint64, otp strin
 	return r0
 }
 
-// UpdateOTPStatus provides a mock function with given fields: ctx, userID, otp, requestID
-func (_m *UserRepository) UpdateOTPStatus(ctx context.Context, userID uint64, otp string, requestID string) error {
-	ret := _m.Called(ctx, userID, otp, requestID)
+// StoreRefreshToken provides a mock function with given fields: ctx, token
+func (_m *UserRepository) StoreRefreshToken(ctx context.Context, token entity.RefreshToken) error {
+	ret := _m.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StoreRefreshToken")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.RefreshToken) error); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// StoreTOTPSecret provides a mock function with given fields: ctx, userID, secret
+func (_m *UserRepository) StoreTOTPSecret(ctx context.Context, userID uint64, secret string) error {
+	ret := _m.Called(ctx, userID, secret)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StoreTOTPSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, string) error); ok {
+		r0 = rf(ctx, userID, secret)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateOTPStatus provides a mock function with given fields: ctx, userID, otp, purpose, challengeID, binding
+func (_m *UserRepository) UpdateOTPStatus(ctx context.Context, userID uint64, otp string, purpose string, challengeID string, binding string) error {
+	ret := _m.Called(ctx, userID, otp, purpose, challengeID, binding)
 
 	if len(ret) == 0 {
 		panic("no return value specified for UpdateOTPStatus")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, uint64, string, string) error); ok {
-		r0 = rf(ctx, userID, otp, requestID)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, string, string, string, string) error); ok {
+		r0 = rf(ctx, userID, otp, purpose, challengeID, binding)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateUser provides a mock function with given fields: ctx, user
+func (_m *UserRepository) UpdateUser(ctx context.Context, user entity.User) (entity.User, error) {
+	ret := _m.Called(ctx, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateUser")
+	}
+
+	var r0 entity.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) (entity.User, error)); ok {
+		return rf(ctx, user)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.User) entity.User); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.User) error); ok {
+		r1 = rf(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UseTOTPCounter provides a mock function with given fields: ctx, userID, counter
+func (_m *UserRepository) UseTOTPCounter(ctx context.Context, userID uint64, counter uint64) error {
+	ret := _m.Called(ctx, userID, counter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseTOTPCounter")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) error); ok {
+		r0 = rf(ctx, userID, counter)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -1,49 +1,517 @@
 package config
 
 import (
-	"fmt"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 const (
-	HTTPPort   = "http.port"
-	DBAddress  = "db.address"
-	DBName     = "db.name"
-	DBUsername = "db.username"
-	DBPassword = "db.password"
+	HTTPPort = "http.port"
+	// /readyz fails for HTTPDrainDelay on shutdown before connections are refused
+	HTTPReadyTimeout = "http.ready_timeout"
+	HTTPDrainDelay   = "http.drain_delay"
+
+	ShutdownGracePeriod = "shutdown.grace_period"
+
+	// a zero LogSamplingInitial disables sampling
+	LogLevel              = "log.level"
+	LogEncoding           = "log.encoding"
+	LogOutput             = "log.output"
+	LogFilePath           = "log.file.path"
+	LogFileMaxSize        = "log.file.max_size"
+	LogFileMaxBackups     = "log.file.max_backups"
+	LogFileMaxAge         = "log.file.max_age"
+	LogFileCompress       = "log.file.compress"
+	LogSamplingInitial    = "log.sampling.initial"
+	LogSamplingThereafter = "log.sampling.thereafter"
+
+	// /metrics and /admin are served on AdminAddress, /admin only with a token
+	AdminToken   = "admin.token"
+	AdminAddress = "admin.address"
+
+	// tracing is off when TracingEndpoint, an OTLP/HTTP host:port, is empty
+	TracingEndpoint    = "tracing.endpoint"
+	TracingInsecure    = "tracing.insecure"
+	TracingSampleRatio = "tracing.sample_ratio"
+	TracingServiceName = "tracing.service_name"
+
+	// a mysql DSN is built from the other DB keys when DBDSN is empty
+	DBDriver      = "db.driver"
+	DBDSN         = "db.dsn"
+	DBAddress     = "db.address"
+	DBName        = "db.name"
+	DBUsername    = "db.username"
+	DBPassword    = "db.password"
+	DBMemoryUsers = "db.memory.users"
+
+	DBPoolMaxOpenConns    = "db.pool.max_open_conns"
+	DBPoolMaxIdleConns    = "db.pool.max_idle_conns"
+	DBPoolConnMaxLifetime = "db.pool.conn_max_lifetime"
+	DBPoolConnMaxIdleTime = "db.pool.conn_max_idle_time"
+
+	// only used to build the mysql DSN
+	DBDialTimeout   = "db.timeout.dial"
+	DBReadTimeout   = "db.timeout.read"
+	DBWriteTimeout  = "db.timeout.write"
+	DBCharset       = "db.charset"
+	DBCollation     = "db.collation"
+	DBTimezone      = "db.timezone"
+	DBTLSMode       = "db.tls.mode"
+	DBTLSCAFile     = "db.tls.ca_file"
+	DBTLSCertFile   = "db.tls.cert_file"
+	DBTLSKeyFile    = "db.tls.key_file"
+	DBTLSServerName = "db.tls.server_name"
+
+	DBReplicaDSN     = "db.replica.dsn"
+	DBReplicaAddress = "db.replica.address"
+
+	DBRequireCurrentSchema = "db.require_current_schema"
+
+	// new OTPs are hashed with the pepper OTPPepperCurrent names in OTPPepperKeys
+	OTPPepperCurrent = "otp.pepper.current"
+	OTPPepperKeys    = "otp.pepper.keys"
+
+	OTPPolicies       = "otp.policies"
+	OTPDefaultPurpose = "otp.default_purpose"
+
+	OTPLockoutBase = "otp.lockout.base_duration"
+	OTPLockoutMax  = "otp.lockout.max_duration"
+
+	// bind an OTP to the X-Device-Fingerprint and IP of the client requesting it
+	OTPBindingFingerprint = "otp.binding.fingerprint"
+	OTPBindingIP          = "otp.binding.ip"
+
+	OTPSweeperEnabled   = "otp.sweeper.enabled"
+	OTPSweeperInterval  = "otp.sweeper.interval"
+	OTPSweeperRetention = "otp.sweeper.retention"
+	OTPSweeperBatchSize = "otp.sweeper.batch_size"
+	OTPSweeperArchive   = "otp.sweeper.archive"
+
+	// changing TOTPEncryptionKey makes every existing enrollment unusable
+	TOTPEncryptionKey = "totp.encryption_key"
+	TOTPIssuer        = "totp.issuer"
+	TOTPDigits        = "totp.digits"
+	TOTPPeriod        = "totp.period"
+	TOTPSkew          = "totp.skew"
+	TOTPMaxAttempts   = "totp.max_attempts"
+
+	TokenIssuer     = "token.issuer"
+	TokenAudience   = "token.audience"
+	TokenAccessTTL  = "token.access_ttl"
+	TokenRefreshTTL = "token.refresh_ttl"
+	TokenCurrentKey = "token.current_key"
+	TokenKeys       = "token.keys"
+
+	// a zero limit disables a RateLimitOTPRequest* rule
+	RateLimitBackend          = "ratelimit.backend"
+	RateLimitRedisAddress     = "ratelimit.redis.address"
+	RateLimitRedisPassword    = "ratelimit.redis.password"
+	RateLimitRedisDB          = "ratelimit.redis.db"
+	RateLimitOTPRequestUser   = "ratelimit.otp_request.user"
+	RateLimitOTPRequestIP     = "ratelimit.otp_request.ip"
+	RateLimitOTPRequestGlobal = "ratelimit.otp_request.global"
+
+	// a delivery channel is only enabled when its address or URL is set
+	DeliveryDefaultChannel = "delivery.default_channel"
+	DeliveryTimeout        = "delivery.timeout"
+	DeliveryEmailAddress   = "delivery.email.address"
+	DeliveryEmailUsername  = "delivery.email.username"
+	DeliveryEmailPassword  = "delivery.email.password"
+	DeliveryEmailFrom      = "delivery.email.from"
+	DeliveryEmailSubject   = "delivery.email.subject"
+	DeliverySMSURL         = "delivery.sms.url"
+	DeliverySMSToken       = "delivery.sms.token"
+	DeliverySMSFrom        = "delivery.sms.from"
+	DeliveryWebhookURL     = "delivery.webhook.url"
+	DeliveryWebhookSecret  = "delivery.webhook.secret"
+
+	// SQETEST_DB_PASSWORD overrides db.password
+	EnvPrefix = "SQETEST"
 
 	fileName = "config"
-	fileType = "json"
 )
 
-var configKeys = []string{HTTPPort, DBAddress, DBName, DBUsername, DBPassword}
+type (
+	// Config is the whole configuration, the key constants above document what
+	// its fields mean.
+	Config struct {
+		HTTP      HTTP      `mapstructure:"http"`
+		Shutdown  Shutdown  `mapstructure:"shutdown"`
+		Log       Log       `mapstructure:"log"`
+		Admin     Admin     `mapstructure:"admin"`
+		Tracing   Tracing   `mapstructure:"tracing"`
+		DB        DB        `mapstructure:"db"`
+		OTP       OTP       `mapstructure:"otp"`
+		TOTP      TOTP      `mapstructure:"totp"`
+		Token     Token     `mapstructure:"token"`
+		RateLimit RateLimit `mapstructure:"ratelimit"`
+		Delivery  Delivery  `mapstructure:"delivery"`
+	}
+
+	HTTP struct {
+		Port         string        `mapstructure:"port"`
+		ReadyTimeout time.Duration `mapstructure:"ready_timeout"`
+		DrainDelay   time.Duration `mapstructure:"drain_delay"`
+	}
+
+	Shutdown struct {
+		GracePeriod time.Duration `mapstructure:"grace_period"`
+	}
+
+	Log struct {
+		Level    string      `mapstructure:"level"`
+		Encoding string      `mapstructure:"encoding"`
+		Output   string      `mapstructure:"output"`
+		File     LogFile     `mapstructure:"file"`
+		Sampling LogSampling `mapstructure:"sampling"`
+	}
+
+	LogFile struct {
+		Path       string `mapstructure:"path"`
+		MaxSize    int    `mapstructure:"max_size"`
+		MaxBackups int    `mapstructure:"max_backups"`
+		MaxAge     int    `mapstructure:"max_age"`
+		Compress   bool   `mapstructure:"compress"`
+	}
+
+	LogSampling struct {
+		Initial    int `mapstructure:"initial"`
+		Thereafter int `mapstructure:"thereafter"`
+	}
+
+	Admin struct {
+		Token   string `mapstructure:"token"`
+		Address string `mapstructure:"address"`
+	}
+
+	Tracing struct {
+		Endpoint    string  `mapstructure:"endpoint"`
+		Insecure    bool    `mapstructure:"insecure"`
+		SampleRatio float64 `mapstructure:"sample_ratio"`
+		ServiceName string  `mapstructure:"service_name"`
+	}
+
+	DB struct {
+		Driver               string    `mapstructure:"driver"`
+		DSN                  string    `mapstructure:"dsn"`
+		Address              string    `mapstructure:"address"`
+		Name                 string    `mapstructure:"name"`
+		Username             string    `mapstructure:"username"`
+		Password             string    `mapstructure:"password"`
+		Pool                 DBPool    `mapstructure:"pool"`
+		Timeout              DBTimeout `mapstructure:"timeout"`
+		Charset              string    `mapstructure:"charset"`
+		Collation            string    `mapstructure:"collation"`
+		Timezone             string    `mapstructure:"timezone"`
+		TLS                  DBTLS     `mapstructure:"tls"`
+		Replica              DBReplica `mapstructure:"replica"`
+		RequireCurrentSchema bool      `mapstructure:"require_current_schema"`
+		Memory               DBMemory  `mapstructure:"memory"`
+	}
+
+	DBPool struct {
+		MaxOpenConns    int           `mapstructure:"max_open_conns"`
+		MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+		ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+		ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"`
+	}
+
+	DBTimeout struct {
+		Dial  time.Duration `mapstructure:"dial"`
+		Read  time.Duration `mapstructure:"read"`
+		Write time.Duration `mapstructure:"write"`
+	}
+
+	DBTLS struct {
+		Mode       string `mapstructure:"mode"`
+		CAFile     string `mapstructure:"ca_file"`
+		CertFile   string `mapstructure:"cert_file"`
+		KeyFile    string `mapstructure:"key_file"`
+		ServerName string `mapstructure:"server_name"`
+	}
+
+	DBReplica struct {
+		DSN     string `mapstructure:"dsn"`
+		Address string `mapstructure:"address"`
+	}
+
+	DBMemory struct {
+		Users []MemoryUser `mapstructure:"users"`
+	}
+
+	MemoryUser struct {
+		UUID       string `mapstructure:"uuid"`
+		Name       string `mapstructure:"name"`
+		Email      string `mapstructure:"email"`
+		Phone      string `mapstructure:"phone"`
+		OTPChannel string `mapstructure:"otp_channel"`
+	}
+
+	OTP struct {
+		Pepper         OTPPepper            `mapstructure:"pepper"`
+		Policies       map[string]OTPPolicy `mapstructure:"policies"`
+		DefaultPurpose string               `mapstructure:"default_purpose"`
+		Lockout        OTPLockout           `mapstructure:"lockout"`
+		Binding        OTPBinding           `mapstructure:"binding"`
+		Sweeper        OTPSweeper           `mapstructure:"sweeper"`
+	}
+
+	OTPPepper struct {
+		Current string            `mapstructure:"current"`
+		Keys    map[string]string `mapstructure:"keys"`
+	}
+
+	OTPPolicy struct {
+		Length         uint8         `mapstructure:"length"`
+		Charset        string        `mapstructure:"charset"`
+		TTL            time.Duration `mapstructure:"ttl"`
+		MaxAttempts    uint8         `mapstructure:"max_attempts"`
+		ResendCooldown time.Duration `mapstructure:"resend_cooldown"`
+		MaxResends     uint8         `mapstructure:"max_resends"`
+	}
+
+	OTPLockout struct {
+		BaseDuration time.Duration `mapstructure:"base_duration"`
+		MaxDuration  time.Duration `mapstructure:"max_duration"`
+	}
+
+	OTPBinding struct {
+		Fingerprint bool `mapstructure:"fingerprint"`
+		IP          bool `mapstructure:"ip"`
+	}
+
+	OTPSweeper struct {
+		Enabled   bool          `mapstructure:"enabled"`
+		Interval  time.Duration `mapstructure:"interval"`
+		Retention time.Duration `mapstructure:"retention"`
+		BatchSize uint          `mapstructure:"batch_size"`
+		Archive   bool          `mapstructure:"archive"`
+	}
+
+	TOTP struct {
+		EncryptionKey string        `mapstructure:"encryption_key"`
+		Issuer        string        `mapstructure:"issuer"`
+		Digits        uint8         `mapstructure:"digits"`
+		Period        time.Duration `mapstructure:"period"`
+		Skew          uint8         `mapstructure:"skew"`
+		MaxAttempts   uint8         `mapstructure:"max_attempts"`
+	}
+
+	Token struct {
+		Issuer     string              `mapstructure:"issuer"`
+		Audience   string              `mapstructure:"audience"`
+		AccessTTL  time.Duration       `mapstructure:"access_ttl"`
+		RefreshTTL time.Duration       `mapstructure:"refresh_ttl"`
+		CurrentKey string              `mapstructure:"current_key"`
+		Keys       map[string]TokenKey `mapstructure:"keys"`
+	}
+
+	TokenKey struct {
+		Algorithm      string `mapstructure:"algorithm"`
+		Secret         string `mapstructure:"secret"`
+		PrivateKeyFile string `mapstructure:"private_key_file"`
+	}
+
+	RateLimit struct {
+		Backend    string              `mapstructure:"backend"`
+		Redis      Redis               `mapstructure:"redis"`
+		OTPRequest RateLimitOTPRequest `mapstructure:"otp_request"`
+	}
+
+	Redis struct {
+		Address  string `mapstructure:"address"`
+		Password string `mapstructure:"password"`
+		DB       int    `mapstructure:"db"`
+	}
+
+	RateLimitOTPRequest struct {
+		User   Rate `mapstructure:"user"`
+		IP     Rate `mapstructure:"ip"`
+		Global Rate `mapstructure:"global"`
+	}
+
+	Rate struct {
+		Limit  int           `mapstructure:"limit"`
+		Period time.Duration `mapstructure:"period"`
+		Burst  int           `mapstructure:"burst"`
+	}
+
+	Delivery struct {
+		DefaultChannel string          `mapstructure:"default_channel"`
+		Timeout        time.Duration   `mapstructure:"timeout"`
+		Email          DeliveryEmail   `mapstructure:"email"`
+		SMS            DeliverySMS     `mapstructure:"sms"`
+		Webhook        DeliveryWebhook `mapstructure:"webhook"`
+	}
+
+	DeliveryEmail struct {
+		Address  string `mapstructure:"address"`
+		Username string `mapstructure:"username"`
+		Password string `mapstructure:"password"`
+		From     string `mapstructure:"from"`
+		Subject  string `mapstructure:"subject"`
+	}
+
+	DeliverySMS struct {
+		URL   string `mapstructure:"url"`
+		Token string `mapstructure:"token"`
+		From  string `mapstructure:"from"`
+	}
+
+	DeliveryWebhook struct {
+		URL    string `mapstructure:"url"`
+		Secret string `mapstructure:"secret"`
+	}
+)
+
+var defaults = map[string]interface{}{
+	HTTPPort:         ":8080",
+	HTTPReadyTimeout: "2s",
+	HTTPDrainDelay:   "5s",
+
+	ShutdownGracePeriod: "25s",
+
+	LogLevel:       "info",
+	LogEncoding:    "json",
+	LogOutput:      "stderr",
+	LogFileMaxSize: 100,
+
+	AdminAddress: ":9090",
+
+	TracingSampleRatio: 1,
+	TracingServiceName: "sqetest",
+
+	DBDriver:              "mysql",
+	DBPoolMaxOpenConns:    50,
+	DBPoolMaxIdleConns:    50,
+	DBPoolConnMaxLifetime: "10s",
+	DBTimezone:            "Local",
+	DBTLSMode:             "disabled",
+
+	OTPDefaultPurpose: "login",
+	OTPPolicies: map[string]interface{}{
+		"login": map[string]interface{}{
+			"length":          5,
+			"charset":         "digits",
+			"ttl":             "5m",
+			"max_attempts":    5,
+			"resend_cooldown": "30s",
+			"max_resends":     3,
+		},
+	},
+	OTPLockoutBase: "1m",
+	OTPLockoutMax:  "1h",
+
+	OTPSweeperEnabled:   true,
+	OTPSweeperInterval:  "1m",
+	OTPSweeperRetention: "168h",
+	OTPSweeperBatchSize: 500,
+
+	TOTPIssuer: "sqetest",
+	TOTPDigits: 6,
+	TOTPPeriod: "30s",
+	TOTPSkew:   1,
+
+	TOTPMaxAttempts: 5,
+
+	TokenIssuer:     "sqetest",
+	TokenAccessTTL:  "15m",
+	TokenRefreshTTL: "720h",
+
+	RateLimitBackend: "memory",
+	RateLimitOTPRequestUser: map[string]interface{}{
+		"limit":  3,
+		"period": "1m",
+	},
+	RateLimitOTPRequestIP: map[string]interface{}{
+		"limit":  10,
+		"period": "1m",
+	},
+	RateLimitOTPRequestGlobal: map[string]interface{}{
+		"limit":  100,
+		"period": "1s",
+	},
+
+	DeliveryDefaultChannel: "sms",
+	DeliveryTimeout:        "10s",
+	DeliveryEmailSubject:   "Your verification code",
+}
 
-func Load() error {
-	viper.SetConfigName(fileName)
-	viper.SetConfigType(fileType)
-	viper.AddConfigPath(".")
+// Load reads the config file at path, which may be JSON, YAML or TOML, and
+// returns the validated configuration. Without a path config.json, config.yaml
+// or config.toml is looked up in the working directory and may be missing as
+// long as the environment sets the required keys. Environment variables
+// override the file and flags bound to the global viper override both.
+func Load(path string) (*Config, error) {
+	return load(viper.GetViper(), path)
+}
+
+func load(v *viper.Viper, path string) (*Config, error) {
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	// AutomaticEnv only applies to keys viper already knows, binding every
+	// scalar key lets the environment set keys the file doesn't have
+	for _, key := range scalarKeys(reflect.TypeOf(Config{}), "") {
+		if err := v.BindEnv(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if path != "" {
+		v.SetConfigFile(path)
+	} else {
+		v.SetConfigName(fileName)
+		v.AddConfigPath(".")
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		var notFoundErr viper.ConfigFileNotFoundError
+		if path != "" || !errors.As(err, &notFoundErr) {
+			return nil, err
+		}
+	}
+
+	return decode(v)
+}
 
-	err := viper.ReadInConfig()
-	if err == nil {
-		return err
+func decode(v *viper.Viper) (*Config, error) {
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, err
 	}
 
-	if err := validate(); err != nil {
-		return err
+	if err := validate(&cfg); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return &cfg, nil
 }
 
-func validate() error {
-	for _, configKey := range configKeys {
-		configValue := viper.GetString(configKey)
+// scalarKeys lists the keys of the fields of t holding a single value, maps
+// and lists are left out since their keys aren't known upfront.
+func scalarKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
 
-		if configValue == "" {
-			return fmt.Errorf("empty value for config key: %s", configKey)
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			keys = append(keys, scalarKeys(field.Type, key+".")...)
+		case reflect.Map, reflect.Slice:
+		default:
+			keys = append(keys, key)
 		}
 	}
 
-	return nil
+	return keys
 }
@@ -0,0 +1,323 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testJSON = `{
+  "db": {"driver": "sqlite3", "dsn": "file:test.db"},
+  "otp": {"pepper": {"current": "v1", "keys": {"v1": "test-pepper-test-pepper"}}},
+  "totp": {"encryption_key": "test-totp-key"},
+  "token": {"current_key": "k1", "keys": {"k1": {"algorithm": "HS256", "secret": "test-secret"}}}
+}`
+
+	testYAML = `db:
+  driver: sqlite3
+  dsn: file:test.db
+otp:
+  pepper:
+    current: v1
+    keys:
+      v1: test-pepper-test-pepper
+totp:
+  encryption_key: test-totp-key
+token:
+  current_key: k1
+  keys:
+    k1:
+      algorithm: HS256
+      secret: test-secret
+`
+
+	testTOML = `[db]
+driver = "sqlite3"
+dsn = "file:test.db"
+
+[otp.pepper]
+current = "v1"
+
+[otp.pepper.keys]
+v1 = "test-pepper-test-pepper"
+
+[totp]
+encryption_key = "test-totp-key"
+
+[token]
+current_key = "k1"
+
+[token.keys.k1]
+algorithm = "HS256"
+secret = "test-secret"
+`
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestLoad_Formats(t *testing.T) {
+	t.Parallel()
+
+	for name, content := range map[string]string{
+		"config.json": testJSON,
+		"config.yaml": testYAML,
+		"config.toml": testTOML,
+	} {
+		name, content := name, content
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := load(viper.New(), writeConfig(t, name, content))
+			require.NoError(t, err)
+
+			assert.Equal(t, "sqlite3", cfg.DB.Driver)
+			assert.Equal(t, "file:test.db", cfg.DB.DSN)
+			assert.Equal(t, map[string]string{"v1": "test-pepper-test-pepper"}, cfg.OTP.Pepper.Keys)
+			assert.Equal(t, TokenKey{Algorithm: "HS256", Secret: "test-secret"}, cfg.Token.Keys["k1"])
+
+			// defaults fill in what the file leaves out
+			assert.Equal(t, ":8080", cfg.HTTP.Port)
+			assert.Equal(t, "info", cfg.Log.Level)
+			assert.Equal(t, DBPool{MaxOpenConns: 50, MaxIdleConns: 50, ConnMaxLifetime: 10 * time.Second},
+				cfg.DB.Pool)
+			assert.Equal(t, "Local", cfg.DB.Timezone)
+			assert.Equal(t, OTPPolicy{
+				Length:         5,
+				Charset:        "digits",
+				TTL:            5 * time.Minute,
+				MaxAttempts:    5,
+				ResendCooldown: 30 * time.Second,
+				MaxResends:     3,
+			}, cfg.OTP.Policies["login"])
+			assert.Equal(t, OTPSweeper{
+				Enabled:   true,
+				Interval:  time.Minute,
+				Retention: 168 * time.Hour,
+				BatchSize: 500,
+			}, cfg.OTP.Sweeper)
+			assert.Equal(t, uint8(6), cfg.TOTP.Digits)
+			assert.Equal(t, 720*time.Hour, cfg.Token.RefreshTTL)
+			assert.Equal(t, Rate{Limit: 100, Period: time.Second}, cfg.RateLimit.OTPRequest.Global)
+		})
+	}
+}
+
+func TestLoad_Environment(t *testing.T) {
+	path := writeConfig(t, "config.json", testJSON)
+
+	t.Setenv("SQETEST_HTTP_PORT", ":9090")
+	t.Setenv("SQETEST_DB_DSN", "file:env.db")
+	t.Setenv("SQETEST_DB_REQUIRE_CURRENT_SCHEMA", "true")
+	t.Setenv("SQETEST_TOKEN_KEYS_K1_SECRET", "env-secret")
+	t.Setenv("SQETEST_DELIVERY_SMS_TOKEN", "env-sms-token")
+	t.Setenv("SQETEST_TOTP_PERIOD", "1m")
+	t.Setenv("SQETEST_OTP_BINDING_IP", "true")
+
+	cfg, err := load(viper.New(), path)
+	require.NoError(t, err)
+
+	assert.Equal(t, ":9090", cfg.HTTP.Port)
+	assert.Equal(t, "file:env.db", cfg.DB.DSN)
+	assert.True(t, cfg.DB.RequireCurrentSchema)
+	assert.Equal(t, "env-secret", cfg.Token.Keys["k1"].Secret)
+	assert.Equal(t, "env-sms-token", cfg.Delivery.SMS.Token)
+	assert.Equal(t, time.Minute, cfg.TOTP.Period)
+	assert.Equal(t, OTPBinding{IP: true}, cfg.OTP.Binding)
+}
+
+func TestLoad_EnvironmentOnly(t *testing.T) {
+	t.Setenv("SQETEST_DB_DRIVER", "memory")
+	t.Setenv("SQETEST_OTP_PEPPER_CURRENT", "v1")
+	t.Setenv("SQETEST_TOTP_ENCRYPTION_KEY", "test-totp-key")
+	t.Setenv("SQETEST_TOKEN_CURRENT_KEY", "k1")
+
+	// there is no config file in the working directory, the maps can't be set
+	// from the environment though
+	_, err := load(viper.New(), "")
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, []KeyError{
+		{Key: OTPPepperKeys, Message: `no pepper for the current key "v1"`},
+		{Key: TokenKeys, Message: `no key for the current key "k1"`},
+	}, validationErr.Errors)
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	t.Parallel()
+
+	_, err := load(viper.New(), filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	t.Parallel()
+
+	path := writeConfig(t, "config.yaml", `http:
+  port: ""
+  ready_timeout: 0s
+shutdown:
+  grace_period: 5s
+log:
+  level: verbose
+  encoding: xml
+  output: file
+db:
+  driver: oracle
+otp:
+  default_purpose: signup
+  pepper:
+    keys:
+      pepper-2024-01-rotated: test-pepper-test-pepper
+  policies:
+    login:
+      length: 0
+      charset: emoji
+  lockout:
+    base_duration: 1h
+    max_duration: 1m
+  sweeper:
+    interval: 0s
+    batch_size: 0
+totp:
+  digits: 4
+  period: 500ms
+  max_attempts: 0
+token:
+  current_key: k1
+  keys:
+    k1:
+      algorithm: none
+ratelimit:
+  backend: memcached
+  otp_request:
+    ip:
+      limit: 5
+      period: 0s
+delivery:
+  default_channel: pigeon
+tracing:
+  sample_ratio: 2
+`)
+
+	_, err := load(viper.New(), path)
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, []KeyError{
+		{Key: DBDriver, Message: "must be mysql, postgres, sqlite3 or memory"},
+		{Key: DeliveryDefaultChannel, Message: "must be email, sms or webhook"},
+		{Key: HTTPPort, Message: "is required"},
+		{Key: HTTPReadyTimeout, Message: "must be positive"},
+		{Key: LogEncoding, Message: "must be json or console"},
+		{Key: LogFilePath, Message: "is required"},
+		{Key: LogLevel, Message: "must be debug, info, warn or error"},
+		{Key: OTPDefaultPurpose, Message: `no otp policy for purpose "signup"`},
+		{Key: OTPLockoutMax, Message: "must not be shorter than the base duration"},
+		{Key: OTPPepperCurrent, Message: "is required"},
+		{Key: OTPPepperKeys + ".pepper-2024-01-rotated", Message: "key id must be at most 16 bytes"},
+		{Key: "otp.policies.login.charset", Message: "must be digits, alphanumeric or crockford"},
+		{Key: "otp.policies.login.length", Message: "must be between 1 and 32"},
+		{Key: OTPSweeperBatchSize, Message: "must be positive"},
+		{Key: OTPSweeperInterval, Message: "must be positive"},
+		{Key: RateLimitBackend, Message: "must be memory or redis"},
+		{Key: "ratelimit.otp_request.ip.period", Message: "must be positive when a limit is set"},
+		{Key: ShutdownGracePeriod, Message: "must be longer than http.drain_delay"},
+		{Key: TokenKeys + ".k1.algorithm", Message: "must be HS256, RS256 or EdDSA"},
+		{Key: TOTPDigits, Message: "must be between 6 and 8"},
+		{Key: TOTPEncryptionKey, Message: "is required"},
+		{Key: TOTPMaxAttempts, Message: "must be positive"},
+		{Key: TOTPPeriod, Message: "must be a whole number of seconds"},
+		{Key: TracingSampleRatio, Message: "must be between 0 and 1"},
+	}, validationErr.Errors)
+	assert.Contains(t, err.Error(), "invalid config: db.driver: must be mysql, postgres, sqlite3 or memory; ")
+}
+
+func TestLoad_InvalidDB(t *testing.T) {
+	t.Parallel()
+
+	for desc, tc := range map[string]struct {
+		db       string
+		expected []KeyError
+	}{
+		"MySQL": {
+			db: `driver: mysql
+  address: localhost:3306
+  name: sqetest
+  timezone: Mars/Olympus_Mons
+  timeout:
+    read: -1s
+  tls:
+    mode: verify
+    cert_file: client.pem
+  pool:
+    max_open_conns: -1`,
+			expected: []KeyError{
+				{Key: DBPoolMaxOpenConns, Message: "must not be negative"},
+				{Key: DBReadTimeout, Message: "must not be negative"},
+				{Key: DBTimezone, Message: `unknown location "Mars/Olympus_Mons"`},
+				{Key: DBTLSKeyFile, Message: "must be set together with " + DBTLSCertFile},
+			},
+		},
+		"MySQLWithDSN": {
+			// the DSN is used as is, the keys shaping a built one don't matter
+			db: `driver: mysql
+  dsn: root@tcp(localhost:3306)/sqetest
+  timezone: Mars/Olympus_Mons
+  tls:
+    mode: always`,
+		},
+		"PostgresReplicaAddress": {
+			db: `driver: postgres
+  dsn: postgres://localhost/sqetest
+  replica:
+    address: replica:5432`,
+			expected: []KeyError{
+				{Key: DBReplicaAddress, Message: "is only supported by mysql, set " + DBReplicaDSN},
+			},
+		},
+	} {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			t.Parallel()
+
+			content := strings.Replace(testYAML, "driver: sqlite3\n  dsn: file:test.db", tc.db, 1)
+			_, err := load(viper.New(), writeConfig(t, "config.yaml", content))
+			if tc.expected == nil {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			var validationErr *ValidationError
+			require.True(t, errors.As(err, &validationErr))
+			assert.Equal(t, tc.expected, validationErr.Errors)
+		})
+	}
+}
+
+func TestScalarKeys(t *testing.T) {
+	t.Parallel()
+
+	keys := scalarKeys(reflect.TypeOf(Config{}), "")
+	assert.Contains(t, keys, HTTPPort)
+	assert.Contains(t, keys, DBRequireCurrentSchema)
+	assert.Contains(t, keys, RateLimitRedisDB)
+	assert.Contains(t, keys, RateLimitOTPRequestGlobal+".burst")
+	assert.NotContains(t, keys, OTPPolicies)
+	assert.NotContains(t, keys, DBMemoryUsers)
+}
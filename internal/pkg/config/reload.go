@@ -0,0 +1,152 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// reloadable lists the keys a running server applies on reload, a change to
+// any other key only takes effect after a restart.
+var reloadable = []string{
+	LogLevel,
+	OTPPolicies,
+	OTPDefaultPurpose,
+	RateLimitOTPRequestUser,
+	RateLimitOTPRequestIP,
+	RateLimitOTPRequestGlobal,
+}
+
+// reloadMu serializes the reloads, a viper instance isn't safe to read
+// concurrently.
+var reloadMu sync.Mutex
+
+// Reload reads the config file found by Load again and returns the validated
+// configuration, the environment and flags still override the file. An invalid
+// file is reported as an error so the caller can keep what it runs with.
+func Reload() (*Config, error) {
+	return reload(viper.GetViper())
+}
+
+func reload(v *viper.Viper) (*Config, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	if err := v.ReadInConfig(); err != nil {
+		var notFoundErr viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFoundErr) {
+			return nil, err
+		}
+	}
+
+	return decode(v)
+}
+
+// Watch calls onChange whenever the config file found by Load is written or
+// replaced until ctx is done, nothing is watched when the configuration only
+// comes from the environment. Watch never reads the file itself, Reload does,
+// so a file change and a SIGHUP can't read it at the same time.
+func Watch(ctx context.Context, onChange func()) error {
+	file := viper.ConfigFileUsed()
+	if file == "" {
+		return nil
+	}
+
+	return watch(ctx, file, onChange)
+}
+
+func watch(ctx context.Context, file string, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	file = filepath.Clean(file)
+	// editors and Kubernetes replace the file rather than write it, watching
+	// the directory sees those too
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		_ = watcher.Close()
+
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		realFile, _ := filepath.EvalSymlinks(file)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				// a Kubernetes ConfigMap swaps the symlink the file resolves
+				// through instead of touching the file
+				currentFile, _ := filepath.EvalSymlinks(file)
+				written := filepath.Clean(event.Name) == file && event.Op&(fsnotify.Write|fsnotify.Create) != 0
+				if written || (currentFile != "" && currentFile != realFile) {
+					realFile = currentFile
+					onChange()
+				}
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// RestartRequired returns the keys that differ between c and next but can't
+// be reloaded, in the order of the Config fields.
+func (c *Config) RestartRequired(next *Config) []string {
+	var keys []string
+	for _, key := range changedKeys(reflect.ValueOf(*c), reflect.ValueOf(*next), "") {
+		if !isReloadable(key) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func changedKeys(a, b reflect.Value, prefix string) []string {
+	var keys []string
+	for i := 0; i < a.NumField(); i++ {
+		key := prefix + a.Type().Field(i).Tag.Get("mapstructure")
+		fa, fb := a.Field(i), b.Field(i)
+
+		if fa.Kind() == reflect.Struct {
+			keys = append(keys, changedKeys(fa, fb, key+".")...)
+
+			continue
+		}
+
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func isReloadable(key string) bool {
+	for _, r := range reloadable {
+		if key == r || strings.HasPrefix(key, r+".") {
+			return true
+		}
+	}
+
+	return false
+}
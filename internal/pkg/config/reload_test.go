@@ -0,0 +1,86 @@
+package config
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	t.Parallel()
+
+	path := writeConfig(t, "config.yaml", testYAML)
+	v := viper.New()
+	_, err := load(v, path)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(testYAML+`log:
+  level: debug
+ratelimit:
+  otp_request:
+    user:
+      limit: 1
+      period: 1h
+`), 0o600))
+
+	cfg, err := reload(v)
+	require.NoError(t, err)
+	assert.Equal(t, "debug", cfg.Log.Level)
+	assert.Equal(t, Rate{Limit: 1, Period: time.Hour}, cfg.RateLimit.OTPRequest.User)
+	// defaults still fill in what the file leaves out
+	assert.Equal(t, Rate{Limit: 10, Period: time.Minute}, cfg.RateLimit.OTPRequest.IP)
+
+	require.NoError(t, os.WriteFile(path, []byte(strings.Replace(testYAML, "driver: sqlite3", "driver: oracle", 1)),
+		0o600))
+
+	_, err = reload(v)
+	assert.ErrorContains(t, err, DBDriver)
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	path := writeConfig(t, "config.yaml", testYAML)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changed := make(chan struct{}, 1)
+	require.NoError(t, watch(ctx, path, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}))
+
+	require.NoError(t, os.WriteFile(path, []byte(testYAML+"log:\n  level: debug\n"), 0o600))
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("onChange wasn't called after the config file was written")
+	}
+}
+
+func TestConfig_RestartRequired(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := load(viper.New(), writeConfig(t, "config.yaml", testYAML))
+	require.NoError(t, err)
+
+	next := *cfg
+	next.Log.Level = "error"
+	next.OTP.Policies = map[string]OTPPolicy{"login": {Length: 8, Charset: "digits", TTL: time.Minute}}
+	next.RateLimit.OTPRequest.Global.Burst = 10
+	assert.Empty(t, cfg.RestartRequired(&next))
+
+	next.HTTP.Port = ":9090"
+	next.DB.DSN = "file:other.db"
+	next.Token.Keys = map[string]TokenKey{"k2": {Algorithm: "HS256", Secret: "other-secret"}}
+	assert.Equal(t, []string{HTTPPort, DBDSN, TokenKeys}, cfg.RestartRequired(&next))
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/subroll/sqetest/internal/pkg/otphash"
+	"github.com/subroll/sqetest/internal/pkg/stringutil"
+	"github.com/subroll/sqetest/internal/pkg/token"
+)
+
+// maxOTPLength caps the length of an OTP policy, longer codes only make users
+// type more.
+const maxOTPLength = 32
+
+type (
+	// ValidationError lists every invalid key of a configuration at once,
+	// sorted by key.
+	ValidationError struct {
+		Errors []KeyError
+	}
+
+	KeyError struct {
+		Key     string
+		Message string
+	}
+
+	validation struct {
+		errs []KeyError
+	}
+)
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, ke := range e.Errors {
+		msgs = append(msgs, ke.Key+": "+ke.Message)
+	}
+
+	return "invalid config: " + strings.Join(msgs, "; ")
+}
+
+func validate(cfg *Config) error {
+	v := &validation{}
+
+	v.required(HTTPPort, cfg.HTTP.Port)
+	v.check(cfg.HTTP.ReadyTimeout > 0, HTTPReadyTimeout, "must be positive")
+	v.check(cfg.HTTP.DrainDelay >= 0, HTTPDrainDelay, "must not be negative")
+	v.check(cfg.Shutdown.GracePeriod > cfg.HTTP.DrainDelay, ShutdownGracePeriod,
+		"must be longer than "+HTTPDrainDelay)
+
+	v.log(cfg.Log)
+
+	v.check(cfg.Tracing.SampleRatio >= 0 && cfg.Tracing.SampleRatio <= 1, TracingSampleRatio,
+		"must be between 0 and 1")
+	v.check(cfg.Tracing.Endpoint == "" || cfg.Tracing.ServiceName != "", TracingServiceName,
+		"is required when tracing is on")
+
+	switch cfg.DB.Driver {
+	case "mysql":
+		if cfg.DB.DSN == "" {
+			v.required(DBAddress, cfg.DB.Address)
+			v.required(DBName, cfg.DB.Name)
+			v.mysql(cfg.DB)
+		}
+		v.pool(cfg.DB.Pool)
+	case "postgres", "sqlite3":
+		v.required(DBDSN, cfg.DB.DSN)
+		v.check(cfg.DB.Replica.Address == "", DBReplicaAddress, "is only supported by mysql, set "+DBReplicaDSN)
+		v.pool(cfg.DB.Pool)
+	case "memory":
+		for i, user := range cfg.DB.Memory.Users {
+			key := fmt.Sprintf("%s.%d", DBMemoryUsers, i)
+			v.required(key+".uuid", user.UUID)
+			v.required(key+".name", user.Name)
+		}
+	default:
+		v.invalid(DBDriver, "must be mysql, postgres, sqlite3 or memory")
+	}
+
+	v.required(OTPPepperCurrent, cfg.OTP.Pepper.Current)
+	for id := range cfg.OTP.Pepper.Keys {
+		v.check(len(id) <= otphash.MaxKeyIDLength, OTPPepperKeys+"."+id,
+			fmt.Sprintf("key id must be at most %d bytes", otphash.MaxKeyIDLength))
+	}
+	if _, ok := cfg.OTP.Pepper.Keys[cfg.OTP.Pepper.Current]; cfg.OTP.Pepper.Current != "" && !ok {
+		v.invalid(OTPPepperKeys, fmt.Sprintf("no pepper for the current key %q", cfg.OTP.Pepper.Current))
+	}
+
+	for purpose, policy := range cfg.OTP.Policies {
+		key := OTPPolicies + "." + purpose
+		if _, ok := stringutil.Charset(policy.Charset); !ok {
+			v.invalid(key+".charset", "must be digits, alphanumeric or crockford")
+		}
+		v.check(policy.Length > 0 && policy.Length <= maxOTPLength, key+".length",
+			fmt.Sprintf("must be between 1 and %d", maxOTPLength))
+		v.check(policy.TTL > 0, key+".ttl", "must be positive")
+		v.check(policy.MaxAttempts > 0, key+".max_attempts", "must be positive")
+		v.check(policy.ResendCooldown >= 0, key+".resend_cooldown", "must not be negative")
+	}
+	if _, ok := cfg.OTP.Policies[cfg.OTP.DefaultPurpose]; !ok {
+		v.invalid(OTPDefaultPurpose, fmt.Sprintf("no otp policy for purpose %q", cfg.OTP.DefaultPurpose))
+	}
+
+	v.check(cfg.OTP.Lockout.BaseDuration > 0, OTPLockoutBase, "must be positive")
+	v.check(cfg.OTP.Lockout.MaxDuration >= cfg.OTP.Lockout.BaseDuration, OTPLockoutMax,
+		"must not be shorter than the base duration")
+	if cfg.OTP.Sweeper.Enabled {
+		v.check(cfg.OTP.Sweeper.Interval > 0, OTPSweeperInterval, "must be positive")
+		v.check(cfg.OTP.Sweeper.Retention >= 0, OTPSweeperRetention, "must not be negative")
+		v.check(cfg.OTP.Sweeper.BatchSize > 0, OTPSweeperBatchSize, "must be positive")
+	}
+
+	v.required(TOTPEncryptionKey, cfg.TOTP.EncryptionKey)
+	v.check(cfg.TOTP.Digits >= 6 && cfg.TOTP.Digits <= 8, TOTPDigits, "must be between 6 and 8")
+	v.check(cfg.TOTP.Period >= time.Second && cfg.TOTP.Period%time.Second == 0, TOTPPeriod,
+		"must be a whole number of seconds")
+	v.check(cfg.TOTP.MaxAttempts > 0, TOTPMaxAttempts, "must be positive")
+
+	v.check(cfg.Token.AccessTTL > 0, TokenAccessTTL, "must be positive")
+	v.check(cfg.Token.RefreshTTL > 0, TokenRefreshTTL, "must be positive")
+	v.required(TokenCurrentKey, cfg.Token.CurrentKey)
+	if _, ok := cfg.Token.Keys[cfg.Token.CurrentKey]; cfg.Token.CurrentKey != "" && !ok {
+		v.invalid(TokenKeys, fmt.Sprintf("no key for the current key %q", cfg.Token.CurrentKey))
+	}
+	for kid, tk := range cfg.Token.Keys {
+		key := TokenKeys + "." + kid
+		switch tk.Algorithm {
+		case token.AlgorithmHS256:
+			v.required(key+".secret", tk.Secret)
+		case token.AlgorithmRS256, token.AlgorithmEdDSA:
+			v.required(key+".private_key_file", tk.PrivateKeyFile)
+		default:
+			v.invalid(key+".algorithm", "must be HS256, RS256 or EdDSA")
+		}
+	}
+
+	switch cfg.RateLimit.Backend {
+	case "memory":
+	case "redis":
+		v.required(RateLimitRedisAddress, cfg.RateLimit.Redis.Address)
+	default:
+		v.invalid(RateLimitBackend, "must be memory or redis")
+	}
+	v.rate(RateLimitOTPRequestUser, cfg.RateLimit.OTPRequest.User)
+	v.rate(RateLimitOTPRequestIP, cfg.RateLimit.OTPRequest.IP)
+	v.rate(RateLimitOTPRequestGlobal, cfg.RateLimit.OTPRequest.Global)
+
+	switch cfg.Delivery.DefaultChannel {
+	case "email", "sms", "webhook":
+	default:
+		v.invalid(DeliveryDefaultChannel, "must be email, sms or webhook")
+	}
+	v.check(cfg.Delivery.Timeout > 0, DeliveryTimeout, "must be positive")
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+
+	sort.Slice(v.errs, func(a, b int) bool {
+		return v.errs[a].Key < v.errs[b].Key
+	})
+
+	return &ValidationError{Errors: v.errs}
+}
+
+func (v *validation) check(ok bool, key, message string) {
+	if !ok {
+		v.invalid(key, message)
+	}
+}
+
+func (v *validation) required(key, value string) {
+	v.check(value != "", key, "is required")
+}
+
+func (v *validation) log(log Log) {
+	switch log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		v.invalid(LogLevel, "must be debug, info, warn or error")
+	}
+
+	switch log.Encoding {
+	case "json", "console":
+	default:
+		v.invalid(LogEncoding, "must be json or console")
+	}
+
+	switch log.Output {
+	case "stderr":
+	case "file", "both":
+		v.required(LogFilePath, log.File.Path)
+	default:
+		v.invalid(LogOutput, "must be stderr, file or both")
+	}
+
+	v.check(log.File.MaxSize >= 0, LogFileMaxSize, "must not be negative")
+	v.check(log.File.MaxBackups >= 0, LogFileMaxBackups, "must not be negative")
+	v.check(log.File.MaxAge >= 0, LogFileMaxAge, "must not be negative")
+	v.check(log.Sampling.Initial >= 0, LogSamplingInitial, "must not be negative")
+	v.check(log.Sampling.Thereafter >= 0, LogSamplingThereafter, "must not be negative")
+}
+
+func (v *validation) mysql(db DB) {
+	v.check(db.Timeout.Dial >= 0, DBDialTimeout, "must not be negative")
+	v.check(db.Timeout.Read >= 0, DBReadTimeout, "must not be negative")
+	v.check(db.Timeout.Write >= 0, DBWriteTimeout, "must not be negative")
+
+	if _, err := time.LoadLocation(db.Timezone); err != nil {
+		v.invalid(DBTimezone, fmt.Sprintf("unknown location %q", db.Timezone))
+	}
+
+	switch db.TLS.Mode {
+	case "disabled", "preferred", "skip-verify":
+	case "verify":
+		v.check((db.TLS.CertFile == "") == (db.TLS.KeyFile == ""), DBTLSKeyFile,
+			"must be set together with "+DBTLSCertFile)
+	default:
+		v.invalid(DBTLSMode, "must be disabled, preferred, skip-verify or verify")
+	}
+}
+
+func (v *validation) pool(pool DBPool) {
+	v.check(pool.MaxOpenConns >= 0, DBPoolMaxOpenConns, "must not be negative")
+	v.check(pool.MaxIdleConns >= 0, DBPoolMaxIdleConns, "must not be negative")
+	v.check(pool.ConnMaxLifetime >= 0, DBPoolConnMaxLifetime, "must not be negative")
+	v.check(pool.ConnMaxIdleTime >= 0, DBPoolConnMaxIdleTime, "must not be negative")
+}
+
+func (v *validation) rate(key string, rate Rate) {
+	v.check(rate.Limit >= 0, key+".limit", "must not be negative")
+	v.check(rate.Limit == 0 || rate.Period > 0, key+".period", "must be positive when a limit is set")
+	v.check(rate.Burst >= 0, key+".burst", "must not be negative")
+}
+
+func (v *validation) invalid(key, message string) {
+	v.errs = append(v.errs, KeyError{Key: key, Message: message})
+}
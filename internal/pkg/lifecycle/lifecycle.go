@@ -0,0 +1,83 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/subroll/sqetest/internal/pkg/log"
+	"go.uber.org/zap"
+)
+
+type (
+	// Manager stops the components of a process one after another, in the
+	// order they were added, so that e.g. the HTTP server stops taking
+	// requests before the database they need is closed.
+	Manager struct {
+		mu    sync.Mutex
+		hooks []hook
+	}
+
+	hook struct {
+		name string
+		stop func(context.Context) error
+	}
+)
+
+// Add appends the hook stopping the component name.
+func (m *Manager) Add(name string, stop func(context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hooks = append(m.hooks, hook{name: name, stop: stop})
+}
+
+// Go runs fn in its own goroutine and adds the hook stopping it, the hook
+// cancels the context given to fn and waits for fn to return.
+func (m *Manager) Go(name string, fn func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(ctx)
+	}()
+
+	m.Add(name, func(stopCtx context.Context) error {
+		cancel()
+
+		select {
+		case <-done:
+			return nil
+		case <-stopCtx.Done():
+			return stopCtx.Err()
+		}
+	})
+}
+
+// Stop runs the hooks in order and forgets them. A failing hook doesn't keep
+// the following ones from running, its error is returned along with the
+// others. Once ctx is done the remaining hooks still run, they are expected to
+// release what they hold without waiting.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	hooks := m.hooks
+	m.hooks = nil
+	m.mu.Unlock()
+
+	var errs []error
+	for _, h := range hooks {
+		start := time.Now()
+		if err := h.stop(ctx); err != nil {
+			log.Error("fail to stop", zap.String("component", h.name), zap.Error(err))
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+
+			continue
+		}
+
+		log.Info("stopped", zap.String("component", h.name), zap.String("latency", time.Since(start).String()))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,75 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager_Stop(t *testing.T) {
+	t.Parallel()
+
+	var (
+		m       Manager
+		stopped []string
+	)
+	m.Add("http", func(context.Context) error {
+		stopped = append(stopped, "http")
+
+		return nil
+	})
+	m.Add("deliveries", func(context.Context) error {
+		stopped = append(stopped, "deliveries")
+
+		return errors.New("fake error")
+	})
+	m.Add("db", func(context.Context) error {
+		stopped = append(stopped, "db")
+
+		return nil
+	})
+
+	err := m.Stop(context.Background())
+	assert.EqualError(t, err, "deliveries: fake error")
+	assert.Equal(t, []string{"http", "deliveries", "db"}, stopped)
+
+	assert.NoError(t, m.Stop(context.Background()))
+	assert.Len(t, stopped, 3)
+}
+
+func TestManager_Go(t *testing.T) {
+	t.Parallel()
+
+	var (
+		m       Manager
+		stopped bool
+	)
+	m.Go("sweeper", func(ctx context.Context) {
+		<-ctx.Done()
+		stopped = true
+	})
+
+	assert.NoError(t, m.Stop(context.Background()))
+	assert.True(t, stopped)
+}
+
+func TestManager_GoTimeout(t *testing.T) {
+	t.Parallel()
+
+	var m Manager
+	release := make(chan struct{})
+	defer close(release)
+	m.Go("stuck", func(context.Context) {
+		<-release
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := m.Stop(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.EqualError(t, err, "stuck: context deadline exceeded")
+}
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"context"
+
+	"github.com/subroll/sqetest/internal/pkg/requestid"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type fieldsKey struct{}
+
+// WithFields returns a copy of ctx carrying fields, they are added to every
+// entry logged with the context on top of the ones ctx already carries.
+func WithFields(ctx context.Context, fields ...zap.Field) context.Context {
+	existing, _ := ctx.Value(fieldsKey{}).([]zap.Field)
+	merged := make([]zap.Field, 0, len(existing)+len(fields))
+	merged = append(merged, existing...)
+	merged = append(merged, fields...)
+
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// FromContext returns the logger with the request ID, the trace ID and the
+// fields carried by ctx attached, for code that logs more than a line or two.
+func FromContext(ctx context.Context) *zap.Logger {
+	return withContext(ctx).WithOptions(zap.AddCallerSkip(-1))
+}
+
+// InfoCtx add log entry with the fields of ctx to info level
+func InfoCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	withContext(ctx).Info(msg, fields...)
+}
+
+// WarnCtx add log entry with the fields of ctx to warn level
+func WarnCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	withContext(ctx).Warn(msg, fields...)
+}
+
+// ErrorCtx add log entry with the fields of ctx to error level
+func ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	withContext(ctx).Error(msg, fields...)
+}
+
+func withContext(ctx context.Context) *zap.Logger {
+	carried, _ := ctx.Value(fieldsKey{}).([]zap.Field)
+	fields := make([]zap.Field, 0, len(carried)+2)
+	if id := requestid.ExtractFromCtx(ctx); id != "" {
+		fields = append(fields, zap.String("request_id", id))
+	}
+	if id := TraceID(ctx); id != "" {
+		fields = append(fields, zap.String("trace_id", id))
+	}
+
+	return log.With(append(fields, carried...)...)
+}
+
+// TraceID returns the ID of the trace ctx is part of, or an empty string when
+// it isn't traced.
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+
+	return sc.TraceID().String()
+}
@@ -0,0 +1,57 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/subroll/sqetest/internal/pkg/requestid"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestInfoCtx(t *testing.T) {
+	var buf bytes.Buffer
+	createLogger(zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.AddSync(&buf), logLevel))
+	t.Cleanup(defaultLog)
+
+	header := http.Header{}
+	header.Set(echo.HeaderXRequestID, "fake-request-id")
+	ctx := requestid.InjectToCtx(header, context.Background())
+	ctx = WithFields(ctx, zap.String("route", "/otp/request"))
+	ctx = WithFields(ctx, zap.String("user_uuid", "fake-uuid"))
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	InfoCtx(ctx, "fake message", zap.Int("attempt", 1))
+	FromContext(context.Background()).Info("no fields")
+
+	dec := json.NewDecoder(&buf)
+
+	var entry map[string]interface{}
+	require.NoError(t, dec.Decode(&entry))
+	assert.Equal(t, "fake message", entry["message"])
+	assert.Equal(t, "fake-request-id", entry["request_id"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", entry["trace_id"])
+	assert.Equal(t, "/otp/request", entry["route"])
+	assert.Equal(t, "fake-uuid", entry["user_uuid"])
+	assert.Equal(t, float64(1), entry["attempt"])
+	assert.Contains(t, entry["caller"], "log/context_test.go")
+
+	entry = nil
+	require.NoError(t, dec.Decode(&entry))
+	assert.Equal(t, "no fields", entry["message"])
+	assert.NotContains(t, entry, "request_id")
+	assert.NotContains(t, entry, "trace_id")
+	assert.Contains(t, entry["caller"], "log/context_test.go")
+}
@@ -1,16 +1,53 @@
 package log
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+type (
+	// Config describes where and how entries are written. Encoding is json or
+	// console and Output is stderr, file or both, the empty values meaning json
+	// and stderr.
+	Config struct {
+		Level    string
+		Encoding string
+		Output   string
+		File     File
+		Sampling Sampling
+	}
+
+	// File is rotated once it reaches MaxSize megabytes, MaxBackups rotated
+	// files are kept for at most MaxAge days, a zero keeps them all.
+	File struct {
+		Path       string
+		MaxSize    int
+		MaxBackups int
+		MaxAge     int
+		Compress   bool
+	}
+
+	// Sampling writes the first Initial entries with the same level and message
+	// every second and then every Thereafter-th one, a zero Initial writes
+	// them all.
+	Sampling struct {
+		Initial    int
+		Thereafter int
+	}
 )
 
 var (
-	loggerOpts = []zap.Option{zap.AddCaller(), zap.AddCallerSkip(1), zap.AddStacktrace(zap.ErrorLevel)}
-	encoder    = zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+	loggerOpts    = []zap.Option{zap.AddCaller(), zap.AddCallerSkip(1), zap.AddStacktrace(zap.ErrorLevel)}
+	encoderConfig = zapcore.EncoderConfig{
 		TimeKey:        "timestamp",
 		LevelKey:       "level",
 		NameKey:        "logger",
@@ -22,11 +59,11 @@ var (
 		EncodeTime:     RFC3339NanoEncoder,
 		EncodeDuration: zapcore.NanosDurationEncoder,
 		EncodeCaller:   zapcore.ShortCallerEncoder,
-	})
+	}
 
-	output   zapcore.WriteSyncer
-	logLevel zapcore.Level
+	logLevel = zap.NewAtomicLevel()
 	log      *zap.Logger
+	file     io.Closer
 	logMu    sync.Mutex
 )
 
@@ -38,17 +75,121 @@ func defaultLog() {
 	logMu.Lock()
 	defer logMu.Unlock()
 
-	output = os.Stderr
-	logLevel = zap.InfoLevel
-	createLogger(output, logLevel)
+	logLevel.SetLevel(zap.InfoLevel)
+	createLogger(zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.Lock(os.Stderr), logLevel))
 }
 
-func createLogger(o zapcore.WriteSyncer, lvl zapcore.Level) {
-	output = o
-	core := zapcore.NewCore(encoder, output, lvl)
+func createLogger(core zapcore.Core) {
 	log = zap.New(core).WithOptions(loggerOpts...)
 }
 
+// Configure replaces the logger written to stderr at info level since the
+// program started with one following cfg. A log file written so far is closed.
+func Configure(cfg Config) error {
+	lvl, err := parseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+
+	var enc zapcore.Encoder
+	switch cfg.Encoding {
+	case "", "json":
+		enc = zapcore.NewJSONEncoder(encoderConfig)
+	case "console":
+		enc = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		return fmt.Errorf("unknown log encoding: %s", cfg.Encoding)
+	}
+
+	var (
+		output  zapcore.WriteSyncer
+		logFile *lumberjack.Logger
+	)
+	switch cfg.Output {
+	case "", "stderr":
+		output = zapcore.Lock(os.Stderr)
+	case "file", "both":
+		if cfg.File.Path == "" {
+			return errors.New("log file path is required")
+		}
+
+		logFile = &lumberjack.Logger{
+			Filename:   cfg.File.Path,
+			MaxSize:    cfg.File.MaxSize,
+			MaxBackups: cfg.File.MaxBackups,
+			MaxAge:     cfg.File.MaxAge,
+			Compress:   cfg.File.Compress,
+		}
+		output = zapcore.AddSync(logFile)
+		if cfg.Output == "both" {
+			output = zapcore.NewMultiWriteSyncer(zapcore.Lock(os.Stderr), output)
+		}
+	default:
+		return fmt.Errorf("unknown log output: %s", cfg.Output)
+	}
+
+	core := zapcore.NewCore(enc, output, logLevel)
+	if cfg.Sampling.Initial > 0 {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, cfg.Sampling.Initial, cfg.Sampling.Thereafter)
+	}
+
+	logMu.Lock()
+	defer logMu.Unlock()
+
+	logLevel.SetLevel(lvl)
+	createLogger(core)
+
+	var closeErr error
+	if file != nil {
+		closeErr = file.Close()
+	}
+
+	file = nil
+	if logFile != nil {
+		file = logFile
+	}
+
+	return closeErr
+}
+
+// Sync flushes buffered entries, it should be called before the program exits.
+func Sync() error {
+	return log.Sync()
+}
+
+// SetLevel changes the minimum level of the entries written from now on, it is
+// safe to call while other goroutines are logging.
+func SetLevel(level string) error {
+	lvl, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	logLevel.SetLevel(lvl)
+
+	return nil
+}
+
+// Level returns the current minimum level.
+func Level() string {
+	return logLevel.String()
+}
+
+// LevelHandler reports the current level as {"level":"info"} on GET and
+// changes it on PUT with a body of the same shape.
+func LevelHandler() http.Handler {
+	return logLevel
+}
+
+func parseLevel(level string) (zapcore.Level, error) {
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return lvl, fmt.Errorf("unknown log level: %s", level)
+	}
+
+	return lvl, nil
+}
+
 // Info add log entry with or without fields to info level
 func Info(msg string, fields ...zap.Field) {
 	log.Info(msg, fields...)
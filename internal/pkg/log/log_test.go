@@ -0,0 +1,53 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigure(t *testing.T) {
+	t.Cleanup(defaultLog)
+
+	path := filepath.Join(t.TempDir(), "sqetest.log")
+	require.NoError(t, Configure(Config{
+		Level:    "warn",
+		Encoding: "console",
+		Output:   "file",
+		File:     File{Path: path, MaxSize: 1},
+	}))
+
+	Info("fake info")
+	Warn("fake warn")
+	require.NoError(t, Sync())
+	assert.Equal(t, "warn", Level())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "fake info")
+	assert.Contains(t, string(content), "warn\tlog/log_test.go")
+	assert.True(t, strings.HasSuffix(string(content), "fake warn\n"))
+
+	// the file is closed once the logger is replaced
+	require.NoError(t, Configure(Config{Level: "info"}))
+	require.NoError(t, SetLevel("debug"))
+	assert.Equal(t, "debug", Level())
+}
+
+func TestConfigure_Invalid(t *testing.T) {
+	t.Cleanup(defaultLog)
+
+	for desc, cfg := range map[string]Config{
+		"Level":    {Level: "verbose"},
+		"Encoding": {Encoding: "xml"},
+		"Output":   {Output: "syslog"},
+		"FilePath": {Output: "both"},
+	} {
+		assert.Error(t, Configure(cfg), desc)
+	}
+	assert.Error(t, SetLevel("verbose"))
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "sqetest"
+
+// Metrics holds the collectors of the service, they are exposed in the
+// Prometheus text format by Handler along with the Go runtime and process
+// metrics.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.HistogramVec
+	otpEvents    *prometheus.CounterVec
+	deliveries   *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of the HTTP requests by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		otpEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "otp_events_total",
+			Help:      "OTPs issued, resent, validated or refused by purpose and outcome.",
+		}, []string{"purpose", "outcome"}),
+		deliveries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "otp_delivery_duration_seconds",
+			Help:      "Latency of the OTP deliveries by channel and result.",
+			Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10},
+		}, []string{"channel", "result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.otpEvents,
+		m.deliveries,
+	)
+
+	return m
+}
+
+// ObserveHTTPRequest records a served request, route is the registered path
+// rather than the requested one to keep the number of series bounded.
+func (m *Metrics) ObserveHTTPRequest(method, route string, status int, latency time.Duration) {
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Observe(latency.Seconds())
+}
+
+// OTPEvent counts an OTP outcome, it is issued, resent, validated or the code
+// of the error the OTP was refused with.
+func (m *Metrics) OTPEvent(purpose, outcome string) {
+	m.otpEvents.WithLabelValues(purpose, outcome).Inc()
+}
+
+// ObserveDelivery records how long delivering an OTP through channel took.
+func (m *Metrics) ObserveDelivery(channel string, latency time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+
+	m.deliveries.WithLabelValues(channel, result).Observe(latency.Seconds())
+}
+
+// RegisterDB exposes the connection pool stats of db, name tells the pools
+// apart, e.g. primary and replica.
+func (m *Metrics) RegisterDB(name string, db *sql.DB) error {
+	return m.registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	m := New()
+	m.ObserveHTTPRequest(http.MethodPost, "/otp/request", http.StatusOK, 20*time.Millisecond)
+	m.OTPEvent("login", "issued")
+	m.OTPEvent("login", "invalid_otp")
+	m.OTPEvent("login", "invalid_otp")
+	m.ObserveDelivery("sms", 300*time.Millisecond, nil)
+	m.ObserveDelivery("email", time.Second, errors.New("fake error"))
+	require.NoError(t, m.RegisterDB("primary", db))
+	assert.Error(t, m.RegisterDB("primary", db), "the name is already taken")
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rec.Body.String()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, body,
+		`sqetest_http_request_duration_seconds_count{method="POST",route="/otp/request",status="200"} 1`)
+	assert.Contains(t, body, `sqetest_otp_events_total{outcome="issued",purpose="login"} 1`)
+	assert.Contains(t, body, `sqetest_otp_events_total{outcome="invalid_otp",purpose="login"} 2`)
+	assert.Contains(t, body, `sqetest_otp_delivery_duration_seconds_count{channel="sms",result="success"} 1`)
+	assert.Contains(t, body, `sqetest_otp_delivery_duration_seconds_count{channel="email",result="failure"} 1`)
+	assert.Contains(t, body, `go_sql_open_connections{db_name="primary"}`)
+	assert.Contains(t, body, "go_goroutines")
+}
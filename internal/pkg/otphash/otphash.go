@@ -0,0 +1,79 @@
+package otphash
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+const (
+	minKeyLength = 16
+	// MaxKeyIDLength is the width of the otps.otp_key_id column.
+	MaxKeyIDLength = 16
+)
+
+var ErrUnknownKey = errors.New("unknown otp pepper key")
+
+// Hasher creates and verifies keyed HMAC-SHA256 digests of OTPs. It holds a set
+// of peppers identified by a key ID so that the current pepper can be rotated
+// while digests created with an older pepper remain verifiable.
+type Hasher struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewHasher creates a Hasher that signs new digests with the pepper identified
+// by current, keys maps every known key ID to its pepper.
+func NewHasher(current string, keys map[string]string) (*Hasher, error) {
+	h := &Hasher{
+		current: current,
+		keys:    make(map[string][]byte, len(keys)),
+	}
+
+	for id, key := range keys {
+		if len(id) > MaxKeyIDLength {
+			return nil, fmt.Errorf("otp pepper key id %q must be at most %d bytes", id, MaxKeyIDLength)
+		}
+		if len(key) < minKeyLength {
+			return nil, fmt.Errorf("otp pepper %q must be at least %d bytes", id, minKeyLength)
+		}
+
+		h.keys[id] = []byte(key)
+	}
+
+	if _, ok := h.keys[current]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, current)
+	}
+
+	return h, nil
+}
+
+// Sum returns the current key ID and the hex encoded digest of otp.
+func (h *Hasher) Sum(otp string) (keyID, digest string) {
+	return h.current, hex.EncodeToString(h.sum(h.keys[h.current], otp))
+}
+
+// Equal reports whether otp matches digest created with the key identified by
+// keyID, the comparison is done in constant time.
+func (h *Hasher) Equal(otp, keyID, digest string) bool {
+	key, ok := h.keys[keyID]
+	if !ok {
+		return false
+	}
+
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(h.sum(key, otp), expected)
+}
+
+func (h *Hasher) sum(key []byte, otp string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(otp))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,162 @@
+package otphash
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHasher(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc    string
+		current string
+		keys    map[string]string
+		err     string
+	}{
+		{
+			desc:    "ErrorShortPepper",
+			current: "v1",
+			keys:    map[string]string{"v1": "short"},
+			err:     `otp pepper "v1" must be at least 16 bytes`,
+		},
+		{
+			desc:    "ErrorLongKeyID",
+			current: "v1",
+			keys:    map[string]string{"v1": "test-pepper-test-pepper", "pepper-2024-01-rotated": "test-pepper-test-pepper"},
+			err:     `otp pepper key id "pepper-2024-01-rotated" must be at most 16 bytes`,
+		},
+		{
+			desc:    "ErrorUnknownCurrentKey",
+			current: "v2",
+			keys:    map[string]string{"v1": "test-pepper-test-pepper"},
+			err:     `unknown otp pepper key: "v2"`,
+		},
+		{
+			desc:    "Success",
+			current: "v1",
+			keys:    map[string]string{"v1": "test-pepper-test-pepper"},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			h, err := NewHasher(tC.current, tC.keys)
+			if tC.err != "" {
+				assert.EqualError(t, err, tC.err)
+				assert.Nil(t, h)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NotNil(t, h)
+		})
+	}
+
+	_, err := NewHasher("v2", map[string]string{"v1": "test-pepper-test-pepper"})
+	assert.True(t, errors.Is(err, ErrUnknownKey))
+}
+
+func TestHasher_Sum(t *testing.T) {
+	t.Parallel()
+
+	h, err := NewHasher("v1", map[string]string{"v1": "test-pepper-test-pepper", "v2": "other-pepper-other-pepper"})
+	require.NoError(t, err)
+
+	// stored digests must keep verifying across releases, HMAC-SHA256 of the
+	// OTP keyed with the pepper
+	keyID, digest := h.Sum("12345")
+	assert.Equal(t, "v1", keyID)
+	assert.Equal(t, "5e059d3638eb6e7b82d5700ba2bcbd88096b5cbd35b93a204ab5c5d9a1fc9690", digest)
+
+	_, again := h.Sum("12345")
+	assert.Equal(t, digest, again)
+
+	_, other := h.Sum("54321")
+	assert.NotEqual(t, digest, other)
+}
+
+func TestHasher_Equal(t *testing.T) {
+	t.Parallel()
+
+	old, err := NewHasher("v1", map[string]string{"v1": "test-pepper-test-pepper"})
+	require.NoError(t, err)
+	keyID, digest := old.Sum("12345")
+
+	// v2 is current after the rotation, v1 is kept to verify older digests
+	rotated, err := NewHasher("v2", map[string]string{"v1": "test-pepper-test-pepper", "v2": "other-pepper-other-pepper"})
+	require.NoError(t, err)
+	newKeyID, newDigest := rotated.Sum("12345")
+	assert.Equal(t, "v2", newKeyID)
+	assert.NotEqual(t, digest, newDigest)
+
+	testCases := []struct {
+		desc   string
+		hasher *Hasher
+		otp    string
+		keyID  string
+		digest string
+		equal  bool
+	}{
+		{
+			desc:   "ErrorWrongOTP",
+			hasher: rotated,
+			otp:    "54321",
+			keyID:  keyID,
+			digest: digest,
+		},
+		{
+			desc:   "ErrorUnknownKey",
+			hasher: old,
+			otp:    "12345",
+			keyID:  newKeyID,
+			digest: newDigest,
+		},
+		{
+			desc:   "ErrorWrongKey",
+			hasher: rotated,
+			otp:    "12345",
+			keyID:  newKeyID,
+			digest: digest,
+		},
+		{
+			desc:   "ErrorMalformedDigest",
+			hasher: rotated,
+			otp:    "12345",
+			keyID:  keyID,
+			digest: "not-hex",
+		},
+		{
+			desc:   "SuccessRetiredKey",
+			hasher: rotated,
+			otp:    "12345",
+			keyID:  keyID,
+			digest: digest,
+			equal:  true,
+		},
+		{
+			desc:   "SuccessCurrentKey",
+			hasher: rotated,
+			otp:    "12345",
+			keyID:  newKeyID,
+			digest: newDigest,
+			equal:  true,
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tC.equal, tC.hasher.Equal(tC.otp, tC.keyID, tC.digest))
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+type (
+	// Memory keeps the buckets in process, it's only accurate when a single
+	// instance serves the traffic.
+	Memory struct {
+		mu        sync.Mutex
+		buckets   map[string]*bucket
+		nowFunc   func() time.Time
+		lastSweep time.Time
+	}
+
+	bucket struct {
+		tokens float64
+		last   time.Time
+		fullAt time.Time
+	}
+)
+
+func NewMemory(nowFunc func() time.Time) *Memory {
+	return &Memory{
+		buckets:   make(map[string]*bucket),
+		nowFunc:   nowFunc,
+		lastSweep: nowFunc(),
+	}
+}
+
+func (m *Memory) Allow(_ context.Context, key string, rate Rate) (Result, error) {
+	if rate.Limit <= 0 {
+		return Result{Allowed: true}, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.nowFunc()
+	m.sweep(now)
+
+	burst, interval := rate.burst(), rate.interval()
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		m.buckets[key] = b
+	}
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+float64(elapsed)/float64(interval))
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		return Result{RetryAfter: time.Duration((1 - b.tokens) * float64(interval))}, nil
+	}
+
+	b.tokens--
+	b.fullAt = now.Add(time.Duration((burst - b.tokens) * float64(interval)))
+
+	return Result{Allowed: true}, nil
+}
+
+// sweep drops the buckets that are full again, they hold no state that a new
+// bucket wouldn't.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+
+	for key, b := range m.buckets {
+		if !b.fullAt.After(now) {
+			delete(m.buckets, key)
+		}
+	}
+	m.lastSweep = now
+}
@@ -0,0 +1,37 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Rate is a token bucket refilled with Limit tokens every Period that holds at
+// most Burst tokens, Burst defaults to Limit. A zero Limit disables the rate.
+type Rate struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
+type Result struct {
+	Allowed    bool
+	RetryAfter time.Duration
+}
+
+// Limiter takes a token from the bucket identified by key.
+type Limiter interface {
+	Allow(ctx context.Context, key string, rate Rate) (Result, error)
+}
+
+func (r Rate) burst() float64 {
+	if r.Burst > 0 {
+		return float64(r.Burst)
+	}
+
+	return float64(r.Limit)
+}
+
+// interval is the time it takes to refill a single token.
+func (r Rate) interval() time.Duration {
+	return r.Period / time.Duration(r.Limit)
+}
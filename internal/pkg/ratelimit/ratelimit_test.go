@@ -0,0 +1,154 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// backends creates every Limiter implementation, the Redis one runs against
+// an in-process miniredis server.
+var backends = map[string]func(*testing.T, func() time.Time) Limiter{
+	"Memory": func(_ *testing.T, nowFunc func() time.Time) Limiter {
+		return NewMemory(nowFunc)
+	},
+	"Redis": func(t *testing.T, nowFunc func() time.Time) Limiter {
+		srv := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+		t.Cleanup(func() { client.Close() })
+
+		return NewRedis(client, "test:", nowFunc)
+	},
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	t.Parallel()
+
+	type step struct {
+		advance time.Duration
+		key     string
+		exp     Result
+	}
+
+	rate := Rate{Limit: 2, Period: time.Minute}
+
+	testCases := []struct {
+		desc  string
+		rate  Rate
+		steps []step
+	}{
+		{
+			desc: "DenyAfterBurst",
+			rate: rate,
+			steps: []step{
+				{key: "a", exp: Result{Allowed: true}},
+				{key: "a", exp: Result{Allowed: true}},
+				{key: "a", exp: Result{RetryAfter: 30 * time.Second}},
+				{advance: 10 * time.Second, key: "a", exp: Result{RetryAfter: 20 * time.Second}},
+			},
+		},
+		{
+			desc: "RefillOverTime",
+			rate: rate,
+			steps: []step{
+				{key: "a", exp: Result{Allowed: true}},
+				{key: "a", exp: Result{Allowed: true}},
+				{advance: 30 * time.Second, key: "a", exp: Result{Allowed: true}},
+				{key: "a", exp: Result{RetryAfter: 30 * time.Second}},
+				{advance: 2 * time.Minute, key: "a", exp: Result{Allowed: true}},
+				{key: "a", exp: Result{Allowed: true}},
+				{key: "a", exp: Result{RetryAfter: 30 * time.Second}},
+			},
+		},
+		{
+			desc: "SeparateKeys",
+			rate: Rate{Limit: 1, Period: time.Minute},
+			steps: []step{
+				{key: "a", exp: Result{Allowed: true}},
+				{key: "b", exp: Result{Allowed: true}},
+				{key: "a", exp: Result{RetryAfter: time.Minute}},
+			},
+		},
+		{
+			desc: "BurstAboveLimit",
+			rate: Rate{Limit: 1, Period: time.Minute, Burst: 3},
+			steps: []step{
+				{key: "a", exp: Result{Allowed: true}},
+				{key: "a", exp: Result{Allowed: true}},
+				{key: "a", exp: Result{Allowed: true}},
+				{key: "a", exp: Result{RetryAfter: time.Minute}},
+			},
+		},
+		{
+			desc: "Disabled",
+			rate: Rate{},
+			steps: []step{
+				{key: "a", exp: Result{Allowed: true}},
+				{key: "a", exp: Result{Allowed: true}},
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			for name, newLimiter := range backends {
+				newLimiter := newLimiter
+				t.Run(name, func(t *testing.T) {
+					clock := &fakeClock{now: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)}
+					limiter := newLimiter(t, clock.Now)
+
+					for i, s := range tC.steps {
+						clock.Add(s.advance)
+
+						got, err := limiter.Allow(context.TODO(), s.key, tC.rate)
+						assert.NoError(t, err)
+						assert.Equal(t, s.exp, got, "step %d", i)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestMemory_Sweep(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := NewMemory(clock.Now)
+	rate := Rate{Limit: 1, Period: time.Second}
+
+	_, err := limiter.Allow(context.TODO(), "a", rate)
+	assert.NoError(t, err)
+	assert.Len(t, limiter.buckets, 1)
+
+	clock.Add(sweepInterval)
+	_, err = limiter.Allow(context.TODO(), "b", rate)
+	assert.NoError(t, err)
+	assert.Len(t, limiter.buckets, 1)
+	assert.Contains(t, limiter.buckets, "b")
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript refills and takes a token atomically. Times are in
+// microseconds, the bucket expires once it would be full again.
+var tokenBucketScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) / interval)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.floor((1 - tokens) * interval + 0.5)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', string.format('%.17g', tokens), 'ts', ARGV[3])
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) * interval / 1000) + 1000)
+
+return {allowed, retry}
+`)
+
+// Redis keeps the buckets in a Redis compatible server so every instance
+// shares them. The instances' clocks are used, they should be kept in sync.
+type Redis struct {
+	client  redis.Scripter
+	prefix  string
+	nowFunc func() time.Time
+}
+
+func NewRedis(client redis.Scripter, prefix string, nowFunc func() time.Time) *Redis {
+	return &Redis{
+		client:  client,
+		prefix:  prefix,
+		nowFunc: nowFunc,
+	}
+}
+
+func (r *Redis) Allow(ctx context.Context, key string, rate Rate) (Result, error) {
+	if rate.Limit <= 0 {
+		return Result{Allowed: true}, nil
+	}
+
+	res, err := tokenBucketScript.Run(ctx, r.client, []string{r.prefix + key},
+		rate.burst(), rate.interval().Microseconds(), r.nowFunc().UnixMicro()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Allowed:    res[0] == 1,
+		RetryAfter: time.Duration(res[1]) * time.Microsecond,
+	}, nil
+}
@@ -17,6 +17,10 @@ func InjectToCtx(resHeaders http.Header, ctx context.Context) context.Context {
 	return context.WithValue(ctx, key, id)
 }
 
+// ExtractFromCtx returns the request ID injected by InjectToCtx, or an empty
+// string when there is none.
 func ExtractFromCtx(ctx context.Context) string {
-	return ctx.Value(key).(string)
+	id, _ := ctx.Value(key).(string)
+
+	return id
 }
@@ -0,0 +1,63 @@
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+const minKeyLength = 16
+
+var ErrMalformed = errors.New("malformed sealed secret")
+
+// Box encrypts secrets at rest with AES-256-GCM, the key is derived from the
+// configured passphrase with SHA-256.
+type Box struct {
+	aead cipher.AEAD
+}
+
+func New(key string) (*Box, error) {
+	if len(key) < minKeyLength {
+		return nil, fmt.Errorf("secret box key must be at least %d bytes", minKeyLength)
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Box{aead: aead}, nil
+}
+
+// Seal encrypts plaintext with a random nonce and returns the base64 encoded
+// nonce followed by the ciphertext.
+func (b *Box) Seal(plaintext []byte) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(b.aead.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// Open decrypts a value created by Seal.
+func (b *Box) Open(sealed string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+
+	return b.aead.Open(nil, nonce, ciphertext, nil)
+}
@@ -0,0 +1,84 @@
+package secretbox
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "test-totp-key-test-totp-key"
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	_, err := New("short")
+	assert.EqualError(t, err, "secret box key must be at least 16 bytes")
+
+	_, err = New(testKey)
+	assert.NoError(t, err)
+}
+
+func TestBox_Open(t *testing.T) {
+	t.Parallel()
+
+	box, err := New(testKey)
+	require.NoError(t, err)
+
+	plaintext := []byte("12345678901234567890")
+	sealed, err := box.Seal(plaintext)
+	require.NoError(t, err)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := box.Open(sealed)
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, got)
+	})
+
+	t.Run("SuccessRandomNonce", func(t *testing.T) {
+		t.Parallel()
+
+		other, err := box.Seal(plaintext)
+		require.NoError(t, err)
+		assert.NotEqual(t, sealed, other)
+
+		got, err := box.Open(other)
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, got)
+	})
+
+	t.Run("ErrorTampered", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := base64.StdEncoding.DecodeString(sealed)
+		require.NoError(t, err)
+		data[len(data)-1] ^= 0x01
+
+		_, err = box.Open(base64.StdEncoding.EncodeToString(data))
+		assert.Error(t, err)
+	})
+
+	t.Run("ErrorWrongKey", func(t *testing.T) {
+		t.Parallel()
+
+		other, err := New("another-totp-key-another-totp-key")
+		require.NoError(t, err)
+
+		_, err = other.Open(sealed)
+		assert.Error(t, err)
+	})
+
+	t.Run("ErrorMalformed", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := box.Open("not base64!")
+		assert.True(t, errors.Is(err, ErrMalformed))
+
+		_, err = box.Open(base64.StdEncoding.EncodeToString([]byte("short")))
+		assert.True(t, errors.Is(err, ErrMalformed))
+	})
+}
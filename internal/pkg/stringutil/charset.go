@@ -0,0 +1,34 @@
+package stringutil
+
+import (
+	"strings"
+)
+
+const (
+	DigitsCharset          = "0123456789"
+	AlphanumericCharset    = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	CrockfordBase32Charset = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+var (
+	charsets = map[string]string{
+		"digits":       DigitsCharset,
+		"alphanumeric": AlphanumericCharset,
+		"crockford":    CrockfordBase32Charset,
+	}
+
+	crockfordReplacer = strings.NewReplacer("-", "", "I", "1", "L", "1", "O", "0")
+)
+
+// Charset returns the characters of the charset registered under name.
+func Charset(name string) (string, bool) {
+	charset, ok := charsets[name]
+
+	return charset, ok
+}
+
+// NormalizeCrockford converts user input to canonical Crockford base32 by
+// upper casing it, dropping hyphens and mapping the ambiguous I, L and O.
+func NormalizeCrockford(s string) string {
+	return crockfordReplacer.Replace(strings.ToUpper(s))
+}
@@ -11,18 +11,26 @@ var randomReaderPool = sync.Pool{New: func() interface{} {
 	return bufio.NewReader(rand.Reader)
 }}
 
-const (
-	randomNumberCharset    = "0123456789"
-	randomNumberCharsetLen = 10
-	randomNumberMaxByte    = 255 - (256 % randomNumberCharsetLen)
-)
-
 func RandomNumbers(length uint8) (string, error) {
+	return RandomString(DigitsCharset, length)
+}
+
+// RandomString returns a cryptographically random string of length characters
+// picked uniformly from charset, which must hold between 1 and 256 bytes.
+func RandomString(charset string, length uint8) (string, error) {
+	if length == 0 {
+		return "", nil
+	}
+
 	reader := randomReaderPool.Get().(*bufio.Reader)
 	defer randomReaderPool.Put(reader)
 
+	charsetLen := len(charset)
+	maxByte := 255 - (256 % charsetLen)
+
 	b := make([]byte, length)
-	r := make([]byte, length+(length/4))
+	// sized in int, length+length/4 overflows uint8 for long strings
+	r := make([]byte, int(length)+int(length)/4)
 	var i uint8 = 0
 
 	for {
@@ -32,11 +40,11 @@ func RandomNumbers(length uint8) (string, error) {
 		}
 
 		for _, rb := range r {
-			if rb > randomNumberMaxByte {
+			if int(rb) > maxByte {
 				continue
 			}
 
-			b[i] = randomNumberCharset[rb%randomNumberCharsetLen]
+			b[i] = charset[int(rb)%charsetLen]
 			i++
 
 			if i == length {
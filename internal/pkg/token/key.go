@@ -0,0 +1,155 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+
+	minSecretLength = 32
+)
+
+var ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+
+// Key is a signing key identified by its kid. HS256 keys hold a shared secret
+// and are never published, RS256 and EdDSA keys hold a private key whose public
+// half is published in the JWKS.
+type Key struct {
+	ID        string
+	Algorithm string
+
+	secret  []byte
+	private crypto.Signer
+}
+
+type (
+	// JWKS is a JSON Web Key Set as described by RFC 7517.
+	JWKS struct {
+		Keys []JWK `json:"keys"`
+	}
+
+	JWK struct {
+		KeyType   string `json:"kty"`
+		Use       string `json:"use"`
+		KeyID     string `json:"kid"`
+		Algorithm string `json:"alg"`
+		N         string `json:"n,omitempty"`
+		E         string `json:"e,omitempty"`
+		Curve     string `json:"crv,omitempty"`
+		X         string `json:"x,omitempty"`
+	}
+)
+
+// NewHMACKey creates an HS256 key from a shared secret.
+func NewHMACKey(id string, secret []byte) (Key, error) {
+	if len(secret) < minSecretLength {
+		return Key{}, fmt.Errorf("hmac secret of key %s must be at least %d bytes", id, minSecretLength)
+	}
+
+	return Key{ID: id, Algorithm: AlgorithmHS256, secret: secret}, nil
+}
+
+// ParsePrivateKey creates an RS256 or EdDSA key from a PEM encoded PKCS #8 or,
+// for RSA, PKCS #1 private key.
+func ParsePrivateKey(id, algorithm string, data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, fmt.Errorf("no pem data found for key %s", id)
+	}
+
+	var (
+		private interface{}
+		err     error
+	)
+	if block.Type == "RSA PRIVATE KEY" {
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("fail to parse key %s: %w", id, err)
+	}
+
+	switch private.(type) {
+	case *rsa.PrivateKey:
+		if algorithm != AlgorithmRS256 {
+			return Key{}, fmt.Errorf("key %s is an rsa key, expecting %s", id, algorithm)
+		}
+	case ed25519.PrivateKey:
+		if algorithm != AlgorithmEdDSA {
+			return Key{}, fmt.Errorf("key %s is an ed25519 key, expecting %s", id, algorithm)
+		}
+	default:
+		return Key{}, fmt.Errorf("key %s: %w", id, ErrUnsupportedAlgorithm)
+	}
+
+	return Key{ID: id, Algorithm: algorithm, private: private.(crypto.Signer)}, nil
+}
+
+func (k Key) method() jwt.SigningMethod {
+	switch k.Algorithm {
+	case AlgorithmHS256:
+		return jwt.SigningMethodHS256
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256
+	default:
+		return jwt.SigningMethodEdDSA
+	}
+}
+
+func (k Key) signingKey() interface{} {
+	if k.secret != nil {
+		return k.secret
+	}
+
+	return k.private
+}
+
+func (k Key) verificationKey() interface{} {
+	if k.secret != nil {
+		return k.secret
+	}
+
+	return k.private.Public()
+}
+
+// jwk returns the public half of the key, shared secrets are never published.
+func (k Key) jwk() (JWK, bool) {
+	enc := base64.RawURLEncoding
+
+	switch public := k.verificationKey().(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType:   "RSA",
+			Use:       "sig",
+			KeyID:     k.ID,
+			Algorithm: k.Algorithm,
+			N:         enc.EncodeToString(public.N.Bytes()),
+			E:         enc.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			KeyType:   "OKP",
+			Use:       "sig",
+			KeyID:     k.ID,
+			Algorithm: k.Algorithm,
+			Curve:     "Ed25519",
+			X:         enc.EncodeToString(public),
+		}, true
+	default:
+		return JWK{}, false
+	}
+}
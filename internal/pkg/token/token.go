@@ -0,0 +1,171 @@
+package token
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	refreshTokenLength = 32
+	challengeIDLength  = 24
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Config configures an Issuer. Tokens are signed with the key identified by
+// CurrentKeyID and verified with any of Keys, so a key can be rotated by adding
+// a new key, switching CurrentKeyID to it and removing the old key once every
+// token it signed has expired.
+type Config struct {
+	Issuer       string
+	Audience     string
+	TTL          time.Duration
+	Keys         []Key
+	CurrentKeyID string
+	NowFunc      func() time.Time
+}
+
+// Issuer signs and verifies short lived access tokens.
+type Issuer struct {
+	issuer   string
+	audience string
+	ttl      time.Duration
+	current  Key
+	keys     map[string]Key
+	methods  []string
+	nowFunc  func() time.Time
+}
+
+func NewIssuer(cfg Config) (*Issuer, error) {
+	if cfg.TTL <= 0 {
+		return nil, errors.New("token ttl must be positive")
+	}
+
+	keys := make(map[string]Key, len(cfg.Keys))
+	methods := make(map[string]struct{})
+	for _, key := range cfg.Keys {
+		if _, ok := keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate token key id: %s", key.ID)
+		}
+
+		keys[key.ID] = key
+		methods[key.Algorithm] = struct{}{}
+	}
+
+	current, ok := keys[cfg.CurrentKeyID]
+	if !ok {
+		return nil, fmt.Errorf("no token key for current key id: %s", cfg.CurrentKeyID)
+	}
+
+	i := &Issuer{
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		ttl:      cfg.TTL,
+		current:  current,
+		keys:     keys,
+		nowFunc:  cfg.NowFunc,
+	}
+	for method := range methods {
+		i.methods = append(i.methods, method)
+	}
+
+	return i, nil
+}
+
+// Issue signs an access token for subject with the current key, it returns the
+// token and how long it is valid for.
+func (i *Issuer) Issue(subject string) (string, time.Duration, error) {
+	now := i.nowFunc()
+	claims := jwt.RegisteredClaims{
+		Issuer:    i.issuer,
+		Subject:   subject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(i.ttl)),
+	}
+	if i.audience != "" {
+		claims.Audience = jwt.ClaimStrings{i.audience}
+	}
+
+	t := jwt.NewWithClaims(i.current.method(), claims)
+	t.Header["kid"] = i.current.ID
+
+	signed, err := t.SignedString(i.current.signingKey())
+	if err != nil {
+		return "", 0, err
+	}
+
+	return signed, i.ttl, nil
+}
+
+// Verify checks the signature, issuer, audience and expiry of an access token
+// and returns its subject.
+func (i *Issuer) Verify(token string) (string, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(i.methods),
+		jwt.WithIssuer(i.issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(i.nowFunc),
+	}
+	if i.audience != "" {
+		opts = append(opts, jwt.WithAudience(i.audience))
+	}
+
+	var claims jwt.RegisteredClaims
+	if _, err := jwt.ParseWithClaims(token, &claims, i.keyFunc, opts...); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return claims.Subject, nil
+}
+
+// JWKS returns the public keys that verify the tokens of the issuer.
+func (i *Issuer) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range i.keys {
+		if jwk, ok := key.jwk(); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	sort.Slice(jwks.Keys, func(a, b int) bool {
+		return jwks.Keys[a].KeyID < jwks.Keys[b].KeyID
+	})
+
+	return jwks
+}
+
+func (i *Issuer) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, ok := i.keys[kid]
+	if !ok || key.method().Alg() != t.Method.Alg() {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+
+	return key.verificationKey(), nil
+}
+
+// NewRefreshToken returns a random, URL safe refresh token.
+func NewRefreshToken() (string, error) {
+	return random(refreshTokenLength)
+}
+
+// NewChallengeID returns a random, URL safe ID the client validates an OTP
+// with, it can't be guessed from anything else the client sees.
+func NewChallengeID() (string, error) {
+	return random(challengeIDLength)
+}
+
+func random(length int) (string, error) {
+	b := make([]byte, length)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
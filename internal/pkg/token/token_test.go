@@ -0,0 +1,157 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestKeys(t *testing.T) map[string]Key {
+	hmacKey, err := NewHMACKey("hs", []byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaKey, err := ParsePrivateKey("rs", AlgorithmRS256, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(rsaPrivate),
+	}))
+	require.NoError(t, err)
+
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(edPrivate)
+	require.NoError(t, err)
+	edKey, err := ParsePrivateKey("ed", AlgorithmEdDSA, pem.EncodeToMemory(&pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: der,
+	}))
+	require.NoError(t, err)
+
+	return map[string]Key{"hs": hmacKey, "rs": rsaKey, "ed": edKey}
+}
+
+func TestIssuer(t *testing.T) {
+	t.Parallel()
+
+	keys := newTestKeys(t)
+
+	newIssuer := func(t *testing.T, current string, now time.Time, ks ...Key) *Issuer {
+		issuer, err := NewIssuer(Config{
+			Issuer:       "sqetest",
+			Audience:     "api",
+			TTL:          5 * time.Minute,
+			Keys:         ks,
+			CurrentKeyID: current,
+			NowFunc: func() time.Time {
+				return now
+			},
+		})
+		require.NoError(t, err)
+
+		return issuer
+	}
+
+	for _, kid := range []string{"hs", "rs", "ed"} {
+		kid := kid
+		t.Run("RoundTrip"+keys[kid].Algorithm, func(t *testing.T) {
+			t.Parallel()
+
+			issuer := newIssuer(t, kid, testNow, keys[kid])
+
+			signed, ttl, err := issuer.Issue("fake-uuid")
+			require.NoError(t, err)
+			assert.Equal(t, 5*time.Minute, ttl)
+
+			subject, err := issuer.Verify(signed)
+			assert.NoError(t, err)
+			assert.Equal(t, "fake-uuid", subject)
+		})
+	}
+
+	t.Run("VerifyRotatedKey", func(t *testing.T) {
+		t.Parallel()
+
+		signed, _, err := newIssuer(t, "rs", testNow, keys["rs"]).Issue("fake-uuid")
+		require.NoError(t, err)
+
+		subject, err := newIssuer(t, "ed", testNow, keys["rs"], keys["ed"]).Verify(signed)
+		assert.NoError(t, err)
+		assert.Equal(t, "fake-uuid", subject)
+	})
+
+	t.Run("ErrorRemovedKey", func(t *testing.T) {
+		t.Parallel()
+
+		signed, _, err := newIssuer(t, "rs", testNow, keys["rs"]).Issue("fake-uuid")
+		require.NoError(t, err)
+
+		_, err = newIssuer(t, "ed", testNow, keys["ed"]).Verify(signed)
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+	})
+
+	t.Run("ErrorExpired", func(t *testing.T) {
+		t.Parallel()
+
+		signed, _, err := newIssuer(t, "hs", testNow, keys["hs"]).Issue("fake-uuid")
+		require.NoError(t, err)
+
+		_, err = newIssuer(t, "hs", testNow.Add(6*time.Minute), keys["hs"]).Verify(signed)
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+	})
+
+	t.Run("ErrorTampered", func(t *testing.T) {
+		t.Parallel()
+
+		issuer := newIssuer(t, "ed", testNow, keys["ed"])
+		signed, _, err := issuer.Issue("fake-uuid")
+		require.NoError(t, err)
+
+		// flip a bit of the signature itself, the last base64 characters may
+		// only carry padding bits and decode to the same signature
+		dot := strings.LastIndex(signed, ".")
+		sig, err := base64.RawURLEncoding.DecodeString(signed[dot+1:])
+		require.NoError(t, err)
+		sig[0] ^= 0x01
+
+		_, err = issuer.Verify(signed[:dot+1] + base64.RawURLEncoding.EncodeToString(sig))
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+	})
+
+	t.Run("JWKSOmitsSharedSecrets", func(t *testing.T) {
+		t.Parallel()
+
+		jwks := newIssuer(t, "hs", testNow, keys["hs"], keys["rs"], keys["ed"]).JWKS()
+		require.Len(t, jwks.Keys, 2)
+		assert.Equal(t, "ed", jwks.Keys[0].KeyID)
+		assert.Equal(t, "OKP", jwks.Keys[0].KeyType)
+		assert.Equal(t, "Ed25519", jwks.Keys[0].Curve)
+		assert.Equal(t, "rs", jwks.Keys[1].KeyID)
+		assert.Equal(t, "RSA", jwks.Keys[1].KeyType)
+		assert.Equal(t, "AQAB", jwks.Keys[1].E)
+	})
+}
+
+func TestNewChallengeID(t *testing.T) {
+	t.Parallel()
+
+	id, err := NewChallengeID()
+	require.NoError(t, err)
+	assert.Len(t, id, 32)
+
+	other, err := NewChallengeID()
+	require.NoError(t, err)
+	assert.NotEqual(t, id, other)
+}
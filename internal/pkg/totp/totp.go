@@ -0,0 +1,112 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const secretLength = 20
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Params are the RFC 6238 parameters shared by the server and the
+// authenticator app. Skew is the number of periods before and after the
+// current one that are still accepted to tolerate clock drift.
+type Params struct {
+	Digits uint8
+	Period time.Duration
+	Skew   uint8
+}
+
+// NewSecret returns a random 160 bits secret as recommended by RFC 4226.
+func NewSecret() ([]byte, error) {
+	secret := make([]byte, secretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// EncodeSecret returns the unpadded base32 form of secret used by
+// authenticator apps.
+func EncodeSecret(secret []byte) string {
+	return encoding.EncodeToString(secret)
+}
+
+// HOTP returns the RFC 4226 code of secret for counter.
+func HOTP(secret []byte, counter uint64, digits uint8) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := uint8(0); i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, code%mod)
+}
+
+// Counter returns the time step of t, Period must be a whole number of seconds.
+func (p Params) Counter(t time.Time) uint64 {
+	return uint64(t.Unix()) / uint64(p.Period/time.Second)
+}
+
+// Code returns the code of secret at t.
+func (p Params) Code(secret []byte, t time.Time) string {
+	return HOTP(secret, p.Counter(t), p.Digits)
+}
+
+// Verify reports whether code is valid for secret at t within the skew window
+// and returns the time step it matched, callers must reject time steps that
+// were already used to prevent replays.
+func (p Params) Verify(secret []byte, code string, t time.Time) (uint64, bool) {
+	if len(code) != int(p.Digits) {
+		return 0, false
+	}
+
+	counter := p.Counter(t)
+	for i := -int64(p.Skew); i <= int64(p.Skew); i++ {
+		c := uint64(int64(counter) + i)
+		if subtle.ConstantTimeCompare([]byte(HOTP(secret, c, p.Digits)), []byte(code)) == 1 {
+			return c, true
+		}
+	}
+
+	return 0, false
+}
+
+// URI returns the otpauth:// provisioning URI of secret understood by
+// authenticator apps.
+func (p Params) URI(issuer, account string, secret []byte) string {
+	query := url.Values{}
+	query.Set("secret", EncodeSecret(secret))
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", strconv.Itoa(int(p.Digits)))
+	query.Set("period", strconv.Itoa(int(p.Period/time.Second)))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+
+	return u.String()
+}
@@ -0,0 +1,149 @@
+package totp
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA-1 secret of the RFC 4226 and RFC 6238 test vectors.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestHOTP(t *testing.T) {
+	t.Parallel()
+
+	// RFC 4226 appendix D
+	expected := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+
+	for counter, code := range expected {
+		counter, code := counter, code
+		t.Run(strconv.Itoa(counter), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, code, HOTP(rfcSecret, uint64(counter), 6))
+		})
+	}
+}
+
+func TestParams_Code(t *testing.T) {
+	t.Parallel()
+
+	p := Params{Digits: 8, Period: 30 * time.Second}
+
+	// RFC 6238 appendix B, SHA-1
+	testCases := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "94287082"},
+		{unix: 1111111109, code: "07081804"},
+		{unix: 1111111111, code: "14050471"},
+		{unix: 1234567890, code: "89005924"},
+		{unix: 2000000000, code: "69279037"},
+		{unix: 20000000000, code: "65353130"},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(strconv.FormatInt(tC.unix, 10), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tC.code, p.Code(rfcSecret, time.Unix(tC.unix, 0)))
+		})
+	}
+}
+
+func TestParams_Verify(t *testing.T) {
+	t.Parallel()
+
+	p := Params{Digits: 6, Period: 30 * time.Second, Skew: 1}
+	now := time.Unix(1111111111, 0)
+	counter := p.Counter(now)
+
+	testCases := []struct {
+		desc    string
+		code    string
+		counter uint64
+		ok      bool
+	}{
+		{
+			desc: "ErrorWrongLength",
+			code: HOTP(rfcSecret, counter, 8),
+		},
+		{
+			desc: "ErrorOutsideSkew",
+			code: HOTP(rfcSecret, counter+2, 6),
+		},
+		{
+			desc: "ErrorWrongCode",
+			code: "000000",
+		},
+		{
+			desc:    "SuccessPreviousStep",
+			code:    HOTP(rfcSecret, counter-1, 6),
+			counter: counter - 1,
+			ok:      true,
+		},
+		{
+			desc:    "SuccessCurrentStep",
+			code:    HOTP(rfcSecret, counter, 6),
+			counter: counter,
+			ok:      true,
+		},
+		{
+			desc:    "SuccessNextStep",
+			code:    HOTP(rfcSecret, counter+1, 6),
+			counter: counter + 1,
+			ok:      true,
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := p.Verify(rfcSecret, tC.code, now)
+			assert.Equal(t, tC.ok, ok)
+			assert.Equal(t, tC.counter, got)
+		})
+	}
+}
+
+func TestParams_URI(t *testing.T) {
+	t.Parallel()
+
+	p := Params{Digits: 6, Period: 30 * time.Second}
+
+	u, err := url.Parse(p.URI("sqetest", "fake-uuid", rfcSecret))
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/sqetest:fake-uuid", u.Path)
+	assert.Equal(t, url.Values{
+		"secret":    {"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"},
+		"issuer":    {"sqetest"},
+		"algorithm": {"SHA1"},
+		"digits":    {"6"},
+		"period":    {"30"},
+	}, u.Query())
+}
+
+func TestNewSecret(t *testing.T) {
+	t.Parallel()
+
+	secret, err := NewSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, secretLength)
+
+	other, err := NewSecret()
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, other)
+}
@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// collector is an OTLP/HTTP collector stub keeping the names of the spans it
+// receives.
+type collector struct {
+	mu    sync.Mutex
+	spans []string
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/traces" {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	var req collectortrace.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	c.mu.Lock()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			for _, span := range ss.Spans {
+				c.spans = append(c.spans, span.Name)
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	res, _ := proto.Marshal(&collectortrace.ExportTraceServiceResponse{})
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(res)
+}
+
+func TestSetup(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	shutdown, err := Setup(context.Background(), Config{
+		Endpoint:    strings.TrimPrefix(srv.URL, "http://"),
+		Insecure:    true,
+		SampleRatio: 1,
+		ServiceName: "sqetest",
+	})
+	assert.NoError(t, err)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	_, child := otel.Tracer("test").Start(ctx, "child")
+	child.End()
+	parent.End()
+
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	assert.Contains(t, carrier.Get("traceparent"), parent.SpanContext().TraceID().String())
+
+	assert.NoError(t, shutdown(context.Background()))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	assert.ElementsMatch(t, c.spans, []string{"parent", "child"})
+}
+
+func TestSetup_NoEndpoint(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/subroll/sqetest/internal/entity"
+)
+
+var ErrUserExist = errors.New("user already exists")
+
+// CreateUser stores a new user and returns it with its ID.
+func (u *User) CreateUser(ctx context.Context, user entity.User) (entity.User, error) {
+	tx, err := u.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: false})
+	if err != nil {
+		return entity.User{}, err
+	}
+	defer tx.Rollback()
+
+	var id uint64
+	if err := u.queryRow(ctx, tx, `SELECT id FROM users WHERE uuid = ? FOR UPDATE;`,
+		user.UUID).Scan(&id); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return entity.User{}, err
+		}
+	}
+
+	if id > 0 {
+		return entity.User{}, ErrUserExist
+	}
+
+	if _, err := u.exec(ctx, tx, `INSERT INTO users (uuid, name, email, phone, otp_channel) `+
+		`VALUES (?, ?, ?, ?, ?);`,
+		user.UUID, user.Name, nullString(user.Email), nullString(user.Phone), nullString(user.OTPChannel)); err != nil {
+		return entity.User{}, err
+	}
+
+	// LastInsertId isn't supported by every driver, so the ID is read back
+	if err := u.queryRow(ctx, tx, `SELECT id FROM users WHERE uuid = ?;`,
+		user.UUID).Scan(&user.ID); err != nil {
+		return entity.User{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return entity.User{}, err
+	}
+
+	return user, nil
+}
+
+// GetUser returns the user whether it's disabled or not, unlike GetUserByUUID
+// it's meant for managing users rather than authenticating them. Anonymized
+// users aren't found.
+func (u *User) GetUser(ctx context.Context, uuid string) (entity.User, error) {
+	var (
+		user                     entity.User
+		email, phone, otpChannel sql.NullString
+		disabledAt               sql.NullTime
+	)
+	if err := u.queryRow(ctx, u.db, `SELECT id, uuid, name, email, phone, otp_channel, disabled_at FROM users `+
+		`WHERE uuid = ? AND anonymized_at IS NULL;`,
+		uuid).Scan(&user.ID, &user.UUID, &user.Name, &email, &phone, &otpChannel, &disabledAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.User{}, ErrNotFound
+		}
+
+		return entity.User{}, err
+	}
+
+	user.Email = email.String
+	user.Phone = phone.String
+	user.OTPChannel = otpChannel.String
+	user.Disabled = disabledAt.Valid
+
+	return user, nil
+}
+
+// ListUsers returns up to limit users ordered by ID, disabled users included
+// and anonymized users left out.
+func (u *User) ListUsers(ctx context.Context, limit, offset uint) ([]entity.User, error) {
+	rows, err := u.query(ctx, u.db, `SELECT id, uuid, name, email, phone, otp_channel, disabled_at `+
+		`FROM users WHERE anonymized_at IS NULL ORDER BY id LIMIT ? OFFSET ?;`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]entity.User, 0)
+	for rows.Next() {
+		var (
+			user                     entity.User
+			email, phone, otpChannel sql.NullString
+			disabledAt               sql.NullTime
+		)
+		if err := rows.Scan(&user.ID, &user.UUID, &user.Name, &email, &phone, &otpChannel,
+			&disabledAt); err != nil {
+			return nil, err
+		}
+
+		user.Email = email.String
+		user.Phone = phone.String
+		user.OTPChannel = otpChannel.String
+		user.Disabled = disabledAt.Valid
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// UpdateUser replaces the name and contact info of the user with the UUID of
+// user and returns the updated user. Anonymized users aren't found.
+func (u *User) UpdateUser(ctx context.Context, user entity.User) (entity.User, error) {
+	tx, err := u.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: false})
+	if err != nil {
+		return entity.User{}, err
+	}
+	defer tx.Rollback()
+
+	var disabledAt sql.NullTime
+	if err := u.queryRow(ctx, tx, `SELECT id, disabled_at FROM users WHERE uuid = ? AND anonymized_at IS NULL `+
+		`FOR UPDATE;`, user.UUID).Scan(&user.ID, &disabledAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.User{}, ErrNotFound
+		}
+
+		return entity.User{}, err
+	}
+
+	if _, err := u.exec(ctx, tx, `UPDATE users SET name = ?, email = ?, phone = ?, otp_channel = ? WHERE id = ?;`,
+		user.Name, nullString(user.Email), nullString(user.Phone), nullString(user.OTPChannel), user.ID); err != nil {
+		return entity.User{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return entity.User{}, err
+	}
+
+	user.Disabled = disabledAt.Valid
+
+	return user, nil
+}
+
+// DisableUser disables the user and revokes everything it could authenticate
+// with: its active OTPs and its refresh tokens. Disabling a disabled user does
+// nothing.
+func (u *User) DisableUser(ctx context.Context, uuid string) error {
+	tx, err := u.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: false})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var (
+		id         uint64
+		disabledAt sql.NullTime
+	)
+	if err := u.queryRow(ctx, tx, `SELECT id, disabled_at FROM users WHERE uuid = ? FOR UPDATE;`,
+		uuid).Scan(&id, &disabledAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+
+		return err
+	}
+
+	if disabledAt.Valid {
+		return nil
+	}
+
+	now := u.nowFunc()
+	if _, err := u.exec(ctx, tx, `UPDATE users SET disabled_at = ? WHERE id = ?;`,
+		now, id); err != nil {
+		return err
+	}
+
+	if _, err := u.exec(ctx, tx, `UPDATE otps SET status = ? WHERE user_id = ? AND status = ?;`,
+		otpStatusInvalidated, id, otpStatusUnused); err != nil {
+		return err
+	}
+
+	if _, err := u.exec(ctx, tx, `UPDATE refresh_tokens SET revoked_at = ? `+
+		`WHERE user_id = ? AND revoked_at IS NULL;`,
+		now, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AnonymizeUser deletes the user as far as its row can be: the row stays for
+// the OTPs and tokens referencing it, but the user is disabled the way
+// DisableUser does, its name and contact info are cleared and its TOTP
+// enrollment is deleted. Anonymized users are no longer found or listed.
+func (u *User) AnonymizeUser(ctx context.Context, uuid string) error {
+	tx, err := u.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: false})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id uint64
+	if err := u.queryRow(ctx, tx, `SELECT id FROM users WHERE uuid = ? AND anonymized_at IS NULL FOR UPDATE;`,
+		uuid).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+
+		return err
+	}
+
+	now := u.nowFunc()
+	if _, err := u.exec(ctx, tx, `UPDATE users SET name = '', email = NULL, phone = NULL, otp_channel = NULL, `+
+		`disabled_at = COALESCE(disabled_at, ?), anonymized_at = ? WHERE id = ?;`,
+		now, now, id); err != nil {
+		return err
+	}
+
+	if _, err := u.exec(ctx, tx, `UPDATE otps SET status = ? WHERE user_id = ? AND status = ?;`,
+		otpStatusInvalidated, id, otpStatusUnused); err != nil {
+		return err
+	}
+
+	if _, err := u.exec(ctx, tx, `UPDATE refresh_tokens SET revoked_at = ? `+
+		`WHERE user_id = ? AND revoked_at IS NULL;`,
+		now, id); err != nil {
+		return err
+	}
+
+	if _, err := u.exec(ctx, tx, `DELETE FROM user_totps WHERE user_id = ?;`, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// nullString stores empty optional columns as NULL, the way they're read back.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,1069 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/subroll/sqetest/internal/entity"
+)
+
+func TestUser_CreateUser(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx  context.Context
+		user entity.User
+	}
+
+	type expectation struct {
+		user entity.User
+		err  error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorStartTx",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin().
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:  context.TODO(),
+						user: entity.User{UUID: "fake-uuid", Name: "fake-name", Phone: "+15550000001"},
+					}, expectation{
+						user: entity.User{},
+						err:  errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorCheckingUUID",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id FROM users WHERE uuid = \? FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:  context.TODO(),
+						user: entity.User{UUID: "fake-uuid", Name: "fake-name", Phone: "+15550000001"},
+					}, expectation{
+						user: entity.User{},
+						err:  errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorUserExist",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id FROM users WHERE uuid = \? FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id"}).
+							AddRow(1))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:  context.TODO(),
+						user: entity.User{UUID: "fake-uuid", Name: "fake-name", Phone: "+15550000001"},
+					}, expectation{
+						user: entity.User{},
+						err:  ErrUserExist,
+					}
+			},
+		},
+		{
+			desc: "ErrorInserting",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id FROM users WHERE uuid = \? FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnError(sql.ErrNoRows)
+
+				mock.
+					ExpectExec(`INSERT INTO users \(uuid, name, email, phone, otp_channel\) VALUES \(\?, \?, \?, \?, \?\);`).
+					WithArgs("fake-uuid", "fake-name", nil, "+15550000001", nil).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:  context.TODO(),
+						user: entity.User{UUID: "fake-uuid", Name: "fake-name", Phone: "+15550000001"},
+					}, expectation{
+						user: entity.User{},
+						err:  errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorReadingID",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id FROM users WHERE uuid = \? FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnError(sql.ErrNoRows)
+
+				mock.
+					ExpectExec(`INSERT INTO users \(uuid, name, email, phone, otp_channel\) VALUES \(\?, \?, \?, \?, \?\);`).
+					WithArgs("fake-uuid", "fake-name", nil, "+15550000001", nil).
+					WillReturnResult(sqlmock.NewResult(4, 1))
+
+				mock.
+					ExpectQuery(`SELECT id FROM users WHERE uuid = \?;`).
+					WithArgs("fake-uuid").
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:  context.TODO(),
+						user: entity.User{UUID: "fake-uuid", Name: "fake-name", Phone: "+15550000001"},
+					}, expectation{
+						user: entity.User{},
+						err:  errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id FROM users WHERE uuid = \? FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnError(sql.ErrNoRows)
+
+				mock.
+					ExpectExec(`INSERT INTO users \(uuid, name, email, phone, otp_channel\) VALUES \(\?, \?, \?, \?, \?\);`).
+					WithArgs("fake-uuid", "fake-name", nil, "+15550000001", nil).
+					WillReturnResult(sqlmock.NewResult(4, 1))
+
+				mock.
+					ExpectQuery(`SELECT id FROM users WHERE uuid = \?;`).
+					WithArgs("fake-uuid").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id"}).
+							AddRow(4))
+
+				mock.
+					ExpectCommit()
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:  context.TODO(),
+						user: entity.User{UUID: "fake-uuid", Name: "fake-name", Phone: "+15550000001"},
+					}, expectation{
+						user: entity.User{ID: 4, UUID: "fake-uuid", Name: "fake-name", Phone: "+15550000001"},
+						err:  nil,
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			got, err := u.CreateUser(a.ctx, a.user)
+			assert.Equal(t, got, e.user)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
+
+func TestUser_GetUser(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx  context.Context
+		uuid string
+	}
+
+	type expectation struct {
+		user entity.User
+		err  error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT id, uuid, name, email, phone, otp_channel, disabled_at FROM users WHERE uuid = \? AND anonymized_at IS NULL;`).
+					WithArgs("fake-uuid").
+					WillReturnError(sql.ErrNoRows)
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{
+						user: entity.User{},
+						err:  ErrNotFound,
+					}
+			},
+		},
+		{
+			desc: "ErrorSQL",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT id, uuid, name, email, phone, otp_channel, disabled_at FROM users WHERE uuid = \? AND anonymized_at IS NULL;`).
+					WithArgs("fake-uuid").
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{
+						user: entity.User{},
+						err:  errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "SuccessDisabledUser",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT id, uuid, name, email, phone, otp_channel, disabled_at FROM users WHERE uuid = \? AND anonymized_at IS NULL;`).
+					WithArgs("fake-uuid").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "uuid", "name", "email", "phone", "otp_channel", "disabled_at"}).
+							AddRow(2, "fake-uuid", "fake-name", "fake@example.com", nil, "email", time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{
+						user: entity.User{ID: 2, UUID: "fake-uuid", Name: "fake-name", Email: "fake@example.com", OTPChannel: "email", Disabled: true},
+						err:  nil,
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			got, err := u.GetUser(a.ctx, a.uuid)
+			assert.Equal(t, got, e.user)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
+
+func TestUser_ListUsers(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx    context.Context
+		limit  uint
+		offset uint
+	}
+
+	type expectation struct {
+		users []entity.User
+		err   error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorSQL",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT id, uuid, name, email, phone, otp_channel, disabled_at FROM users WHERE anonymized_at IS NULL ORDER BY id LIMIT \? OFFSET \?;`).
+					WithArgs(uint(2), uint(1)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:    context.TODO(),
+						limit:  2,
+						offset: 1,
+					}, expectation{
+						users: nil,
+						err:   errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorRows",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT id, uuid, name, email, phone, otp_channel, disabled_at FROM users WHERE anonymized_at IS NULL ORDER BY id LIMIT \? OFFSET \?;`).
+					WithArgs(uint(2), uint(1)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "uuid", "name", "email", "phone", "otp_channel", "disabled_at"}).
+							AddRow(2, "fake-uuid", "fake-name", nil, nil, nil, nil).
+							RowError(0, errors.New("fake error")))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:    context.TODO(),
+						limit:  2,
+						offset: 1,
+					}, expectation{
+						users: nil,
+						err:   errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT id, uuid, name, email, phone, otp_channel, disabled_at FROM users WHERE anonymized_at IS NULL ORDER BY id LIMIT \? OFFSET \?;`).
+					WithArgs(uint(2), uint(1)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "uuid", "name", "email", "phone", "otp_channel", "disabled_at"}).
+							AddRow(2, "fake-uuid", "fake-name", "fake@example.com", nil, "email", nil).
+							AddRow(3, "fake-uuid-2", "fake-name-2", nil, "+15550000003", nil, time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:    context.TODO(),
+						limit:  2,
+						offset: 1,
+					}, expectation{
+						users: []entity.User{
+							{ID: 2, UUID: "fake-uuid", Name: "fake-name", Email: "fake@example.com", OTPChannel: "email"},
+							{ID: 3, UUID: "fake-uuid-2", Name: "fake-name-2", Phone: "+15550000003", Disabled: true},
+						},
+						err: nil,
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			got, err := u.ListUsers(a.ctx, a.limit, a.offset)
+			assert.Equal(t, got, e.users)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
+
+func TestUser_UpdateUser(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx  context.Context
+		user entity.User
+	}
+
+	type expectation struct {
+		user entity.User
+		err  error
+	}
+
+	testUser := entity.User{UUID: "fake-uuid", Name: "fake-name", Phone: "+15550000002", OTPChannel: "sms"}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorStartTx",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin().
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:  context.TODO(),
+						user: testUser,
+					}, expectation{
+						user: entity.User{},
+						err:  errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id, disabled_at FROM users WHERE uuid = \? AND anonymized_at IS NULL FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnError(sql.ErrNoRows)
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:  context.TODO(),
+						user: testUser,
+					}, expectation{
+						user: entity.User{},
+						err:  ErrNotFound,
+					}
+			},
+		},
+		{
+			desc: "ErrorUpdatingUser",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id, disabled_at FROM users WHERE uuid = \? AND anonymized_at IS NULL FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "disabled_at"}).
+							AddRow(2, nil))
+
+				mock.
+					ExpectExec(`UPDATE users SET name = \?, email = \?, phone = \?, otp_channel = \? WHERE id = \?;`).
+					WithArgs("fake-name", sql.NullString{}, sql.NullString{String: "+15550000002", Valid: true},
+						sql.NullString{String: "sms", Valid: true}, uint64(2)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:  context.TODO(),
+						user: testUser,
+					}, expectation{
+						user: entity.User{},
+						err:  errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id, disabled_at FROM users WHERE uuid = \? AND anonymized_at IS NULL FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "disabled_at"}).
+							AddRow(2, time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)))
+
+				mock.
+					ExpectExec(`UPDATE users SET name = \?, email = \?, phone = \?, otp_channel = \? WHERE id = \?;`).
+					WithArgs("fake-name", sql.NullString{}, sql.NullString{String: "+15550000002", Valid: true},
+						sql.NullString{String: "sms", Valid: true}, uint64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.
+					ExpectCommit()
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:  context.TODO(),
+						user: testUser,
+					}, expectation{
+						user: entity.User{ID: 2, UUID: "fake-uuid", Name: "fake-name", Phone: "+15550000002", OTPChannel: "sms", Disabled: true},
+						err:  nil,
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			got, err := u.UpdateUser(a.ctx, a.user)
+			assert.Equal(t, got, e.user)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
+
+func TestUser_DisableUser(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx  context.Context
+		uuid string
+	}
+
+	type expectation struct {
+		err error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorStartTx",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin().
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id, disabled_at FROM users WHERE uuid = \? FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnError(sql.ErrNoRows)
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{
+						err: ErrNotFound,
+					}
+			},
+		},
+		{
+			desc: "ErrorGettingUser",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id, disabled_at FROM users WHERE uuid = \? FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "SuccessAlreadyDisabled",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id, disabled_at FROM users WHERE uuid = \? FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "disabled_at"}).
+							AddRow(2, time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)))
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{}
+			},
+		},
+		{
+			desc: "ErrorDisablingUser",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id, disabled_at FROM users WHERE uuid = \? FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "disabled_at"}).
+							AddRow(2, nil))
+
+				mock.
+					ExpectExec(`UPDATE users SET disabled_at = \? WHERE id = \?;`).
+					WithArgs(time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), uint64(2)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorInvalidatingOTPs",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id, disabled_at FROM users WHERE uuid = \? FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "disabled_at"}).
+							AddRow(2, nil))
+
+				mock.
+					ExpectExec(`UPDATE users SET disabled_at = \? WHERE id = \?;`).
+					WithArgs(time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), uint64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.
+					ExpectExec(`UPDATE otps SET status = \? WHERE user_id = \? AND status = \?;`).
+					WithArgs(otpStatusInvalidated, uint64(2), otpStatusUnused).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorRevokingRefreshTokens",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id, disabled_at FROM users WHERE uuid = \? FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "disabled_at"}).
+							AddRow(2, nil))
+
+				mock.
+					ExpectExec(`UPDATE users SET disabled_at = \? WHERE id = \?;`).
+					WithArgs(time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), uint64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.
+					ExpectExec(`UPDATE otps SET status = \? WHERE user_id = \? AND status = \?;`).
+					WithArgs(otpStatusInvalidated, uint64(2), otpStatusUnused).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.
+					ExpectExec(`UPDATE refresh_tokens SET revoked_at = \? WHERE user_id = \? AND revoked_at IS NULL;`).
+					WithArgs(time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), uint64(2)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id, disabled_at FROM users WHERE uuid = \? FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "disabled_at"}).
+							AddRow(2, nil))
+
+				mock.
+					ExpectExec(`UPDATE users SET disabled_at = \? WHERE id = \?;`).
+					WithArgs(time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), uint64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.
+					ExpectExec(`UPDATE otps SET status = \? WHERE user_id = \? AND status = \?;`).
+					WithArgs(otpStatusInvalidated, uint64(2), otpStatusUnused).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.
+					ExpectExec(`UPDATE refresh_tokens SET revoked_at = \? WHERE user_id = \? AND revoked_at IS NULL;`).
+					WithArgs(time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), uint64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+
+				mock.
+					ExpectCommit()
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			err := u.DisableUser(a.ctx, a.uuid)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
+
+func TestUser_AnonymizeUser(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx  context.Context
+		uuid string
+	}
+
+	type expectation struct {
+		err error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorStartTx",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin().
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id FROM users WHERE uuid = \? AND anonymized_at IS NULL FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnError(sql.ErrNoRows)
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{
+						err: ErrNotFound,
+					}
+			},
+		},
+		{
+			desc: "ErrorAnonymizingUser",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id FROM users WHERE uuid = \? AND anonymized_at IS NULL FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id"}).
+							AddRow(2))
+
+				mock.
+					ExpectExec(`UPDATE users SET name = '', email = NULL, phone = NULL, otp_channel = NULL, disabled_at = COALESCE\(disabled_at, \?\), anonymized_at = \? WHERE id = \?;`).
+					WithArgs(time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), uint64(2)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorDeletingTOTP",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id FROM users WHERE uuid = \? AND anonymized_at IS NULL FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id"}).
+							AddRow(2))
+
+				mock.
+					ExpectExec(`UPDATE users SET name = '', email = NULL, phone = NULL, otp_channel = NULL, disabled_at = COALESCE\(disabled_at, \?\), anonymized_at = \? WHERE id = \?;`).
+					WithArgs(time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), uint64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.
+					ExpectExec(`UPDATE otps SET status = \? WHERE user_id = \? AND status = \?;`).
+					WithArgs(otpStatusInvalidated, uint64(2), otpStatusUnused).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.
+					ExpectExec(`UPDATE refresh_tokens SET revoked_at = \? WHERE user_id = \? AND revoked_at IS NULL;`).
+					WithArgs(time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), uint64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+
+				mock.
+					ExpectExec(`DELETE FROM user_totps WHERE user_id = \?;`).
+					WithArgs(uint64(2)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT id FROM users WHERE uuid = \? AND anonymized_at IS NULL FOR UPDATE;`).
+					WithArgs("fake-uuid").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id"}).
+							AddRow(2))
+
+				mock.
+					ExpectExec(`UPDATE users SET name = '', email = NULL, phone = NULL, otp_channel = NULL, disabled_at = COALESCE\(disabled_at, \?\), anonymized_at = \? WHERE id = \?;`).
+					WithArgs(time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), uint64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.
+					ExpectExec(`UPDATE otps SET status = \? WHERE user_id = \? AND status = \?;`).
+					WithArgs(otpStatusInvalidated, uint64(2), otpStatusUnused).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.
+					ExpectExec(`UPDATE refresh_tokens SET revoked_at = \? WHERE user_id = \? AND revoked_at IS NULL;`).
+					WithArgs(time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), uint64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+
+				mock.
+					ExpectExec(`DELETE FROM user_totps WHERE user_id = \?;`).
+					WithArgs(uint64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.
+					ExpectCommit()
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			err := u.AnonymizeUser(a.ctx, a.uuid)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
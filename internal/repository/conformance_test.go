@@ -0,0 +1,704 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/subroll/sqetest/internal/entity"
+	"github.com/subroll/sqetest/internal/migration"
+)
+
+const (
+	conformanceUserID   = 2
+	conformanceUserUUID = "ead5f356-ad40-4b71-bc1f-f015cf2dbf26"
+
+	conformanceLockoutBase = time.Minute
+	conformanceLockoutMax  = 4 * time.Minute
+)
+
+type (
+	// conformanceRepository is service.UserRepository, which can't be imported
+	// from here without an import cycle.
+	conformanceRepository interface {
+		GetUserIDByUUID(ctx context.Context, uuid string) (uint64, error)
+		GetUserByUUID(ctx context.Context, uuid string) (entity.User, error)
+		StoreOTP(ctx context.Context, otp entity.OTP) error
+		ResendOTP(ctx context.Context, otp entity.OTP) (entity.OTP, error)
+		UpdateOTPStatus(ctx context.Context, userID uint64, otp, purpose, requestID, binding string) error
+		StoreTOTPSecret(ctx context.Context, userID uint64, secret string) error
+		GetTOTP(ctx context.Context, userID uint64) (entity.TOTP, error)
+		ConfirmTOTP(ctx context.Context, userID, counter uint64) error
+		UseTOTPCounter(ctx context.Context, userID, counter uint64) error
+		FailTOTPAttempt(ctx context.Context, userID uint64, maxAttempts uint8) error
+		CheckLockout(ctx context.Context, userID uint64) error
+		StoreRefreshToken(ctx context.Context, token entity.RefreshToken) error
+		RotateRefreshToken(ctx context.Context, oldToken string, newToken entity.RefreshToken) (entity.RefreshToken,
+			error)
+		CreateUser(ctx context.Context, user entity.User) (entity.User, error)
+		ListUsers(ctx context.Context, limit, offset uint) ([]entity.User, error)
+		DisableUser(ctx context.Context, uuid string) error
+		GetUser(ctx context.Context, uuid string) (entity.User, error)
+		UpdateUser(ctx context.Context, user entity.User) (entity.User, error)
+		AnonymizeUser(ctx context.Context, uuid string) error
+		ListOTPs(ctx context.Context, userID uint64, limit uint) ([]entity.OTP, error)
+		RevokeOTP(ctx context.Context, userID uint64, purpose string) error
+		ExpireOTP(ctx context.Context, userID uint64, purpose string) error
+		ExpireOTPs(ctx context.Context, limit uint) (int64, error)
+		DeleteOTPs(ctx context.Context, before time.Time, limit uint) (int64, error)
+		ArchiveOTPs(ctx context.Context, before time.Time, limit uint) (int64, error)
+		AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	}
+
+	// conformanceClock is the time seen by a backend, scenarios move it forward
+	// instead of sleeping.
+	conformanceClock struct {
+		now time.Time
+	}
+
+	conformanceBackend struct {
+		name string
+		new  func(t *testing.T, deps Dependencies) conformanceRepository
+	}
+)
+
+func (c *conformanceClock) Now() time.Time {
+	return c.now
+}
+
+func (c *conformanceClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// conformanceBackends returns every backend the suite runs against, MySQL and
+// PostgreSQL only run when SQETEST_MYSQL_DSN or SQETEST_POSTGRES_DSN is set, the
+// MySQL DSN needs parseTime=true. Their databases are migrated down and up again
+// for every scenario, so don't point them at a database you care about.
+func conformanceBackends() []conformanceBackend {
+	backends := []conformanceBackend{
+		{
+			name: "Memory",
+			new: func(t *testing.T, deps Dependencies) conformanceRepository {
+				m := NewMemory(deps)
+				for _, user := range []entity.User{
+					{UUID: "ac304b86-1437-43bc-a7a9-239c262c2e17", Name: "Robert", Email: "robert@example.com",
+						Phone: "+15550000001"},
+					{UUID: conformanceUserUUID, Name: "Jhon", Email: "jhon@example.com", OTPChannel: "email"},
+					{UUID: "0ea89b50-828d-495f-a48a-3d1d97f8c3cd", Name: "George", Phone: "+15550000003",
+						OTPChannel: "sms"},
+				} {
+					_, err := m.CreateUser(context.Background(), user)
+					assert.NoError(t, err)
+				}
+
+				return m
+			},
+		},
+		{
+			name: "SQLite",
+			new: func(t *testing.T, deps Dependencies) conformanceRepository {
+				dsn := filepath.Join(t.TempDir(), "sqetest.db") + "?_txlock=immediate"
+
+				return newConformanceSQL(t, DialectSQLite, dsn, deps)
+			},
+		},
+	}
+
+	if dsn := os.Getenv("SQETEST_POSTGRES_DSN"); dsn != "" {
+		backends = append(backends, conformanceBackend{
+			name: "PostgreSQL",
+			new: func(t *testing.T, deps Dependencies) conformanceRepository {
+				return newConformanceSQL(t, DialectPostgreSQL, dsn, deps)
+			},
+		})
+	}
+
+	if dsn := os.Getenv("SQETEST_MYSQL_DSN"); dsn != "" {
+		backends = append(backends, conformanceBackend{
+			name: "MySQL",
+			new: func(t *testing.T, deps Dependencies) conformanceRepository {
+				return newConformanceSQL(t, DialectMySQL, dsn, deps)
+			},
+		})
+	}
+
+	return backends
+}
+
+func newConformanceSQL(t *testing.T, dialect Dialect, dsn string, deps Dependencies) conformanceRepository {
+	db, err := sql.Open(string(dialect), dsn)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	migrator, err := migration.New(db, string(dialect), deps.NowFunc)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	ctx := context.Background()
+	if _, err := migrator.To(ctx, 0); !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	if _, err := migrator.Up(ctx); !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	seed, err := os.ReadFile(filepath.Join("..", "..", "sql", "seed.sql"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	if _, err := db.Exec(string(seed)); !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	deps.DB = db
+	deps.Dialect = dialect
+
+	return NewUser(deps)
+}
+
+func TestConformance(t *testing.T) {
+	scenarios := []struct {
+		name string
+		run  func(t *testing.T, repo conformanceRepository, clock *conformanceClock)
+	}{
+		{name: "GetUser", run: conformanceGetUser},
+		{name: "StoreOTP", run: conformanceStoreOTP},
+		{name: "ResendOTP", run: conformanceResendOTP},
+		{name: "UpdateOTPStatus", run: conformanceUpdateOTPStatus},
+		{name: "OTPBinding", run: conformanceOTPBinding},
+		{name: "Lockout", run: conformanceLockout},
+		{name: "TOTP", run: conformanceTOTP},
+		{name: "RefreshToken", run: conformanceRefreshToken},
+		{name: "Account", run: conformanceAccount},
+		{name: "ManageUser", run: conformanceManageUser},
+		{name: "Support", run: conformanceSupport},
+		{name: "Sweeper", run: conformanceSweeper},
+		{name: "Lease", run: conformanceLease},
+	}
+
+	for _, backend := range conformanceBackends() {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			for _, scenario := range scenarios {
+				scenario := scenario
+				t.Run(scenario.name, func(t *testing.T) {
+					clock := &conformanceClock{now: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)}
+					repo := backend.new(t, Dependencies{
+						OTPHasher:           createOTPHasher(t),
+						NowFunc:             clock.Now,
+						LockoutBaseDuration: conformanceLockoutBase,
+						LockoutMaxDuration:  conformanceLockoutMax,
+					})
+
+					scenario.run(t, repo, clock)
+				})
+			}
+		})
+	}
+}
+
+func conformanceOTP(code string) entity.OTP {
+	return entity.OTP{
+		UserID:         conformanceUserID,
+		Code:           code,
+		Purpose:        "login",
+		RequestID:      "fake-request-id",
+		ChallengeID:    "fake-challenge-id",
+		TTL:            5 * time.Minute,
+		MaxAttempts:    3,
+		ResendCooldown: 30 * time.Second,
+		MaxResends:     1,
+	}
+}
+
+func conformanceGetUser(t *testing.T, repo conformanceRepository, _ *conformanceClock) {
+	ctx := context.Background()
+
+	id, err := repo.GetUserIDByUUID(ctx, conformanceUserUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, id, uint64(conformanceUserID))
+
+	_, err = repo.GetUserIDByUUID(ctx, "unknown-uuid")
+	assert.Equal(t, err, ErrNotFound)
+
+	user, err := repo.GetUserByUUID(ctx, conformanceUserUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, user, entity.User{
+		ID:         conformanceUserID,
+		UUID:       conformanceUserUUID,
+		Name:       "Jhon",
+		Email:      "jhon@example.com",
+		OTPChannel: "email",
+	})
+
+	_, err = repo.GetUserByUUID(ctx, "unknown-uuid")
+	assert.Equal(t, err, ErrNotFound)
+}
+
+func conformanceStoreOTP(t *testing.T, repo conformanceRepository, clock *conformanceClock) {
+	ctx := context.Background()
+
+	assert.NoError(t, repo.StoreOTP(ctx, conformanceOTP("11111")))
+	assert.Equal(t, repo.StoreOTP(ctx, conformanceOTP("22222")), ErrOTPExist)
+
+	otherPurpose := conformanceOTP("33333")
+	otherPurpose.Purpose = "transaction"
+	assert.NoError(t, repo.StoreOTP(ctx, otherPurpose))
+
+	clock.Advance(6 * time.Minute)
+	assert.NoError(t, repo.StoreOTP(ctx, conformanceOTP("44444")))
+	assert.Equal(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "11111", "login", "fake-challenge-id", ""),
+		ErrInvalidOTP)
+	assert.NoError(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "44444", "login", "fake-challenge-id", ""))
+}
+
+func conformanceResendOTP(t *testing.T, repo conformanceRepository, clock *conformanceClock) {
+	ctx := context.Background()
+
+	_, err := repo.ResendOTP(ctx, conformanceOTP("22222"))
+	assert.Equal(t, err, ErrOTPNotFound)
+
+	assert.NoError(t, repo.StoreOTP(ctx, conformanceOTP("11111")))
+
+	clock.Advance(10 * time.Second)
+	_, err = repo.ResendOTP(ctx, conformanceOTP("22222"))
+	assert.Equal(t, err, &CooldownError{RetryAfter: 20 * time.Second})
+
+	clock.Advance(20 * time.Second)
+	resend := conformanceOTP("22222")
+	resend.RequestID = "fake-resend-request-id"
+	resend.ChallengeID = "fake-resend-challenge-id"
+	got, err := repo.ResendOTP(ctx, resend)
+	assert.NoError(t, err)
+	assert.Equal(t, got.RequestID, "fake-request-id")
+	assert.Equal(t, got.ChallengeID, "fake-challenge-id")
+	assert.Equal(t, got.ResendCount, uint8(1))
+
+	clock.Advance(time.Minute)
+	_, err = repo.ResendOTP(ctx, conformanceOTP("33333"))
+	assert.Equal(t, err, ErrResendLimit)
+
+	// the resend restarted the expiry, so the code is still valid after the
+	// original five minutes
+	clock.Advance(4 * time.Minute)
+	assert.Equal(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "11111", "login", "fake-challenge-id", ""),
+		ErrInvalidOTP)
+	assert.NoError(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "22222", "login", "fake-challenge-id", ""))
+
+	assert.NoError(t, repo.StoreOTP(ctx, conformanceOTP("44444")))
+	clock.Advance(6 * time.Minute)
+	_, err = repo.ResendOTP(ctx, conformanceOTP("55555"))
+	assert.Equal(t, err, ErrOTPExpired)
+}
+
+func conformanceUpdateOTPStatus(t *testing.T, repo conformanceRepository, clock *conformanceClock) {
+	ctx := context.Background()
+
+	assert.Equal(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "11111", "login", "fake-challenge-id", ""),
+		ErrInvalidOTP)
+
+	assert.NoError(t, repo.StoreOTP(ctx, conformanceOTP("11111")))
+	assert.Equal(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "11111", "transaction", "fake-challenge-id", ""),
+		ErrInvalidOTP)
+	assert.Equal(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "22222", "login", "fake-challenge-id", ""),
+		ErrInvalidOTP)
+	assert.NoError(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "11111", "login", "fake-challenge-id", ""))
+	assert.Equal(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "11111", "login", "fake-challenge-id", ""),
+		ErrInvalidOTP)
+
+	assert.NoError(t, repo.StoreOTP(ctx, conformanceOTP("33333")))
+	clock.Advance(6 * time.Minute)
+	assert.Equal(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "33333", "login", "fake-challenge-id", ""),
+		ErrOTPExpired)
+}
+
+func conformanceOTPBinding(t *testing.T, repo conformanceRepository, _ *conformanceClock) {
+	ctx := context.Background()
+
+	otp := conformanceOTP("11111")
+	otp.Binding = "fake-binding"
+	assert.NoError(t, repo.StoreOTP(ctx, otp))
+
+	// mismatches are told apart from a wrong code and don't count as attempts
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "11111", "login", "other-challenge-id",
+			"fake-binding"), ErrChallengeMismatch)
+		assert.Equal(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "11111", "login", "fake-challenge-id",
+			"other-binding"), ErrBindingMismatch)
+	}
+	assert.NoError(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "11111", "login", "fake-challenge-id",
+		"fake-binding"))
+
+	// an OTP stored without a binding can be validated by any client
+	otp = conformanceOTP("22222")
+	otp.Purpose = "transaction"
+	assert.NoError(t, repo.StoreOTP(ctx, otp))
+	assert.NoError(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "22222", "transaction", "fake-challenge-id",
+		"fake-binding"))
+}
+
+func conformanceLockout(t *testing.T, repo conformanceRepository, clock *conformanceClock) {
+	ctx := context.Background()
+
+	lockOut := func(window time.Duration) {
+		assert.NoError(t, repo.StoreOTP(ctx, conformanceOTP("11111")))
+		for i := 0; i < 2; i++ {
+			assert.Equal(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "00000", "login", "fake-challenge-id", ""),
+				ErrInvalidOTP)
+		}
+		assert.Equal(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "00000", "login", "fake-challenge-id", ""),
+			&LockedError{Err: ErrTooManyAttempts, RetryAfter: window})
+	}
+
+	lockOut(conformanceLockoutBase)
+
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, repo.StoreOTP(ctx, conformanceOTP("22222")),
+		&LockedError{Err: ErrUserLocked, RetryAfter: 30 * time.Second})
+	_, err := repo.ResendOTP(ctx, conformanceOTP("22222"))
+	assert.Equal(t, err, &LockedError{Err: ErrUserLocked, RetryAfter: 30 * time.Second})
+	assert.Equal(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "11111", "login", "fake-challenge-id", ""),
+		&LockedError{Err: ErrUserLocked, RetryAfter: 30 * time.Second})
+
+	clock.Advance(30 * time.Second)
+	lockOut(2 * conformanceLockoutBase)
+
+	clock.Advance(2 * conformanceLockoutBase)
+	lockOut(4 * conformanceLockoutBase)
+
+	clock.Advance(4 * conformanceLockoutBase)
+	lockOut(conformanceLockoutMax)
+
+	// a successful validation resets the lockout window
+	clock.Advance(conformanceLockoutMax)
+	assert.NoError(t, repo.StoreOTP(ctx, conformanceOTP("11111")))
+	assert.NoError(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "11111", "login", "fake-challenge-id", ""))
+	lockOut(conformanceLockoutBase)
+}
+
+func conformanceTOTP(t *testing.T, repo conformanceRepository, clock *conformanceClock) {
+	ctx := context.Background()
+
+	_, err := repo.GetTOTP(ctx, conformanceUserID)
+	assert.Equal(t, err, ErrTOTPNotFound)
+	assert.Equal(t, repo.ConfirmTOTP(ctx, conformanceUserID, 10), ErrTOTPNotFound)
+
+	assert.NoError(t, repo.StoreTOTPSecret(ctx, conformanceUserID, "fake-secret"))
+	assert.NoError(t, repo.StoreTOTPSecret(ctx, conformanceUserID, "fake-new-secret"))
+
+	got, err := repo.GetTOTP(ctx, conformanceUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, got, entity.TOTP{UserID: conformanceUserID, Secret: "fake-new-secret"})
+	assert.Equal(t, repo.UseTOTPCounter(ctx, conformanceUserID, 10), ErrOTPReplayed)
+
+	assert.NoError(t, repo.ConfirmTOTP(ctx, conformanceUserID, 10))
+	assert.Equal(t, repo.ConfirmTOTP(ctx, conformanceUserID, 11), ErrTOTPNotFound)
+	assert.Equal(t, repo.StoreTOTPSecret(ctx, conformanceUserID, "fake-secret"), ErrTOTPEnrolled)
+
+	assert.Equal(t, repo.UseTOTPCounter(ctx, conformanceUserID, 10), ErrOTPReplayed)
+	assert.NoError(t, repo.UseTOTPCounter(ctx, conformanceUserID, 12))
+	assert.Equal(t, repo.UseTOTPCounter(ctx, conformanceUserID, 11), ErrOTPReplayed)
+
+	got, err = repo.GetTOTP(ctx, conformanceUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, got, entity.TOTP{UserID: conformanceUserID, Secret: "fake-new-secret", Confirmed: true,
+		LastCounter: 12})
+
+	// invalid codes lock the user out like invalid OTPs, a valid code starts
+	// the count over
+	assert.Equal(t, repo.FailTOTPAttempt(ctx, conformanceUserID, 2), ErrInvalidOTP)
+	assert.NoError(t, repo.UseTOTPCounter(ctx, conformanceUserID, 13))
+	assert.Equal(t, repo.FailTOTPAttempt(ctx, conformanceUserID, 2), ErrInvalidOTP)
+	assert.Equal(t, repo.FailTOTPAttempt(ctx, conformanceUserID, 2),
+		&LockedError{Err: ErrTooManyAttempts, RetryAfter: conformanceLockoutBase})
+	assert.Equal(t, repo.CheckLockout(ctx, conformanceUserID),
+		&LockedError{Err: ErrUserLocked, RetryAfter: conformanceLockoutBase})
+	assert.Equal(t, repo.FailTOTPAttempt(ctx, conformanceUserID, 2),
+		&LockedError{Err: ErrUserLocked, RetryAfter: conformanceLockoutBase})
+
+	clock.Advance(conformanceLockoutBase)
+	assert.NoError(t, repo.CheckLockout(ctx, conformanceUserID))
+	assert.Equal(t, repo.FailTOTPAttempt(ctx, conformanceUserID, 2), ErrInvalidOTP)
+	assert.Equal(t, repo.FailTOTPAttempt(ctx, conformanceUserID, 2),
+		&LockedError{Err: ErrTooManyAttempts, RetryAfter: 2 * conformanceLockoutBase})
+}
+
+func conformanceRefreshToken(t *testing.T, repo conformanceRepository, clock *conformanceClock) {
+	ctx := context.Background()
+
+	newToken := func(token string, ttl time.Duration) entity.RefreshToken {
+		return entity.RefreshToken{UserID: conformanceUserID, Token: token, ExpiresAt: clock.Now().Add(ttl)}
+	}
+
+	_, err := repo.RotateRefreshToken(ctx, "fake-unknown-token", newToken("fake-token-b", time.Hour))
+	assert.Equal(t, err, ErrRefreshTokenNotFound)
+
+	first := newToken("fake-token-a", time.Hour)
+	assert.NoError(t, repo.StoreRefreshToken(ctx, first))
+
+	got, err := repo.RotateRefreshToken(ctx, "fake-token-a", newToken("fake-token-b", time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, got.UserID, uint64(conformanceUserID))
+	assert.Equal(t, got.UserUUID, conformanceUserUUID)
+	assert.True(t, got.ExpiresAt.Equal(first.ExpiresAt))
+
+	_, err = repo.RotateRefreshToken(ctx, "fake-token-a", newToken("fake-token-c", time.Hour))
+	assert.True(t, errors.Is(err, ErrRefreshTokenReused))
+
+	// reusing a revoked token revoked every other token of the user
+	_, err = repo.RotateRefreshToken(ctx, "fake-token-b", newToken("fake-token-d", time.Hour))
+	assert.True(t, errors.Is(err, ErrRefreshTokenReused))
+
+	assert.NoError(t, repo.StoreRefreshToken(ctx, newToken("fake-token-e", time.Minute)))
+	clock.Advance(2 * time.Minute)
+	_, err = repo.RotateRefreshToken(ctx, "fake-token-e", newToken("fake-token-f", time.Hour))
+	assert.Equal(t, err, ErrRefreshTokenExpired)
+}
+
+func conformanceAccount(t *testing.T, repo conformanceRepository, clock *conformanceClock) {
+	ctx := context.Background()
+
+	user := entity.User{UUID: "fake-new-uuid", Name: "Jane", Phone: "+15550000004", OTPChannel: "sms"}
+	created, err := repo.CreateUser(ctx, user)
+	assert.NoError(t, err)
+	user.ID = 4
+	assert.Equal(t, created, user)
+
+	_, err = repo.CreateUser(ctx, user)
+	assert.Equal(t, err, ErrUserExist)
+
+	got, err := repo.GetUserByUUID(ctx, "fake-new-uuid")
+	assert.NoError(t, err)
+	assert.Equal(t, got, user)
+
+	users, err := repo.ListUsers(ctx, 2, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, users, 2) {
+		assert.Equal(t, users[0].UUID, conformanceUserUUID)
+		assert.Equal(t, users[1].ID, uint64(3))
+	}
+
+	users, err = repo.ListUsers(ctx, 10, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, users)
+
+	assert.NoError(t, repo.StoreOTP(ctx, conformanceOTP("11111")))
+	assert.NoError(t, repo.StoreRefreshToken(ctx, entity.RefreshToken{UserID: conformanceUserID,
+		Token: "fake-token-a", ExpiresAt: clock.Now().Add(time.Hour)}))
+
+	assert.NoError(t, repo.DisableUser(ctx, conformanceUserUUID))
+	assert.NoError(t, repo.DisableUser(ctx, conformanceUserUUID))
+	assert.Equal(t, repo.DisableUser(ctx, "unknown-uuid"), ErrNotFound)
+
+	_, err = repo.GetUserIDByUUID(ctx, conformanceUserUUID)
+	assert.Equal(t, err, ErrNotFound)
+	_, err = repo.GetUserByUUID(ctx, conformanceUserUUID)
+	assert.Equal(t, err, ErrNotFound)
+
+	// disabling revoked everything the user could authenticate with
+	assert.Equal(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "11111", "login", "fake-challenge-id", ""),
+		ErrInvalidOTP)
+	_, err = repo.RotateRefreshToken(ctx, "fake-token-a", entity.RefreshToken{Token: "fake-token-b",
+		ExpiresAt: clock.Now().Add(time.Hour)})
+	assert.True(t, errors.Is(err, ErrRefreshTokenReused))
+
+	users, err = repo.ListUsers(ctx, 10, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, users, 4) {
+		assert.False(t, users[0].Disabled)
+		assert.True(t, users[1].Disabled)
+	}
+}
+
+func conformanceManageUser(t *testing.T, repo conformanceRepository, clock *conformanceClock) {
+	ctx := context.Background()
+
+	user, err := repo.GetUser(ctx, conformanceUserUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, uint64(conformanceUserID))
+
+	user.Name = "John"
+	user.Email = ""
+	user.Phone = "+15550000002"
+	user.OTPChannel = "sms"
+	updated, err := repo.UpdateUser(ctx, user)
+	assert.NoError(t, err)
+	assert.Equal(t, updated, user)
+
+	got, err := repo.GetUserByUUID(ctx, conformanceUserUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, got, user)
+
+	_, err = repo.UpdateUser(ctx, entity.User{UUID: "unknown-uuid", Name: "John"})
+	assert.Equal(t, err, ErrNotFound)
+	_, err = repo.GetUser(ctx, "unknown-uuid")
+	assert.Equal(t, err, ErrNotFound)
+
+	// disabled users are still managed
+	assert.NoError(t, repo.DisableUser(ctx, "0ea89b50-828d-495f-a48a-3d1d97f8c3cd"))
+	got, err = repo.GetUser(ctx, "0ea89b50-828d-495f-a48a-3d1d97f8c3cd")
+	assert.NoError(t, err)
+	assert.True(t, got.Disabled)
+
+	assert.NoError(t, repo.StoreOTP(ctx, conformanceOTP("11111")))
+	assert.NoError(t, repo.StoreRefreshToken(ctx, entity.RefreshToken{UserID: conformanceUserID,
+		Token: "fake-token-a", ExpiresAt: clock.Now().Add(time.Hour)}))
+	assert.NoError(t, repo.StoreTOTPSecret(ctx, conformanceUserID, "fake-secret"))
+
+	assert.NoError(t, repo.AnonymizeUser(ctx, conformanceUserUUID))
+	assert.Equal(t, repo.AnonymizeUser(ctx, conformanceUserUUID), ErrNotFound)
+	assert.Equal(t, repo.AnonymizeUser(ctx, "unknown-uuid"), ErrNotFound)
+
+	_, err = repo.GetUser(ctx, conformanceUserUUID)
+	assert.Equal(t, err, ErrNotFound)
+	_, err = repo.GetUserByUUID(ctx, conformanceUserUUID)
+	assert.Equal(t, err, ErrNotFound)
+	_, err = repo.UpdateUser(ctx, user)
+	assert.Equal(t, err, ErrNotFound)
+
+	// anonymizing revoked everything the user could authenticate with
+	assert.Equal(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "11111", "login", "fake-challenge-id", ""),
+		ErrInvalidOTP)
+	_, err = repo.RotateRefreshToken(ctx, "fake-token-a", entity.RefreshToken{Token: "fake-token-b",
+		ExpiresAt: clock.Now().Add(time.Hour)})
+	assert.True(t, errors.Is(err, ErrRefreshTokenReused))
+	_, err = repo.GetTOTP(ctx, conformanceUserID)
+	assert.Equal(t, err, ErrTOTPNotFound)
+
+	users, err := repo.ListUsers(ctx, 10, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, users, 2) {
+		assert.Equal(t, users[0].ID, uint64(1))
+		assert.Equal(t, users[1].ID, uint64(3))
+	}
+}
+
+func conformanceSupport(t *testing.T, repo conformanceRepository, clock *conformanceClock) {
+	ctx := context.Background()
+
+	otps, err := repo.ListOTPs(ctx, conformanceUserID, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, otps)
+
+	assert.NoError(t, repo.StoreOTP(ctx, conformanceOTP("11111")))
+	transaction := conformanceOTP("22222")
+	transaction.Purpose = "transaction"
+	transaction.RequestID = "fake-transaction-request-id"
+	assert.NoError(t, repo.StoreOTP(ctx, transaction))
+	assert.Equal(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "00000", "login", "fake-challenge-id", ""),
+		ErrInvalidOTP)
+
+	otps, err = repo.ListOTPs(ctx, conformanceUserID, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, otps, 2) {
+		assert.Equal(t, otps[0].Purpose, "transaction")
+		assert.Equal(t, otps[0].RequestID, "fake-transaction-request-id")
+		assert.Equal(t, otps[1].Status, entity.OTPStatusActive)
+		assert.Equal(t, otps[1].Attempts, uint8(1))
+		assert.Equal(t, otps[1].MaxAttempts, uint8(3))
+		assert.True(t, otps[1].ExpiredAt.Equal(clock.Now().Add(5*time.Minute)))
+		assert.Empty(t, otps[1].Code)
+	}
+
+	assert.NoError(t, repo.RevokeOTP(ctx, conformanceUserID, "login"))
+	assert.Equal(t, repo.RevokeOTP(ctx, conformanceUserID, "login"), ErrOTPNotFound)
+	assert.NoError(t, repo.ExpireOTP(ctx, conformanceUserID, "transaction"))
+	assert.Equal(t, repo.ExpireOTP(ctx, conformanceUserID, "transaction"), ErrOTPNotFound)
+
+	otps, err = repo.ListOTPs(ctx, conformanceUserID, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, otps, 1) {
+		assert.Equal(t, otps[0].Status, entity.OTPStatusExpired)
+	}
+
+	assert.Equal(t, repo.UpdateOTPStatus(ctx, conformanceUserID, "11111", "login", "fake-challenge-id", ""),
+		ErrInvalidOTP)
+	assert.NoError(t, repo.StoreOTP(ctx, conformanceOTP("33333")))
+	assert.NoError(t, repo.StoreOTP(ctx, transaction))
+}
+
+func conformanceSweeper(t *testing.T, repo conformanceRepository, clock *conformanceClock) {
+	ctx := context.Background()
+
+	assert.NoError(t, repo.StoreOTP(ctx, conformanceOTP("11111")))
+	transaction := conformanceOTP("22222")
+	transaction.Purpose = "transaction"
+	assert.NoError(t, repo.StoreOTP(ctx, transaction))
+
+	n, err := repo.ExpireOTPs(ctx, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, n, int64(0))
+
+	clock.Advance(6 * time.Minute)
+	n, err = repo.ExpireOTPs(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, n, int64(1))
+	n, err = repo.ExpireOTPs(ctx, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, n, int64(1))
+
+	otps, err := repo.ListOTPs(ctx, conformanceUserID, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, otps, 2) {
+		assert.Equal(t, otps[0].Status, entity.OTPStatusExpired)
+		assert.Equal(t, otps[1].Status, entity.OTPStatusExpired)
+	}
+
+	n, err = repo.DeleteOTPs(ctx, clock.Now().Add(-2*time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, n, int64(0))
+
+	n, err = repo.ArchiveOTPs(ctx, clock.Now(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, n, int64(1))
+	n, err = repo.DeleteOTPs(ctx, clock.Now(), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, n, int64(1))
+
+	otps, err = repo.ListOTPs(ctx, conformanceUserID, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, otps)
+
+	assert.NoError(t, repo.StoreOTP(ctx, conformanceOTP("33333")))
+}
+
+func conformanceLease(t *testing.T, repo conformanceRepository, clock *conformanceClock) {
+	ctx := context.Background()
+
+	ok, err := repo.AcquireLease(ctx, "sweeper", "replica-a", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.AcquireLease(ctx, "sweeper", "replica-b", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	clock.Advance(30 * time.Second)
+	ok, err = repo.AcquireLease(ctx, "sweeper", "replica-a", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	clock.Advance(61 * time.Second)
+	ok, err = repo.AcquireLease(ctx, "sweeper", "replica-b", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.AcquireLease(ctx, "sweeper", "replica-a", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
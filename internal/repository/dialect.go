@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect adapts the queries of User, which are written for MySQL, to the SQL
+// database behind it. The zero value is MySQL.
+type Dialect string
+
+const (
+	DialectMySQL      Dialect = "mysql"
+	DialectPostgreSQL Dialect = "postgres"
+	DialectSQLite     Dialect = "sqlite3"
+)
+
+func ParseDialect(driver string) (Dialect, error) {
+	switch d := Dialect(driver); d {
+	case DialectMySQL, DialectPostgreSQL, DialectSQLite:
+		return d, nil
+	default:
+		return "", fmt.Errorf("unsupported sql dialect: %s", driver)
+	}
+}
+
+// rebind rewrites a MySQL query for the dialect: PostgreSQL uses numbered
+// placeholders and SQLite has no row locks, its transactions lock the whole
+// database instead.
+func (d Dialect) rebind(query string) string {
+	switch d {
+	case DialectPostgreSQL:
+		var (
+			b strings.Builder
+			n int
+		)
+		for _, r := range query {
+			if r != '?' {
+				b.WriteRune(r)
+
+				continue
+			}
+
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+		}
+
+		return b.String()
+	case DialectSQLite:
+		return strings.Replace(query, " FOR UPDATE", "", 1)
+	default:
+		return query
+	}
+}
+
+// upsert returns the clause that turns an INSERT into an update of columns
+// when a row with the same key column already exists.
+func (d Dialect) upsert(key string, columns ...string) string {
+	assignments := make([]string, 0, len(columns))
+	switch d {
+	case DialectPostgreSQL, DialectSQLite:
+		for _, c := range columns {
+			assignments = append(assignments, c+" = excluded."+c)
+		}
+
+		return "ON CONFLICT (" + key + ") DO UPDATE SET " + strings.Join(assignments, ", ")
+	default:
+		for _, c := range columns {
+			assignments = append(assignments, c+" = VALUES("+c+")")
+		}
+
+		return "ON DUPLICATE KEY UPDATE " + strings.Join(assignments, ", ")
+	}
+}
+
+// ignoreConflict returns the clause that turns an INSERT into a no-op when a
+// row with the same key column already exists.
+func (d Dialect) ignoreConflict(key string) string {
+	switch d {
+	case DialectPostgreSQL, DialectSQLite:
+		return "ON CONFLICT (" + key + ") DO NOTHING"
+	default:
+		return "ON DUPLICATE KEY UPDATE " + key + " = " + key
+	}
+}
+
+func (u *User) rebind(query string) string {
+	return u.dialect.rebind(query)
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialect_rebind(t *testing.T) {
+	t.Parallel()
+
+	query := `SELECT id FROM otps WHERE user_id = ? AND status = ? FOR UPDATE;`
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		expected string
+	}{
+		{
+			name:     "MySQL",
+			dialect:  DialectMySQL,
+			expected: query,
+		},
+		{
+			name:     "ZeroValue",
+			expected: query,
+		},
+		{
+			name:     "PostgreSQL",
+			dialect:  DialectPostgreSQL,
+			expected: `SELECT id FROM otps WHERE user_id = $1 AND status = $2 FOR UPDATE;`,
+		},
+		{
+			name:     "SQLite",
+			dialect:  DialectSQLite,
+			expected: `SELECT id FROM otps WHERE user_id = ? AND status = ?;`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.dialect.rebind(query), test.expected)
+		})
+	}
+}
+
+func TestDialect_upsert(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		expected string
+	}{
+		{
+			name:     "MySQL",
+			dialect:  DialectMySQL,
+			expected: `ON DUPLICATE KEY UPDATE secret = VALUES(secret), last_counter = VALUES(last_counter)`,
+		},
+		{
+			name:     "PostgreSQL",
+			dialect:  DialectPostgreSQL,
+			expected: `ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, last_counter = excluded.last_counter`,
+		},
+		{
+			name:     "SQLite",
+			dialect:  DialectSQLite,
+			expected: `ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, last_counter = excluded.last_counter`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.dialect.upsert("user_id", "secret", "last_counter"), test.expected)
+		})
+	}
+}
+
+func TestDialect_ignoreConflict(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		expected string
+	}{
+		{
+			name:     "MySQL",
+			dialect:  DialectMySQL,
+			expected: `ON DUPLICATE KEY UPDATE name = name`,
+		},
+		{
+			name:     "PostgreSQL",
+			dialect:  DialectPostgreSQL,
+			expected: `ON CONFLICT (name) DO NOTHING`,
+		},
+		{
+			name:     "SQLite",
+			dialect:  DialectSQLite,
+			expected: `ON CONFLICT (name) DO NOTHING`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.dialect.ignoreConflict("name"), test.expected)
+		})
+	}
+}
+
+func TestParseDialect(t *testing.T) {
+	t.Parallel()
+
+	for _, driver := range []string{"mysql", "postgres", "sqlite3"} {
+		got, err := ParseDialect(driver)
+		assert.NoError(t, err)
+		assert.Equal(t, got, Dialect(driver))
+	}
+
+	_, err := ParseDialect("oracle")
+	assert.Error(t, err)
+}
@@ -0,0 +1,660 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/subroll/sqetest/internal/entity"
+	"github.com/subroll/sqetest/internal/pkg/otphash"
+)
+
+type (
+	// Memory keeps everything User stores in SQL in process memory, it behaves
+	// the same as User and is meant for local development and tests.
+	Memory struct {
+		mu        sync.Mutex
+		otpHasher *otphash.Hasher
+		nowFunc   func() time.Time
+
+		lockoutBase time.Duration
+		lockoutMax  time.Duration
+
+		users         map[string]entity.User
+		anonymized    map[string]bool
+		nextUserID    uint64
+		otps          []*memoryOTP
+		lockouts      map[uint64]*memoryLockout
+		totps         map[uint64]*memoryTOTP
+		refreshTokens map[string]*memoryRefreshToken
+		archivedOTPs  []*memoryOTP
+		leases        map[string]memoryLease
+	}
+
+	memoryOTP struct {
+		id          uint64
+		userID      uint64
+		digest      string
+		keyID       string
+		purpose     string
+		requestID   string
+		challengeID string
+		binding     string
+		status      int
+		attempts    uint8
+		maxAttempts uint8
+		resendCount uint8
+		lastSentAt  time.Time
+		expiredAt   time.Time
+	}
+
+	memoryLockout struct {
+		lockouts    uint
+		lockedUntil time.Time
+	}
+
+	memoryTOTP struct {
+		secret      string
+		confirmed   bool
+		lastCounter uint64
+		attempts    uint8
+	}
+
+	memoryRefreshToken struct {
+		userID    uint64
+		expiresAt time.Time
+		revoked   bool
+	}
+
+	memoryLease struct {
+		holder    string
+		expiresAt time.Time
+	}
+)
+
+func NewMemory(deps Dependencies) *Memory {
+	return &Memory{
+		otpHasher:     deps.OTPHasher,
+		nowFunc:       deps.NowFunc,
+		lockoutBase:   deps.LockoutBaseDuration,
+		lockoutMax:    deps.LockoutMaxDuration,
+		users:         make(map[string]entity.User),
+		anonymized:    make(map[string]bool),
+		lockouts:      make(map[uint64]*memoryLockout),
+		totps:         make(map[uint64]*memoryTOTP),
+		refreshTokens: make(map[string]*memoryRefreshToken),
+		leases:        make(map[string]memoryLease),
+	}
+}
+
+func (m *Memory) GetUserIDByUUID(_ context.Context, uuid string) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[uuid]
+	if !ok || user.Disabled {
+		return 0, ErrNotFound
+	}
+
+	return user.ID, nil
+}
+
+func (m *Memory) GetUserByUUID(_ context.Context, uuid string) (entity.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[uuid]
+	if !ok || user.Disabled {
+		return entity.User{}, ErrNotFound
+	}
+
+	return user, nil
+}
+
+func (m *Memory) CreateUser(_ context.Context, user entity.User) (entity.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[user.UUID]; ok {
+		return entity.User{}, ErrUserExist
+	}
+
+	m.nextUserID++
+	user.ID = m.nextUserID
+	user.Disabled = false
+	m.users[user.UUID] = user
+
+	return user, nil
+}
+
+func (m *Memory) GetUser(_ context.Context, uuid string) (entity.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[uuid]
+	if !ok || m.anonymized[uuid] {
+		return entity.User{}, ErrNotFound
+	}
+
+	return user, nil
+}
+
+func (m *Memory) ListUsers(_ context.Context, limit, offset uint) ([]entity.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	users := make([]entity.User, 0, len(m.users))
+	for uuid, user := range m.users {
+		if !m.anonymized[uuid] {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(a, b int) bool {
+		return users[a].ID < users[b].ID
+	})
+
+	if offset >= uint(len(users)) {
+		return []entity.User{}, nil
+	}
+	users = users[offset:]
+	if limit < uint(len(users)) {
+		users = users[:limit]
+	}
+
+	return users, nil
+}
+
+func (m *Memory) UpdateUser(_ context.Context, user entity.User) (entity.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.users[user.UUID]
+	if !ok || m.anonymized[user.UUID] {
+		return entity.User{}, ErrNotFound
+	}
+
+	stored.Name = user.Name
+	stored.Email = user.Email
+	stored.Phone = user.Phone
+	stored.OTPChannel = user.OTPChannel
+	m.users[user.UUID] = stored
+
+	return stored, nil
+}
+
+func (m *Memory) DisableUser(_ context.Context, uuid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[uuid]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if !user.Disabled {
+		m.disableUser(user)
+	}
+
+	return nil
+}
+
+func (m *Memory) AnonymizeUser(_ context.Context, uuid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[uuid]
+	if !ok || m.anonymized[uuid] {
+		return ErrNotFound
+	}
+
+	m.disableUser(user)
+	m.users[uuid] = entity.User{ID: user.ID, UUID: user.UUID, Disabled: true}
+	m.anonymized[uuid] = true
+	delete(m.totps, user.ID)
+
+	return nil
+}
+
+// disableUser disables user and revokes its active OTPs and refresh tokens.
+func (m *Memory) disableUser(user entity.User) {
+	user.Disabled = true
+	m.users[user.UUID] = user
+
+	for _, otp := range m.otps {
+		if otp.userID == user.ID && otp.status == otpStatusUnused {
+			otp.status = otpStatusInvalidated
+		}
+	}
+
+	for _, token := range m.refreshTokens {
+		if token.userID == user.ID {
+			token.revoked = true
+		}
+	}
+}
+
+func (m *Memory) StoreOTP(_ context.Context, otp entity.OTP) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.checkLockout(otp.UserID); err != nil {
+		return err
+	}
+
+	now := m.nowFunc()
+	if active := m.activeOTP(otp.UserID, otp.Purpose); active != nil {
+		if active.expiredAt.After(now) {
+			return ErrOTPExist
+		}
+
+		active.status = otpStatusExpired
+	}
+
+	keyID, digest := m.otpHasher.Sum(otp.Code)
+	m.otps = append(m.otps, &memoryOTP{
+		id:          uint64(len(m.otps) + 1),
+		userID:      otp.UserID,
+		digest:      digest,
+		keyID:       keyID,
+		purpose:     otp.Purpose,
+		requestID:   otp.RequestID,
+		challengeID: otp.ChallengeID,
+		binding:     otp.Binding,
+		maxAttempts: otp.MaxAttempts,
+		lastSentAt:  now,
+		expiredAt:   now.Add(otp.TTL),
+	})
+
+	return nil
+}
+
+func (m *Memory) ResendOTP(_ context.Context, otp entity.OTP) (entity.OTP, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.checkLockout(otp.UserID); err != nil {
+		return entity.OTP{}, err
+	}
+
+	active := m.activeOTP(otp.UserID, otp.Purpose)
+	if active == nil {
+		return entity.OTP{}, ErrOTPNotFound
+	}
+
+	now := m.nowFunc()
+	if active.expiredAt.Before(now) {
+		return entity.OTP{}, ErrOTPExpired
+	}
+
+	if active.resendCount >= otp.MaxResends {
+		return entity.OTP{}, ErrResendLimit
+	}
+
+	if next := active.lastSentAt.Add(otp.ResendCooldown); next.After(now) {
+		return entity.OTP{}, &CooldownError{RetryAfter: next.Sub(now)}
+	}
+
+	active.keyID, active.digest = m.otpHasher.Sum(otp.Code)
+	active.resendCount++
+	active.lastSentAt = now
+	active.expiredAt = now.Add(otp.TTL)
+
+	otp.RequestID = active.requestID
+	otp.ChallengeID = active.challengeID
+	otp.ResendCount = active.resendCount
+
+	return otp, nil
+}
+
+func (m *Memory) UpdateOTPStatus(_ context.Context, userID uint64, otp, purpose, challengeID,
+	binding string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lockouts, err := m.checkLockout(userID)
+	if err != nil {
+		return err
+	}
+
+	active := m.activeOTP(userID, purpose)
+	if active == nil {
+		return ErrInvalidOTP
+	}
+
+	if err := checkOTPIssuance(active.challengeID, active.binding, challengeID, binding); err != nil {
+		return err
+	}
+
+	if !m.otpHasher.Equal(otp, active.keyID, active.digest) {
+		return m.failOTPAttempt(userID, active, lockouts)
+	}
+
+	if active.expiredAt.Before(m.nowFunc()) {
+		return ErrOTPExpired
+	}
+
+	active.status = otpStatusUsed
+	delete(m.lockouts, userID)
+
+	return nil
+}
+
+func (m *Memory) StoreTOTPSecret(_ context.Context, userID uint64, secret string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if totp, ok := m.totps[userID]; ok && totp.confirmed {
+		return ErrTOTPEnrolled
+	}
+
+	m.totps[userID] = &memoryTOTP{secret: secret}
+
+	return nil
+}
+
+func (m *Memory) GetTOTP(_ context.Context, userID uint64) (entity.TOTP, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	totp, ok := m.totps[userID]
+	if !ok {
+		return entity.TOTP{}, ErrTOTPNotFound
+	}
+
+	return entity.TOTP{
+		UserID:      userID,
+		Secret:      totp.secret,
+		Confirmed:   totp.confirmed,
+		LastCounter: totp.lastCounter,
+	}, nil
+}
+
+func (m *Memory) ConfirmTOTP(_ context.Context, userID, counter uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	totp, ok := m.totps[userID]
+	if !ok || totp.confirmed {
+		return ErrTOTPNotFound
+	}
+
+	totp.confirmed = true
+	totp.lastCounter = counter
+	totp.attempts = 0
+
+	return nil
+}
+
+func (m *Memory) UseTOTPCounter(_ context.Context, userID, counter uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	totp, ok := m.totps[userID]
+	if !ok || !totp.confirmed || totp.lastCounter >= counter {
+		return ErrOTPReplayed
+	}
+
+	totp.lastCounter = counter
+	totp.attempts = 0
+	delete(m.lockouts, userID)
+
+	return nil
+}
+
+func (m *Memory) FailTOTPAttempt(_ context.Context, userID uint64, maxAttempts uint8) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lockouts, err := m.checkLockout(userID)
+	if err != nil {
+		return err
+	}
+
+	totp, ok := m.totps[userID]
+	if !ok {
+		return ErrTOTPNotFound
+	}
+
+	if totp.attempts++; totp.attempts < maxAttempts {
+		return ErrInvalidOTP
+	}
+
+	totp.attempts = 0
+
+	return &LockedError{Err: ErrTooManyAttempts, RetryAfter: m.lockOut(userID, lockouts)}
+}
+
+func (m *Memory) CheckLockout(_ context.Context, userID uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.checkLockout(userID)
+
+	return err
+}
+
+func (m *Memory) StoreRefreshToken(_ context.Context, token entity.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.refreshTokens[hashRefreshToken(token.Token)] = &memoryRefreshToken{
+		userID:    token.UserID,
+		expiresAt: token.ExpiresAt,
+	}
+
+	return nil
+}
+
+func (m *Memory) RotateRefreshToken(_ context.Context, oldToken string,
+	newToken entity.RefreshToken) (entity.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.refreshTokens[hashRefreshToken(oldToken)]
+	if !ok {
+		return entity.RefreshToken{}, ErrRefreshTokenNotFound
+	}
+
+	if old.revoked {
+		for _, token := range m.refreshTokens {
+			if token.userID == old.userID {
+				token.revoked = true
+			}
+		}
+
+		return entity.RefreshToken{}, ErrRefreshTokenReused
+	}
+
+	if old.expiresAt.Before(m.nowFunc()) {
+		return entity.RefreshToken{}, ErrRefreshTokenExpired
+	}
+
+	old.revoked = true
+	m.refreshTokens[hashRefreshToken(newToken.Token)] = &memoryRefreshToken{
+		userID:    old.userID,
+		expiresAt: newToken.ExpiresAt,
+	}
+
+	rotated := entity.RefreshToken{UserID: old.userID, ExpiresAt: old.expiresAt}
+	for _, user := range m.users {
+		if user.ID == old.userID {
+			rotated.UserUUID = user.UUID
+		}
+	}
+
+	return rotated, nil
+}
+
+func (m *Memory) ListOTPs(_ context.Context, userID uint64, limit uint) ([]entity.OTP, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	otps := make([]entity.OTP, 0)
+	for i := len(m.otps) - 1; i >= 0 && uint(len(otps)) < limit; i-- {
+		otp := m.otps[i]
+		if otp.userID != userID {
+			continue
+		}
+
+		otps = append(otps, entity.OTP{
+			ID:          otp.id,
+			UserID:      otp.userID,
+			Purpose:     otp.purpose,
+			RequestID:   otp.requestID,
+			Status:      otpStatusNames[otp.status],
+			Attempts:    otp.attempts,
+			MaxAttempts: otp.maxAttempts,
+			ResendCount: otp.resendCount,
+			LastSentAt:  otp.lastSentAt,
+			ExpiredAt:   otp.expiredAt,
+		})
+	}
+
+	return otps, nil
+}
+
+func (m *Memory) RevokeOTP(_ context.Context, userID uint64, purpose string) error {
+	return m.setActiveOTPStatus(userID, purpose, otpStatusInvalidated)
+}
+
+func (m *Memory) ExpireOTP(_ context.Context, userID uint64, purpose string) error {
+	return m.setActiveOTPStatus(userID, purpose, otpStatusExpired)
+}
+
+func (m *Memory) setActiveOTPStatus(userID uint64, purpose string, status int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	active := m.activeOTP(userID, purpose)
+	if active == nil {
+		return ErrOTPNotFound
+	}
+
+	active.status = status
+
+	return nil
+}
+
+func (m *Memory) activeOTP(userID uint64, purpose string) *memoryOTP {
+	for _, otp := range m.otps {
+		if otp.userID == userID && otp.purpose == purpose && otp.status == otpStatusUnused {
+			return otp
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) checkLockout(userID uint64) (uint, error) {
+	lockout, ok := m.lockouts[userID]
+	if !ok {
+		return 0, nil
+	}
+
+	if now := m.nowFunc(); lockout.lockedUntil.After(now) {
+		return lockout.lockouts, &LockedError{Err: ErrUserLocked, RetryAfter: lockout.lockedUntil.Sub(now)}
+	}
+
+	return lockout.lockouts, nil
+}
+
+func (m *Memory) failOTPAttempt(userID uint64, otp *memoryOTP, lockouts uint) error {
+	otp.attempts++
+	if otp.attempts < otp.maxAttempts {
+		return ErrInvalidOTP
+	}
+
+	otp.status = otpStatusInvalidated
+
+	return &LockedError{Err: ErrTooManyAttempts, RetryAfter: m.lockOut(userID, lockouts)}
+}
+
+func (m *Memory) lockOut(userID uint64, lockouts uint) time.Duration {
+	window := m.lockoutBase
+	for i := uint(0); i < lockouts && window < m.lockoutMax; i++ {
+		window *= 2
+	}
+	if window > m.lockoutMax {
+		window = m.lockoutMax
+	}
+
+	m.lockouts[userID] = &memoryLockout{
+		lockouts:    lockouts + 1,
+		lockedUntil: m.nowFunc().Add(window),
+	}
+
+	return window
+}
+
+func (m *Memory) ExpireOTPs(_ context.Context, limit uint) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	now := m.nowFunc()
+	for _, otp := range m.otps {
+		if uint(n) == limit {
+			break
+		}
+
+		if otp.status == otpStatusUnused && otp.expiredAt.Before(now) {
+			otp.status = otpStatusExpired
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+func (m *Memory) DeleteOTPs(_ context.Context, before time.Time, limit uint) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return int64(len(m.removeOTPs(before, limit))), nil
+}
+
+func (m *Memory) ArchiveOTPs(_ context.Context, before time.Time, limit uint) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := m.removeOTPs(before, limit)
+	m.archivedOTPs = append(m.archivedOTPs, removed...)
+
+	return int64(len(removed)), nil
+}
+
+// removeOTPs takes at most limit OTPs that expired before before out of m.otps
+// and returns them.
+func (m *Memory) removeOTPs(before time.Time, limit uint) []*memoryOTP {
+	var (
+		kept    = m.otps[:0]
+		removed []*memoryOTP
+	)
+	for _, otp := range m.otps {
+		if uint(len(removed)) < limit && otp.expiredAt.Before(before) {
+			removed = append(removed, otp)
+
+			continue
+		}
+
+		kept = append(kept, otp)
+	}
+	m.otps = kept
+
+	return removed
+}
+
+func (m *Memory) AcquireLease(_ context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.nowFunc()
+	if lease, ok := m.leases[name]; ok && lease.holder != holder && !lease.expiresAt.Before(now) {
+		return false, nil
+	}
+
+	m.leases[name] = memoryLease{holder: holder, expiresAt: now.Add(ttl)}
+
+	return true, nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+
+	"github.com/subroll/sqetest/internal/entity"
+)
+
+func (u *User) StoreRefreshToken(ctx context.Context, token entity.RefreshToken) error {
+	if _, err := u.exec(ctx, u.db, `INSERT INTO refresh_tokens (user_id, token_hash, expires_at) `+
+		`VALUES (?, ?, ?);`,
+		token.UserID, hashRefreshToken(token.Token), token.ExpiresAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RotateRefreshToken revokes the refresh token oldToken and stores newToken for
+// the same user, it returns the revoked token's user. Presenting a token that
+// was already revoked means it leaked, so every active token of its user is
+// revoked as well.
+func (u *User) RotateRefreshToken(ctx context.Context, oldToken string,
+	newToken entity.RefreshToken) (entity.RefreshToken, error) {
+	tx, err := u.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: false})
+	if err != nil {
+		return entity.RefreshToken{}, err
+	}
+	defer tx.Rollback()
+
+	var (
+		id        uint64
+		old       entity.RefreshToken
+		revokedAt sql.NullTime
+	)
+	if err := u.queryRow(ctx, tx, `SELECT rt.id, rt.user_id, u.uuid, rt.expires_at, rt.revoked_at `+
+		`FROM refresh_tokens rt `+
+		`JOIN users u ON u.id = rt.user_id WHERE rt.token_hash = ? FOR UPDATE;`, hashRefreshToken(oldToken)).
+		Scan(&id, &old.UserID, &old.UserUUID, &old.ExpiresAt, &revokedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.RefreshToken{}, ErrRefreshTokenNotFound
+		}
+
+		return entity.RefreshToken{}, err
+	}
+
+	now := u.nowFunc()
+	if revokedAt.Valid {
+		if _, err := u.exec(ctx, tx, `UPDATE refresh_tokens SET revoked_at = ? `+
+			`WHERE user_id = ? AND revoked_at IS NULL;`,
+			now, old.UserID); err != nil {
+			return entity.RefreshToken{}, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return entity.RefreshToken{}, err
+		}
+
+		return entity.RefreshToken{}, ErrRefreshTokenReused
+	}
+
+	if old.ExpiresAt.Before(now) {
+		return entity.RefreshToken{}, ErrRefreshTokenExpired
+	}
+
+	if _, err := u.exec(ctx, tx, `UPDATE refresh_tokens SET revoked_at = ? WHERE id = ?;`,
+		now, id); err != nil {
+		return entity.RefreshToken{}, err
+	}
+
+	if _, err := u.exec(ctx, tx, `INSERT INTO refresh_tokens (user_id, token_hash, expires_at) `+
+		`VALUES (?, ?, ?);`,
+		old.UserID, hashRefreshToken(newToken.Token), newToken.ExpiresAt); err != nil {
+		return entity.RefreshToken{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return entity.RefreshToken{}, err
+	}
+
+	return old, nil
+}
+
+// hashRefreshToken digests a refresh token before it is stored or looked up,
+// tokens are random enough that a plain SHA-256 is sufficient.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,418 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/subroll/sqetest/internal/entity"
+)
+
+var newToken = entity.RefreshToken{
+	UserID:    1,
+	Token:     "new-refresh-token",
+	ExpiresAt: time.Date(2024, time.January, 31, 0, 0, 0, 0, time.Local),
+}
+
+func TestUser_StoreRefreshToken(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx   context.Context
+		token entity.RefreshToken
+	}
+
+	type expectation struct {
+		err error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorStoringRefreshToken",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectExec(`INSERT INTO refresh_tokens \(user_id, token_hash, expires_at\) VALUES \(\?, \?, \?\);`).
+					WithArgs(uint64(1), "c40dd1765d767caae2588f0ee1de9181d8a44cc9306261eb2c9e526351188338", time.Date(2024, time.January, 31, 0, 0, 0, 0, time.Local)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:   context.TODO(),
+						token: newToken,
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "SuccessStoringRefreshToken",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectExec(`INSERT INTO refresh_tokens \(user_id, token_hash, expires_at\) VALUES \(\?, \?, \?\);`).
+					WithArgs(uint64(1), "c40dd1765d767caae2588f0ee1de9181d8a44cc9306261eb2c9e526351188338", time.Date(2024, time.January, 31, 0, 0, 0, 0, time.Local)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:   context.TODO(),
+						token: newToken,
+					}, expectation{}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			err := u.StoreRefreshToken(a.ctx, a.token)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
+
+func TestUser_RotateRefreshToken(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx      context.Context
+		oldToken string
+		newToken entity.RefreshToken
+	}
+
+	type expectation struct {
+		token entity.RefreshToken
+		err   error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorStartTx",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin().
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:      context.TODO(),
+						oldToken: "fake-refresh-token",
+						newToken: newToken,
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorRefreshTokenNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT rt.id, rt.user_id, u.uuid, rt.expires_at, rt.revoked_at FROM refresh_tokens rt JOIN users u ON u.id = rt.user_id WHERE rt.token_hash = \? FOR UPDATE;`).
+					WithArgs("daeeb2bd7cad42cd18622a5371cfc55b4687bed099305844d1797e653700d51e").
+					WillReturnError(sql.ErrNoRows)
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:      context.TODO(),
+						oldToken: "fake-refresh-token",
+						newToken: newToken,
+					}, expectation{
+						err: ErrRefreshTokenNotFound,
+					}
+			},
+		},
+		{
+			desc: "ErrorGetRefreshTokenData",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT rt.id, rt.user_id, u.uuid, rt.expires_at, rt.revoked_at FROM refresh_tokens rt JOIN users u ON u.id = rt.user_id WHERE rt.token_hash = \? FOR UPDATE;`).
+					WithArgs("daeeb2bd7cad42cd18622a5371cfc55b4687bed099305844d1797e653700d51e").
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:      context.TODO(),
+						oldToken: "fake-refresh-token",
+						newToken: newToken,
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorRefreshTokenReused",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT rt.id, rt.user_id, u.uuid, rt.expires_at, rt.revoked_at FROM refresh_tokens rt JOIN users u ON u.id = rt.user_id WHERE rt.token_hash = \? FOR UPDATE;`).
+					WithArgs("daeeb2bd7cad42cd18622a5371cfc55b4687bed099305844d1797e653700d51e").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "user_id", "uuid", "expires_at", "revoked_at"}).
+							AddRow(uint64(7), uint64(1), "fake-uuid", time.Date(2024, time.January, 8, 0, 0, 0, 0, time.Local), time.Date(2023, time.December, 31, 0, 0, 0, 0, time.Local)))
+
+				mock.
+					ExpectExec(`UPDATE refresh_tokens SET revoked_at = \? WHERE user_id = \? AND revoked_at IS NULL;`).
+					WithArgs(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local), uint64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+
+				mock.
+					ExpectCommit()
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:      context.TODO(),
+						oldToken: "fake-refresh-token",
+						newToken: newToken,
+					}, expectation{
+						err: ErrRefreshTokenReused,
+					}
+			},
+		},
+		{
+			desc: "ErrorRefreshTokenExpired",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT rt.id, rt.user_id, u.uuid, rt.expires_at, rt.revoked_at FROM refresh_tokens rt JOIN users u ON u.id = rt.user_id WHERE rt.token_hash = \? FOR UPDATE;`).
+					WithArgs("daeeb2bd7cad42cd18622a5371cfc55b4687bed099305844d1797e653700d51e").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "user_id", "uuid", "expires_at", "revoked_at"}).
+							AddRow(uint64(7), uint64(1), "fake-uuid", time.Date(2023, time.December, 31, 0, 0, 0, 0, time.Local), nil))
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:      context.TODO(),
+						oldToken: "fake-refresh-token",
+						newToken: newToken,
+					}, expectation{
+						err: ErrRefreshTokenExpired,
+					}
+			},
+		},
+		{
+			desc: "ErrorRevokingRefreshToken",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT rt.id, rt.user_id, u.uuid, rt.expires_at, rt.revoked_at FROM refresh_tokens rt JOIN users u ON u.id = rt.user_id WHERE rt.token_hash = \? FOR UPDATE;`).
+					WithArgs("daeeb2bd7cad42cd18622a5371cfc55b4687bed099305844d1797e653700d51e").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "user_id", "uuid", "expires_at", "revoked_at"}).
+							AddRow(uint64(7), uint64(1), "fake-uuid", time.Date(2024, time.January, 8, 0, 0, 0, 0, time.Local), nil))
+
+				mock.
+					ExpectExec(`UPDATE refresh_tokens SET revoked_at = \? WHERE id = \?;`).
+					WithArgs(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local), uint64(7)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:      context.TODO(),
+						oldToken: "fake-refresh-token",
+						newToken: newToken,
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorStoringRefreshToken",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT rt.id, rt.user_id, u.uuid, rt.expires_at, rt.revoked_at FROM refresh_tokens rt JOIN users u ON u.id = rt.user_id WHERE rt.token_hash = \? FOR UPDATE;`).
+					WithArgs("daeeb2bd7cad42cd18622a5371cfc55b4687bed099305844d1797e653700d51e").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "user_id", "uuid", "expires_at", "revoked_at"}).
+							AddRow(uint64(7), uint64(1), "fake-uuid", time.Date(2024, time.January, 8, 0, 0, 0, 0, time.Local), nil))
+
+				mock.
+					ExpectExec(`UPDATE refresh_tokens SET revoked_at = \? WHERE id = \?;`).
+					WithArgs(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local), uint64(7)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.
+					ExpectExec(`INSERT INTO refresh_tokens \(user_id, token_hash, expires_at\) VALUES \(\?, \?, \?\);`).
+					WithArgs(uint64(1), "c40dd1765d767caae2588f0ee1de9181d8a44cc9306261eb2c9e526351188338", time.Date(2024, time.January, 31, 0, 0, 0, 0, time.Local)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:      context.TODO(),
+						oldToken: "fake-refresh-token",
+						newToken: newToken,
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorCommitting",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT rt.id, rt.user_id, u.uuid, rt.expires_at, rt.revoked_at FROM refresh_tokens rt JOIN users u ON u.id = rt.user_id WHERE rt.token_hash = \? FOR UPDATE;`).
+					WithArgs("daeeb2bd7cad42cd18622a5371cfc55b4687bed099305844d1797e653700d51e").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "user_id", "uuid", "expires_at", "revoked_at"}).
+							AddRow(uint64(7), uint64(1), "fake-uuid", time.Date(2024, time.January, 8, 0, 0, 0, 0, time.Local), nil))
+
+				mock.
+					ExpectExec(`UPDATE refresh_tokens SET revoked_at = \? WHERE id = \?;`).
+					WithArgs(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local), uint64(7)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.
+					ExpectExec(`INSERT INTO refresh_tokens \(user_id, token_hash, expires_at\) VALUES \(\?, \?, \?\);`).
+					WithArgs(uint64(1), "c40dd1765d767caae2588f0ee1de9181d8a44cc9306261eb2c9e526351188338", time.Date(2024, time.January, 31, 0, 0, 0, 0, time.Local)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.
+					ExpectCommit().
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:      context.TODO(),
+						oldToken: "fake-refresh-token",
+						newToken: newToken,
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "SuccessRotatingRefreshToken",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT rt.id, rt.user_id, u.uuid, rt.expires_at, rt.revoked_at FROM refresh_tokens rt JOIN users u ON u.id = rt.user_id WHERE rt.token_hash = \? FOR UPDATE;`).
+					WithArgs("daeeb2bd7cad42cd18622a5371cfc55b4687bed099305844d1797e653700d51e").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "user_id", "uuid", "expires_at", "revoked_at"}).
+							AddRow(uint64(7), uint64(1), "fake-uuid", time.Date(2024, time.January, 8, 0, 0, 0, 0, time.Local), nil))
+
+				mock.
+					ExpectExec(`UPDATE refresh_tokens SET revoked_at = \? WHERE id = \?;`).
+					WithArgs(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local), uint64(7)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.
+					ExpectExec(`INSERT INTO refresh_tokens \(user_id, token_hash, expires_at\) VALUES \(\?, \?, \?\);`).
+					WithArgs(uint64(1), "c40dd1765d767caae2588f0ee1de9181d8a44cc9306261eb2c9e526351188338", time.Date(2024, time.January, 31, 0, 0, 0, 0, time.Local)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.
+					ExpectCommit()
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:      context.TODO(),
+						oldToken: "fake-refresh-token",
+						newToken: newToken,
+					}, expectation{
+						token: entity.RefreshToken{
+							UserID:    1,
+							UserUUID:  "fake-uuid",
+							ExpiresAt: time.Date(2024, time.January, 8, 0, 0, 0, 0, time.Local),
+						},
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			got, err := u.RotateRefreshToken(a.ctx, a.oldToken, a.newToken)
+			assert.Equal(t, got, e.token)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
@@ -3,10 +3,24 @@ package repository
 import (
 	"database/sql"
 	"time"
+
+	"github.com/subroll/sqetest/internal/pkg/otphash"
 )
 
 type Dependencies struct {
-	DB *sql.DB
+	DB        *sql.DB
+	Dialect   Dialect
+	OTPHasher *otphash.Hasher
+
+	// ReplicaDB is an optional read replica of DB, only lookups that can
+	// tolerate replication lag go there.
+	ReplicaDB *sql.DB
 
 	NowFunc func() time.Time
+
+	// Once an OTP reaches its maximum attempts the user is locked out for
+	// LockoutBaseDuration, doubled on every consecutive lockout and capped at
+	// LockoutMaxDuration.
+	LockoutBaseDuration time.Duration
+	LockoutMaxDuration  time.Duration
 }
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/subroll/sqetest/internal/entity"
+)
+
+var otpStatusNames = map[int]string{
+	otpStatusUnused:      entity.OTPStatusActive,
+	otpStatusUsed:        entity.OTPStatusUsed,
+	otpStatusExpired:     entity.OTPStatusExpired,
+	otpStatusInvalidated: entity.OTPStatusInvalidated,
+}
+
+// ListOTPs returns the user's latest OTPs, newest first, for support staff to
+// inspect. The codes themselves are never returned.
+func (u *User) ListOTPs(ctx context.Context, userID uint64, limit uint) ([]entity.OTP, error) {
+	rows, err := u.query(ctx, u.db, `SELECT id, purpose, request_id, status, attempts, max_attempts, `+
+		`resend_count, last_sent_at, expired_at FROM otps WHERE user_id = ? ORDER BY id DESC LIMIT ?;`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	otps := make([]entity.OTP, 0)
+	for rows.Next() {
+		var (
+			otp    = entity.OTP{UserID: userID}
+			status int
+		)
+		if err := rows.Scan(&otp.ID, &otp.Purpose, &otp.RequestID, &status, &otp.Attempts, &otp.MaxAttempts,
+			&otp.ResendCount, &otp.LastSentAt, &otp.ExpiredAt); err != nil {
+			return nil, err
+		}
+
+		otp.Status = otpStatusNames[status]
+		otps = append(otps, otp)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return otps, nil
+}
+
+// RevokeOTP invalidates the user's active OTP for purpose, it fails with
+// ErrOTPNotFound when there is none.
+func (u *User) RevokeOTP(ctx context.Context, userID uint64, purpose string) error {
+	return u.updateActiveOTP(ctx, `UPDATE otps SET status = ? `+
+		`WHERE user_id = ? AND purpose = ? AND status = ?;`,
+		otpStatusInvalidated, userID, purpose, otpStatusUnused)
+}
+
+// ExpireOTP expires the user's active OTP for purpose ahead of time, so the
+// user can request a new one straight away. It fails with ErrOTPNotFound when
+// there is no active OTP.
+func (u *User) ExpireOTP(ctx context.Context, userID uint64, purpose string) error {
+	return u.updateActiveOTP(ctx, `UPDATE otps SET status = ? `+
+		`WHERE user_id = ? AND purpose = ? AND status = ?;`,
+		otpStatusExpired, userID, purpose, otpStatusUnused)
+}
+
+func (u *User) updateActiveOTP(ctx context.Context, query string, args ...interface{}) error {
+	res, err := u.exec(ctx, u.db, query, args...)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrOTPNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,351 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/subroll/sqetest/internal/entity"
+)
+
+func TestUser_ListOTPs(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx    context.Context
+		userID uint64
+		limit  uint
+	}
+
+	type expectation struct {
+		otps []entity.OTP
+		err  error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorSQL",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT id, purpose, request_id, status, attempts, max_attempts, resend_count, last_sent_at, expired_at FROM otps WHERE user_id = \? ORDER BY id DESC LIMIT \?;`).
+					WithArgs(uint64(2), uint(10)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:    context.TODO(),
+						userID: 2,
+						limit:  10,
+					}, expectation{
+						otps: nil,
+						err:  errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorRows",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT id, purpose, request_id, status, attempts, max_attempts, resend_count, last_sent_at, expired_at FROM otps WHERE user_id = \? ORDER BY id DESC LIMIT \?;`).
+					WithArgs(uint64(2), uint(10)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "purpose", "request_id", "status", "attempts", "max_attempts", "resend_count", "last_sent_at", "expired_at"}).
+							AddRow(5, "login", "fake-request-id", 0, 1, 5, 0, time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), time.Date(2024, time.January, 1, 0, 6, 0, 0, time.Local)).
+							RowError(0, errors.New("fake error")))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:    context.TODO(),
+						userID: 2,
+						limit:  10,
+					}, expectation{
+						otps: nil,
+						err:  errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT id, purpose, request_id, status, attempts, max_attempts, resend_count, last_sent_at, expired_at FROM otps WHERE user_id = \? ORDER BY id DESC LIMIT \?;`).
+					WithArgs(uint64(2), uint(10)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "purpose", "request_id", "status", "attempts", "max_attempts", "resend_count", "last_sent_at", "expired_at"}).
+							AddRow(5, "login", "fake-request-id", 0, 1, 5, 0, time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), time.Date(2024, time.January, 1, 0, 6, 0, 0, time.Local)).
+							AddRow(4, "transaction", "fake-request-id-2", 3, 3, 3, 1, time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), time.Date(2024, time.January, 1, 0, 6, 0, 0, time.Local)))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:    context.TODO(),
+						userID: 2,
+						limit:  10,
+					}, expectation{
+						otps: []entity.OTP{
+							{ID: 5, UserID: 2, Purpose: "login", RequestID: "fake-request-id", Status: entity.OTPStatusActive, Attempts: 1,
+								MaxAttempts: 5, LastSentAt: time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), ExpiredAt: time.Date(2024, time.January, 1, 0, 6, 0, 0, time.Local)},
+							{ID: 4, UserID: 2, Purpose: "transaction", RequestID: "fake-request-id-2", Status: entity.OTPStatusInvalidated,
+								Attempts: 3, MaxAttempts: 3, ResendCount: 1, LastSentAt: time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), ExpiredAt: time.Date(2024, time.January, 1, 0, 6, 0, 0, time.Local)},
+						},
+						err: nil,
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			got, err := u.ListOTPs(a.ctx, a.userID, a.limit)
+			assert.Equal(t, got, e.otps)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
+
+func TestUser_RevokeOTP(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx     context.Context
+		userID  uint64
+		purpose string
+	}
+
+	type expectation struct {
+		err error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorSQL",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectExec(`UPDATE otps SET status = \? WHERE user_id = \? AND purpose = \? AND status = \?;`).
+					WithArgs(otpStatusInvalidated, uint64(2), "login", otpStatusUnused).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:     context.TODO(),
+						userID:  2,
+						purpose: "login",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorRowsAffected",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectExec(`UPDATE otps SET status = \? WHERE user_id = \? AND purpose = \? AND status = \?;`).
+					WithArgs(otpStatusInvalidated, uint64(2), "login", otpStatusUnused).
+					WillReturnResult(sqlmock.NewErrorResult(errors.New("fake error")))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:     context.TODO(),
+						userID:  2,
+						purpose: "login",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectExec(`UPDATE otps SET status = \? WHERE user_id = \? AND purpose = \? AND status = \?;`).
+					WithArgs(otpStatusInvalidated, uint64(2), "login", otpStatusUnused).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:     context.TODO(),
+						userID:  2,
+						purpose: "login",
+					}, expectation{
+						err: ErrOTPNotFound,
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectExec(`UPDATE otps SET status = \? WHERE user_id = \? AND purpose = \? AND status = \?;`).
+					WithArgs(otpStatusInvalidated, uint64(2), "login", otpStatusUnused).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:     context.TODO(),
+						userID:  2,
+						purpose: "login",
+					}, expectation{}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			err := u.RevokeOTP(a.ctx, a.userID, a.purpose)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
+
+func TestUser_ExpireOTP(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx     context.Context
+		userID  uint64
+		purpose string
+	}
+
+	type expectation struct {
+		err error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorSQL",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectExec(`UPDATE otps SET status = \? WHERE user_id = \? AND purpose = \? AND status = \?;`).
+					WithArgs(otpStatusExpired, uint64(2), "login", otpStatusUnused).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:     context.TODO(),
+						userID:  2,
+						purpose: "login",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorRowsAffected",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectExec(`UPDATE otps SET status = \? WHERE user_id = \? AND purpose = \? AND status = \?;`).
+					WithArgs(otpStatusExpired, uint64(2), "login", otpStatusUnused).
+					WillReturnResult(sqlmock.NewErrorResult(errors.New("fake error")))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:     context.TODO(),
+						userID:  2,
+						purpose: "login",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectExec(`UPDATE otps SET status = \? WHERE user_id = \? AND purpose = \? AND status = \?;`).
+					WithArgs(otpStatusExpired, uint64(2), "login", otpStatusUnused).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:     context.TODO(),
+						userID:  2,
+						purpose: "login",
+					}, expectation{
+						err: ErrOTPNotFound,
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectExec(`UPDATE otps SET status = \? WHERE user_id = \? AND purpose = \? AND status = \?;`).
+					WithArgs(otpStatusExpired, uint64(2), "login", otpStatusUnused).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:     context.TODO(),
+						userID:  2,
+						purpose: "login",
+					}, expectation{}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			err := u.ExpireOTP(a.ctx, a.userID, a.purpose)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// ExpireOTPs marks at most limit active OTPs that are past their expiry as
+// expired, oldest first, and returns how many it marked.
+func (u *User) ExpireOTPs(ctx context.Context, limit uint) (int64, error) {
+	ids, err := u.otpIDs(ctx, u.db, `SELECT id FROM otps WHERE status = ? AND expired_at < ? ORDER BY id LIMIT ?;`,
+		otpStatusUnused, u.nowFunc(), limit)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	res, err := u.exec(ctx, u.db, `UPDATE otps SET status = ? WHERE status = ? AND id IN (`+placeholders(len(ids))+`);`,
+		append([]interface{}{otpStatusExpired, otpStatusUnused}, ids...)...)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// DeleteOTPs deletes at most limit OTPs that expired before before, oldest
+// first, and returns how many it deleted.
+func (u *User) DeleteOTPs(ctx context.Context, before time.Time, limit uint) (int64, error) {
+	ids, err := u.otpIDs(ctx, u.db, `SELECT id FROM otps WHERE expired_at < ? ORDER BY id LIMIT ?;`, before, limit)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	res, err := u.exec(ctx, u.db, `DELETE FROM otps WHERE id IN (`+placeholders(len(ids))+`);`, ids...)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// ArchiveOTPs moves at most limit OTPs that expired before before, oldest
+// first, to otps_archive and returns how many it moved. The codes aren't
+// archived.
+func (u *User) ArchiveOTPs(ctx context.Context, before time.Time, limit uint) (int64, error) {
+	tx, err := u.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: false})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	ids, err := u.otpIDs(ctx, tx, `SELECT id FROM otps WHERE expired_at < ? ORDER BY id LIMIT ? FOR UPDATE;`,
+		before, limit)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	in := placeholders(len(ids))
+	if _, err := u.exec(ctx, tx, `INSERT INTO otps_archive (id, user_id, purpose, request_id, status, attempts, `+
+		`max_attempts, resend_count, last_sent_at, expired_at, archived_at) `+
+		`SELECT id, user_id, purpose, request_id, status, attempts, max_attempts, resend_count, last_sent_at, `+
+		`expired_at, ? FROM otps WHERE id IN (`+in+`);`,
+		append([]interface{}{u.nowFunc()}, ids...)...); err != nil {
+		return 0, err
+	}
+
+	res, err := u.exec(ctx, tx, `DELETE FROM otps WHERE id IN (`+in+`);`, ids...)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// AcquireLease gives the lease name to holder for ttl unless another holder
+// has it and it hasn't expired yet, it tells whether holder has the lease. A
+// holder keeps the lease by acquiring it again before it expires.
+func (u *User) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	now := u.nowFunc()
+	res, err := u.exec(ctx, u.db, `INSERT INTO leases (name, holder, expires_at) VALUES (?, ?, ?) `+
+		u.dialect.ignoreConflict("name")+`;`, name, holder, now.Add(ttl))
+	if err != nil {
+		return false, err
+	}
+
+	if n, err := res.RowsAffected(); err != nil || n == 1 {
+		return n == 1, err
+	}
+
+	res, err = u.exec(ctx, u.db, `UPDATE leases SET holder = ?, expires_at = ? `+
+		`WHERE name = ? AND (holder = ? OR expires_at < ?);`, holder, now.Add(ttl), name, holder, now)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func (u *User) otpIDs(ctx context.Context, q querier, query string, args ...interface{}) ([]interface{}, error) {
+	rows, err := u.query(ctx, q, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []interface{}
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// placeholders returns n comma separated placeholders for an IN clause.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
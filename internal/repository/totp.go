@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/subroll/sqetest/internal/entity"
+)
+
+// StoreTOTPSecret saves a new unconfirmed TOTP secret for the user, replacing
+// any pending enrollment. A confirmed enrollment is never replaced.
+func (u *User) StoreTOTPSecret(ctx context.Context, userID uint64, secret string) error {
+	tx, err := u.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: false})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var confirmedAt sql.NullTime
+	if err := u.queryRow(ctx, tx, `SELECT confirmed_at FROM user_totps WHERE user_id = ? FOR UPDATE;`,
+		userID).Scan(&confirmedAt); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+
+	if confirmedAt.Valid {
+		return ErrTOTPEnrolled
+	}
+
+	if _, err := u.exec(ctx, tx, `INSERT INTO user_totps (user_id, secret, last_counter, attempts) `+
+		`VALUES (?, ?, 0, 0) `+u.dialect.upsert("user_id", "secret", "last_counter", "attempts")+`;`,
+		userID, secret); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (u *User) GetTOTP(ctx context.Context, userID uint64) (entity.TOTP, error) {
+	var (
+		totp        = entity.TOTP{UserID: userID}
+		confirmedAt sql.NullTime
+	)
+	if err := u.queryRow(ctx, u.db, `SELECT secret, confirmed_at, last_counter FROM user_totps `+
+		`WHERE user_id = ?;`,
+		userID).Scan(&totp.Secret, &confirmedAt, &totp.LastCounter); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.TOTP{}, ErrTOTPNotFound
+		}
+
+		return entity.TOTP{}, err
+	}
+
+	totp.Confirmed = confirmedAt.Valid
+
+	return totp, nil
+}
+
+// ConfirmTOTP completes a pending enrollment, counter is the time step of the
+// code used to confirm it so that code can't be used again.
+func (u *User) ConfirmTOTP(ctx context.Context, userID, counter uint64) error {
+	res, err := u.exec(ctx, u.db, `UPDATE user_totps SET confirmed_at = ?, last_counter = ?, attempts = 0 `+
+		`WHERE user_id = ? AND confirmed_at IS NULL;`, u.nowFunc(), counter, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrTOTPNotFound
+	}
+
+	return nil
+}
+
+// UseTOTPCounter records counter as the last accepted time step, it fails
+// with ErrOTPReplayed when the same or a later time step was already used.
+// Like a valid OTP it clears the invalid attempts and lockouts of the user.
+func (u *User) UseTOTPCounter(ctx context.Context, userID, counter uint64) error {
+	tx, err := u.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: false})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := u.exec(ctx, tx, `UPDATE user_totps SET last_counter = ?, attempts = 0 `+
+		`WHERE user_id = ? AND confirmed_at IS NOT NULL AND last_counter < ?;`, counter, userID, counter)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrOTPReplayed
+	}
+
+	if _, err := u.exec(ctx, tx, `DELETE FROM user_lockouts WHERE user_id = ?;`, userID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FailTOTPAttempt records an invalid authenticator app code. It fails with
+// ErrInvalidOTP until maxAttempts codes in a row were invalid, then the user
+// is locked out exactly like after too many invalid OTPs.
+func (u *User) FailTOTPAttempt(ctx context.Context, userID uint64, maxAttempts uint8) error {
+	tx, err := u.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: false})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	lockouts, err := u.checkLockout(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
+	var attempts uint8
+	if err := u.queryRow(ctx, tx, `SELECT attempts FROM user_totps WHERE user_id = ? FOR UPDATE;`,
+		userID).Scan(&attempts); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTOTPNotFound
+		}
+
+		return err
+	}
+
+	if attempts++; attempts < maxAttempts {
+		if _, err := u.exec(ctx, tx, `UPDATE user_totps SET attempts = ? WHERE user_id = ?;`,
+			attempts, userID); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+		return ErrInvalidOTP
+	}
+
+	if _, err := u.exec(ctx, tx, `UPDATE user_totps SET attempts = 0 WHERE user_id = ?;`, userID); err != nil {
+		return err
+	}
+
+	window, err := u.lockOut(ctx, tx, userID, lockouts)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return &LockedError{Err: ErrTooManyAttempts, RetryAfter: window}
+}
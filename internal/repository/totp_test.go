@@ -0,0 +1,837 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/subroll/sqetest/internal/entity"
+)
+
+func TestUser_StoreTOTPSecret(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx    context.Context
+		userID uint64
+		secret string
+	}
+
+	type expectation struct {
+		err error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorStartTx",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin().
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:    context.TODO(),
+						userID: 1,
+						secret: "fake-secret",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorGetTOTPData",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT confirmed_at FROM user_totps WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:    context.TODO(),
+						userID: 1,
+						secret: "fake-secret",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorTOTPAlreadyEnrolled",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT confirmed_at FROM user_totps WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"confirmed_at"}).
+							AddRow(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:    context.TODO(),
+						userID: 1,
+						secret: "fake-secret",
+					}, expectation{
+						err: ErrTOTPEnrolled,
+					}
+			},
+		},
+		{
+			desc: "ErrorStoringSecret",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT confirmed_at FROM user_totps WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnError(sql.ErrNoRows)
+
+				mock.
+					ExpectExec(`INSERT INTO user_totps \(user_id, secret, last_counter, attempts\) VALUES \(\?, \?, 0, 0\) ON DUPLICATE KEY UPDATE secret = VALUES\(secret\), last_counter = VALUES\(last_counter\), attempts = VALUES\(attempts\);`).
+					WithArgs(uint64(1), "fake-secret").
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:    context.TODO(),
+						userID: 1,
+						secret: "fake-secret",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorCommittingSecret",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT confirmed_at FROM user_totps WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnError(sql.ErrNoRows)
+
+				mock.
+					ExpectExec(`INSERT INTO user_totps \(user_id, secret, last_counter, attempts\) VALUES \(\?, \?, 0, 0\) ON DUPLICATE KEY UPDATE secret = VALUES\(secret\), last_counter = VALUES\(last_counter\), attempts = VALUES\(attempts\);`).
+					WithArgs(uint64(1), "fake-secret").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.
+					ExpectCommit().
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:    context.TODO(),
+						userID: 1,
+						secret: "fake-secret",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "SuccessReplacingPendingSecret",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT confirmed_at FROM user_totps WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"confirmed_at"}).
+							AddRow(nil))
+
+				mock.
+					ExpectExec(`INSERT INTO user_totps \(user_id, secret, last_counter, attempts\) VALUES \(\?, \?, 0, 0\) ON DUPLICATE KEY UPDATE secret = VALUES\(secret\), last_counter = VALUES\(last_counter\), attempts = VALUES\(attempts\);`).
+					WithArgs(uint64(1), "fake-secret").
+					WillReturnResult(sqlmock.NewResult(1, 2))
+
+				mock.
+					ExpectCommit()
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:    context.TODO(),
+						userID: 1,
+						secret: "fake-secret",
+					}, expectation{}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			err := u.StoreTOTPSecret(a.ctx, a.userID, a.secret)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
+
+func TestUser_GetTOTP(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx    context.Context
+		userID uint64
+	}
+
+	type expectation struct {
+		totp entity.TOTP
+		err  error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorSQL",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT secret, confirmed_at, last_counter FROM user_totps WHERE user_id = \?;`).
+					WithArgs(uint64(1)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:    context.TODO(),
+						userID: 1,
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT secret, confirmed_at, last_counter FROM user_totps WHERE user_id = \?;`).
+					WithArgs(uint64(1)).
+					WillReturnError(sql.ErrNoRows)
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:    context.TODO(),
+						userID: 1,
+					}, expectation{
+						err: ErrTOTPNotFound,
+					}
+			},
+		},
+		{
+			desc: "SuccessPending",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT secret, confirmed_at, last_counter FROM user_totps WHERE user_id = \?;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"secret", "confirmed_at", "last_counter"}).
+							AddRow("fake-secret", nil, 0))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:    context.TODO(),
+						userID: 1,
+					}, expectation{
+						totp: entity.TOTP{
+							UserID: 1,
+							Secret: "fake-secret",
+						},
+					}
+			},
+		},
+		{
+			desc: "SuccessConfirmed",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT secret, confirmed_at, last_counter FROM user_totps WHERE user_id = \?;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"secret", "confirmed_at", "last_counter"}).
+							AddRow("fake-secret", time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local), 56802))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:    context.TODO(),
+						userID: 1,
+					}, expectation{
+						totp: entity.TOTP{
+							UserID:      1,
+							Secret:      "fake-secret",
+							Confirmed:   true,
+							LastCounter: 56802,
+						},
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			got, err := u.GetTOTP(a.ctx, a.userID)
+			assert.Equal(t, got, e.totp)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
+
+func TestUser_ConfirmTOTP(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx     context.Context
+		userID  uint64
+		counter uint64
+	}
+
+	type expectation struct {
+		err error
+	}
+
+	nowFunc := func() time.Time {
+		return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorSQL",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectExec(`UPDATE user_totps SET confirmed_at = \?, last_counter = \?, attempts = 0 WHERE user_id = \? AND confirmed_at IS NULL;`).
+					WithArgs(nowFunc(), uint64(56802), uint64(1)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db:      db,
+						nowFunc: nowFunc,
+					}, arg{
+						ctx:     context.TODO(),
+						userID:  1,
+						counter: 56802,
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorNoPendingEnrollment",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectExec(`UPDATE user_totps SET confirmed_at = \?, last_counter = \?, attempts = 0 WHERE user_id = \? AND confirmed_at IS NULL;`).
+					WithArgs(nowFunc(), uint64(56802), uint64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				return &User{
+						db:      db,
+						nowFunc: nowFunc,
+					}, arg{
+						ctx:     context.TODO(),
+						userID:  1,
+						counter: 56802,
+					}, expectation{
+						err: ErrTOTPNotFound,
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectExec(`UPDATE user_totps SET confirmed_at = \?, last_counter = \?, attempts = 0 WHERE user_id = \? AND confirmed_at IS NULL;`).
+					WithArgs(nowFunc(), uint64(56802), uint64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				return &User{
+						db:      db,
+						nowFunc: nowFunc,
+					}, arg{
+						ctx:     context.TODO(),
+						userID:  1,
+						counter: 56802,
+					}, expectation{}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			err := u.ConfirmTOTP(a.ctx, a.userID, a.counter)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
+
+func TestUser_UseTOTPCounter(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx     context.Context
+		userID  uint64
+		counter uint64
+	}
+
+	type expectation struct {
+		err error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorStartTx",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin().
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:     context.TODO(),
+						userID:  1,
+						counter: 56802,
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorSQL",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectExec(`UPDATE user_totps SET last_counter = \?, attempts = 0 WHERE user_id = \? AND confirmed_at IS NOT NULL AND last_counter < \?;`).
+					WithArgs(uint64(56802), uint64(1), uint64(56802)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:     context.TODO(),
+						userID:  1,
+						counter: 56802,
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorOTPReplayed",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectExec(`UPDATE user_totps SET last_counter = \?, attempts = 0 WHERE user_id = \? AND confirmed_at IS NOT NULL AND last_counter < \?;`).
+					WithArgs(uint64(56802), uint64(1), uint64(56802)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:     context.TODO(),
+						userID:  1,
+						counter: 56802,
+					}, expectation{
+						err: ErrOTPReplayed,
+					}
+			},
+		},
+		{
+			desc: "ErrorClearLockouts",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectExec(`UPDATE user_totps SET last_counter = \?, attempts = 0 WHERE user_id = \? AND confirmed_at IS NOT NULL AND last_counter < \?;`).
+					WithArgs(uint64(56802), uint64(1), uint64(56802)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.
+					ExpectExec(`DELETE FROM user_lockouts WHERE user_id = \?;`).
+					WithArgs(uint64(1)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:     context.TODO(),
+						userID:  1,
+						counter: 56802,
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectExec(`UPDATE user_totps SET last_counter = \?, attempts = 0 WHERE user_id = \? AND confirmed_at IS NOT NULL AND last_counter < \?;`).
+					WithArgs(uint64(56802), uint64(1), uint64(56802)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.
+					ExpectExec(`DELETE FROM user_lockouts WHERE user_id = \?;`).
+					WithArgs(uint64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.
+					ExpectCommit()
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:     context.TODO(),
+						userID:  1,
+						counter: 56802,
+					}, expectation{}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			err := u.UseTOTPCounter(a.ctx, a.userID, a.counter)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
+
+func TestUser_FailTOTPAttempt(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx         context.Context
+		userID      uint64
+		maxAttempts uint8
+	}
+
+	type expectation struct {
+		err error
+	}
+
+	nowFunc := func() time.Time {
+		return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorStartTx",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin().
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db:          db,
+						lockoutBase: time.Minute,
+						lockoutMax:  time.Hour,
+						nowFunc:     nowFunc,
+					}, arg{
+						ctx:         context.TODO(),
+						userID:      1,
+						maxAttempts: 3,
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorUserLocked",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"lockouts", "locked_until"}).
+							AddRow(1, time.Date(2024, time.January, 1, 0, 3, 0, 0, time.Local)))
+
+				return &User{
+						db:          db,
+						lockoutBase: time.Minute,
+						lockoutMax:  time.Hour,
+						nowFunc:     nowFunc,
+					}, arg{
+						ctx:         context.TODO(),
+						userID:      1,
+						maxAttempts: 3,
+					}, expectation{
+						err: &LockedError{Err: ErrUserLocked, RetryAfter: 2 * time.Minute},
+					}
+			},
+		},
+		{
+			desc: "ErrorTOTPNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT attempts FROM user_totps WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnError(sql.ErrNoRows)
+
+				return &User{
+						db:          db,
+						lockoutBase: time.Minute,
+						lockoutMax:  time.Hour,
+						nowFunc:     nowFunc,
+					}, arg{
+						ctx:         context.TODO(),
+						userID:      1,
+						maxAttempts: 3,
+					}, expectation{
+						err: ErrTOTPNotFound,
+					}
+			},
+		},
+		{
+			desc: "ErrorUpdateAttempts",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT attempts FROM user_totps WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"attempts"}).AddRow(1))
+
+				mock.
+					ExpectExec(`UPDATE user_totps SET attempts = \? WHERE user_id = \?;`).
+					WithArgs(uint8(2), uint64(1)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db:          db,
+						lockoutBase: time.Minute,
+						lockoutMax:  time.Hour,
+						nowFunc:     nowFunc,
+					}, arg{
+						ctx:         context.TODO(),
+						userID:      1,
+						maxAttempts: 3,
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorInvalidOTP",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT attempts FROM user_totps WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"attempts"}).AddRow(1))
+
+				mock.
+					ExpectExec(`UPDATE user_totps SET attempts = \? WHERE user_id = \?;`).
+					WithArgs(uint8(2), uint64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.
+					ExpectCommit()
+
+				return &User{
+						db:          db,
+						lockoutBase: time.Minute,
+						lockoutMax:  time.Hour,
+						nowFunc:     nowFunc,
+					}, arg{
+						ctx:         context.TODO(),
+						userID:      1,
+						maxAttempts: 3,
+					}, expectation{
+						err: ErrInvalidOTP,
+					}
+			},
+		},
+		{
+			desc: "ErrorTooManyAttempts",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"lockouts", "locked_until"}).
+							AddRow(2, time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)))
+
+				mock.
+					ExpectQuery(`SELECT attempts FROM user_totps WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"attempts"}).AddRow(2))
+
+				mock.
+					ExpectExec(`UPDATE user_totps SET attempts = 0 WHERE user_id = \?;`).
+					WithArgs(uint64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.
+					ExpectExec(`INSERT INTO user_lockouts \(user_id, lockouts, locked_until\) VALUES \(\?, \?, \?\) ON DUPLICATE KEY UPDATE lockouts = VALUES\(lockouts\), locked_until = VALUES\(locked_until\);`).
+					WithArgs(uint64(1), uint(3), time.Date(2024, time.January, 1, 0, 5, 0, 0, time.Local)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.
+					ExpectCommit()
+
+				return &User{
+						db:          db,
+						lockoutBase: time.Minute,
+						lockoutMax:  time.Hour,
+						nowFunc:     nowFunc,
+					}, arg{
+						ctx:         context.TODO(),
+						userID:      1,
+						maxAttempts: 3,
+					}, expectation{
+						err: &LockedError{Err: ErrTooManyAttempts, RetryAfter: 4 * time.Minute},
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			err := u.FailTOTPAttempt(a.ctx, a.userID, a.maxAttempts)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/subroll/sqetest/internal/repository")
+
+// querier is what *sql.DB and *sql.Tx have in common, every statement of User
+// goes through exec, query or queryRow so it gets its own span.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// exec, query and queryRow rebind the MySQL query for the dialect and run it
+// on q inside a span named after the statement.
+func (u *User) exec(ctx context.Context, q querier, query string, args ...interface{}) (sql.Result, error) {
+	query = u.rebind(query)
+	ctx, span := u.startSpan(ctx, query)
+	defer span.End()
+
+	res, err := q.ExecContext(ctx, query, args...)
+	recordError(span, err)
+
+	return res, err
+}
+
+func (u *User) query(ctx context.Context, q querier, query string, args ...interface{}) (*sql.Rows, error) {
+	query = u.rebind(query)
+	ctx, span := u.startSpan(ctx, query)
+	defer span.End()
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	recordError(span, err)
+
+	return rows, err
+}
+
+func (u *User) queryRow(ctx context.Context, q querier, query string, args ...interface{}) *sql.Row {
+	query = u.rebind(query)
+	ctx, span := u.startSpan(ctx, query)
+	defer span.End()
+
+	row := q.QueryRowContext(ctx, query, args...)
+	recordError(span, row.Err())
+
+	return row
+}
+
+func (u *User) startSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, spanName(query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(u.dialect.system(), semconv.DBStatement(query)))
+}
+
+// system returns the db.system attribute of the dialect.
+func (d Dialect) system() attribute.KeyValue {
+	switch d {
+	case DialectPostgreSQL:
+		return semconv.DBSystemPostgreSQL
+	case DialectSQLite:
+		return semconv.DBSystemSqlite
+	default:
+		return semconv.DBSystemMySQL
+	}
+}
+
+// spanName names a statement by its operation and table, e.g. "SELECT otps",
+// query parameters never end up in it.
+func spanName(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "SQL"
+	}
+
+	op := strings.ToUpper(fields[0])
+	var after string
+	switch op {
+	case "SELECT", "DELETE":
+		after = "FROM"
+	case "INSERT":
+		after = "INTO"
+	case "UPDATE":
+		if len(fields) > 1 {
+			return op + " " + fields[1]
+		}
+
+		return op
+	default:
+		return op
+	}
+
+	for i := 1; i < len(fields)-1; i++ {
+		if strings.EqualFold(fields[i], after) {
+			return op + " " + strings.TrimSuffix(fields[i+1], ";")
+		}
+	}
+
+	return op
+}
+
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+func TestSpanName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{
+			name:     "Select",
+			query:    `SELECT id, expired_at FROM otps WHERE user_id = ? FOR UPDATE;`,
+			expected: "SELECT otps",
+		},
+		{
+			name:     "Insert",
+			query:    `INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES (?, ?, ?);`,
+			expected: "INSERT refresh_tokens",
+		},
+		{
+			name:     "Update",
+			query:    `UPDATE otps SET status = ? WHERE id = ?;`,
+			expected: "UPDATE otps",
+		},
+		{
+			name:     "Delete",
+			query:    `DELETE FROM user_lockouts WHERE user_id = ?;`,
+			expected: "DELETE user_lockouts",
+		},
+		{
+			name:     "Other",
+			query:    `PRAGMA foreign_keys = ON;`,
+			expected: "PRAGMA",
+		},
+		{
+			name:     "Empty",
+			expected: "SQL",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, spanName(test.query), test.expected)
+		})
+	}
+}
+
+func TestUser_exec(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevTracer := tracer
+	tracer = provider.Tracer("github.com/subroll/sqetest/internal/repository")
+	t.Cleanup(func() {
+		tracer = prevTracer
+		_ = provider.Shutdown(context.Background())
+	})
+
+	db, mock := createDBMock(t)
+	mock.ExpectExec(`UPDATE traced SET status = \$1 WHERE id = \$2;`).
+		WithArgs(1, 2).
+		WillReturnError(errors.New("fake-error"))
+
+	u := NewUser(Dependencies{DB: db, Dialect: DialectPostgreSQL})
+	_, err := u.exec(context.Background(), db, `UPDATE traced SET status = ? WHERE id = ?;`, 1, 2)
+	assert.EqualError(t, err, "fake-error")
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	var found bool
+	for _, span := range recorder.Ended() {
+		if span.Name() != "UPDATE traced" {
+			continue
+		}
+
+		found = true
+		assert.Equal(t, span.Status().Code, codes.Error)
+		assert.Contains(t, span.Attributes(), semconv.DBSystemPostgreSQL)
+		assert.Contains(t, span.Attributes(),
+			attribute.String("db.statement", `UPDATE traced SET status = $1 WHERE id = $2;`))
+	}
+	assert.True(t, found)
+}
@@ -5,38 +5,102 @@ import (
 	"database/sql"
 	"errors"
 	"time"
+
+	"github.com/subroll/sqetest/internal/entity"
+	"github.com/subroll/sqetest/internal/pkg/otphash"
 )
 
 var (
-	ErrNotFound   = errors.New("data not found")
-	ErrOTPExist   = errors.New("there is still an active otp")
-	ErrOTPExpired = errors.New("otp expired")
-	ErrInvalidOTP = errors.New("invalid otp")
+	ErrNotFound        = errors.New("data not found")
+	ErrOTPExist        = errors.New("there is still an active otp")
+	ErrOTPExpired      = errors.New("otp expired")
+	ErrInvalidOTP      = errors.New("invalid otp")
+	ErrTooManyAttempts = errors.New("too many invalid otp attempts")
+	ErrUserLocked      = errors.New("user is locked out")
+	ErrTOTPNotFound    = errors.New("totp not enrolled")
+	ErrTOTPEnrolled    = errors.New("totp already enrolled")
+	ErrOTPReplayed     = errors.New("otp already used")
+	ErrOTPNotFound     = errors.New("no active otp")
+	ErrResendLimit     = errors.New("otp resend limit reached")
+	ErrResendCooldown  = errors.New("otp resend cooldown")
+
+	ErrChallengeMismatch = errors.New("otp issued for another challenge")
+	ErrBindingMismatch   = errors.New("otp issued to another client")
+
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenExpired  = errors.New("refresh token expired")
+	ErrRefreshTokenReused   = errors.New("refresh token already used")
 )
 
 const (
 	otpStatusUnused = iota
 	otpStatusUsed
 	otpStatusExpired
+	otpStatusInvalidated
 )
 
 type (
 	User struct {
-		db      *sql.DB
-		nowFunc func() time.Time
+		db        *sql.DB
+		replica   *sql.DB
+		dialect   Dialect
+		otpHasher *otphash.Hasher
+		nowFunc   func() time.Time
+
+		lockoutBase time.Duration
+		lockoutMax  time.Duration
+	}
+
+	// LockedError is returned when a user is locked out, it wraps either
+	// ErrUserLocked or ErrTooManyAttempts.
+	LockedError struct {
+		Err        error
+		RetryAfter time.Duration
+	}
+
+	// CooldownError is returned when an OTP is resent before its cooldown has
+	// passed, it wraps ErrResendCooldown.
+	CooldownError struct {
+		RetryAfter time.Duration
 	}
 )
 
+func (e *LockedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LockedError) Unwrap() error {
+	return e.Err
+}
+
+func (e *CooldownError) Error() string {
+	return ErrResendCooldown.Error()
+}
+
+func (e *CooldownError) Unwrap() error {
+	return ErrResendCooldown
+}
+
 func NewUser(deps Dependencies) *User {
 	return &User{
-		db:      deps.DB,
-		nowFunc: deps.NowFunc,
+		db:          deps.DB,
+		replica:     deps.ReplicaDB,
+		dialect:     deps.Dialect,
+		otpHasher:   deps.OTPHasher,
+		nowFunc:     deps.NowFunc,
+		lockoutBase: deps.LockoutBaseDuration,
+		lockoutMax:  deps.LockoutMaxDuration,
 	}
 }
 
+// GetUserIDByUUID and GetUserByUUID fail with ErrNotFound for disabled users,
+// every authentication starts with one of them. GetUserIDByUUID reads from the
+// replica, a user may take as long as the replication lag to appear or
+// disappear there.
 func (u *User) GetUserIDByUUID(ctx context.Context, uuid string) (uint64, error) {
 	var id uint64
-	if err := u.db.QueryRowContext(ctx, `SELECT id FROM users WHERE uuid = ?;`, uuid).Scan(&id); err != nil {
+	if err := u.queryRow(ctx, u.reader(), `SELECT id FROM users WHERE uuid = ? AND disabled_at IS NULL;`,
+		uuid).Scan(&id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return 0, ErrNotFound
 		}
@@ -47,19 +111,46 @@ func (u *User) GetUserIDByUUID(ctx context.Context, uuid string) (uint64, error)
 	return id, nil
 }
 
-func (u *User) StoreOTP(ctx context.Context, userID uint64, otp, requestID string) error {
+func (u *User) GetUserByUUID(ctx context.Context, uuid string) (entity.User, error) {
+	var (
+		user                     entity.User
+		email, phone, otpChannel sql.NullString
+	)
+	if err := u.queryRow(ctx, u.db, `SELECT id, uuid, name, email, phone, otp_channel FROM users `+
+		`WHERE uuid = ? AND disabled_at IS NULL;`,
+		uuid).Scan(&user.ID, &user.UUID, &user.Name, &email, &phone, &otpChannel); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.User{}, ErrNotFound
+		}
+
+		return entity.User{}, err
+	}
+
+	user.Email = email.String
+	user.Phone = phone.String
+	user.OTPChannel = otpChannel.String
+
+	return user, nil
+}
+
+func (u *User) StoreOTP(ctx context.Context, otp entity.OTP) error {
 	tx, err := u.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: false})
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	if _, err := u.checkLockout(ctx, tx, otp.UserID); err != nil {
+		return err
+	}
+
 	var (
 		uid       uint64
 		expiredAt time.Time
 	)
-	if err := tx.QueryRowContext(ctx, `SELECT id, expired_at FROM otps WHERE user_id = ? AND status = ? FOR UPDATE;`,
-		userID, otpStatusUnused).Scan(&uid, &expiredAt); err != nil {
+	if err := u.queryRow(ctx, tx, `SELECT id, expired_at FROM otps `+
+		`WHERE user_id = ? AND purpose = ? AND status = ? FOR UPDATE;`,
+		otp.UserID, otp.Purpose, otpStatusUnused).Scan(&uid, &expiredAt); err != nil {
 		if !errors.Is(err, sql.ErrNoRows) {
 			return err
 		}
@@ -73,13 +164,17 @@ func (u *User) StoreOTP(ctx context.Context, userID uint64, otp, requestID strin
 		return ErrOTPExist
 	}
 
-	if _, err := tx.ExecContext(ctx, `UPDATE otps SET status = ? WHERE id = ?;`, otpStatusExpired, uid); err != nil {
+	if _, err := u.exec(ctx, tx, `UPDATE otps SET status = ? WHERE id = ?;`,
+		otpStatusExpired, uid); err != nil {
 		return err
 	}
 
-	fiveMinFromNow := u.nowFunc().Add(5 * time.Minute)
-	if _, err := tx.ExecContext(ctx, `INSERT INTO otps (user_id, otp, request_id, expired_at) VALUES (?, ?, ?, ?);`,
-		userID, otp, requestID, fiveMinFromNow); err != nil {
+	now := u.nowFunc()
+	keyID, digest := u.otpHasher.Sum(otp.Code)
+	if _, err := u.exec(ctx, tx, `INSERT INTO otps (user_id, otp, otp_key_id, purpose, request_id, challenge_id, `+
+		`binding, max_attempts, last_sent_at, expired_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+		otp.UserID, digest, keyID, otp.Purpose, otp.RequestID, otp.ChallengeID, otp.Binding, otp.MaxAttempts, now,
+		now.Add(otp.TTL)); err != nil {
 		return err
 	}
 
@@ -90,19 +185,97 @@ func (u *User) StoreOTP(ctx context.Context, userID uint64, otp, requestID strin
 	return nil
 }
 
-func (u *User) UpdateOTPStatus(ctx context.Context, userID uint64, otp, requestID string) error {
+// ResendOTP replaces the code of the user's active OTP for otp.Purpose with
+// otp.Code and restarts its expiry, the request and challenge IDs and the
+// attempts made so far are kept. It returns the OTP with its stored request and
+// challenge IDs and resend count.
+func (u *User) ResendOTP(ctx context.Context, otp entity.OTP) (entity.OTP, error) {
+	tx, err := u.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: false})
+	if err != nil {
+		return entity.OTP{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := u.checkLockout(ctx, tx, otp.UserID); err != nil {
+		return entity.OTP{}, err
+	}
+
+	var (
+		uid                    uint64
+		requestID, challengeID string
+		resendCount            uint8
+		lastSentAt, expiredAt  time.Time
+	)
+	if err := u.queryRow(ctx, tx, `SELECT id, request_id, challenge_id, resend_count, last_sent_at, expired_at `+
+		`FROM otps WHERE user_id = ? AND purpose = ? AND status = ? FOR UPDATE;`,
+		otp.UserID, otp.Purpose, otpStatusUnused).Scan(&uid, &requestID, &challengeID, &resendCount, &lastSentAt,
+		&expiredAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.OTP{}, ErrOTPNotFound
+		}
+
+		return entity.OTP{}, err
+	}
+
+	now := u.nowFunc()
+	if expiredAt.Before(now) {
+		return entity.OTP{}, ErrOTPExpired
+	}
+
+	if resendCount >= otp.MaxResends {
+		return entity.OTP{}, ErrResendLimit
+	}
+
+	if next := lastSentAt.Add(otp.ResendCooldown); next.After(now) {
+		return entity.OTP{}, &CooldownError{RetryAfter: next.Sub(now)}
+	}
+
+	keyID, digest := u.otpHasher.Sum(otp.Code)
+	if _, err := u.exec(ctx, tx, `UPDATE otps SET otp = ?, otp_key_id = ?, resend_count = ?, last_sent_at = ?, `+
+		`expired_at = ? WHERE id = ?;`, digest, keyID, resendCount+1, now, now.Add(otp.TTL), uid); err != nil {
+		return entity.OTP{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return entity.OTP{}, err
+	}
+
+	otp.RequestID = requestID
+	otp.ChallengeID = challengeID
+	otp.ResendCount = resendCount + 1
+
+	return otp, nil
+}
+
+// UpdateOTPStatus marks the user's active OTP for purpose as used when otp is
+// its code. The OTP must have been issued for challengeID, and to binding when
+// it is bound to a client, a mismatch is reported before the code is compared
+// and doesn't count as an attempt.
+func (u *User) UpdateOTPStatus(ctx context.Context, userID uint64, otp, purpose, challengeID,
+	binding string) error {
 	tx, err := u.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: false})
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	lockouts, err := u.checkLockout(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
 	var (
-		uid       uint64
-		expiredAt time.Time
+		uid                   uint64
+		digest, keyID         string
+		storedChallengeID     string
+		storedBinding         string
+		attempts, maxAttempts uint8
+		expiredAt             time.Time
 	)
-	if err := tx.QueryRowContext(ctx, `SELECT id, expired_at FROM otps WHERE user_id = ? AND otp = ? AND status = ? FOR UPDATE;`,
-		userID, otp, otpStatusUnused).Scan(&uid, &expiredAt); err != nil {
+	if err := u.queryRow(ctx, tx, `SELECT id, otp, otp_key_id, challenge_id, binding, attempts, max_attempts, `+
+		`expired_at FROM otps WHERE user_id = ? AND purpose = ? AND status = ? FOR UPDATE;`,
+		userID, purpose, otpStatusUnused).Scan(&uid, &digest, &keyID, &storedChallengeID, &storedBinding, &attempts,
+		&maxAttempts, &expiredAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrInvalidOTP
 		}
@@ -110,6 +283,14 @@ func (u *User) UpdateOTPStatus(ctx context.Context, userID uint64, otp, requestI
 		return err
 	}
 
+	if err := checkOTPIssuance(storedChallengeID, storedBinding, challengeID, binding); err != nil {
+		return err
+	}
+
+	if !u.otpHasher.Equal(otp, keyID, digest) {
+		return u.failOTPAttempt(ctx, tx, userID, uid, attempts+1, maxAttempts, lockouts)
+	}
+
 	if expiredAt.Before(u.nowFunc()) {
 		if err := tx.Commit(); err != nil {
 			return err
@@ -118,13 +299,131 @@ func (u *User) UpdateOTPStatus(ctx context.Context, userID uint64, otp, requestI
 		return ErrOTPExpired
 	}
 
-	if _, err := tx.ExecContext(ctx, `UPDATE otps SET status = ? WHERE id = ?;`, otpStatusUsed, uid); err != nil {
+	if _, err := u.exec(ctx, tx, `UPDATE otps SET status = ? WHERE id = ?;`,
+		otpStatusUsed, uid); err != nil {
 		return err
 	}
 
+	if lockouts > 0 {
+		if _, err := u.exec(ctx, tx, `DELETE FROM user_lockouts WHERE user_id = ?;`, userID); err != nil {
+			return err
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// checkOTPIssuance makes sure an OTP issued for storedChallengeID to
+// storedBinding is validated for the same challenge by the same client, an OTP
+// stored without a binding can be validated by any client.
+func checkOTPIssuance(storedChallengeID, storedBinding, challengeID, binding string) error {
+	if storedChallengeID != challengeID {
+		return ErrChallengeMismatch
+	}
+
+	if storedBinding != "" && storedBinding != binding {
+		return ErrBindingMismatch
+	}
+
+	return nil
+}
+
+// CheckLockout fails with a LockedError while the user is locked out, it lets
+// codes verified outside the repository honor the lockout of invalid OTPs.
+func (u *User) CheckLockout(ctx context.Context, userID uint64) error {
+	_, err := u.checkLockout(ctx, u.db, userID)
+
+	return err
+}
+
+// checkLockout returns how many times the user has been locked out since the
+// last successful validation, or a LockedError if the user is locked out now.
+func (u *User) checkLockout(ctx context.Context, q querier, userID uint64) (uint, error) {
+	var (
+		lockouts    uint
+		lockedUntil sql.NullTime
+	)
+	if err := u.queryRow(ctx, q, `SELECT lockouts, locked_until FROM user_lockouts `+
+		`WHERE user_id = ? FOR UPDATE;`,
+		userID).Scan(&lockouts, &lockedUntil); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	if now := u.nowFunc(); lockedUntil.Valid && lockedUntil.Time.After(now) {
+		return lockouts, &LockedError{Err: ErrUserLocked, RetryAfter: lockedUntil.Time.Sub(now)}
+	}
+
+	return lockouts, nil
+}
+
+// failOTPAttempt records an invalid attempt against the OTP. Once the maximum
+// number of attempts is reached the OTP is invalidated and the user is locked
+// out, each consecutive lockout doubles the previous window up to lockoutMax.
+func (u *User) failOTPAttempt(ctx context.Context, tx *sql.Tx, userID, otpID uint64, attempts, maxAttempts uint8,
+	lockouts uint) error {
+	if attempts < maxAttempts {
+		if _, err := u.exec(ctx, tx, `UPDATE otps SET attempts = ? WHERE id = ?;`,
+			attempts, otpID); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+		return ErrInvalidOTP
+	}
+
+	if _, err := u.exec(ctx, tx, `UPDATE otps SET attempts = ?, status = ? WHERE id = ?;`,
+		attempts, otpStatusInvalidated, otpID); err != nil {
+		return err
+	}
+
+	window, err := u.lockOut(ctx, tx, userID, lockouts)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return &LockedError{Err: ErrTooManyAttempts, RetryAfter: window}
+}
+
+// lockOut locks the user out for the window following lockouts consecutive
+// lockouts and returns the window.
+func (u *User) lockOut(ctx context.Context, tx *sql.Tx, userID uint64, lockouts uint) (time.Duration, error) {
+	window := u.lockoutBase
+	for i := uint(0); i < lockouts && window < u.lockoutMax; i++ {
+		window *= 2
+	}
+	if window > u.lockoutMax {
+		window = u.lockoutMax
+	}
+
+	if _, err := u.exec(ctx, tx, `INSERT INTO user_lockouts (user_id, lockouts, locked_until) `+
+		`VALUES (?, ?, ?) `+u.dialect.upsert("user_id", "lockouts", "locked_until")+`;`,
+		userID, lockouts+1, u.nowFunc().Add(window)); err != nil {
+		return 0, err
+	}
+
+	return window, nil
+}
+
+// reader returns the read replica, or the primary database without one.
+func (u *User) reader() *sql.DB {
+	if u.replica != nil {
+		return u.replica
+	}
+
+	return u.db
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
+	"github.com/subroll/sqetest/internal/entity"
+	"github.com/subroll/sqetest/internal/pkg/otphash"
 )
 
 func createDBMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
@@ -18,25 +20,41 @@ func createDBMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
 	return db, mock
 }
 
+func createOTPHasher(t *testing.T) *otphash.Hasher {
+	hasher, err := otphash.NewHasher("v1", map[string]string{"v1": "fake-pepper-0123456789"})
+	assert.NoError(t, err)
+
+	return hasher
+}
+
 func TestNewUser(t *testing.T) {
 	db, _ := createDBMock(t)
+	replica, _ := createDBMock(t)
 	nowFunc := func() time.Time {
 		return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
 	}
 
+	hasher := createOTPHasher(t)
+
 	exp := &User{
-		db:      db,
-		nowFunc: nowFunc,
+		db:        db,
+		replica:   replica,
+		otpHasher: hasher,
+		nowFunc:   nowFunc,
 	}
 
 	got := NewUser(Dependencies{
-		DB:      db,
-		NowFunc: nowFunc,
+		DB:        db,
+		ReplicaDB: replica,
+		OTPHasher: hasher,
+		NowFunc:   nowFunc,
 	})
 
 	assert.NotNil(t, exp, got)
 	assert.NotNil(t, exp.nowFunc, got.nowFunc)
 	assert.Equal(t, exp.db, got.db)
+	assert.Equal(t, exp.replica, got.replica)
+	assert.Equal(t, exp.otpHasher, got.otpHasher)
 }
 
 func TestUser_GetUserIDByUUID(t *testing.T) {
@@ -62,7 +80,7 @@ func TestUser_GetUserIDByUUID(t *testing.T) {
 				db, mock := createDBMock(t)
 
 				mock.
-					ExpectQuery(`SELECT id FROM users WHERE uuid = \?;`).
+					ExpectQuery(`SELECT id FROM users WHERE uuid = \? AND disabled_at IS NULL;`).
 					WithArgs("fake-uuid").
 					WillReturnError(errors.New("fake error"))
 
@@ -83,7 +101,7 @@ func TestUser_GetUserIDByUUID(t *testing.T) {
 				db, mock := createDBMock(t)
 
 				mock.
-					ExpectQuery(`SELECT id FROM users WHERE uuid = \?;`).
+					ExpectQuery(`SELECT id FROM users WHERE uuid = \? AND disabled_at IS NULL;`).
 					WithArgs("fake-uuid").
 					WillReturnError(sql.ErrNoRows)
 
@@ -104,7 +122,7 @@ func TestUser_GetUserIDByUUID(t *testing.T) {
 				db, mock := createDBMock(t)
 
 				mock.
-					ExpectQuery(`SELECT id FROM users WHERE uuid = \?;`).
+					ExpectQuery(`SELECT id FROM users WHERE uuid = \? AND disabled_at IS NULL;`).
 					WithArgs("fake-uuid").
 					WillReturnRows(
 						sqlmock.NewRows([]string{"id"}).
@@ -121,6 +139,31 @@ func TestUser_GetUserIDByUUID(t *testing.T) {
 					}
 			},
 		},
+		{
+			desc: "SuccessFromReplica",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, _ := createDBMock(t)
+				replica, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT id FROM users WHERE uuid = \? AND disabled_at IS NULL;`).
+					WithArgs("fake-uuid").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id"}).
+							AddRow(1))
+
+				return &User{
+						db:      db,
+						replica: replica,
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{
+						id:  1,
+						err: nil,
+					}
+			},
+		},
 	}
 
 	for _, tC := range testCases {
@@ -137,13 +180,112 @@ func TestUser_GetUserIDByUUID(t *testing.T) {
 	}
 }
 
+func TestUser_GetUserByUUID(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx  context.Context
+		uuid string
+	}
+
+	type expectation struct {
+		user entity.User
+		err  error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorSQL",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT id, uuid, name, email, phone, otp_channel FROM users WHERE uuid = \? AND disabled_at IS NULL;`).
+					WithArgs("fake-uuid").
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT id, uuid, name, email, phone, otp_channel FROM users WHERE uuid = \? AND disabled_at IS NULL;`).
+					WithArgs("fake-uuid").
+					WillReturnError(sql.ErrNoRows)
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{
+						err: ErrNotFound,
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT id, uuid, name, email, phone, otp_channel FROM users WHERE uuid = \? AND disabled_at IS NULL;`).
+					WithArgs("fake-uuid").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "uuid", "name", "email", "phone", "otp_channel"}).
+							AddRow(1, "fake-uuid", "Robert", "robert@example.com", nil, nil))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:  context.TODO(),
+						uuid: "fake-uuid",
+					}, expectation{
+						user: entity.User{
+							ID:    1,
+							UUID:  "fake-uuid",
+							Name:  "Robert",
+							Email: "robert@example.com",
+						},
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			got, err := u.GetUserByUUID(a.ctx, a.uuid)
+			assert.Equal(t, got, e.user)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
+
 func TestUser_StoreOTP(t *testing.T) {
 	t.Parallel()
 
 	type arg struct {
-		ctx            context.Context
-		userID         uint64
-		otp, requestID string
+		ctx context.Context
+		otp entity.OTP
 	}
 
 	type expectation struct {
@@ -166,15 +308,57 @@ func TestUser_StoreOTP(t *testing.T) {
 				return &User{
 						db: db,
 					}, arg{
-						ctx:       context.TODO(),
-						userID:    1,
-						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						otp: entity.OTP{
+							UserID:      1,
+							Code:        "xxxxx",
+							Purpose:     "login",
+							RequestID:   "fake-request-id",
+							ChallengeID: "fake-challenge-id",
+							TTL:         5 * time.Minute,
+							MaxAttempts: 3,
+						},
 					}, expectation{
 						err: errors.New("fake error"),
 					}
 			},
 		},
+		{
+			desc: "ErrorUserLocked",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"lockouts", "locked_until"}).
+							AddRow(1, time.Date(2024, time.January, 1, 0, 3, 0, 0, time.Local)))
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx: context.TODO(),
+						otp: entity.OTP{
+							UserID:      1,
+							Code:        "xxxxx",
+							Purpose:     "login",
+							RequestID:   "fake-request-id",
+							ChallengeID: "fake-challenge-id",
+							TTL:         5 * time.Minute,
+							MaxAttempts: 3,
+						},
+					}, expectation{
+						err: &LockedError{Err: ErrUserLocked, RetryAfter: 2 * time.Minute},
+					}
+			},
+		},
 		{
 			desc: "ErrorGetOTPData",
 			mockFn: func(*testing.T) (*User, arg, expectation) {
@@ -184,17 +368,28 @@ func TestUser_StoreOTP(t *testing.T) {
 					ExpectBegin()
 
 				mock.
-					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND status = \? FOR UPDATE;`).
-					WithArgs(uint64(1), otpStatusUnused).
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
 					WillReturnError(errors.New("fake error"))
 
 				return &User{
 						db: db,
 					}, arg{
-						ctx:       context.TODO(),
-						userID:    1,
-						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						otp: entity.OTP{
+							UserID:      1,
+							Code:        "xxxxx",
+							Purpose:     "login",
+							RequestID:   "fake-request-id",
+							ChallengeID: "fake-challenge-id",
+							TTL:         5 * time.Minute,
+							MaxAttempts: 3,
+						},
 					}, expectation{
 						err: errors.New("fake error"),
 					}
@@ -209,8 +404,13 @@ func TestUser_StoreOTP(t *testing.T) {
 					ExpectBegin()
 
 				mock.
-					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND status = \? FOR UPDATE;`).
-					WithArgs(uint64(1), otpStatusUnused).
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
 					WillReturnRows(
 						sqlmock.NewRows([]string{"id", "expired_at"}).
 							AddRow(uint64(1), time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)))
@@ -220,15 +420,22 @@ func TestUser_StoreOTP(t *testing.T) {
 					WillReturnError(errors.New("fake error"))
 
 				return &User{
-						db: db,
+						db:        db,
+						otpHasher: createOTPHasher(t),
 						nowFunc: func() time.Time {
 							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
 						},
 					}, arg{
-						ctx:       context.TODO(),
-						userID:    1,
-						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						otp: entity.OTP{
+							UserID:      1,
+							Code:        "xxxxx",
+							Purpose:     "login",
+							RequestID:   "fake-request-id",
+							ChallengeID: "fake-challenge-id",
+							TTL:         5 * time.Minute,
+							MaxAttempts: 3,
+						},
 					}, expectation{
 						err: errors.New("fake error"),
 					}
@@ -243,8 +450,13 @@ func TestUser_StoreOTP(t *testing.T) {
 					ExpectBegin()
 
 				mock.
-					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND status = \? FOR UPDATE;`).
-					WithArgs(uint64(1), otpStatusUnused).
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
 					WillReturnRows(
 						sqlmock.NewRows([]string{"id", "expired_at"}).
 							AddRow(uint64(1), time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)))
@@ -253,15 +465,22 @@ func TestUser_StoreOTP(t *testing.T) {
 					ExpectCommit()
 
 				return &User{
-						db: db,
+						db:        db,
+						otpHasher: createOTPHasher(t),
 						nowFunc: func() time.Time {
 							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
 						},
 					}, arg{
-						ctx:       context.TODO(),
-						userID:    1,
-						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						otp: entity.OTP{
+							UserID:      1,
+							Code:        "xxxxx",
+							Purpose:     "login",
+							RequestID:   "fake-request-id",
+							ChallengeID: "fake-challenge-id",
+							TTL:         5 * time.Minute,
+							MaxAttempts: 3,
+						},
 					}, expectation{
 						err: ErrOTPExist,
 					}
@@ -276,8 +495,13 @@ func TestUser_StoreOTP(t *testing.T) {
 					ExpectBegin()
 
 				mock.
-					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND status = \? FOR UPDATE;`).
-					WithArgs(uint64(1), otpStatusUnused).
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
 					WillReturnRows(
 						sqlmock.NewRows([]string{"id", "expired_at"}).
 							AddRow(uint64(1), time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)))
@@ -288,15 +512,22 @@ func TestUser_StoreOTP(t *testing.T) {
 					WillReturnError(errors.New("fake error"))
 
 				return &User{
-						db: db,
+						db:        db,
+						otpHasher: createOTPHasher(t),
 						nowFunc: func() time.Time {
 							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
 						},
 					}, arg{
-						ctx:       context.TODO(),
-						userID:    1,
-						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						otp: entity.OTP{
+							UserID:      1,
+							Code:        "xxxxx",
+							Purpose:     "login",
+							RequestID:   "fake-request-id",
+							ChallengeID: "fake-challenge-id",
+							TTL:         5 * time.Minute,
+							MaxAttempts: 3,
+						},
 					}, expectation{
 						err: errors.New("fake error"),
 					}
@@ -311,8 +542,13 @@ func TestUser_StoreOTP(t *testing.T) {
 					ExpectBegin()
 
 				mock.
-					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND status = \? FOR UPDATE;`).
-					WithArgs(uint64(1), otpStatusUnused).
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
 					WillReturnRows(
 						sqlmock.NewRows([]string{"id", "expired_at"}).
 							AddRow(uint64(1), time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)))
@@ -323,20 +559,27 @@ func TestUser_StoreOTP(t *testing.T) {
 					WillReturnResult(sqlmock.NewResult(1, 1))
 
 				mock.
-					ExpectExec(`INSERT INTO otps \(user_id, otp, request_id, expired_at\) VALUES \(\?, \?, \?, \?\);`).
-					WithArgs(uint64(1), "xxxxx", "fake-request-id", time.Date(2024, time.January, 1, 0, 6, 0, 0, time.Local)).
+					ExpectExec(`INSERT INTO otps \(user_id, otp, otp_key_id, purpose, request_id, challenge_id, binding, max_attempts, last_sent_at, expired_at\) VALUES \(\?, \?, \?, \?, \?, \?, \?, \?, \?, \?\);`).
+					WithArgs(uint64(1), "53750a1a8d215ef0b0f1e7dba73639ac6860577f71771e3c397e6da994ed7450", "v1", "login", "fake-request-id", "fake-challenge-id", "", uint8(3), time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), time.Date(2024, time.January, 1, 0, 6, 0, 0, time.Local)).
 					WillReturnError(errors.New("fake error"))
 
 				return &User{
-						db: db,
+						db:        db,
+						otpHasher: createOTPHasher(t),
 						nowFunc: func() time.Time {
 							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
 						},
 					}, arg{
-						ctx:       context.TODO(),
-						userID:    1,
-						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						otp: entity.OTP{
+							UserID:      1,
+							Code:        "xxxxx",
+							Purpose:     "login",
+							RequestID:   "fake-request-id",
+							ChallengeID: "fake-challenge-id",
+							TTL:         5 * time.Minute,
+							MaxAttempts: 3,
+						},
 					}, expectation{
 						err: errors.New("fake error"),
 					}
@@ -351,8 +594,13 @@ func TestUser_StoreOTP(t *testing.T) {
 					ExpectBegin()
 
 				mock.
-					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND status = \? FOR UPDATE;`).
-					WithArgs(uint64(1), otpStatusUnused).
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
 					WillReturnRows(
 						sqlmock.NewRows([]string{"id", "expired_at"}).
 							AddRow(uint64(1), time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)))
@@ -363,8 +611,8 @@ func TestUser_StoreOTP(t *testing.T) {
 					WillReturnResult(sqlmock.NewResult(1, 1))
 
 				mock.
-					ExpectExec(`INSERT INTO otps \(user_id, otp, request_id, expired_at\) VALUES \(\?, \?, \?, \?\);`).
-					WithArgs(uint64(1), "xxxxx", "fake-request-id", time.Date(2024, time.January, 1, 0, 6, 0, 0, time.Local)).
+					ExpectExec(`INSERT INTO otps \(user_id, otp, otp_key_id, purpose, request_id, challenge_id, binding, max_attempts, last_sent_at, expired_at\) VALUES \(\?, \?, \?, \?, \?, \?, \?, \?, \?, \?\);`).
+					WithArgs(uint64(1), "53750a1a8d215ef0b0f1e7dba73639ac6860577f71771e3c397e6da994ed7450", "v1", "login", "fake-request-id", "fake-challenge-id", "", uint8(3), time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), time.Date(2024, time.January, 1, 0, 6, 0, 0, time.Local)).
 					WillReturnResult(sqlmock.NewResult(2, 1))
 
 				mock.
@@ -372,15 +620,22 @@ func TestUser_StoreOTP(t *testing.T) {
 					WillReturnError(errors.New("fake error"))
 
 				return &User{
-						db: db,
+						db:        db,
+						otpHasher: createOTPHasher(t),
 						nowFunc: func() time.Time {
 							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
 						},
 					}, arg{
-						ctx:       context.TODO(),
-						userID:    1,
-						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						otp: entity.OTP{
+							UserID:      1,
+							Code:        "xxxxx",
+							Purpose:     "login",
+							RequestID:   "fake-request-id",
+							ChallengeID: "fake-challenge-id",
+							TTL:         5 * time.Minute,
+							MaxAttempts: 3,
+						},
 					}, expectation{
 						err: errors.New("fake error"),
 					}
@@ -395,8 +650,13 @@ func TestUser_StoreOTP(t *testing.T) {
 					ExpectBegin()
 
 				mock.
-					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND status = \? FOR UPDATE;`).
-					WithArgs(uint64(1), otpStatusUnused).
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
 					WillReturnRows(
 						sqlmock.NewRows([]string{"id", "expired_at"}).
 							AddRow(uint64(1), time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)))
@@ -407,23 +667,30 @@ func TestUser_StoreOTP(t *testing.T) {
 					WillReturnResult(sqlmock.NewResult(1, 1))
 
 				mock.
-					ExpectExec(`INSERT INTO otps \(user_id, otp, request_id, expired_at\) VALUES \(\?, \?, \?, \?\);`).
-					WithArgs(uint64(1), "xxxxx", "fake-request-id", time.Date(2024, time.January, 1, 0, 6, 0, 0, time.Local)).
+					ExpectExec(`INSERT INTO otps \(user_id, otp, otp_key_id, purpose, request_id, challenge_id, binding, max_attempts, last_sent_at, expired_at\) VALUES \(\?, \?, \?, \?, \?, \?, \?, \?, \?, \?\);`).
+					WithArgs(uint64(1), "53750a1a8d215ef0b0f1e7dba73639ac6860577f71771e3c397e6da994ed7450", "v1", "login", "fake-request-id", "fake-challenge-id", "", uint8(3), time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), time.Date(2024, time.January, 1, 0, 6, 0, 0, time.Local)).
 					WillReturnResult(sqlmock.NewResult(2, 1))
 
 				mock.
 					ExpectCommit()
 
 				return &User{
-						db: db,
+						db:        db,
+						otpHasher: createOTPHasher(t),
 						nowFunc: func() time.Time {
 							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
 						},
 					}, arg{
-						ctx:       context.TODO(),
-						userID:    1,
-						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						otp: entity.OTP{
+							UserID:      1,
+							Code:        "xxxxx",
+							Purpose:     "login",
+							RequestID:   "fake-request-id",
+							ChallengeID: "fake-challenge-id",
+							TTL:         5 * time.Minute,
+							MaxAttempts: 3,
+						},
 					}, expectation{}
 			},
 		},
@@ -436,25 +703,35 @@ func TestUser_StoreOTP(t *testing.T) {
 
 			u, a, e := tC.mockFn(t)
 
-			err := u.StoreOTP(a.ctx, a.userID, a.otp, a.requestID)
+			err := u.StoreOTP(a.ctx, a.otp)
 			assert.Equal(t, err, e.err)
 		})
 	}
 }
 
-func TestUser_UpdateOTPStatus(t *testing.T) {
+func TestUser_ResendOTP(t *testing.T) {
 	t.Parallel()
 
 	type arg struct {
-		ctx            context.Context
-		userID         uint64
-		otp, requestID string
+		ctx context.Context
+		otp entity.OTP
 	}
 
 	type expectation struct {
+		otp entity.OTP
 		err error
 	}
 
+	resendOTP := entity.OTP{
+		UserID:         1,
+		Code:           "xxxxx",
+		Purpose:        "login",
+		TTL:            5 * time.Minute,
+		MaxAttempts:    3,
+		ResendCooldown: 30 * time.Second,
+		MaxResends:     2,
+	}
+
 	testCases := []struct {
 		desc   string
 		mockFn func(*testing.T) (*User, arg, expectation)
@@ -469,19 +746,21 @@ func TestUser_UpdateOTPStatus(t *testing.T) {
 					WillReturnError(errors.New("fake error"))
 
 				return &User{
-						db: db,
+						db:        db,
+						otpHasher: createOTPHasher(t),
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)
+						},
 					}, arg{
-						ctx:       context.TODO(),
-						userID:    1,
-						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						otp: resendOTP,
 					}, expectation{
 						err: errors.New("fake error"),
 					}
 			},
 		},
 		{
-			desc: "ErrorOTPNotFound",
+			desc: "ErrorUserLocked",
 			mockFn: func(*testing.T) (*User, arg, expectation) {
 				db, mock := createDBMock(t)
 
@@ -489,24 +768,28 @@ func TestUser_UpdateOTPStatus(t *testing.T) {
 					ExpectBegin()
 
 				mock.
-					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND otp = \? AND status = \? FOR UPDATE;`).
-					WithArgs(uint64(1), "xxxxx", otpStatusUnused).
-					WillReturnError(sql.ErrNoRows)
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"lockouts", "locked_until"}).
+							AddRow(1, time.Date(2024, time.January, 1, 0, 3, 0, 0, time.Local)))
 
 				return &User{
-						db: db,
+						db:        db,
+						otpHasher: createOTPHasher(t),
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)
+						},
 					}, arg{
-						ctx:       context.TODO(),
-						userID:    1,
-						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						otp: resendOTP,
 					}, expectation{
-						err: ErrInvalidOTP,
+						err: &LockedError{Err: ErrUserLocked, RetryAfter: time.Minute},
 					}
 			},
 		},
 		{
-			desc: "ErrorGetOTPData",
+			desc: "ErrorOTPNotFound",
 			mockFn: func(*testing.T) (*User, arg, expectation) {
 				db, mock := createDBMock(t)
 
@@ -514,24 +797,31 @@ func TestUser_UpdateOTPStatus(t *testing.T) {
 					ExpectBegin()
 
 				mock.
-					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND otp = \? AND status = \? FOR UPDATE;`).
-					WithArgs(uint64(1), "xxxxx", otpStatusUnused).
-					WillReturnError(errors.New("fake error"))
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, request_id, challenge_id, resend_count, last_sent_at, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
+					WillReturnError(sql.ErrNoRows)
 
 				return &User{
-						db: db,
+						db:        db,
+						otpHasher: createOTPHasher(t),
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)
+						},
 					}, arg{
-						ctx:       context.TODO(),
-						userID:    1,
-						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						otp: resendOTP,
 					}, expectation{
-						err: errors.New("fake error"),
+						err: ErrOTPNotFound,
 					}
 			},
 		},
 		{
-			desc: "ErrorCommitIfOTPExpired",
+			desc: "ErrorGetOTPData",
 			mockFn: func(*testing.T) (*User, arg, expectation) {
 				db, mock := createDBMock(t)
 
@@ -539,33 +829,31 @@ func TestUser_UpdateOTPStatus(t *testing.T) {
 					ExpectBegin()
 
 				mock.
-					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND otp = \? AND status = \? FOR UPDATE;`).
-					WithArgs(uint64(1), "xxxxx", otpStatusUnused).
-					WillReturnRows(
-						sqlmock.NewRows([]string{"id", "expired_at"}).
-							AddRow(uint64(1), time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)))
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
 
 				mock.
-					ExpectCommit().
+					ExpectQuery(`SELECT id, request_id, challenge_id, resend_count, last_sent_at, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
 					WillReturnError(errors.New("fake error"))
 
 				return &User{
-						db: db,
+						db:        db,
+						otpHasher: createOTPHasher(t),
 						nowFunc: func() time.Time {
-							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+							return time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)
 						},
 					}, arg{
-						ctx:       context.TODO(),
-						userID:    1,
-						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						otp: resendOTP,
 					}, expectation{
 						err: errors.New("fake error"),
 					}
 			},
 		},
 		{
-			desc: "ErrorOTPAlreadyExpired",
+			desc: "ErrorOTPExpired",
 			mockFn: func(*testing.T) (*User, arg, expectation) {
 				db, mock := createDBMock(t)
 
@@ -573,32 +861,33 @@ func TestUser_UpdateOTPStatus(t *testing.T) {
 					ExpectBegin()
 
 				mock.
-					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND otp = \? AND status = \? FOR UPDATE;`).
-					WithArgs(uint64(1), "xxxxx", otpStatusUnused).
-					WillReturnRows(
-						sqlmock.NewRows([]string{"id", "expired_at"}).
-							AddRow(uint64(1), time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)))
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
 
 				mock.
-					ExpectCommit()
+					ExpectQuery(`SELECT id, request_id, challenge_id, resend_count, last_sent_at, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "request_id", "challenge_id", "resend_count", "last_sent_at", "expired_at"}).
+							AddRow(uint64(1), "fake-request-id", "fake-challenge-id", 0, time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local), time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)))
 
 				return &User{
-						db: db,
+						db:        db,
+						otpHasher: createOTPHasher(t),
 						nowFunc: func() time.Time {
-							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+							return time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)
 						},
 					}, arg{
-						ctx:       context.TODO(),
-						userID:    1,
-						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						otp: resendOTP,
 					}, expectation{
 						err: ErrOTPExpired,
 					}
 			},
 		},
 		{
-			desc: "ErrorUpdatingOTPStatus",
+			desc: "ErrorResendLimit",
 			mockFn: func(*testing.T) (*User, arg, expectation) {
 				db, mock := createDBMock(t)
 
@@ -606,34 +895,33 @@ func TestUser_UpdateOTPStatus(t *testing.T) {
 					ExpectBegin()
 
 				mock.
-					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND otp = \? AND status = \? FOR UPDATE;`).
-					WithArgs(uint64(1), "xxxxx", otpStatusUnused).
-					WillReturnRows(
-						sqlmock.NewRows([]string{"id", "expired_at"}).
-							AddRow(uint64(1), time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)))
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
 
 				mock.
-					ExpectExec(`UPDATE otps SET status = \? WHERE id = \?;`).
-					WithArgs(otpStatusUsed, uint64(1)).
-					WillReturnError(errors.New("fake error"))
+					ExpectQuery(`SELECT id, request_id, challenge_id, resend_count, last_sent_at, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "request_id", "challenge_id", "resend_count", "last_sent_at", "expired_at"}).
+							AddRow(uint64(1), "fake-request-id", "fake-challenge-id", 2, time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local), time.Date(2024, time.January, 1, 0, 5, 0, 0, time.Local)))
 
 				return &User{
-						db: db,
+						db:        db,
+						otpHasher: createOTPHasher(t),
 						nowFunc: func() time.Time {
-							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+							return time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)
 						},
 					}, arg{
-						ctx:       context.TODO(),
-						userID:    1,
-						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						otp: resendOTP,
 					}, expectation{
-						err: errors.New("fake error"),
+						err: ErrResendLimit,
 					}
 			},
 		},
 		{
-			desc: "ErrorCommittingOTPStatus",
+			desc: "ErrorResendCooldown",
 			mockFn: func(*testing.T) (*User, arg, expectation) {
 				db, mock := createDBMock(t)
 
@@ -641,23 +929,667 @@ func TestUser_UpdateOTPStatus(t *testing.T) {
 					ExpectBegin()
 
 				mock.
-					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND otp = \? AND status = \? FOR UPDATE;`).
-					WithArgs(uint64(1), "xxxxx", otpStatusUnused).
-					WillReturnRows(
-						sqlmock.NewRows([]string{"id", "expired_at"}).
-							AddRow(uint64(1), time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)))
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
 
 				mock.
-					ExpectExec(`UPDATE otps SET status = \? WHERE id = \?;`).
-					WithArgs(otpStatusUsed, uint64(1)).
+					ExpectQuery(`SELECT id, request_id, challenge_id, resend_count, last_sent_at, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "request_id", "challenge_id", "resend_count", "last_sent_at", "expired_at"}).
+							AddRow(uint64(1), "fake-request-id", "fake-challenge-id", 0, time.Date(2024, time.January, 1, 0, 1, 45, 0, time.Local), time.Date(2024, time.January, 1, 0, 5, 0, 0, time.Local)))
+
+				return &User{
+						db:        db,
+						otpHasher: createOTPHasher(t),
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx: context.TODO(),
+						otp: resendOTP,
+					}, expectation{
+						err: &CooldownError{RetryAfter: 15 * time.Second},
+					}
+			},
+		},
+		{
+			desc: "ErrorUpdatingOTP",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, request_id, challenge_id, resend_count, last_sent_at, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "request_id", "challenge_id", "resend_count", "last_sent_at", "expired_at"}).
+							AddRow(uint64(1), "fake-request-id", "fake-challenge-id", 0, time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), time.Date(2024, time.January, 1, 0, 5, 0, 0, time.Local)))
+
+				mock.
+					ExpectExec(`UPDATE otps SET otp = \?, otp_key_id = \?, resend_count = \?, last_sent_at = \?, expired_at = \? WHERE id = \?;`).
+					WithArgs("53750a1a8d215ef0b0f1e7dba73639ac6860577f71771e3c397e6da994ed7450", "v1", uint8(1), time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local), time.Date(2024, time.January, 1, 0, 7, 0, 0, time.Local), uint64(1)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db:        db,
+						otpHasher: createOTPHasher(t),
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx: context.TODO(),
+						otp: resendOTP,
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorCommittingOTP",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, request_id, challenge_id, resend_count, last_sent_at, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "request_id", "challenge_id", "resend_count", "last_sent_at", "expired_at"}).
+							AddRow(uint64(1), "fake-request-id", "fake-challenge-id", 0, time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), time.Date(2024, time.January, 1, 0, 5, 0, 0, time.Local)))
+
+				mock.
+					ExpectExec(`UPDATE otps SET otp = \?, otp_key_id = \?, resend_count = \?, last_sent_at = \?, expired_at = \? WHERE id = \?;`).
+					WithArgs("53750a1a8d215ef0b0f1e7dba73639ac6860577f71771e3c397e6da994ed7450", "v1", uint8(1), time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local), time.Date(2024, time.January, 1, 0, 7, 0, 0, time.Local), uint64(1)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.
+					ExpectCommit().
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db:        db,
+						otpHasher: createOTPHasher(t),
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx: context.TODO(),
+						otp: resendOTP,
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "SuccessResendingOTP",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, request_id, challenge_id, resend_count, last_sent_at, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "request_id", "challenge_id", "resend_count", "last_sent_at", "expired_at"}).
+							AddRow(uint64(1), "fake-request-id", "fake-challenge-id", 0, time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local), time.Date(2024, time.January, 1, 0, 5, 0, 0, time.Local)))
+
+				mock.
+					ExpectExec(`UPDATE otps SET otp = \?, otp_key_id = \?, resend_count = \?, last_sent_at = \?, expired_at = \? WHERE id = \?;`).
+					WithArgs("53750a1a8d215ef0b0f1e7dba73639ac6860577f71771e3c397e6da994ed7450", "v1", uint8(1), time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local), time.Date(2024, time.January, 1, 0, 7, 0, 0, time.Local), uint64(1)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.
+					ExpectCommit()
+
+				return &User{
+						db:        db,
+						otpHasher: createOTPHasher(t),
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx: context.TODO(),
+						otp: resendOTP,
+					}, expectation{
+						otp: entity.OTP{
+							UserID:         1,
+							Code:           "xxxxx",
+							Purpose:        "login",
+							RequestID:      "fake-request-id",
+							ChallengeID:    "fake-challenge-id",
+							TTL:            5 * time.Minute,
+							MaxAttempts:    3,
+							ResendCooldown: 30 * time.Second,
+							MaxResends:     2,
+							ResendCount:    1,
+						},
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			got, err := u.ResendOTP(a.ctx, a.otp)
+			assert.Equal(t, got, e.otp)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
+
+func TestUser_UpdateOTPStatus(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx                     context.Context
+		userID                  uint64
+		otp, purpose, challengeID, binding string
+	}
+
+	type expectation struct {
+		err error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorStartTx",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin().
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:       context.TODO(),
+						userID:    1,
+						otp:       "xxxxx",
+						purpose:   "login",
+						challengeID: "fake-challenge-id",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorOTPNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, otp, otp_key_id, challenge_id, binding, attempts, max_attempts, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
+					WillReturnError(sql.ErrNoRows)
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:       context.TODO(),
+						userID:    1,
+						otp:       "xxxxx",
+						purpose:   "login",
+						challengeID: "fake-challenge-id",
+					}, expectation{
+						err: ErrInvalidOTP,
+					}
+			},
+		},
+		{
+			desc: "ErrorChallengeMismatch",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, otp, otp_key_id, challenge_id, binding, attempts, max_attempts, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "otp", "otp_key_id", "challenge_id", "binding", "attempts", "max_attempts", "expired_at"}).
+							AddRow(uint64(1), "53750a1a8d215ef0b0f1e7dba73639ac6860577f71771e3c397e6da994ed7450", "v1", "other-challenge-id", "", 0, 3, time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)))
+
+				return &User{
+						db:        db,
+						otpHasher: createOTPHasher(t),
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:       context.TODO(),
+						userID:    1,
+						otp:       "xxxxx",
+						purpose:   "login",
+						challengeID: "fake-challenge-id",
+						binding:   "",
+					}, expectation{
+						err: ErrChallengeMismatch,
+					}
+			},
+		},
+		{
+			desc: "ErrorBindingMismatch",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, otp, otp_key_id, challenge_id, binding, attempts, max_attempts, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "otp", "otp_key_id", "challenge_id", "binding", "attempts", "max_attempts", "expired_at"}).
+							AddRow(uint64(1), "53750a1a8d215ef0b0f1e7dba73639ac6860577f71771e3c397e6da994ed7450", "v1", "fake-challenge-id", "fake-binding", 0, 3, time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)))
+
+				return &User{
+						db:        db,
+						otpHasher: createOTPHasher(t),
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:       context.TODO(),
+						userID:    1,
+						otp:       "xxxxx",
+						purpose:   "login",
+						challengeID: "fake-challenge-id",
+						binding:   "other-binding",
+					}, expectation{
+						err: ErrBindingMismatch,
+					}
+			},
+		},
+		{
+			desc: "ErrorGetOTPData",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, otp, otp_key_id, challenge_id, binding, attempts, max_attempts, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:       context.TODO(),
+						userID:    1,
+						otp:       "xxxxx",
+						purpose:   "login",
+						challengeID: "fake-challenge-id",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorCheckingLockout",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db: db,
+					}, arg{
+						ctx:       context.TODO(),
+						userID:    1,
+						otp:       "xxxxx",
+						purpose:   "login",
+						challengeID: "fake-challenge-id",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorUserLocked",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"lockouts", "locked_until"}).
+							AddRow(1, time.Date(2024, time.January, 1, 0, 3, 0, 0, time.Local)))
+
+				return &User{
+						db: db,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:       context.TODO(),
+						userID:    1,
+						otp:       "xxxxx",
+						purpose:   "login",
+						challengeID: "fake-challenge-id",
+					}, expectation{
+						err: &LockedError{Err: ErrUserLocked, RetryAfter: 2 * time.Minute},
+					}
+			},
+		},
+		{
+			desc: "ErrorOTPMismatch",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, otp, otp_key_id, challenge_id, binding, attempts, max_attempts, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "otp", "otp_key_id", "challenge_id", "binding", "attempts", "max_attempts", "expired_at"}).
+							AddRow(uint64(1), "53750a1a8d215ef0b0f1e7dba73639ac6860577f71771e3c397e6da994ed7450", "v1", "fake-challenge-id", "", 0, 3, time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)))
+
+				mock.
+					ExpectExec(`UPDATE otps SET attempts = \? WHERE id = \?;`).
+					WithArgs(uint8(1), uint64(1)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.
+					ExpectCommit()
+
+				return &User{
+						db:        db,
+						otpHasher: createOTPHasher(t),
+					}, arg{
+						ctx:       context.TODO(),
+						userID:    1,
+						otp:       "yyyyy",
+						purpose:   "login",
+						challengeID: "fake-challenge-id",
+					}, expectation{
+						err: ErrInvalidOTP,
+					}
+			},
+		},
+		{
+			desc: "ErrorTooManyAttempts",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"lockouts", "locked_until"}).
+							AddRow(2, time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)))
+
+				mock.
+					ExpectQuery(`SELECT id, otp, otp_key_id, challenge_id, binding, attempts, max_attempts, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "otp", "otp_key_id", "challenge_id", "binding", "attempts", "max_attempts", "expired_at"}).
+							AddRow(uint64(1), "53750a1a8d215ef0b0f1e7dba73639ac6860577f71771e3c397e6da994ed7450", "v1", "fake-challenge-id", "", 2, 3, time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)))
+
+				mock.
+					ExpectExec(`UPDATE otps SET attempts = \?, status = \? WHERE id = \?;`).
+					WithArgs(uint8(3), otpStatusInvalidated, uint64(1)).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 
+				mock.
+					ExpectExec(`INSERT INTO user_lockouts \(user_id, lockouts, locked_until\) VALUES \(\?, \?, \?\) ON DUPLICATE KEY UPDATE lockouts = VALUES\(lockouts\), locked_until = VALUES\(locked_until\);`).
+					WithArgs(uint64(1), uint(3), time.Date(2024, time.January, 1, 0, 5, 0, 0, time.Local)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.
+					ExpectCommit()
+
+				return &User{
+						db:          db,
+						otpHasher:   createOTPHasher(t),
+						lockoutBase: time.Minute,
+						lockoutMax:  time.Hour,
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:       context.TODO(),
+						userID:    1,
+						otp:       "yyyyy",
+						purpose:   "login",
+						challengeID: "fake-challenge-id",
+					}, expectation{
+						err: &LockedError{Err: ErrTooManyAttempts, RetryAfter: 4 * time.Minute},
+					}
+			},
+		},
+		{
+			desc: "ErrorCommitIfOTPExpired",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, otp, otp_key_id, challenge_id, binding, attempts, max_attempts, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "otp", "otp_key_id", "challenge_id", "binding", "attempts", "max_attempts", "expired_at"}).
+							AddRow(uint64(1), "53750a1a8d215ef0b0f1e7dba73639ac6860577f71771e3c397e6da994ed7450", "v1", "fake-challenge-id", "", 0, 3, time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)))
+
 				mock.
 					ExpectCommit().
 					WillReturnError(errors.New("fake error"))
 
 				return &User{
-						db: db,
+						db:        db,
+						otpHasher: createOTPHasher(t),
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:       context.TODO(),
+						userID:    1,
+						otp:       "xxxxx",
+						purpose:   "login",
+						challengeID: "fake-challenge-id",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorOTPAlreadyExpired",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, otp, otp_key_id, challenge_id, binding, attempts, max_attempts, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "otp", "otp_key_id", "challenge_id", "binding", "attempts", "max_attempts", "expired_at"}).
+							AddRow(uint64(1), "53750a1a8d215ef0b0f1e7dba73639ac6860577f71771e3c397e6da994ed7450", "v1", "fake-challenge-id", "", 0, 3, time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)))
+
+				mock.
+					ExpectCommit()
+
+				return &User{
+						db:        db,
+						otpHasher: createOTPHasher(t),
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:       context.TODO(),
+						userID:    1,
+						otp:       "xxxxx",
+						purpose:   "login",
+						challengeID: "fake-challenge-id",
+					}, expectation{
+						err: ErrOTPExpired,
+					}
+			},
+		},
+		{
+			desc: "ErrorUpdatingOTPStatus",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, otp, otp_key_id, challenge_id, binding, attempts, max_attempts, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "otp", "otp_key_id", "challenge_id", "binding", "attempts", "max_attempts", "expired_at"}).
+							AddRow(uint64(1), "53750a1a8d215ef0b0f1e7dba73639ac6860577f71771e3c397e6da994ed7450", "v1", "fake-challenge-id", "", 0, 3, time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)))
+
+				mock.
+					ExpectExec(`UPDATE otps SET status = \? WHERE id = \?;`).
+					WithArgs(otpStatusUsed, uint64(1)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db:        db,
+						otpHasher: createOTPHasher(t),
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:       context.TODO(),
+						userID:    1,
+						otp:       "xxxxx",
+						purpose:   "login",
+						challengeID: "fake-challenge-id",
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorCommittingOTPStatus",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, otp, otp_key_id, challenge_id, binding, attempts, max_attempts, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "otp", "otp_key_id", "challenge_id", "binding", "attempts", "max_attempts", "expired_at"}).
+							AddRow(uint64(1), "53750a1a8d215ef0b0f1e7dba73639ac6860577f71771e3c397e6da994ed7450", "v1", "fake-challenge-id", "", 0, 3, time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)))
+
+				mock.
+					ExpectExec(`UPDATE otps SET status = \? WHERE id = \?;`).
+					WithArgs(otpStatusUsed, uint64(1)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.
+					ExpectCommit().
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db:        db,
+						otpHasher: createOTPHasher(t),
 						nowFunc: func() time.Time {
 							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
 						},
@@ -665,7 +1597,8 @@ func TestUser_UpdateOTPStatus(t *testing.T) {
 						ctx:       context.TODO(),
 						userID:    1,
 						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						purpose:   "login",
+						challengeID: "fake-challenge-id",
 					}, expectation{
 						err: errors.New("fake error"),
 					}
@@ -680,11 +1613,16 @@ func TestUser_UpdateOTPStatus(t *testing.T) {
 					ExpectBegin()
 
 				mock.
-					ExpectQuery(`SELECT id, expired_at FROM otps WHERE user_id = \? AND otp = \? AND status = \? FOR UPDATE;`).
-					WithArgs(uint64(1), "xxxxx", otpStatusUnused).
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				mock.
+					ExpectQuery(`SELECT id, otp, otp_key_id, challenge_id, binding, attempts, max_attempts, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
 					WillReturnRows(
-						sqlmock.NewRows([]string{"id", "expired_at"}).
-							AddRow(uint64(1), time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)))
+						sqlmock.NewRows([]string{"id", "otp", "otp_key_id", "challenge_id", "binding", "attempts", "max_attempts", "expired_at"}).
+							AddRow(uint64(1), "53750a1a8d215ef0b0f1e7dba73639ac6860577f71771e3c397e6da994ed7450", "v1", "fake-challenge-id", "", 0, 3, time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)))
 
 				mock.
 					ExpectExec(`UPDATE otps SET status = \? WHERE id = \?;`).
@@ -695,7 +1633,58 @@ func TestUser_UpdateOTPStatus(t *testing.T) {
 					ExpectCommit()
 
 				return &User{
-						db: db,
+						db:        db,
+						otpHasher: createOTPHasher(t),
+						nowFunc: func() time.Time {
+							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+						},
+					}, arg{
+						ctx:       context.TODO(),
+						userID:    1,
+						otp:       "xxxxx",
+						purpose:   "login",
+						challengeID: "fake-challenge-id",
+					}, expectation{}
+			},
+		},
+		{
+			desc: "SuccessResettingLockout",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectBegin()
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"lockouts", "locked_until"}).
+							AddRow(1, time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)))
+
+				mock.
+					ExpectQuery(`SELECT id, otp, otp_key_id, challenge_id, binding, attempts, max_attempts, expired_at FROM otps WHERE user_id = \? AND purpose = \? AND status = \? FOR UPDATE;`).
+					WithArgs(uint64(1), "login", otpStatusUnused).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "otp", "otp_key_id", "challenge_id", "binding", "attempts", "max_attempts", "expired_at"}).
+							AddRow(uint64(1), "53750a1a8d215ef0b0f1e7dba73639ac6860577f71771e3c397e6da994ed7450", "v1", "fake-challenge-id", "", 0, 3, time.Date(2024, time.January, 1, 0, 2, 0, 0, time.Local)))
+
+				mock.
+					ExpectExec(`UPDATE otps SET status = \? WHERE id = \?;`).
+					WithArgs(otpStatusUsed, uint64(1)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.
+					ExpectExec(`DELETE FROM user_lockouts WHERE user_id = \?;`).
+					WithArgs(uint64(1)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.
+					ExpectCommit()
+
+				return &User{
+						db:        db,
+						otpHasher: createOTPHasher(t),
 						nowFunc: func() time.Time {
 							return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
 						},
@@ -703,7 +1692,127 @@ func TestUser_UpdateOTPStatus(t *testing.T) {
 						ctx:       context.TODO(),
 						userID:    1,
 						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						purpose:   "login",
+						challengeID: "fake-challenge-id",
+					}, expectation{}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			err := u.UpdateOTPStatus(a.ctx, a.userID, a.otp, a.purpose, a.challengeID, a.binding)
+			assert.Equal(t, err, e.err)
+		})
+	}
+}
+
+func TestUser_CheckLockout(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx    context.Context
+		userID uint64
+	}
+
+	type expectation struct {
+		err error
+	}
+
+	nowFunc := func() time.Time {
+		return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.Local)
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectation)
+	}{
+		{
+			desc: "ErrorSQL",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnError(errors.New("fake error"))
+
+				return &User{
+						db:      db,
+						nowFunc: nowFunc,
+					}, arg{
+						ctx:    context.TODO(),
+						userID: 1,
+					}, expectation{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorUserLocked",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"lockouts", "locked_until"}).
+							AddRow(1, time.Date(2024, time.January, 1, 0, 3, 0, 0, time.Local)))
+
+				return &User{
+						db:      db,
+						nowFunc: nowFunc,
+					}, arg{
+						ctx:    context.TODO(),
+						userID: 1,
+					}, expectation{
+						err: &LockedError{Err: ErrUserLocked, RetryAfter: 2 * time.Minute},
+					}
+			},
+		},
+		{
+			desc: "SuccessLockoutPassed",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"lockouts", "locked_until"}).
+							AddRow(1, time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)))
+
+				return &User{
+						db:      db,
+						nowFunc: nowFunc,
+					}, arg{
+						ctx:    context.TODO(),
+						userID: 1,
+					}, expectation{}
+			},
+		},
+		{
+			desc: "SuccessNeverLocked",
+			mockFn: func(*testing.T) (*User, arg, expectation) {
+				db, mock := createDBMock(t)
+
+				mock.
+					ExpectQuery(`SELECT lockouts, locked_until FROM user_lockouts WHERE user_id = \? FOR UPDATE;`).
+					WithArgs(uint64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"lockouts", "locked_until"}))
+
+				return &User{
+						db:      db,
+						nowFunc: nowFunc,
+					}, arg{
+						ctx:    context.TODO(),
+						userID: 1,
 					}, expectation{}
 			},
 		},
@@ -716,7 +1825,7 @@ func TestUser_UpdateOTPStatus(t *testing.T) {
 
 			u, a, e := tC.mockFn(t)
 
-			err := u.UpdateOTPStatus(a.ctx, a.userID, a.otp, a.requestID)
+			err := u.CheckLockout(a.ctx, a.userID)
 			assert.Equal(t, err, e.err)
 		})
 	}
@@ -0,0 +1,145 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+
+	"github.com/subroll/sqetest/internal/service"
+)
+
+type (
+	EmailConfig struct {
+		// Address is the host:port of the SMTP server.
+		Address  string
+		Username string
+		Password string
+		From     string
+		Subject  string
+
+		// Timeout bounds dialing and the whole SMTP exchange of a delivery on
+		// top of the deadline of its context, there is no bound when it is 0.
+		Timeout time.Duration
+	}
+
+	// Email delivers OTPs over SMTP, STARTTLS is used whenever the server
+	// offers it.
+	Email struct {
+		cfg     EmailConfig
+		host    string
+		nowFunc func() time.Time
+	}
+)
+
+func NewEmail(cfg EmailConfig) (*Email, error) {
+	host, _, err := net.SplitHostPort(cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Email{
+		cfg:     cfg,
+		host:    host,
+		nowFunc: time.Now,
+	}, nil
+}
+
+// Ping checks that the SMTP server accepts connections.
+func (e *Email) Ping(ctx context.Context) error {
+	return dial(ctx, e.cfg.Address)
+}
+
+func (e *Email) Send(ctx context.Context, msg service.Message) (string, error) {
+	if msg.Recipient.Email == "" {
+		return "", service.ErrRecipientUnreachable
+	}
+
+	ref, err := newReference()
+	if err != nil {
+		return "", err
+	}
+
+	if e.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.cfg.Timeout)
+		defer cancel()
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", e.cfg.Address)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return "", err
+		}
+	}
+
+	c, err := smtp.NewClient(conn, e.host)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: e.host}); err != nil {
+			return "", err
+		}
+	}
+
+	if e.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.host)); err != nil {
+			return "", err
+		}
+	}
+
+	if err := c.Mail(e.cfg.From); err != nil {
+		return "", err
+	}
+
+	if err := c.Rcpt(msg.Recipient.Email); err != nil {
+		return "", err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := w.Write(e.compose(ref, msg)); err != nil {
+		return "", err
+	}
+
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	if err := c.Quit(); err != nil {
+		return "", err
+	}
+
+	return ref, nil
+}
+
+func (e *Email) compose(ref string, msg service.Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.Recipient.Email)
+	fmt.Fprintf(&b, "Subject: %s\r\n", e.cfg.Subject)
+	fmt.Fprintf(&b, "Message-ID: <%s@%s>\r\n", ref, e.host)
+	fmt.Fprintf(&b, "Date: %s\r\n", e.nowFunc().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(messageText(msg.OTP))
+	b.WriteString("\r\n")
+
+	return b.Bytes()
+}
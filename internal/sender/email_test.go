@@ -0,0 +1,144 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subroll/sqetest/internal/entity"
+	"github.com/subroll/sqetest/internal/sender/sendertest"
+	"github.com/subroll/sqetest/internal/service"
+)
+
+func TestEmail_Send(t *testing.T) {
+	t.Parallel()
+
+	type expectation struct {
+		err  error
+		mail *sendertest.Mail
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*Email, *sendertest.SMTPServer, service.Message, expectation)
+	}{
+		{
+			desc: "ErrorNoEmailAddress",
+			mockFn: func(t *testing.T) (*Email, *sendertest.SMTPServer, service.Message, expectation) {
+				email, err := NewEmail(EmailConfig{Address: "127.0.0.1:25"})
+				assert.NoError(t, err)
+
+				return email, nil, service.Message{
+						Recipient: entity.User{UUID: "fake-uuid"},
+						OTP:       "12345",
+					}, expectation{
+						err: service.ErrRecipientUnreachable,
+					}
+			},
+		},
+		{
+			desc: "SuccessSendingEmail",
+			mockFn: func(t *testing.T) (*Email, *sendertest.SMTPServer, service.Message, expectation) {
+				server, err := sendertest.NewSMTPServer()
+				assert.NoError(t, err)
+
+				email, err := NewEmail(EmailConfig{
+					Address: server.Addr(),
+					From:    "otp@example.com",
+					Subject: "Your OTP",
+				})
+				assert.NoError(t, err)
+				email.nowFunc = func() time.Time {
+					return time.Date(2024, time.January, 1, 0, 1, 0, 0, time.UTC)
+				}
+
+				return email, server, service.Message{
+						Recipient: entity.User{UUID: "fake-uuid", Email: "user@example.com"},
+						OTP:       "12345",
+					}, expectation{
+						mail: &sendertest.Mail{
+							From: "otp@example.com",
+							To:   []string{"user@example.com"},
+						},
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			e, server, msg, exp := tC.mockFn(t)
+			if server != nil {
+				defer server.Close()
+			}
+
+			ref, err := e.Send(context.TODO(), msg)
+			assert.Equal(t, exp.err, err)
+			if exp.mail == nil {
+				assert.Empty(t, ref)
+				return
+			}
+
+			mails := server.Mails()
+			assert.Len(t, mails, 1)
+			assert.Equal(t, exp.mail.From, mails[0].From)
+			assert.Equal(t, exp.mail.To, mails[0].To)
+			assert.Contains(t, mails[0].Data, "Message-ID: <"+ref+"@127.0.0.1>")
+			assert.Contains(t, mails[0].Data, "Subject: Your OTP")
+			assert.Contains(t, mails[0].Data, "Your verification code is 12345.")
+		})
+	}
+}
+
+func TestEmail_SendTimeout(t *testing.T) {
+	t.Parallel()
+
+	// a server that accepts connections but never greets
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_, _ = io.Copy(io.Discard, conn)
+	}()
+
+	email, err := NewEmail(EmailConfig{Address: l.Addr().String(), Timeout: 50 * time.Millisecond})
+	assert.NoError(t, err)
+
+	start := time.Now()
+	_, err = email.Send(context.TODO(), service.Message{
+		Recipient: entity.User{UUID: "fake-uuid", Email: "user@example.com"},
+		OTP:       "12345",
+	})
+
+	var netErr net.Error
+	assert.True(t, errors.As(err, &netErr) && netErr.Timeout())
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestEmail_Ping(t *testing.T) {
+	t.Parallel()
+
+	server, err := sendertest.NewSMTPServer()
+	assert.NoError(t, err)
+
+	email, err := NewEmail(EmailConfig{Address: server.Addr()})
+	assert.NoError(t, err)
+	assert.NoError(t, email.Ping(context.TODO()))
+
+	assert.NoError(t, server.Close())
+	assert.Error(t, email.Ping(context.TODO()))
+}
@@ -0,0 +1,81 @@
+package sender
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+const messageFormat = "Your verification code is %s."
+
+func messageText(otp string) string {
+	return fmt.Sprintf(messageFormat, otp)
+}
+
+// newReference creates a random delivery reference for providers that don't
+// return one of their own.
+func newReference() (string, error) {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func checkResponse(res *http.Response) error {
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected response status: %s", res.Status)
+	}
+
+	return nil
+}
+
+// dial opens a TCP connection to address and closes it right away, it tells
+// whether a provider is reachable without delivering anything.
+func dial(ctx context.Context, address string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// urlAddress returns the host:port rawURL points to, the port defaults to the
+// one of its scheme.
+func urlAddress(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	if u.Port() != "" {
+		return u.Host, nil
+	}
+
+	switch u.Scheme {
+	case "http":
+		return net.JoinHostPort(u.Hostname(), "80"), nil
+	case "https":
+		return net.JoinHostPort(u.Hostname(), "443"), nil
+	default:
+		return "", fmt.Errorf("no port in url: %s", rawURL)
+	}
+}
+
+// pingURL dials the host of rawURL.
+func pingURL(ctx context.Context, rawURL string) error {
+	address, err := urlAddress(rawURL)
+	if err != nil {
+		return err
+	}
+
+	return dial(ctx, address)
+}
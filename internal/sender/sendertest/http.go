@@ -0,0 +1,61 @@
+package sendertest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+type (
+	Request struct {
+		Header http.Header
+		Body   []byte
+	}
+
+	// HTTPSink is an HTTP server that records every request it receives and
+	// replies with a fixed status and body.
+	HTTPSink struct {
+		*httptest.Server
+
+		status int
+		body   []byte
+
+		mu       sync.Mutex
+		requests []Request
+	}
+)
+
+// NewHTTPSink starts an HTTPSink that responds with status and body.
+func NewHTTPSink(status int, body string) *HTTPSink {
+	s := &HTTPSink{
+		status: status,
+		body:   []byte(body),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	return s
+}
+
+func (s *HTTPSink) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *HTTPSink) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Header: r.Header.Clone(), Body: body})
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(s.status)
+	_, _ = w.Write(s.body)
+}
@@ -0,0 +1,141 @@
+// Package sendertest provides in-process SMTP and HTTP sinks that record what
+// the senders deliver, so that delivery can be exercised offline.
+package sendertest
+
+import (
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+type (
+	Mail struct {
+		From string
+		To   []string
+		Data string
+	}
+
+	// SMTPServer is a minimal plaintext SMTP server that accepts every mail
+	// and keeps it in memory.
+	SMTPServer struct {
+		listener net.Listener
+		wg       sync.WaitGroup
+
+		mu    sync.Mutex
+		mails []Mail
+	}
+)
+
+// NewSMTPServer starts an SMTPServer listening on a random local port.
+func NewSMTPServer() (*SMTPServer, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SMTPServer{listener: l}
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+func (s *SMTPServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *SMTPServer) Mails() []Mail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Mail(nil), s.mails...)
+}
+
+func (s *SMTPServer) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+
+	return err
+}
+
+func (s *SMTPServer) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+
+			s.handle(textproto.NewConn(conn))
+		}()
+	}
+}
+
+func (s *SMTPServer) handle(conn *textproto.Conn) {
+	var mail Mail
+	if err := conn.PrintfLine("220 sendertest ESMTP"); err != nil {
+		return
+	}
+
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			err = conn.PrintfLine("250 sendertest")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			mail = Mail{From: trimAddress(line[len("MAIL FROM:"):])}
+			err = conn.PrintfLine("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			mail.To = append(mail.To, trimAddress(line[len("RCPT TO:"):]))
+			err = conn.PrintfLine("250 OK")
+		case cmd == "DATA":
+			if err = conn.PrintfLine("354 End data with <CR><LF>.<CR><LF>"); err != nil {
+				return
+			}
+
+			var data []byte
+			if data, err = conn.ReadDotBytes(); err != nil {
+				return
+			}
+
+			mail.Data = string(data)
+			s.mu.Lock()
+			s.mails = append(s.mails, mail)
+			s.mu.Unlock()
+
+			err = conn.PrintfLine("250 OK")
+		case cmd == "RSET", cmd == "NOOP":
+			err = conn.PrintfLine("250 OK")
+		case cmd == "QUIT":
+			_ = conn.PrintfLine("221 Bye")
+			return
+		default:
+			err = conn.PrintfLine("502 Command not implemented")
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func trimAddress(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		s = s[:i]
+	}
+
+	return strings.Trim(s, "<>")
+}
@@ -0,0 +1,96 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/subroll/sqetest/internal/service"
+)
+
+type (
+	SMSConfig struct {
+		// URL is the endpoint of the SMS gateway that accepts a JSON message.
+		URL   string
+		Token string
+		From  string
+	}
+
+	// SMS is a generic HTTP SMS-gateway adapter. It posts a JSON message with
+	// a bearer token and uses the "id" returned by the gateway as reference.
+	SMS struct {
+		cfg    SMSConfig
+		client *http.Client
+	}
+
+	smsRequest struct {
+		From      string `json:"from,omitempty"`
+		To        string `json:"to"`
+		Message   string `json:"message"`
+		Reference string `json:"reference"`
+	}
+
+	smsResponse struct {
+		ID string `json:"id"`
+	}
+)
+
+func NewSMS(cfg SMSConfig, client *http.Client) *SMS {
+	return &SMS{
+		cfg:    cfg,
+		client: client,
+	}
+}
+
+// Ping checks that the SMS gateway accepts connections, no message is posted.
+func (s *SMS) Ping(ctx context.Context) error {
+	return pingURL(ctx, s.cfg.URL)
+}
+
+func (s *SMS) Send(ctx context.Context, msg service.Message) (string, error) {
+	if msg.Recipient.Phone == "" {
+		return "", service.ErrRecipientUnreachable
+	}
+
+	ref, err := newReference()
+	if err != nil {
+		return "", err
+	}
+
+	body, err := json.Marshal(smsRequest{
+		From:      s.cfg.From,
+		To:        msg.Recipient.Phone,
+		Message:   messageText(msg.OTP),
+		Reference: ref,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.Token)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if err := checkResponse(res); err != nil {
+		return "", err
+	}
+
+	var smsRes smsResponse
+	if err := json.NewDecoder(res.Body).Decode(&smsRes); err != nil || smsRes.ID == "" {
+		return ref, nil
+	}
+
+	return smsRes.ID, nil
+}
@@ -0,0 +1,103 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subroll/sqetest/internal/entity"
+	"github.com/subroll/sqetest/internal/sender/sendertest"
+	"github.com/subroll/sqetest/internal/service"
+)
+
+func TestSMS_Send(t *testing.T) {
+	t.Parallel()
+
+	type expectation struct {
+		ref  string
+		err  error
+		body string
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*SMS, *sendertest.HTTPSink, service.Message, expectation)
+	}{
+		{
+			desc: "ErrorNoPhoneNumber",
+			mockFn: func(*testing.T) (*SMS, *sendertest.HTTPSink, service.Message, expectation) {
+				sink := sendertest.NewHTTPSink(http.StatusOK, `{"id":"fake-gateway-id"}`)
+
+				return NewSMS(SMSConfig{URL: sink.URL}, sink.Client()), sink, service.Message{
+						Recipient: entity.User{UUID: "fake-uuid"},
+						OTP:       "12345",
+					}, expectation{
+						err: service.ErrRecipientUnreachable,
+					}
+			},
+		},
+		{
+			desc: "ErrorGatewayStatus",
+			mockFn: func(*testing.T) (*SMS, *sendertest.HTTPSink, service.Message, expectation) {
+				sink := sendertest.NewHTTPSink(http.StatusBadGateway, ``)
+
+				return NewSMS(SMSConfig{URL: sink.URL}, sink.Client()), sink, service.Message{
+						Recipient: entity.User{UUID: "fake-uuid", Phone: "+15550000001"},
+						OTP:       "12345",
+					}, expectation{
+						err: errors.New("unexpected response status: 502 Bad Gateway"),
+					}
+			},
+		},
+		{
+			desc: "SuccessSendingSMS",
+			mockFn: func(*testing.T) (*SMS, *sendertest.HTTPSink, service.Message, expectation) {
+				sink := sendertest.NewHTTPSink(http.StatusOK, `{"id":"fake-gateway-id"}`)
+
+				return NewSMS(SMSConfig{URL: sink.URL, Token: "fake-token", From: "sqetest"}, sink.Client()), sink, service.Message{
+						Recipient: entity.User{UUID: "fake-uuid", Phone: "+15550000001"},
+						OTP:       "12345",
+					}, expectation{
+						ref:  "fake-gateway-id",
+						body: `"from":"sqetest","to":"+15550000001","message":"Your verification code is 12345."`,
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			s, sink, msg, exp := tC.mockFn(t)
+			defer sink.Close()
+
+			ref, err := s.Send(context.TODO(), msg)
+			assert.Equal(t, exp.err, err)
+			assert.Equal(t, exp.ref, ref)
+			if exp.body == "" {
+				return
+			}
+
+			requests := sink.Requests()
+			assert.Len(t, requests, 1)
+			assert.Equal(t, "Bearer fake-token", requests[0].Header.Get("Authorization"))
+			assert.Contains(t, string(requests[0].Body), exp.body)
+		})
+	}
+}
+
+func TestSMS_Ping(t *testing.T) {
+	t.Parallel()
+
+	sink := sendertest.NewHTTPSink(http.StatusOK, `{}`)
+	s := NewSMS(SMSConfig{URL: sink.URL}, sink.Client())
+	assert.NoError(t, s.Ping(context.TODO()))
+	assert.Empty(t, sink.Requests())
+
+	sink.Close()
+	assert.Error(t, s.Ping(context.TODO()))
+}
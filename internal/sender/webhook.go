@@ -0,0 +1,102 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/subroll/sqetest/internal/service"
+)
+
+const SignatureHeader = "X-Webhook-Signature"
+
+type (
+	WebhookConfig struct {
+		URL string
+		// Secret, when set, is used to sign the payload with HMAC-SHA256, the
+		// signature is sent hex encoded in SignatureHeader.
+		Secret string
+	}
+
+	// Webhook posts the OTP to an outbound webhook so that the receiver can
+	// deliver it through its own channel.
+	Webhook struct {
+		cfg    WebhookConfig
+		client *http.Client
+	}
+
+	WebhookPayload struct {
+		DeliveryID string `json:"delivery_id"`
+		UserID     string `json:"user_id"`
+		Email      string `json:"email,omitempty"`
+		Phone      string `json:"phone,omitempty"`
+		OTP        string `json:"otp"`
+		RequestID  string `json:"request_id"`
+	}
+)
+
+func NewWebhook(cfg WebhookConfig, client *http.Client) *Webhook {
+	return &Webhook{
+		cfg:    cfg,
+		client: client,
+	}
+}
+
+// Ping checks that the webhook receiver accepts connections, no payload is
+// posted.
+func (w *Webhook) Ping(ctx context.Context) error {
+	return pingURL(ctx, w.cfg.URL)
+}
+
+func (w *Webhook) Send(ctx context.Context, msg service.Message) (string, error) {
+	ref, err := newReference()
+	if err != nil {
+		return "", err
+	}
+
+	body, err := json.Marshal(WebhookPayload{
+		DeliveryID: ref,
+		UserID:     msg.Recipient.UUID,
+		Email:      msg.Recipient.Email,
+		Phone:      msg.Recipient.Phone,
+		OTP:        msg.OTP,
+		RequestID:  msg.RequestID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.cfg.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.cfg.Secret, body))
+	}
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if err := checkResponse(res); err != nil {
+		return "", err
+	}
+
+	return ref, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body, receivers can use it to
+// verify SignatureHeader.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
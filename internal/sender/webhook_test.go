@@ -0,0 +1,95 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subroll/sqetest/internal/entity"
+	"github.com/subroll/sqetest/internal/sender/sendertest"
+	"github.com/subroll/sqetest/internal/service"
+)
+
+func TestWebhook_Send(t *testing.T) {
+	t.Parallel()
+
+	type expectation struct {
+		err     error
+		payload *WebhookPayload
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*Webhook, *sendertest.HTTPSink, service.Message, expectation)
+	}{
+		{
+			desc: "ErrorWebhookStatus",
+			mockFn: func(*testing.T) (*Webhook, *sendertest.HTTPSink, service.Message, expectation) {
+				sink := sendertest.NewHTTPSink(http.StatusInternalServerError, ``)
+
+				return NewWebhook(WebhookConfig{URL: sink.URL}, sink.Client()), sink, service.Message{
+						Recipient: entity.User{UUID: "fake-uuid"},
+						OTP:       "12345",
+						RequestID: "fake-request-id",
+					}, expectation{
+						err: errors.New("unexpected response status: 500 Internal Server Error"),
+					}
+			},
+		},
+		{
+			desc: "SuccessSendingWebhook",
+			mockFn: func(*testing.T) (*Webhook, *sendertest.HTTPSink, service.Message, expectation) {
+				sink := sendertest.NewHTTPSink(http.StatusNoContent, ``)
+
+				return NewWebhook(WebhookConfig{URL: sink.URL, Secret: "fake-secret"}, sink.Client()), sink, service.Message{
+						Recipient: entity.User{UUID: "fake-uuid", Email: "user@example.com"},
+						OTP:       "12345",
+						RequestID: "fake-request-id",
+					}, expectation{
+						payload: &WebhookPayload{
+							UserID:    "fake-uuid",
+							Email:     "user@example.com",
+							OTP:       "12345",
+							RequestID: "fake-request-id",
+						},
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			w, sink, msg, exp := tC.mockFn(t)
+			defer sink.Close()
+
+			ref, err := w.Send(context.TODO(), msg)
+			assert.Equal(t, exp.err, err)
+			if exp.payload == nil {
+				assert.Empty(t, ref)
+				return
+			}
+
+			requests := sink.Requests()
+			assert.Len(t, requests, 1)
+			assert.Equal(t, Sign("fake-secret", requests[0].Body), requests[0].Header.Get(SignatureHeader))
+
+			var payload WebhookPayload
+			assert.NoError(t, json.Unmarshal(requests[0].Body, &payload))
+			exp.payload.DeliveryID = ref
+			assert.Equal(t, *exp.payload, payload)
+		})
+	}
+}
+
+func TestWebhook_Ping(t *testing.T) {
+	t.Parallel()
+
+	w := NewWebhook(WebhookConfig{URL: "ftp://example.com/otp"}, http.DefaultClient)
+	assert.EqualError(t, w.Ping(context.TODO()), "no port in url: ftp://example.com/otp")
+}
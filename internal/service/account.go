@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/subroll/sqetest/internal/entity"
+)
+
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+
+	maxNameLength  = 50
+	maxEmailLength = 255
+	maxPhoneLength = 20
+)
+
+type (
+	// CreateUserParams describes a new user, OTPChannel is the user's preferred
+	// delivery channel and is left empty to use the default one.
+	CreateUserParams struct {
+		Name       string
+		Email      string
+		Phone      string
+		OTPChannel string
+	}
+
+	// UpdateUserParams replaces the name and contact info of the user UserUUID,
+	// fields left empty are cleared except for Name, which is required.
+	UpdateUserParams struct {
+		UserUUID   string
+		Name       string
+		Email      string
+		Phone      string
+		OTPChannel string
+	}
+
+	// ListUsersParams pages through users ordered by ID, Limit defaults to
+	// DefaultListLimit and can't exceed MaxListLimit.
+	ListUsersParams struct {
+		Limit  uint
+		Offset uint
+	}
+)
+
+// CreateUser validates and stores a new user with a generated UUID.
+func (u *User) CreateUser(ctx context.Context, params CreateUserParams) (entity.User, error) {
+	ctx, span := startSpan(ctx, "User.CreateUser")
+	defer span.End()
+
+	user := entity.User{
+		Name:       strings.TrimSpace(params.Name),
+		Email:      strings.TrimSpace(params.Email),
+		Phone:      strings.TrimSpace(params.Phone),
+		OTPChannel: params.OTPChannel,
+	}
+	if err := validateUser(user); err != nil {
+		return entity.User{}, ErrInvalidUser.wrap(err)
+	}
+
+	user.UUID = u.uuidGenerator()
+	user, err := u.userRepo.CreateUser(ctx, user)
+	if err != nil {
+		return entity.User{}, translateError(err)
+	}
+
+	return user, nil
+}
+
+// GetUser returns the user, disabled or not.
+func (u *User) GetUser(ctx context.Context, userUUID string) (entity.User, error) {
+	ctx, span := startSpan(ctx, "User.GetUser")
+	defer span.End()
+
+	user, err := u.userRepo.GetUser(ctx, userUUID)
+	if err != nil {
+		return entity.User{}, translateError(err)
+	}
+
+	return user, nil
+}
+
+// UpdateUser validates and stores the new name and contact info of the user.
+func (u *User) UpdateUser(ctx context.Context, params UpdateUserParams) (entity.User, error) {
+	ctx, span := startSpan(ctx, "User.UpdateUser")
+	defer span.End()
+
+	user := entity.User{
+		UUID:       params.UserUUID,
+		Name:       strings.TrimSpace(params.Name),
+		Email:      strings.TrimSpace(params.Email),
+		Phone:      strings.TrimSpace(params.Phone),
+		OTPChannel: params.OTPChannel,
+	}
+	if err := validateUser(user); err != nil {
+		return entity.User{}, ErrInvalidUser.wrap(err)
+	}
+
+	user, err := u.userRepo.UpdateUser(ctx, user)
+	if err != nil {
+		return entity.User{}, translateError(err)
+	}
+
+	return user, nil
+}
+
+func (u *User) ListUsers(ctx context.Context, params ListUsersParams) ([]entity.User, error) {
+	ctx, span := startSpan(ctx, "User.ListUsers")
+	defer span.End()
+
+	limit := params.Limit
+	switch {
+	case limit == 0:
+		limit = DefaultListLimit
+	case limit > MaxListLimit:
+		limit = MaxListLimit
+	}
+
+	users, err := u.userRepo.ListUsers(ctx, limit, params.Offset)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return users, nil
+}
+
+// DisableUser disables the user for good, its active OTPs and refresh tokens
+// are revoked along the way.
+func (u *User) DisableUser(ctx context.Context, userUUID string) error {
+	ctx, span := startSpan(ctx, "User.DisableUser")
+	defer span.End()
+
+	if err := u.userRepo.DisableUser(ctx, userUUID); err != nil {
+		return translateError(err)
+	}
+
+	return nil
+}
+
+// AnonymizeUser deletes the user: it's disabled, its personal data is erased
+// and it's no longer found. Its row is kept for the OTPs and tokens referencing
+// it.
+func (u *User) AnonymizeUser(ctx context.Context, userUUID string) error {
+	ctx, span := startSpan(ctx, "User.AnonymizeUser")
+	defer span.End()
+
+	if err := u.userRepo.AnonymizeUser(ctx, userUUID); err != nil {
+		return translateError(err)
+	}
+
+	return nil
+}
+
+func validateUser(user entity.User) error {
+	switch {
+	case user.Name == "":
+		return errors.New("name is required")
+	case len(user.Name) > maxNameLength:
+		return fmt.Errorf("name is longer than %d characters", maxNameLength)
+	case len(user.Email) > maxEmailLength:
+		return fmt.Errorf("email is longer than %d characters", maxEmailLength)
+	case user.Email != "" && !strings.Contains(user.Email, "@"):
+		return errors.New("email is invalid")
+	case len(user.Phone) > maxPhoneLength:
+		return fmt.Errorf("phone is longer than %d characters", maxPhoneLength)
+	}
+
+	switch user.OTPChannel {
+	case "", ChannelEmail, ChannelSMS, ChannelWebhook:
+	default:
+		return fmt.Errorf("unknown otp channel: %s", user.OTPChannel)
+	}
+
+	return nil
+}
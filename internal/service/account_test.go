@@ -0,0 +1,580 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subroll/sqetest/internal/entity"
+	mockrepo "github.com/subroll/sqetest/internal/mocks/service"
+	"github.com/subroll/sqetest/internal/repository"
+)
+
+func newAccountUser(userRepo UserRepository) *User {
+	return NewUser(Dependencies{
+		User:           userRepo,
+		DefaultPurpose: "login",
+		UUIDGenerator: func() string {
+			return "fake-uuid"
+		},
+	})
+}
+
+func TestUser_CreateUser(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx    context.Context
+		params CreateUserParams
+	}
+
+	type expectaion struct {
+		user entity.User
+		err  error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectaion)
+	}{
+		{
+			desc: "ErrorNameRequired",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				user := newAccountUser(mockrepo.NewUserRepository(t))
+
+				return user, arg{
+						ctx:    context.TODO(),
+						params: CreateUserParams{Name: "  ", Email: "john@example.com"},
+					}, expectaion{
+						err: ErrInvalidUser.wrap(errors.New("name is required")),
+					}
+			},
+		},
+		{
+			desc: "ErrorNameTooLong",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				user := newAccountUser(mockrepo.NewUserRepository(t))
+
+				return user, arg{
+						ctx:    context.TODO(),
+						params: CreateUserParams{Name: strings.Repeat("a", 51)},
+					}, expectaion{
+						err: ErrInvalidUser.wrap(errors.New("name is longer than 50 characters")),
+					}
+			},
+		},
+		{
+			desc: "ErrorInvalidEmail",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				user := newAccountUser(mockrepo.NewUserRepository(t))
+
+				return user, arg{
+						ctx:    context.TODO(),
+						params: CreateUserParams{Name: "John Doe", Email: "john"},
+					}, expectaion{
+						err: ErrInvalidUser.wrap(errors.New("email is invalid")),
+					}
+			},
+		},
+		{
+			desc: "ErrorPhoneTooLong",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				user := newAccountUser(mockrepo.NewUserRepository(t))
+
+				return user, arg{
+						ctx:    context.TODO(),
+						params: CreateUserParams{Name: "John Doe", Phone: strings.Repeat("1", 21)},
+					}, expectaion{
+						err: ErrInvalidUser.wrap(errors.New("phone is longer than 20 characters")),
+					}
+			},
+		},
+		{
+			desc: "ErrorUnknownChannel",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				user := newAccountUser(mockrepo.NewUserRepository(t))
+
+				return user, arg{
+						ctx:    context.TODO(),
+						params: CreateUserParams{Name: "John Doe", OTPChannel: "fax"},
+					}, expectaion{
+						err: ErrInvalidUser.wrap(errors.New("unknown otp channel: fax")),
+					}
+			},
+		},
+		{
+			desc: "ErrorUserExist",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.
+					On("CreateUser", context.TODO(), entity.User{UUID: "fake-uuid", Name: "John Doe"}).
+					Return(entity.User{}, repository.ErrUserExist)
+
+				return user, arg{
+						ctx:    context.TODO(),
+						params: CreateUserParams{Name: "John Doe"},
+					}, expectaion{
+						err: ErrUserExist.wrap(repository.ErrUserExist),
+					}
+			},
+		},
+		{
+			desc: "ErrorCreatingUser",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.
+					On("CreateUser", context.TODO(), entity.User{UUID: "fake-uuid", Name: "John Doe"}).
+					Return(entity.User{}, errors.New("fake error"))
+
+				return user, arg{
+						ctx:    context.TODO(),
+						params: CreateUserParams{Name: "John Doe"},
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				newUser := entity.User{
+					UUID:       "fake-uuid",
+					Name:       "John Doe",
+					Email:      "john@example.com",
+					Phone:      "+15550000001",
+					OTPChannel: ChannelEmail,
+				}
+				created := newUser
+				created.ID = 1
+
+				userRepo.On("CreateUser", context.TODO(), newUser).Return(created, nil)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: CreateUserParams{
+							Name:       " John Doe ",
+							Email:      "john@example.com",
+							Phone:      "+15550000001",
+							OTPChannel: ChannelEmail,
+						},
+					}, expectaion{
+						user: created,
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			got, err := u.CreateUser(a.ctx, a.params)
+			assert.Equal(t, e.user, got)
+			assert.Equal(t, e.err, err)
+		})
+	}
+}
+
+func TestUser_GetUser(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx      context.Context
+		userUUID string
+	}
+
+	type expectaion struct {
+		user entity.User
+		err  error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectaion)
+	}{
+		{
+			desc: "ErrorUserNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.On("GetUser", context.TODO(), "fake-uuid").Return(entity.User{}, repository.ErrNotFound)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{
+						err: ErrUserNotFound.wrap(repository.ErrNotFound),
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.
+					On("GetUser", context.TODO(), "fake-uuid").
+					Return(entity.User{ID: 1, UUID: "fake-uuid", Name: "John Doe", Disabled: true}, nil)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{
+						user: entity.User{ID: 1, UUID: "fake-uuid", Name: "John Doe", Disabled: true},
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			got, err := u.GetUser(a.ctx, a.userUUID)
+			assert.Equal(t, e.user, got)
+			assert.Equal(t, e.err, err)
+		})
+	}
+}
+
+func TestUser_UpdateUser(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx    context.Context
+		params UpdateUserParams
+	}
+
+	type expectaion struct {
+		user entity.User
+		err  error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectaion)
+	}{
+		{
+			desc: "ErrorNameRequired",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				user := newAccountUser(mockrepo.NewUserRepository(t))
+
+				return user, arg{
+						ctx:    context.TODO(),
+						params: UpdateUserParams{UserUUID: "fake-uuid", Email: "john@example.com"},
+					}, expectaion{
+						err: ErrInvalidUser.wrap(errors.New("name is required")),
+					}
+			},
+		},
+		{
+			desc: "ErrorUnknownChannel",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				user := newAccountUser(mockrepo.NewUserRepository(t))
+
+				return user, arg{
+						ctx:    context.TODO(),
+						params: UpdateUserParams{UserUUID: "fake-uuid", Name: "John Doe", OTPChannel: "fax"},
+					}, expectaion{
+						err: ErrInvalidUser.wrap(errors.New("unknown otp channel: fax")),
+					}
+			},
+		},
+		{
+			desc: "ErrorUserNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.
+					On("UpdateUser", context.TODO(), entity.User{UUID: "fake-uuid", Name: "John Doe"}).
+					Return(entity.User{}, repository.ErrNotFound)
+
+				return user, arg{
+						ctx:    context.TODO(),
+						params: UpdateUserParams{UserUUID: "fake-uuid", Name: "John Doe"},
+					}, expectaion{
+						err: ErrUserNotFound.wrap(repository.ErrNotFound),
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.
+					On("UpdateUser", context.TODO(), entity.User{
+						UUID:       "fake-uuid",
+						Name:       "John Doe",
+						Email:      "john@example.com",
+						OTPChannel: ChannelEmail,
+					}).
+					Return(entity.User{
+						ID:         1,
+						UUID:       "fake-uuid",
+						Name:       "John Doe",
+						Email:      "john@example.com",
+						OTPChannel: ChannelEmail,
+					}, nil)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: UpdateUserParams{
+							UserUUID:   "fake-uuid",
+							Name:       " John Doe ",
+							Email:      "john@example.com ",
+							OTPChannel: ChannelEmail,
+						},
+					}, expectaion{
+						user: entity.User{
+							ID:         1,
+							UUID:       "fake-uuid",
+							Name:       "John Doe",
+							Email:      "john@example.com",
+							OTPChannel: ChannelEmail,
+						},
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			got, err := u.UpdateUser(a.ctx, a.params)
+			assert.Equal(t, e.user, got)
+			assert.Equal(t, e.err, err)
+		})
+	}
+}
+
+func TestUser_ListUsers(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx    context.Context
+		params ListUsersParams
+	}
+
+	type expectaion struct {
+		users []entity.User
+		err   error
+	}
+
+	fakeUsers := []entity.User{
+		{ID: 1, UUID: "fake-uuid-1", Name: "John Doe"},
+		{ID: 2, UUID: "fake-uuid-2", Name: "Jane Doe", Disabled: true},
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectaion)
+	}{
+		{
+			desc: "ErrorListingUsers",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.On("ListUsers", context.TODO(), uint(10), uint(0)).Return(nil, errors.New("fake error"))
+
+				return user, arg{
+						ctx:    context.TODO(),
+						params: ListUsersParams{Limit: 10},
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "SuccessDefaultLimit",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.On("ListUsers", context.TODO(), uint(DefaultListLimit), uint(5)).Return(fakeUsers, nil)
+
+				return user, arg{
+						ctx:    context.TODO(),
+						params: ListUsersParams{Offset: 5},
+					}, expectaion{
+						users: fakeUsers,
+					}
+			},
+		},
+		{
+			desc: "SuccessLimitCapped",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.On("ListUsers", context.TODO(), uint(MaxListLimit), uint(0)).Return(fakeUsers, nil)
+
+				return user, arg{
+						ctx:    context.TODO(),
+						params: ListUsersParams{Limit: MaxListLimit + 1},
+					}, expectaion{
+						users: fakeUsers,
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			got, err := u.ListUsers(a.ctx, a.params)
+			assert.Equal(t, e.users, got)
+			assert.Equal(t, e.err, err)
+		})
+	}
+}
+
+func TestUser_DisableUser(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx      context.Context
+		userUUID string
+	}
+
+	type expectaion struct {
+		err error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectaion)
+	}{
+		{
+			desc: "ErrorUserNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.On("DisableUser", context.TODO(), "fake-uuid").Return(repository.ErrNotFound)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{
+						err: ErrUserNotFound.wrap(repository.ErrNotFound),
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.On("DisableUser", context.TODO(), "fake-uuid").Return(nil)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			err := u.DisableUser(a.ctx, a.userUUID)
+			assert.Equal(t, e.err, err)
+		})
+	}
+}
+
+func TestUser_AnonymizeUser(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx      context.Context
+		userUUID string
+	}
+
+	type expectaion struct {
+		err error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectaion)
+	}{
+		{
+			desc: "ErrorUserNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.On("AnonymizeUser", context.TODO(), "fake-uuid").Return(repository.ErrNotFound)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{
+						err: ErrUserNotFound.wrap(repository.ErrNotFound),
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.On("AnonymizeUser", context.TODO(), "fake-uuid").Return(nil)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			err := u.AnonymizeUser(a.ctx, a.userUUID)
+			assert.Equal(t, e.err, err)
+		})
+	}
+}
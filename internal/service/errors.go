@@ -0,0 +1,174 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/subroll/sqetest/internal/repository"
+)
+
+// ErrorKind classifies a domain error so that delivery layers can translate it
+// into their own status codes without knowing every individual error.
+type ErrorKind uint8
+
+const (
+	KindInternal ErrorKind = iota
+	KindNotFound
+	KindConflict
+	KindExpired
+	KindUnauthorized
+	KindTooManyRequests
+	KindLocked
+	KindInvalid
+	KindUnprocessable
+	KindUnavailable
+)
+
+var (
+	ErrUserNotFound = &Error{Kind: KindNotFound, Code: "user_not_found", Message: "User not found."}
+	ErrUserExist    = &Error{Kind: KindConflict, Code: "user_exist", Message: "User already exists."}
+	ErrInvalidUser  = &Error{Kind: KindInvalid, Code: "invalid_user", Message: "Invalid user."}
+	ErrOTPExist     = &Error{Kind: KindConflict, Code: "otp_exist", Message: "There is still an active OTP."}
+	ErrOTPExpired   = &Error{Kind: KindExpired, Code: "otp_expired", Message: "OTP has expired."}
+	ErrInvalidOTP   = &Error{Kind: KindUnauthorized, Code: "invalid_otp", Message: "Invalid OTP."}
+	ErrOTPReplayed  = &Error{Kind: KindUnauthorized, Code: "otp_replayed", Message: "OTP has already been used."}
+	ErrNoActiveOTP  = &Error{Kind: KindNotFound, Code: "otp_not_found", Message: "There is no active OTP to resend."}
+
+	ErrOTPChallengeMismatch = &Error{Kind: KindUnauthorized, Code: "otp_challenge_mismatch",
+		Message: "OTP was issued for another challenge."}
+	ErrOTPClientMismatch = &Error{Kind: KindUnauthorized, Code: "otp_client_mismatch",
+		Message: "OTP was issued to another client."}
+
+	ErrInvalidRefreshToken = &Error{Kind: KindUnauthorized, Code: "invalid_refresh_token",
+		Message: "Invalid refresh token."}
+	ErrRefreshTokenExpired = &Error{Kind: KindUnauthorized, Code: "refresh_token_expired",
+		Message: "Refresh token has expired."}
+
+	ErrTOTPNotEnrolled = &Error{Kind: KindNotFound, Code: "totp_not_enrolled",
+		Message: "No authenticator app enrollment found."}
+	ErrTOTPAlreadyEnrolled = &Error{Kind: KindConflict, Code: "totp_already_enrolled",
+		Message: "An authenticator app is already enrolled."}
+
+	ErrTooManyAttempts = &Error{Kind: KindTooManyRequests, Code: "too_many_attempts",
+		Message: "Too many invalid attempts, the OTP has been invalidated."}
+	ErrUserLocked  = &Error{Kind: KindLocked, Code: "user_locked", Message: "User is temporarily locked out."}
+	ErrRateLimited = &Error{Kind: KindTooManyRequests, Code: "rate_limited",
+		Message: "Too many requests, please retry later."}
+	ErrResendCooldown = &Error{Kind: KindTooManyRequests, Code: "resend_cooldown",
+		Message: "Please wait before requesting another resend."}
+	ErrResendLimit = &Error{Kind: KindTooManyRequests, Code: "resend_limit_reached",
+		Message: "The OTP can't be resent anymore, request a new one once it expires."}
+
+	ErrUnsupportedChannel = &Error{Kind: KindInvalid, Code: "unsupported_channel",
+		Message: "The requested delivery channel is not supported."}
+	ErrUnsupportedPurpose = &Error{Kind: KindInvalid, Code: "unsupported_purpose",
+		Message: "The requested OTP purpose is not supported."}
+	ErrRecipientUnreachable = &Error{Kind: KindUnprocessable, Code: "recipient_unreachable",
+		Message: "The user has no address for the requested delivery channel."}
+	ErrDeliveryFailed = &Error{Kind: KindUnavailable, Code: "delivery_failed", Message: "Failed to deliver the OTP."}
+)
+
+// Error is a domain error returned by the service layer. Code is a stable,
+// machine-readable identifier and Message is safe to show to end users.
+// RetryAfter, when set, tells the caller how long to wait before retrying.
+type Error struct {
+	Kind       ErrorKind
+	Code       string
+	Message    string
+	RetryAfter time.Duration
+
+	err error
+}
+
+func (e *Error) Error() string {
+	if e.err != nil {
+		return e.Code + ": " + e.err.Error()
+	}
+
+	return e.Code
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether target is a domain error with the same code, so that
+// errors.Is works against the exported sentinels after wrapping.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+
+	return ok && t.Code == e.Code
+}
+
+// WithRetryAfter returns a copy of e asking the caller to wait d before
+// retrying.
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	we := *e
+	we.RetryAfter = d
+
+	return &we
+}
+
+func (e *Error) wrap(err error) *Error {
+	we := *e
+	we.err = err
+
+	return &we
+}
+
+// translateError maps repository errors to domain errors, any other error is
+// returned untouched and treated as internal by the callers.
+func translateError(err error) error {
+	var (
+		lockedErr   *repository.LockedError
+		cooldownErr *repository.CooldownError
+	)
+
+	switch {
+	case errors.As(err, &lockedErr):
+		base := ErrUserLocked
+		if errors.Is(err, repository.ErrTooManyAttempts) {
+			base = ErrTooManyAttempts
+		}
+
+		we := base.wrap(err)
+		we.RetryAfter = lockedErr.RetryAfter
+
+		return we
+	case errors.As(err, &cooldownErr):
+		we := ErrResendCooldown.wrap(err)
+		we.RetryAfter = cooldownErr.RetryAfter
+
+		return we
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrUserNotFound.wrap(err)
+	case errors.Is(err, repository.ErrUserExist):
+		return ErrUserExist.wrap(err)
+	case errors.Is(err, repository.ErrOTPExist):
+		return ErrOTPExist.wrap(err)
+	case errors.Is(err, repository.ErrOTPNotFound):
+		return ErrNoActiveOTP.wrap(err)
+	case errors.Is(err, repository.ErrResendLimit):
+		return ErrResendLimit.wrap(err)
+	case errors.Is(err, repository.ErrOTPExpired):
+		return ErrOTPExpired.wrap(err)
+	case errors.Is(err, repository.ErrInvalidOTP):
+		return ErrInvalidOTP.wrap(err)
+	case errors.Is(err, repository.ErrOTPReplayed):
+		return ErrOTPReplayed.wrap(err)
+	case errors.Is(err, repository.ErrChallengeMismatch):
+		return ErrOTPChallengeMismatch.wrap(err)
+	case errors.Is(err, repository.ErrBindingMismatch):
+		return ErrOTPClientMismatch.wrap(err)
+	case errors.Is(err, repository.ErrRefreshTokenNotFound), errors.Is(err, repository.ErrRefreshTokenReused):
+		return ErrInvalidRefreshToken.wrap(err)
+	case errors.Is(err, repository.ErrRefreshTokenExpired):
+		return ErrRefreshTokenExpired.wrap(err)
+	case errors.Is(err, repository.ErrTOTPNotFound):
+		return ErrTOTPNotEnrolled.wrap(err)
+	case errors.Is(err, repository.ErrTOTPEnrolled):
+		return ErrTOTPAlreadyEnrolled.wrap(err)
+	default:
+		return err
+	}
+}
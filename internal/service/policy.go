@@ -0,0 +1,46 @@
+package service
+
+import (
+	"time"
+
+	"github.com/subroll/sqetest/internal/pkg/stringutil"
+)
+
+// Policy describes how the OTPs of a purpose, e.g. login or password reset,
+// are generated, how long and how often they can be validated and how often
+// they can be resent.
+type Policy struct {
+	Length         uint8
+	Charset        string
+	TTL            time.Duration
+	MaxAttempts    uint8
+	ResendCooldown time.Duration
+	MaxResends     uint8
+}
+
+// normalize converts user input to the canonical form of the policy's charset
+// before it is compared.
+func (p Policy) normalize(otp string) string {
+	if p.Charset == stringutil.CrockfordBase32Charset {
+		return stringutil.NormalizeCrockford(otp)
+	}
+
+	return otp
+}
+
+// delivery describes a delivery of the OTP of challengeID that has been resent
+// resends times so far and expires at expiresAt.
+func (p Policy) delivery(channel, ref, challengeID string, expiresAt time.Time, resends uint8) Delivery {
+	d := Delivery{
+		Channel:     channel,
+		Reference:   ref,
+		ChallengeID: challengeID,
+		ExpiresAt:   expiresAt,
+	}
+	if resends < p.MaxResends {
+		d.ResendAfter = p.ResendCooldown
+		d.ResendsLeft = p.MaxResends - resends
+	}
+
+	return d
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/subroll/sqetest/internal/entity"
+)
+
+const (
+	ChannelEmail   = "email"
+	ChannelSMS     = "sms"
+	ChannelWebhook = "webhook"
+)
+
+type (
+	// Sender delivers an OTP to a user through a single channel and returns a
+	// reference that identifies the delivery at the provider.
+	Sender interface {
+		Send(ctx context.Context, msg Message) (string, error)
+	}
+
+	// SenderFunc adapts an ordinary function to a Sender.
+	SenderFunc func(ctx context.Context, msg Message) (string, error)
+
+	Message struct {
+		Recipient entity.User
+		OTP       string
+		RequestID string
+	}
+
+	// Delivery describes where an OTP was sent to. ChallengeID identifies the
+	// OTP, it has to be given back to ValidateOTP before ExpiresAt.
+	// ResendAfter is how long the caller has to wait before the OTP can be
+	// resent and ResendsLeft is how many more resends are allowed.
+	Delivery struct {
+		Channel     string
+		Reference   string
+		ChallengeID string
+		ExpiresAt   time.Time
+		ResendAfter time.Duration
+		ResendsLeft uint8
+	}
+)
+
+func (f SenderFunc) Send(ctx context.Context, msg Message) (string, error) {
+	return f(ctx, msg)
+}
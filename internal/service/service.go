@@ -2,16 +2,92 @@ package service
 
 import (
 	"context"
+	"time"
+
+	"github.com/subroll/sqetest/internal/entity"
+	"github.com/subroll/sqetest/internal/pkg/totp"
 )
 
 type Dependencies struct {
 	User UserRepository
 
-	RandNumberGenerator func(uint8) (string, error)
+	RandStringGenerator func(charset string, length uint8) (string, error)
+
+	// ChallengeIDGenerator generates the ID a client validates an OTP with,
+	// it must not be guessable.
+	ChallengeIDGenerator func() (string, error)
+
+	// Policies maps an OTP purpose to its policy, DefaultPurpose is used when
+	// a request doesn't name one.
+	Policies       map[string]Policy
+	DefaultPurpose string
+
+	// Senders maps a delivery channel to its Sender, DefaultChannel is used
+	// when neither the request nor the user picks a channel.
+	Senders        map[string]Sender
+	DefaultChannel string
+
+	// TOTP configures authenticator app codes, secrets are sealed with
+	// SecretBox before they are stored. TOTPMaxAttempts invalid codes in a
+	// row lock the user out.
+	TOTP                totp.Params
+	TOTPIssuer          string
+	TOTPMaxAttempts     uint8
+	TOTPSecretGenerator func() ([]byte, error)
+	SecretBox           SecretBox
+	QRCodeEncoder       func(content string) ([]byte, error)
+	NowFunc             func() time.Time
+
+	// Tokens signs the access token of a session once an OTP is validated,
+	// refresh tokens are generated with RefreshTokenGenerator and are valid
+	// for RefreshTokenTTL.
+	Tokens                TokenIssuer
+	RefreshTokenGenerator func() (string, error)
+	RefreshTokenTTL       time.Duration
+
+	// UUIDGenerator generates the UUID of users created with CreateUser.
+	UUIDGenerator func() string
+
+	// BindFingerprint and BindIP bind an OTP to the fingerprint and the IP of
+	// the client requesting it, only the same client can validate it then.
+	BindFingerprint bool
+	BindIP          bool
+
+	// OTPEvents, when set, is told the outcome of every OTP issued, resent or
+	// validated.
+	OTPEvents func(purpose, outcome string)
 }
 
 type UserRepository interface {
 	GetUserIDByUUID(ctx context.Context, uuid string) (uint64, error)
-	StoreOTP(ctx context.Context, userID uint64, otp, requestID string) error
-	UpdateOTPStatus(ctx context.Context, userID uint64, otp, requestID string) error
+	GetUserByUUID(ctx context.Context, uuid string) (entity.User, error)
+	StoreOTP(ctx context.Context, otp entity.OTP) error
+	ResendOTP(ctx context.Context, otp entity.OTP) (entity.OTP, error)
+	UpdateOTPStatus(ctx context.Context, userID uint64, otp, purpose, challengeID, binding string) error
+	StoreTOTPSecret(ctx context.Context, userID uint64, secret string) error
+	GetTOTP(ctx context.Context, userID uint64) (entity.TOTP, error)
+	ConfirmTOTP(ctx context.Context, userID, counter uint64) error
+	UseTOTPCounter(ctx context.Context, userID, counter uint64) error
+	FailTOTPAttempt(ctx context.Context, userID uint64, maxAttempts uint8) error
+	CheckLockout(ctx context.Context, userID uint64) error
+	StoreRefreshToken(ctx context.Context, token entity.RefreshToken) error
+	RotateRefreshToken(ctx context.Context, oldToken string, newToken entity.RefreshToken) (entity.RefreshToken, error)
+	CreateUser(ctx context.Context, user entity.User) (entity.User, error)
+	ListUsers(ctx context.Context, limit, offset uint) ([]entity.User, error)
+	DisableUser(ctx context.Context, uuid string) error
+	GetUser(ctx context.Context, uuid string) (entity.User, error)
+	UpdateUser(ctx context.Context, user entity.User) (entity.User, error)
+	AnonymizeUser(ctx context.Context, uuid string) error
+	ListOTPs(ctx context.Context, userID uint64, limit uint) ([]entity.OTP, error)
+	RevokeOTP(ctx context.Context, userID uint64, purpose string) error
+	ExpireOTP(ctx context.Context, userID uint64, purpose string) error
+}
+
+type SecretBox interface {
+	Seal(plaintext []byte) (string, error)
+	Open(sealed string) ([]byte, error)
+}
+
+type TokenIssuer interface {
+	Issue(subject string) (string, time.Duration, error)
 }
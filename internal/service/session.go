@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/subroll/sqetest/internal/entity"
+)
+
+// Session is what a user gets once an OTP is validated. AccessToken is a signed
+// token other services can verify offline, valid for ExpiresIn, RefreshToken is
+// exchanged for a new session with RefreshSession.
+type Session struct {
+	AccessToken  string
+	ExpiresIn    time.Duration
+	RefreshToken string
+}
+
+// RefreshSession exchanges a refresh token for a new session, the refresh token
+// is single use and is revoked in the process.
+func (u *User) RefreshSession(ctx context.Context, refreshToken string) (Session, error) {
+	ctx, span := startSpan(ctx, "User.RefreshSession")
+	defer span.End()
+
+	newToken, err := u.refreshTokenGenerator()
+	if err != nil {
+		return Session{}, err
+	}
+
+	old, err := u.userRepo.RotateRefreshToken(ctx, refreshToken, entity.RefreshToken{
+		Token:     newToken,
+		ExpiresAt: u.nowFunc().Add(u.refreshTokenTTL),
+	})
+	if err != nil {
+		return Session{}, translateError(err)
+	}
+
+	accessToken, expiresIn, err := u.tokens.Issue(old.UserUUID)
+	if err != nil {
+		return Session{}, err
+	}
+
+	return Session{
+		AccessToken:  accessToken,
+		ExpiresIn:    expiresIn,
+		RefreshToken: newToken,
+	}, nil
+}
+
+func (u *User) newSession(ctx context.Context, userID uint64, userUUID string) (Session, error) {
+	accessToken, expiresIn, err := u.tokens.Issue(userUUID)
+	if err != nil {
+		return Session{}, err
+	}
+
+	refreshToken, err := u.refreshTokenGenerator()
+	if err != nil {
+		return Session{}, err
+	}
+
+	if err := u.userRepo.StoreRefreshToken(ctx, entity.RefreshToken{
+		UserID:    userID,
+		Token:     refreshToken,
+		ExpiresAt: u.nowFunc().Add(u.refreshTokenTTL),
+	}); err != nil {
+		return Session{}, err
+	}
+
+	return Session{
+		AccessToken:  accessToken,
+		ExpiresIn:    expiresIn,
+		RefreshToken: refreshToken,
+	}, nil
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subroll/sqetest/internal/entity"
+	mockrepo "github.com/subroll/sqetest/internal/mocks/service"
+	"github.com/subroll/sqetest/internal/repository"
+)
+
+const testRefreshTokenTTL = 30 * 24 * time.Hour
+
+var (
+	testNow = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)
+
+	testRefreshToken = entity.RefreshToken{
+		UserID:    1,
+		Token:     "fake-refresh-token",
+		ExpiresAt: testNow.Add(testRefreshTokenTTL),
+	}
+
+	testSession = Session{
+		AccessToken:  "fake-access-token",
+		ExpiresIn:    5 * time.Minute,
+		RefreshToken: "fake-refresh-token",
+	}
+)
+
+func testNowFunc() time.Time {
+	return testNow
+}
+
+func testRefreshTokenGenerator() (string, error) {
+	return "fake-refresh-token", nil
+}
+
+func TestUser_RefreshSession(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx          context.Context
+		refreshToken string
+	}
+
+	type expectaion struct {
+		session Session
+		err     error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectaion)
+	}{
+		{
+			desc: "ErrorGeneratingRefreshToken",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				tokens := mockrepo.NewTokenIssuer(t)
+				user := NewUser(Dependencies{
+					User:                  userRepo,
+					NowFunc:               testNowFunc,
+					Tokens:                tokens,
+					RefreshTokenGenerator: func() (string, error) {
+						return "", errors.New("fake error")
+					},
+					RefreshTokenTTL:       testRefreshTokenTTL,
+				})
+
+				return user, arg{
+						ctx:          context.TODO(),
+						refreshToken: "old-refresh-token",
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorInvalidRefreshToken",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				tokens := mockrepo.NewTokenIssuer(t)
+				user := NewUser(Dependencies{
+					User:                  userRepo,
+					NowFunc:               testNowFunc,
+					Tokens:                tokens,
+					RefreshTokenGenerator: testRefreshTokenGenerator,
+					RefreshTokenTTL:       testRefreshTokenTTL,
+				})
+
+				newToken := testRefreshToken
+				newToken.UserID = 0
+
+				userRepo.
+					On("RotateRefreshToken", context.TODO(), "old-refresh-token", newToken).
+					Return(entity.RefreshToken{}, repository.ErrRefreshTokenNotFound)
+
+				return user, arg{
+						ctx:          context.TODO(),
+						refreshToken: "old-refresh-token",
+					}, expectaion{
+						err: ErrInvalidRefreshToken.wrap(repository.ErrRefreshTokenNotFound),
+					}
+			},
+		},
+		{
+			desc: "ErrorRefreshTokenReused",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				tokens := mockrepo.NewTokenIssuer(t)
+				user := NewUser(Dependencies{
+					User:                  userRepo,
+					NowFunc:               testNowFunc,
+					Tokens:                tokens,
+					RefreshTokenGenerator: testRefreshTokenGenerator,
+					RefreshTokenTTL:       testRefreshTokenTTL,
+				})
+
+				newToken := testRefreshToken
+				newToken.UserID = 0
+
+				userRepo.
+					On("RotateRefreshToken", context.TODO(), "old-refresh-token", newToken).
+					Return(entity.RefreshToken{}, repository.ErrRefreshTokenReused)
+
+				return user, arg{
+						ctx:          context.TODO(),
+						refreshToken: "old-refresh-token",
+					}, expectaion{
+						err: ErrInvalidRefreshToken.wrap(repository.ErrRefreshTokenReused),
+					}
+			},
+		},
+		{
+			desc: "ErrorRefreshTokenExpired",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				tokens := mockrepo.NewTokenIssuer(t)
+				user := NewUser(Dependencies{
+					User:                  userRepo,
+					NowFunc:               testNowFunc,
+					Tokens:                tokens,
+					RefreshTokenGenerator: testRefreshTokenGenerator,
+					RefreshTokenTTL:       testRefreshTokenTTL,
+				})
+
+				newToken := testRefreshToken
+				newToken.UserID = 0
+
+				userRepo.
+					On("RotateRefreshToken", context.TODO(), "old-refresh-token", newToken).
+					Return(entity.RefreshToken{}, repository.ErrRefreshTokenExpired)
+
+				return user, arg{
+						ctx:          context.TODO(),
+						refreshToken: "old-refresh-token",
+					}, expectaion{
+						err: ErrRefreshTokenExpired.wrap(repository.ErrRefreshTokenExpired),
+					}
+			},
+		},
+		{
+			desc: "ErrorIssuingToken",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				tokens := mockrepo.NewTokenIssuer(t)
+				user := NewUser(Dependencies{
+					User:                  userRepo,
+					NowFunc:               testNowFunc,
+					Tokens:                tokens,
+					RefreshTokenGenerator: testRefreshTokenGenerator,
+					RefreshTokenTTL:       testRefreshTokenTTL,
+				})
+
+				newToken := testRefreshToken
+				newToken.UserID = 0
+
+				userRepo.
+					On("RotateRefreshToken", context.TODO(), "old-refresh-token", newToken).
+					Return(entity.RefreshToken{UserID: 1, UserUUID: "fake-uuid"}, nil)
+				tokens.On("Issue", "fake-uuid").Return("", time.Duration(0), errors.New("fake error"))
+
+				return user, arg{
+						ctx:          context.TODO(),
+						refreshToken: "old-refresh-token",
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				tokens := mockrepo.NewTokenIssuer(t)
+				user := NewUser(Dependencies{
+					User:                  userRepo,
+					NowFunc:               testNowFunc,
+					Tokens:                tokens,
+					RefreshTokenGenerator: testRefreshTokenGenerator,
+					RefreshTokenTTL:       testRefreshTokenTTL,
+				})
+
+				newToken := testRefreshToken
+				newToken.UserID = 0
+
+				userRepo.
+					On("RotateRefreshToken", context.TODO(), "old-refresh-token", newToken).
+					Return(entity.RefreshToken{UserID: 1, UserUUID: "fake-uuid"}, nil)
+				tokens.On("Issue", "fake-uuid").Return("fake-access-token", 5*time.Minute, nil)
+
+				return user, arg{
+						ctx:          context.TODO(),
+						refreshToken: "old-refresh-token",
+					}, expectaion{
+						session: testSession,
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			got, err := u.RefreshSession(a.ctx, a.refreshToken)
+			assert.Equal(t, e.session, got)
+			assert.Equal(t, e.err, err)
+		})
+	}
+}
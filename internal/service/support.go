@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+
+	"github.com/subroll/sqetest/internal/entity"
+)
+
+// InspectOTPs returns the user's latest OTPs, newest first, so support staff
+// can see what was sent and why a code was refused. Limit is capped the same
+// way as in ListUsers. Like the other support operations it finds disabled
+// users too, they are the ones support is most likely asked about.
+func (u *User) InspectOTPs(ctx context.Context, userUUID string, limit uint) ([]entity.OTP, error) {
+	ctx, span := startSpan(ctx, "User.InspectOTPs")
+	defer span.End()
+
+	switch {
+	case limit == 0:
+		limit = DefaultListLimit
+	case limit > MaxListLimit:
+		limit = MaxListLimit
+	}
+
+	user, err := u.userRepo.GetUser(ctx, userUUID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	otps, err := u.userRepo.ListOTPs(ctx, user.ID, limit)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return otps, nil
+}
+
+// RevokeOTP invalidates the user's active OTP of purpose, the default purpose
+// when empty, so the code can't be used anymore.
+func (u *User) RevokeOTP(ctx context.Context, userUUID, purpose string) error {
+	ctx, span := startSpan(ctx, "User.RevokeOTP")
+	defer span.End()
+
+	return u.updateActiveOTP(ctx, userUUID, purpose, u.userRepo.RevokeOTP)
+}
+
+// ExpireOTP expires the user's active OTP of purpose, the default purpose when
+// empty, so the user can request a new one without waiting for it to expire.
+func (u *User) ExpireOTP(ctx context.Context, userUUID, purpose string) error {
+	ctx, span := startSpan(ctx, "User.ExpireOTP")
+	defer span.End()
+
+	return u.updateActiveOTP(ctx, userUUID, purpose, u.userRepo.ExpireOTP)
+}
+
+func (u *User) updateActiveOTP(ctx context.Context, userUUID, purpose string,
+	update func(context.Context, uint64, string) error) error {
+	if purpose == "" {
+		purpose = u.policies.Load().defaultPurpose
+	}
+
+	user, err := u.userRepo.GetUser(ctx, userUUID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	if err := update(ctx, user.ID, purpose); err != nil {
+		return translateError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subroll/sqetest/internal/entity"
+	mockrepo "github.com/subroll/sqetest/internal/mocks/service"
+	"github.com/subroll/sqetest/internal/repository"
+)
+
+func TestUser_InspectOTPs(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx      context.Context
+		userUUID string
+		limit    uint
+	}
+
+	type expectaion struct {
+		otps []entity.OTP
+		err  error
+	}
+
+	fakeOTPs := []entity.OTP{
+		{
+			ID:          2,
+			UserID:      1,
+			Purpose:     "login",
+			Status:      entity.OTPStatusActive,
+			MaxAttempts: 5,
+			LastSentAt:  testNow,
+			ExpiredAt:   testNow.Add(5 * time.Minute),
+		},
+		{
+			ID:          1,
+			UserID:      1,
+			Purpose:     "login",
+			Status:      entity.OTPStatusExpired,
+			Attempts:    2,
+			MaxAttempts: 5,
+			LastSentAt:  testNow.Add(-time.Hour),
+			ExpiredAt:   testNow.Add(-55 * time.Minute),
+		},
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectaion)
+	}{
+		{
+			desc: "ErrorUserNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.On("GetUser", context.TODO(), "fake-uuid").Return(entity.User{}, repository.ErrNotFound)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{
+						err: ErrUserNotFound.wrap(repository.ErrNotFound),
+					}
+			},
+		},
+		{
+			desc: "ErrorListingOTPs",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.On("GetUser", context.TODO(), "fake-uuid").Return(entity.User{ID: 1, UUID: "fake-uuid"}, nil)
+				userRepo.On("ListOTPs", context.TODO(), uint64(1), uint(DefaultListLimit)).
+					Return(nil, errors.New("fake error"))
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.On("GetUser", context.TODO(), "fake-uuid").Return(entity.User{ID: 1, UUID: "fake-uuid"}, nil)
+				userRepo.On("ListOTPs", context.TODO(), uint64(1), uint(MaxListLimit)).Return(fakeOTPs, nil)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+						limit:    MaxListLimit * 2,
+					}, expectaion{
+						otps: fakeOTPs,
+					}
+			},
+		},
+		{
+			desc: "SuccessDisabledUser",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.On("GetUser", context.TODO(), "fake-uuid").
+					Return(entity.User{ID: 1, UUID: "fake-uuid", Disabled: true}, nil)
+				userRepo.On("ListOTPs", context.TODO(), uint64(1), uint(DefaultListLimit)).Return(fakeOTPs, nil)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{
+						otps: fakeOTPs,
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			got, err := u.InspectOTPs(a.ctx, a.userUUID, a.limit)
+			assert.Equal(t, e.otps, got)
+			assert.Equal(t, e.err, err)
+		})
+	}
+}
+
+func TestUser_RevokeOTP(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx      context.Context
+		userUUID string
+		purpose  string
+	}
+
+	type expectaion struct {
+		err error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectaion)
+	}{
+		{
+			desc: "ErrorUserNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.On("GetUser", context.TODO(), "fake-uuid").Return(entity.User{}, repository.ErrNotFound)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{
+						err: ErrUserNotFound.wrap(repository.ErrNotFound),
+					}
+			},
+		},
+		{
+			desc: "ErrorNoActiveOTP",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.On("GetUser", context.TODO(), "fake-uuid").Return(entity.User{ID: 1, UUID: "fake-uuid"}, nil)
+				userRepo.On("RevokeOTP", context.TODO(), uint64(1), "password_reset").Return(repository.ErrOTPNotFound)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+						purpose:  "password_reset",
+					}, expectaion{
+						err: ErrNoActiveOTP.wrap(repository.ErrOTPNotFound),
+					}
+			},
+		},
+		{
+			desc: "SuccessDefaultPurpose",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.On("GetUser", context.TODO(), "fake-uuid").Return(entity.User{ID: 1, UUID: "fake-uuid"}, nil)
+				userRepo.On("RevokeOTP", context.TODO(), uint64(1), "login").Return(nil)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{}
+			},
+		},
+		{
+			desc: "SuccessDisabledUser",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.On("GetUser", context.TODO(), "fake-uuid").
+					Return(entity.User{ID: 1, UUID: "fake-uuid", Disabled: true}, nil)
+				userRepo.On("RevokeOTP", context.TODO(), uint64(1), "login").Return(nil)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			err := u.RevokeOTP(a.ctx, a.userUUID, a.purpose)
+			assert.Equal(t, e.err, err)
+		})
+	}
+}
+
+func TestUser_ExpireOTP(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx      context.Context
+		userUUID string
+		purpose  string
+	}
+
+	type expectaion struct {
+		err error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectaion)
+	}{
+		{
+			desc: "ErrorNoActiveOTP",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.On("GetUser", context.TODO(), "fake-uuid").Return(entity.User{ID: 1, UUID: "fake-uuid"}, nil)
+				userRepo.On("ExpireOTP", context.TODO(), uint64(1), "login").Return(repository.ErrOTPNotFound)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{
+						err: ErrNoActiveOTP.wrap(repository.ErrOTPNotFound),
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newAccountUser(userRepo)
+
+				userRepo.On("GetUser", context.TODO(), "fake-uuid").Return(entity.User{ID: 1, UUID: "fake-uuid"}, nil)
+				userRepo.On("ExpireOTP", context.TODO(), uint64(1), "transaction").Return(nil)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+						purpose:  "transaction",
+					}, expectaion{}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			err := u.ExpireOTP(a.ctx, a.userUUID, a.purpose)
+			assert.Equal(t, e.err, err)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// SweeperLease is the name of the lease the replica sweeping OTPs holds.
+const SweeperLease = "otp_sweeper"
+
+type (
+	SweeperRepository interface {
+		AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+		ExpireOTPs(ctx context.Context, limit uint) (int64, error)
+		DeleteOTPs(ctx context.Context, before time.Time, limit uint) (int64, error)
+		ArchiveOTPs(ctx context.Context, before time.Time, limit uint) (int64, error)
+	}
+
+	SweeperDependencies struct {
+		Repository SweeperRepository
+
+		// Holder identifies this replica, only the holder of SweeperLease
+		// sweeps and it keeps the lease for LeaseTTL after every sweep.
+		Holder   string
+		LeaseTTL time.Duration
+
+		// OTPs that expired more than Retention ago are archived when Archive
+		// is set and deleted otherwise, BatchSize rows at a time.
+		Retention time.Duration
+		Archive   bool
+		BatchSize uint
+
+		NowFunc func() time.Time
+	}
+
+	// Sweeper marks the OTPs that are past their expiry as expired and removes
+	// the ones kept longer than the retention period, which StoreOTP alone
+	// only does for the user and purpose of a new OTP.
+	Sweeper struct {
+		repo      SweeperRepository
+		holder    string
+		leaseTTL  time.Duration
+		retention time.Duration
+		archive   bool
+		batchSize uint
+		nowFunc   func() time.Time
+	}
+
+	// SweepResult tells whether the sweep ran, i.e. this replica held the
+	// lease, and how many OTPs it expired and archived or deleted.
+	SweepResult struct {
+		Leader  bool
+		Expired int64
+		Removed int64
+	}
+)
+
+func NewSweeper(deps SweeperDependencies) *Sweeper {
+	return &Sweeper{
+		repo:      deps.Repository,
+		holder:    deps.Holder,
+		leaseTTL:  deps.LeaseTTL,
+		retention: deps.Retention,
+		archive:   deps.Archive,
+		batchSize: deps.BatchSize,
+		nowFunc:   deps.NowFunc,
+	}
+}
+
+// Sweep expires and then removes stale OTPs in batches when this replica gets
+// or keeps the lease, it does nothing while another replica holds it.
+func (s *Sweeper) Sweep(ctx context.Context) (SweepResult, error) {
+	ctx, span := startSpan(ctx, "Sweeper.Sweep")
+	defer span.End()
+
+	leader, err := s.repo.AcquireLease(ctx, SweeperLease, s.holder, s.leaseTTL)
+	if err != nil || !leader {
+		return SweepResult{}, err
+	}
+
+	res := SweepResult{Leader: true}
+	res.Expired, err = s.batches(func() (int64, error) {
+		return s.repo.ExpireOTPs(ctx, s.batchSize)
+	})
+	if err != nil {
+		return res, err
+	}
+
+	remove := s.repo.DeleteOTPs
+	if s.archive {
+		remove = s.repo.ArchiveOTPs
+	}
+
+	before := s.nowFunc().Add(-s.retention)
+	res.Removed, err = s.batches(func() (int64, error) {
+		return remove(ctx, before, s.batchSize)
+	})
+
+	return res, err
+}
+
+// batches calls batch until it handles less than a full batch and returns how
+// many rows it handled in total.
+func (s *Sweeper) batches(batch func() (int64, error)) (int64, error) {
+	var total int64
+	for {
+		n, err := batch()
+		total += n
+		if err != nil || n < int64(s.batchSize) {
+			return total, err
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	mockrepo "github.com/subroll/sqetest/internal/mocks/service"
+)
+
+const testRetention = 24 * time.Hour
+
+func testSweeper(t *testing.T, archive bool) (*Sweeper, *mockrepo.SweeperRepository) {
+	repo := mockrepo.NewSweeperRepository(t)
+
+	return NewSweeper(SweeperDependencies{
+		Repository: repo,
+		Holder:     "replica-a",
+		LeaseTTL:   2 * time.Minute,
+		Retention:  testRetention,
+		Archive:    archive,
+		BatchSize:  2,
+		NowFunc:    testNowFunc,
+	}), repo
+}
+
+func TestSweeper_Sweep(t *testing.T) {
+	t.Parallel()
+
+	type expectaion struct {
+		result SweepResult
+		err    error
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*Sweeper, expectaion)
+	}{
+		{
+			desc: "ErrorAcquiringLease",
+			mockFn: func(t *testing.T) (*Sweeper, expectaion) {
+				sweeper, repo := testSweeper(t, false)
+				repo.On("AcquireLease", context.TODO(), SweeperLease, "replica-a", 2*time.Minute).
+					Return(false, errors.New("fake error"))
+
+				return sweeper, expectaion{
+					err: errors.New("fake error"),
+				}
+			},
+		},
+		{
+			desc: "NotLeader",
+			mockFn: func(t *testing.T) (*Sweeper, expectaion) {
+				sweeper, repo := testSweeper(t, false)
+				repo.On("AcquireLease", context.TODO(), SweeperLease, "replica-a", 2*time.Minute).
+					Return(false, nil)
+
+				return sweeper, expectaion{}
+			},
+		},
+		{
+			desc: "ErrorExpiringOTPs",
+			mockFn: func(t *testing.T) (*Sweeper, expectaion) {
+				sweeper, repo := testSweeper(t, false)
+				repo.On("AcquireLease", context.TODO(), SweeperLease, "replica-a", 2*time.Minute).
+					Return(true, nil)
+				repo.On("ExpireOTPs", context.TODO(), uint(2)).Return(int64(2), nil).Once()
+				repo.On("ExpireOTPs", context.TODO(), uint(2)).Return(int64(0), errors.New("fake error")).Once()
+
+				return sweeper, expectaion{
+					result: SweepResult{Leader: true, Expired: 2},
+					err:    errors.New("fake error"),
+				}
+			},
+		},
+		{
+			desc: "ErrorDeletingOTPs",
+			mockFn: func(t *testing.T) (*Sweeper, expectaion) {
+				sweeper, repo := testSweeper(t, false)
+				repo.On("AcquireLease", context.TODO(), SweeperLease, "replica-a", 2*time.Minute).
+					Return(true, nil)
+				repo.On("ExpireOTPs", context.TODO(), uint(2)).Return(int64(1), nil).Once()
+				repo.On("DeleteOTPs", context.TODO(), testNow.Add(-testRetention), uint(2)).
+					Return(int64(0), errors.New("fake error")).Once()
+
+				return sweeper, expectaion{
+					result: SweepResult{Leader: true, Expired: 1},
+					err:    errors.New("fake error"),
+				}
+			},
+		},
+		{
+			desc: "SuccessDelete",
+			mockFn: func(t *testing.T) (*Sweeper, expectaion) {
+				sweeper, repo := testSweeper(t, false)
+				repo.On("AcquireLease", context.TODO(), SweeperLease, "replica-a", 2*time.Minute).
+					Return(true, nil)
+				repo.On("ExpireOTPs", context.TODO(), uint(2)).Return(int64(2), nil).Twice()
+				repo.On("ExpireOTPs", context.TODO(), uint(2)).Return(int64(1), nil).Once()
+				repo.On("DeleteOTPs", context.TODO(), testNow.Add(-testRetention), uint(2)).
+					Return(int64(2), nil).Once()
+				repo.On("DeleteOTPs", context.TODO(), testNow.Add(-testRetention), uint(2)).
+					Return(int64(0), nil).Once()
+
+				return sweeper, expectaion{
+					result: SweepResult{Leader: true, Expired: 5, Removed: 2},
+				}
+			},
+		},
+		{
+			desc: "SuccessArchive",
+			mockFn: func(t *testing.T) (*Sweeper, expectaion) {
+				sweeper, repo := testSweeper(t, true)
+				repo.On("AcquireLease", context.TODO(), SweeperLease, "replica-a", 2*time.Minute).
+					Return(true, nil)
+				repo.On("ExpireOTPs", context.TODO(), uint(2)).Return(int64(0), nil).Once()
+				repo.On("ArchiveOTPs", context.TODO(), testNow.Add(-testRetention), uint(2)).
+					Return(int64(1), nil).Once()
+
+				return sweeper, expectaion{
+					result: SweepResult{Leader: true, Removed: 1},
+				}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			s, e := tC.mockFn(t)
+
+			got, err := s.Sweep(context.TODO())
+			assert.Equal(t, e.result, got)
+			assert.Equal(t, e.err, err)
+		})
+	}
+}
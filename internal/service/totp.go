@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+
+	"github.com/subroll/sqetest/internal/entity"
+	"github.com/subroll/sqetest/internal/pkg/totp"
+)
+
+const (
+	MethodOTP  = "otp"
+	MethodTOTP = "totp"
+)
+
+// TOTPEnrollment is what the user needs to add the secret to an authenticator
+// app, QRCode is a PNG encoding URI.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+	QRCode []byte
+}
+
+// EnrollTOTP starts an authenticator app enrollment with a new secret, the
+// enrollment only becomes usable once it is confirmed with ConfirmTOTP.
+// Starting again before confirming replaces the pending secret.
+func (u *User) EnrollTOTP(ctx context.Context, userUUID string) (TOTPEnrollment, error) {
+	ctx, span := startSpan(ctx, "User.EnrollTOTP")
+	defer span.End()
+
+	user, err := u.userRepo.GetUserByUUID(ctx, userUUID)
+	if err != nil {
+		return TOTPEnrollment{}, translateError(err)
+	}
+
+	secret, err := u.totpSecretGenerator()
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+
+	sealed, err := u.secretBox.Seal(secret)
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+
+	if err := u.userRepo.StoreTOTPSecret(ctx, user.ID, sealed); err != nil {
+		return TOTPEnrollment{}, translateError(err)
+	}
+
+	uri := u.totp.URI(u.totpIssuer, totpAccount(user), secret)
+	qrCode, err := u.qrCodeEncoder(uri)
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+
+	return TOTPEnrollment{
+		Secret: totp.EncodeSecret(secret),
+		URI:    uri,
+		QRCode: qrCode,
+	}, nil
+}
+
+// ConfirmTOTP completes a pending enrollment once the user proves the app
+// generates valid codes.
+func (u *User) ConfirmTOTP(ctx context.Context, userUUID, code string) error {
+	ctx, span := startSpan(ctx, "User.ConfirmTOTP")
+	defer span.End()
+
+	userID, err := u.userRepo.GetUserIDByUUID(ctx, userUUID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	if err := u.userRepo.CheckLockout(ctx, userID); err != nil {
+		return translateError(err)
+	}
+
+	enrollment, err := u.userRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	if enrollment.Confirmed {
+		return ErrTOTPAlreadyEnrolled
+	}
+
+	counter, err := u.verifyTOTP(ctx, enrollment, code)
+	if err != nil {
+		return err
+	}
+
+	return translateError(u.userRepo.ConfirmTOTP(ctx, userID, counter))
+}
+
+func (u *User) validateTOTP(ctx context.Context, userUUID, code string) (uint64, error) {
+	userID, err := u.userRepo.GetUserIDByUUID(ctx, userUUID)
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	if err := u.userRepo.CheckLockout(ctx, userID); err != nil {
+		return 0, translateError(err)
+	}
+
+	enrollment, err := u.userRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	if !enrollment.Confirmed {
+		return 0, ErrTOTPNotEnrolled
+	}
+
+	counter, err := u.verifyTOTP(ctx, enrollment, code)
+	if err != nil {
+		return 0, err
+	}
+
+	if counter <= enrollment.LastCounter {
+		return 0, ErrOTPReplayed
+	}
+
+	if err := u.userRepo.UseTOTPCounter(ctx, userID, counter); err != nil {
+		return 0, translateError(err)
+	}
+
+	return userID, nil
+}
+
+// verifyTOTP returns the time step code was generated for, an invalid code
+// counts towards the lockout of the user like an invalid OTP.
+func (u *User) verifyTOTP(ctx context.Context, enrollment entity.TOTP, code string) (uint64, error) {
+	secret, err := u.secretBox.Open(enrollment.Secret)
+	if err != nil {
+		return 0, err
+	}
+
+	counter, ok := u.totp.Verify(secret, code, u.nowFunc())
+	if !ok {
+		if err := u.userRepo.FailTOTPAttempt(ctx, enrollment.UserID, u.totpMaxAttempts); err != nil {
+			return 0, translateError(err)
+		}
+
+		return 0, ErrInvalidOTP
+	}
+
+	return counter, nil
+}
+
+func totpAccount(user entity.User) string {
+	if user.Email != "" {
+		return user.Email
+	}
+
+	return user.UUID
+}
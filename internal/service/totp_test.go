@@ -0,0 +1,660 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subroll/sqetest/internal/entity"
+	mockrepo "github.com/subroll/sqetest/internal/mocks/service"
+	"github.com/subroll/sqetest/internal/pkg/totp"
+	"github.com/subroll/sqetest/internal/repository"
+)
+
+var (
+	// testTOTPSecret and testTOTPCode are the RFC 6238 test secret and its
+	// 6 digits code at testTOTPTime, time step 1.
+	testTOTPSecret = []byte("12345678901234567890")
+	testTOTPCode   = "287082"
+	testTOTPTime   = time.Unix(59, 0)
+
+	testTOTPURI = "otpauth://totp/sqetest:john@example.com?algorithm=SHA1&digits=6&issuer=sqetest&period=30" +
+		"&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+)
+
+func newTOTPUser(userRepo UserRepository, secretBox SecretBox) *User {
+	return NewUser(Dependencies{
+		User:            userRepo,
+		TOTP:            totp.Params{Digits: 6, Period: 30 * time.Second, Skew: 1},
+		TOTPIssuer:      "sqetest",
+		TOTPMaxAttempts: 3,
+		TOTPSecretGenerator: func() ([]byte, error) {
+			return testTOTPSecret, nil
+		},
+		SecretBox: secretBox,
+		QRCodeEncoder: func(content string) ([]byte, error) {
+			return []byte("png:" + content), nil
+		},
+		NowFunc: func() time.Time {
+			return testTOTPTime
+		},
+	})
+}
+
+func TestUser_EnrollTOTP(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx      context.Context
+		userUUID string
+	}
+
+	type expectaion struct {
+		enrollment TOTPEnrollment
+		err        error
+	}
+
+	fakeUser := entity.User{
+		ID:    1,
+		UUID:  "fake-uuid",
+		Name:  "John Doe",
+		Email: "john@example.com",
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectaion)
+	}{
+		{
+			desc: "ErrorUserNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newTOTPUser(userRepo, mockrepo.NewSecretBox(t))
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(entity.User{}, repository.ErrNotFound)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{
+						err: ErrUserNotFound.wrap(repository.ErrNotFound),
+					}
+			},
+		},
+		{
+			desc: "ErrorGeneratingSecret",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newTOTPUser(userRepo, mockrepo.NewSecretBox(t))
+				user.totpSecretGenerator = func() ([]byte, error) {
+					return nil, errors.New("fake error")
+				}
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorSealingSecret",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				secretBox := mockrepo.NewSecretBox(t)
+				user := newTOTPUser(userRepo, secretBox)
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				secretBox.On("Seal", testTOTPSecret).Return("", errors.New("fake error"))
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorAlreadyEnrolled",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				secretBox := mockrepo.NewSecretBox(t)
+				user := newTOTPUser(userRepo, secretBox)
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				secretBox.On("Seal", testTOTPSecret).Return("fake-sealed-secret", nil)
+				userRepo.On("StoreTOTPSecret", context.TODO(), uint64(1), "fake-sealed-secret").
+					Return(repository.ErrTOTPEnrolled)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{
+						err: ErrTOTPAlreadyEnrolled.wrap(repository.ErrTOTPEnrolled),
+					}
+			},
+		},
+		{
+			desc: "ErrorEncodingQRCode",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				secretBox := mockrepo.NewSecretBox(t)
+				user := newTOTPUser(userRepo, secretBox)
+				user.qrCodeEncoder = func(string) ([]byte, error) {
+					return nil, errors.New("fake error")
+				}
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				secretBox.On("Seal", testTOTPSecret).Return("fake-sealed-secret", nil)
+				userRepo.On("StoreTOTPSecret", context.TODO(), uint64(1), "fake-sealed-secret").Return(nil)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				secretBox := mockrepo.NewSecretBox(t)
+				user := newTOTPUser(userRepo, secretBox)
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				secretBox.On("Seal", testTOTPSecret).Return("fake-sealed-secret", nil)
+				userRepo.On("StoreTOTPSecret", context.TODO(), uint64(1), "fake-sealed-secret").Return(nil)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+					}, expectaion{
+						enrollment: TOTPEnrollment{
+							Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+							URI:    testTOTPURI,
+							QRCode: []byte("png:" + testTOTPURI),
+						},
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			got, err := u.EnrollTOTP(a.ctx, a.userUUID)
+			assert.Equal(t, e.enrollment, got)
+			assert.Equal(t, e.err, err)
+		})
+	}
+}
+
+func TestUser_ConfirmTOTP(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx      context.Context
+		userUUID string
+		code     string
+	}
+
+	type expectaion struct {
+		err error
+	}
+
+	pending := entity.TOTP{UserID: 1, Secret: "fake-sealed-secret"}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectaion)
+	}{
+		{
+			desc: "ErrorGetUserIDByUUID",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newTOTPUser(userRepo, mockrepo.NewSecretBox(t))
+
+				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(0), errors.New("fake error"))
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+						code:     testTOTPCode,
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorUserLocked",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newTOTPUser(userRepo, mockrepo.NewSecretBox(t))
+
+				lockedErr := &repository.LockedError{Err: repository.ErrUserLocked, RetryAfter: time.Minute}
+
+				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
+				userRepo.On("CheckLockout", context.TODO(), uint64(1)).Return(lockedErr)
+
+				expErr := ErrUserLocked.wrap(lockedErr)
+				expErr.RetryAfter = time.Minute
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+						code:     testTOTPCode,
+					}, expectaion{
+						err: expErr,
+					}
+			},
+		},
+		{
+			desc: "ErrorNotEnrolled",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newTOTPUser(userRepo, mockrepo.NewSecretBox(t))
+
+				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
+				userRepo.On("CheckLockout", context.TODO(), uint64(1)).Return(nil)
+				userRepo.On("GetTOTP", context.TODO(), uint64(1)).Return(entity.TOTP{}, repository.ErrTOTPNotFound)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+						code:     testTOTPCode,
+					}, expectaion{
+						err: ErrTOTPNotEnrolled.wrap(repository.ErrTOTPNotFound),
+					}
+			},
+		},
+		{
+			desc: "ErrorAlreadyConfirmed",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newTOTPUser(userRepo, mockrepo.NewSecretBox(t))
+
+				confirmed := pending
+				confirmed.Confirmed = true
+
+				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
+				userRepo.On("CheckLockout", context.TODO(), uint64(1)).Return(nil)
+				userRepo.On("GetTOTP", context.TODO(), uint64(1)).Return(confirmed, nil)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+						code:     testTOTPCode,
+					}, expectaion{
+						err: ErrTOTPAlreadyEnrolled,
+					}
+			},
+		},
+		{
+			desc: "ErrorOpeningSecret",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				secretBox := mockrepo.NewSecretBox(t)
+				user := newTOTPUser(userRepo, secretBox)
+
+				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
+				userRepo.On("CheckLockout", context.TODO(), uint64(1)).Return(nil)
+				userRepo.On("GetTOTP", context.TODO(), uint64(1)).Return(pending, nil)
+				secretBox.On("Open", "fake-sealed-secret").Return(nil, errors.New("fake error"))
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+						code:     testTOTPCode,
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorInvalidCode",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				secretBox := mockrepo.NewSecretBox(t)
+				user := newTOTPUser(userRepo, secretBox)
+
+				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
+				userRepo.On("CheckLockout", context.TODO(), uint64(1)).Return(nil)
+				userRepo.On("GetTOTP", context.TODO(), uint64(1)).Return(pending, nil)
+				secretBox.On("Open", "fake-sealed-secret").Return(testTOTPSecret, nil)
+				userRepo.On("FailTOTPAttempt", context.TODO(), uint64(1), uint8(3)).Return(repository.ErrInvalidOTP)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+						code:     "000000",
+					}, expectaion{
+						err: ErrInvalidOTP.wrap(repository.ErrInvalidOTP),
+					}
+			},
+		},
+		{
+			desc: "ErrorTooManyAttempts",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				secretBox := mockrepo.NewSecretBox(t)
+				user := newTOTPUser(userRepo, secretBox)
+
+				lockedErr := &repository.LockedError{Err: repository.ErrTooManyAttempts, RetryAfter: time.Minute}
+
+				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
+				userRepo.On("CheckLockout", context.TODO(), uint64(1)).Return(nil)
+				userRepo.On("GetTOTP", context.TODO(), uint64(1)).Return(pending, nil)
+				secretBox.On("Open", "fake-sealed-secret").Return(testTOTPSecret, nil)
+				userRepo.On("FailTOTPAttempt", context.TODO(), uint64(1), uint8(3)).Return(lockedErr)
+
+				expErr := ErrTooManyAttempts.wrap(lockedErr)
+				expErr.RetryAfter = time.Minute
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+						code:     "000000",
+					}, expectaion{
+						err: expErr,
+					}
+			},
+		},
+		{
+			desc: "ErrorConfirming",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				secretBox := mockrepo.NewSecretBox(t)
+				user := newTOTPUser(userRepo, secretBox)
+
+				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
+				userRepo.On("CheckLockout", context.TODO(), uint64(1)).Return(nil)
+				userRepo.On("GetTOTP", context.TODO(), uint64(1)).Return(pending, nil)
+				secretBox.On("Open", "fake-sealed-secret").Return(testTOTPSecret, nil)
+				userRepo.On("ConfirmTOTP", context.TODO(), uint64(1), uint64(1)).Return(errors.New("fake error"))
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+						code:     testTOTPCode,
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "Success",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				secretBox := mockrepo.NewSecretBox(t)
+				user := newTOTPUser(userRepo, secretBox)
+
+				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
+				userRepo.On("CheckLockout", context.TODO(), uint64(1)).Return(nil)
+				userRepo.On("GetTOTP", context.TODO(), uint64(1)).Return(pending, nil)
+				secretBox.On("Open", "fake-sealed-secret").Return(testTOTPSecret, nil)
+				userRepo.On("ConfirmTOTP", context.TODO(), uint64(1), uint64(1)).Return(nil)
+
+				return user, arg{
+						ctx:      context.TODO(),
+						userUUID: "fake-uuid",
+						code:     testTOTPCode,
+					}, expectaion{}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			err := u.ConfirmTOTP(a.ctx, a.userUUID, a.code)
+			assert.Equal(t, e.err, err)
+		})
+	}
+}
+
+func TestUser_ValidateOTPWithTOTP(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx    context.Context
+		params ValidateOTPParams
+	}
+
+	type expectaion struct {
+		session Session
+		err     error
+	}
+
+	confirmed := entity.TOTP{UserID: 1, Secret: "fake-sealed-secret", Confirmed: true}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectaion)
+	}{
+		{
+			desc: "ErrorUserLocked",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newTOTPUser(userRepo, mockrepo.NewSecretBox(t))
+
+				lockedErr := &repository.LockedError{Err: repository.ErrUserLocked, RetryAfter: time.Minute}
+
+				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
+				userRepo.On("CheckLockout", context.TODO(), uint64(1)).Return(lockedErr)
+
+				expErr := ErrUserLocked.wrap(lockedErr)
+				expErr.RetryAfter = time.Minute
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID: "fake-uuid",
+							OTP:      testTOTPCode,
+							Method:   MethodTOTP,
+						},
+					}, expectaion{
+						err: expErr,
+					}
+			},
+		},
+		{
+			desc: "ErrorNotConfirmed",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := newTOTPUser(userRepo, mockrepo.NewSecretBox(t))
+
+				pending := confirmed
+				pending.Confirmed = false
+
+				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
+				userRepo.On("CheckLockout", context.TODO(), uint64(1)).Return(nil)
+				userRepo.On("GetTOTP", context.TODO(), uint64(1)).Return(pending, nil)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID: "fake-uuid",
+							OTP:      testTOTPCode,
+							Method:   MethodTOTP,
+						},
+					}, expectaion{
+						err: ErrTOTPNotEnrolled,
+					}
+			},
+		},
+		{
+			desc: "ErrorInvalidCode",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				secretBox := mockrepo.NewSecretBox(t)
+				user := newTOTPUser(userRepo, secretBox)
+
+				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
+				userRepo.On("CheckLockout", context.TODO(), uint64(1)).Return(nil)
+				userRepo.On("GetTOTP", context.TODO(), uint64(1)).Return(confirmed, nil)
+				secretBox.On("Open", "fake-sealed-secret").Return(testTOTPSecret, nil)
+				userRepo.On("FailTOTPAttempt", context.TODO(), uint64(1), uint8(3)).Return(repository.ErrInvalidOTP)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID: "fake-uuid",
+							OTP:      "000000",
+							Method:   MethodTOTP,
+						},
+					}, expectaion{
+						err: ErrInvalidOTP.wrap(repository.ErrInvalidOTP),
+					}
+			},
+		},
+		{
+			desc: "ErrorTooManyAttempts",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				secretBox := mockrepo.NewSecretBox(t)
+				user := newTOTPUser(userRepo, secretBox)
+
+				lockedErr := &repository.LockedError{Err: repository.ErrTooManyAttempts, RetryAfter: time.Minute}
+
+				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
+				userRepo.On("CheckLockout", context.TODO(), uint64(1)).Return(nil)
+				userRepo.On("GetTOTP", context.TODO(), uint64(1)).Return(confirmed, nil)
+				secretBox.On("Open", "fake-sealed-secret").Return(testTOTPSecret, nil)
+				userRepo.On("FailTOTPAttempt", context.TODO(), uint64(1), uint8(3)).Return(lockedErr)
+
+				expErr := ErrTooManyAttempts.wrap(lockedErr)
+				expErr.RetryAfter = time.Minute
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID: "fake-uuid",
+							OTP:      "000000",
+							Method:   MethodTOTP,
+						},
+					}, expectaion{
+						err: expErr,
+					}
+			},
+		},
+		{
+			desc: "ErrorReplayedCode",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				secretBox := mockrepo.NewSecretBox(t)
+				user := newTOTPUser(userRepo, secretBox)
+
+				used := confirmed
+				used.LastCounter = 1
+
+				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
+				userRepo.On("CheckLockout", context.TODO(), uint64(1)).Return(nil)
+				userRepo.On("GetTOTP", context.TODO(), uint64(1)).Return(used, nil)
+				secretBox.On("Open", "fake-sealed-secret").Return(testTOTPSecret, nil)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID: "fake-uuid",
+							OTP:      testTOTPCode,
+							Method:   MethodTOTP,
+						},
+					}, expectaion{
+						err: ErrOTPReplayed,
+					}
+			},
+		},
+		{
+			desc: "ErrorReplayedConcurrently",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				secretBox := mockrepo.NewSecretBox(t)
+				user := newTOTPUser(userRepo, secretBox)
+
+				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
+				userRepo.On("CheckLockout", context.TODO(), uint64(1)).Return(nil)
+				userRepo.On("GetTOTP", context.TODO(), uint64(1)).Return(confirmed, nil)
+				secretBox.On("Open", "fake-sealed-secret").Return(testTOTPSecret, nil)
+				userRepo.On("UseTOTPCounter", context.TODO(), uint64(1), uint64(1)).Return(repository.ErrOTPReplayed)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID: "fake-uuid",
+							OTP:      testTOTPCode,
+							Method:   MethodTOTP,
+						},
+					}, expectaion{
+						err: ErrOTPReplayed.wrap(repository.ErrOTPReplayed),
+					}
+			},
+		},
+		{
+			desc: "SuccessWithinSkew",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				secretBox := mockrepo.NewSecretBox(t)
+				user := newTOTPUser(userRepo, secretBox)
+				tokens := mockrepo.NewTokenIssuer(t)
+				user.nowFunc = func() time.Time {
+					return testTOTPTime.Add(30 * time.Second)
+				}
+				user.tokens = tokens
+				user.refreshTokenGenerator = testRefreshTokenGenerator
+				user.refreshTokenTTL = testRefreshTokenTTL
+
+				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
+				userRepo.On("CheckLockout", context.TODO(), uint64(1)).Return(nil)
+				userRepo.On("GetTOTP", context.TODO(), uint64(1)).Return(confirmed, nil)
+				secretBox.On("Open", "fake-sealed-secret").Return(testTOTPSecret, nil)
+				userRepo.On("UseTOTPCounter", context.TODO(), uint64(1), uint64(1)).Return(nil)
+				tokens.On("Issue", "fake-uuid").Return("fake-access-token", 5*time.Minute, nil)
+				userRepo.On("StoreRefreshToken", context.TODO(), entity.RefreshToken{
+					UserID:    1,
+					Token:     "fake-refresh-token",
+					ExpiresAt: testTOTPTime.Add(30*time.Second + testRefreshTokenTTL),
+				}).Return(nil)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID: "fake-uuid",
+							OTP:      testTOTPCode,
+							Method:   MethodTOTP,
+						},
+					}, expectaion{
+						session: testSession,
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			got, err := u.ValidateOTP(a.ctx, a.params)
+			assert.Equal(t, e.session, got)
+			assert.Equal(t, e.err, err)
+		})
+	}
+}
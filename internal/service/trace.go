@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/subroll/sqetest/internal/service")
+
+// startSpan starts the span of an exported method of User. A span that isn't
+// recorded has nothing to pass on, ctx is returned as is then so the
+// repository keeps seeing the caller's span.
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	spanCtx, span := tracer.Start(ctx, name)
+	if !span.IsRecording() {
+		return ctx, span
+	}
+
+	return spanCtx, span
+}
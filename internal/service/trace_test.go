@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	mockrepo "github.com/subroll/sqetest/internal/mocks/service"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestUser_startSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevTracer := tracer
+	tracer = provider.Tracer("github.com/subroll/sqetest/internal/service")
+	t.Cleanup(func() {
+		tracer = prevTracer
+		_ = provider.Shutdown(context.Background())
+	})
+
+	userRepo := mockrepo.NewUserRepository(t)
+	user := newAccountUser(userRepo)
+
+	var repoSpan trace.SpanContext
+	userRepo.On("DisableUser", mock.Anything, "fake-uuid").
+		Run(func(args mock.Arguments) {
+			repoSpan = trace.SpanContextFromContext(args.Get(0).(context.Context))
+		}).
+		Return(nil)
+
+	assert.NoError(t, user.DisableUser(context.Background(), "fake-uuid"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "User.DisableUser", spans[0].Name())
+	assert.Equal(t, spans[0].SpanContext(), repoSpan)
+}
@@ -2,49 +2,396 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/subroll/sqetest/internal/entity"
+	"github.com/subroll/sqetest/internal/pkg/totp"
+	"github.com/subroll/sqetest/internal/repository"
 )
 
+// Outcomes of a successful OTP operation reported to Dependencies.OTPEvents, a
+// failed one reports the code of its domain error or OTPFailed.
 const (
-	otpLength = 5
+	OTPIssued    = "issued"
+	OTPResent    = "resent"
+	OTPValidated = "validated"
+	OTPFailed    = "internal_error"
 )
 
 type (
 	User struct {
-		userRepo     UserRepository
-		otpGenerator func(uint8) (string, error)
+		userRepo       UserRepository
+		otpGenerator   func(string, uint8) (string, error)
+		challengeIDGen func() (string, error)
+		senders        map[string]Sender
+		defaultChannel string
+		policies       atomic.Pointer[policySet]
+
+		totp                totp.Params
+		totpIssuer          string
+		totpMaxAttempts     uint8
+		totpSecretGenerator func() ([]byte, error)
+		secretBox           SecretBox
+		qrCodeEncoder       func(string) ([]byte, error)
+		nowFunc             func() time.Time
+
+		tokens                TokenIssuer
+		refreshTokenGenerator func() (string, error)
+		refreshTokenTTL       time.Duration
+
+		uuidGenerator func() string
+
+		bindFingerprint bool
+		bindIP          bool
+
+		otpEvents func(purpose, outcome string)
+	}
+
+	policySet struct {
+		policies       map[string]Policy
+		defaultPurpose string
+	}
+
+	// GenerateOTPParams issues an OTP to Client, ValidateOTP has to be given
+	// the same client. RequestID is only passed on to trace the request.
+	GenerateOTPParams struct {
+		UserUUID  string
+		Purpose   string
+		Channel   string
+		RequestID string
+		Client    Client
+	}
+
+	// Client is where a request comes from, Fingerprint identifies the device
+	// and is given by the client itself.
+	Client struct {
+		Fingerprint string
+		IP          string
+	}
+
+	// ResendOTPParams resends the user's active OTP of Purpose through
+	// Channel, the channel is resolved the same way as in GenerateOTP.
+	ResendOTPParams struct {
+		UserUUID string
+		Purpose  string
+		Channel  string
+	}
+
+	// ValidateOTPParams validates a code sent by GenerateOTP for the challenge
+	// ChallengeID, or a code of the user's authenticator app when Method is
+	// MethodTOTP. Purpose, ChallengeID and Client don't apply to authenticator
+	// app codes.
+	ValidateOTPParams struct {
+		UserUUID    string
+		OTP         string
+		Method      string
+		Purpose     string
+		ChallengeID string
+		Client      Client
 	}
 )
 
 func NewUser(deps Dependencies) *User {
-	return &User{
-		userRepo:     deps.User,
-		otpGenerator: deps.RandNumberGenerator,
+	u := &User{
+		userRepo:       deps.User,
+		otpGenerator:   deps.RandStringGenerator,
+		challengeIDGen: deps.ChallengeIDGenerator,
+		senders:        deps.Senders,
+		defaultChannel: deps.DefaultChannel,
+
+		totp:                deps.TOTP,
+		totpIssuer:          deps.TOTPIssuer,
+		totpMaxAttempts:     deps.TOTPMaxAttempts,
+		totpSecretGenerator: deps.TOTPSecretGenerator,
+		secretBox:           deps.SecretBox,
+		qrCodeEncoder:       deps.QRCodeEncoder,
+		nowFunc:             deps.NowFunc,
+
+		tokens:                deps.Tokens,
+		refreshTokenGenerator: deps.RefreshTokenGenerator,
+		refreshTokenTTL:       deps.RefreshTokenTTL,
+
+		uuidGenerator: deps.UUIDGenerator,
+
+		bindFingerprint: deps.BindFingerprint,
+		bindIP:          deps.BindIP,
+
+		otpEvents: deps.OTPEvents,
+	}
+	if u.otpEvents == nil {
+		u.otpEvents = func(string, string) {}
+	}
+	u.SetPolicies(deps.Policies, deps.DefaultPurpose)
+
+	return u
+}
+
+// SetPolicies replaces the OTP policies and the default purpose, requests
+// already being served keep the policy they started with.
+func (u *User) SetPolicies(policies map[string]Policy, defaultPurpose string) {
+	u.policies.Store(&policySet{policies: policies, defaultPurpose: defaultPurpose})
+}
+
+// GenerateOTP creates a new OTP following the policy of the requested purpose
+// and delivers it through the requested channel, falling back to the user's
+// preferred channel and then the default channel when no channel is given.
+func (u *User) GenerateOTP(ctx context.Context, params GenerateOTPParams) (Delivery, error) {
+	ctx, span := startSpan(ctx, "User.GenerateOTP")
+	defer span.End()
+
+	purpose, policy, err := u.policy(params.Purpose)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	delivery, err := u.generateOTP(ctx, purpose, policy, params)
+	u.otpEvent(purpose, OTPIssued, err)
+
+	return delivery, err
+}
+
+func (u *User) generateOTP(ctx context.Context, purpose string, policy Policy,
+	params GenerateOTPParams) (Delivery, error) {
+	user, err := u.userRepo.GetUserByUUID(ctx, params.UserUUID)
+	if err != nil {
+		return Delivery{}, translateError(err)
+	}
+
+	channel, sender, err := u.sender(params.Channel, user)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	otp, err := u.otpGenerator(policy.Charset, policy.Length)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	challengeID, err := u.challengeIDGen()
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	expiresAt := u.nowFunc().Add(policy.TTL)
+	if err := u.userRepo.StoreOTP(ctx, entity.OTP{
+		UserID:         user.ID,
+		Code:           otp,
+		Purpose:        purpose,
+		RequestID:      params.RequestID,
+		ChallengeID:    challengeID,
+		Binding:        u.binding(params.Client),
+		TTL:            policy.TTL,
+		MaxAttempts:    policy.MaxAttempts,
+		ResendCooldown: policy.ResendCooldown,
+		MaxResends:     policy.MaxResends,
+	}); err != nil {
+		return Delivery{}, translateError(err)
+	}
+
+	ref, err := send(ctx, sender, user, otp, params.RequestID)
+	if err != nil {
+		// the code never reached the user, expire it so that a new one can be
+		// requested straight away instead of failing with ErrOTPExist
+		if expireErr := u.userRepo.ExpireOTP(ctx, user.ID, purpose); expireErr != nil &&
+			!errors.Is(expireErr, repository.ErrOTPNotFound) {
+			return Delivery{}, errors.Join(err, expireErr)
+		}
+
+		return Delivery{}, err
+	}
+
+	return policy.delivery(channel, ref, challengeID, expiresAt, 0), nil
+}
+
+// ResendOTP replaces the code of the user's active OTP with a new one and
+// delivers it again, keeping the original challenge ID. Resends are limited by
+// the cooldown and the maximum number of resends of the purpose's policy.
+func (u *User) ResendOTP(ctx context.Context, params ResendOTPParams) (Delivery, error) {
+	ctx, span := startSpan(ctx, "User.ResendOTP")
+	defer span.End()
+
+	purpose, policy, err := u.policy(params.Purpose)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	delivery, err := u.resendOTP(ctx, purpose, policy, params)
+	u.otpEvent(purpose, OTPResent, err)
+
+	return delivery, err
+}
+
+func (u *User) resendOTP(ctx context.Context, purpose string, policy Policy,
+	params ResendOTPParams) (Delivery, error) {
+	user, err := u.userRepo.GetUserByUUID(ctx, params.UserUUID)
+	if err != nil {
+		return Delivery{}, translateError(err)
+	}
+
+	channel, sender, err := u.sender(params.Channel, user)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	otp, err := u.otpGenerator(policy.Charset, policy.Length)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	expiresAt := u.nowFunc().Add(policy.TTL)
+	stored, err := u.userRepo.ResendOTP(ctx, entity.OTP{
+		UserID:         user.ID,
+		Code:           otp,
+		Purpose:        purpose,
+		TTL:            policy.TTL,
+		MaxAttempts:    policy.MaxAttempts,
+		ResendCooldown: policy.ResendCooldown,
+		MaxResends:     policy.MaxResends,
+	})
+	if err != nil {
+		return Delivery{}, translateError(err)
+	}
+
+	ref, err := send(ctx, sender, user, otp, stored.RequestID)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	return policy.delivery(channel, ref, stored.ChallengeID, expiresAt, stored.ResendCount), nil
+}
+
+// ValidateOTP validates the code and starts a session for the user.
+func (u *User) ValidateOTP(ctx context.Context, params ValidateOTPParams) (Session, error) {
+	ctx, span := startSpan(ctx, "User.ValidateOTP")
+	defer span.End()
+
+	var (
+		userID uint64
+		err    error
+	)
+	if params.Method == MethodTOTP {
+		userID, err = u.validateTOTP(ctx, params.UserUUID, params.OTP)
+	} else {
+		userID, err = u.validateOTP(ctx, params)
+	}
+	if err != nil {
+		return Session{}, err
+	}
+
+	return u.newSession(ctx, userID, params.UserUUID)
+}
+
+func (u *User) validateOTP(ctx context.Context, params ValidateOTPParams) (uint64, error) {
+	purpose, policy, err := u.policy(params.Purpose)
+	if err != nil {
+		return 0, err
 	}
+
+	userID, err := u.checkOTP(ctx, purpose, policy, params)
+	u.otpEvent(purpose, OTPValidated, err)
+
+	return userID, err
 }
 
-func (u *User) GenerateOTP(ctx context.Context, userUUID, requestID string) (string, error) {
-	userID, err := u.userRepo.GetUserIDByUUID(ctx, userUUID)
+func (u *User) checkOTP(ctx context.Context, purpose string, policy Policy,
+	params ValidateOTPParams) (uint64, error) {
+	userID, err := u.userRepo.GetUserIDByUUID(ctx, params.UserUUID)
 	if err != nil {
-		return "", err
+		return 0, translateError(err)
+	}
+
+	if err := u.userRepo.UpdateOTPStatus(ctx, userID, policy.normalize(params.OTP), purpose,
+		params.ChallengeID, u.binding(params.Client)); err != nil {
+		return 0, translateError(err)
+	}
+
+	return userID, nil
+}
+
+// binding digests the parts of client OTPs are bound to, it is empty when they
+// aren't bound.
+func (u *User) binding(client Client) string {
+	if !u.bindFingerprint && !u.bindIP {
+		return ""
+	}
+
+	h := sha256.New()
+	if u.bindFingerprint {
+		h.Write([]byte("fingerprint:" + client.Fingerprint + "\n"))
+	}
+	if u.bindIP {
+		h.Write([]byte("ip:" + client.IP + "\n"))
 	}
 
-	otp, err := u.otpGenerator(otpLength)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// otpEvent reports the outcome of an OTP operation of purpose, success when
+// err is nil and the code of the domain error otherwise.
+func (u *User) otpEvent(purpose, success string, err error) {
+	outcome := success
 	if err != nil {
-		return "", err
+		outcome = OTPFailed
+
+		var svcErr *Error
+		if errors.As(err, &svcErr) {
+			outcome = svcErr.Code
+		}
+	}
+
+	u.otpEvents(purpose, outcome)
+}
+
+func (u *User) policy(purpose string) (string, Policy, error) {
+	set := u.policies.Load()
+	if purpose == "" {
+		purpose = set.defaultPurpose
 	}
 
-	if err := u.userRepo.StoreOTP(ctx, userID, otp, requestID); err != nil {
-		return "", err
+	policy, ok := set.policies[purpose]
+	if !ok {
+		return "", Policy{}, ErrUnsupportedPurpose
 	}
 
-	return otp, nil
+	return purpose, policy, nil
 }
 
-func (u *User) ValidateOTP(ctx context.Context, userUUID, otp, requestID string) error {
-	userID, err := u.userRepo.GetUserIDByUUID(ctx, userUUID)
+// sender resolves the channel to deliver the user's OTP through and its
+// Sender.
+func (u *User) sender(channel string, user entity.User) (string, Sender, error) {
+	switch {
+	case channel != "":
+	case user.OTPChannel != "":
+		channel = user.OTPChannel
+	default:
+		channel = u.defaultChannel
+	}
+
+	sender, ok := u.senders[channel]
+	if !ok {
+		return "", nil, ErrUnsupportedChannel
+	}
+
+	return channel, sender, nil
+}
+
+func send(ctx context.Context, sender Sender, user entity.User, otp, requestID string) (string, error) {
+	ref, err := sender.Send(ctx, Message{
+		Recipient: user,
+		OTP:       otp,
+		RequestID: requestID,
+	})
 	if err != nil {
-		return err
+		if errors.Is(err, ErrRecipientUnreachable) {
+			return "", err
+		}
+
+		return "", ErrDeliveryFailed.wrap(err)
 	}
 
-	return u.userRepo.UpdateOTPStatus(ctx, userID, otp, requestID)
+	return ref, nil
 }
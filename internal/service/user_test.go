@@ -4,116 +4,1119 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/subroll/sqetest/internal/entity"
 	mockrepo "github.com/subroll/sqetest/internal/mocks/service"
+	"github.com/subroll/sqetest/internal/pkg/stringutil"
+	"github.com/subroll/sqetest/internal/repository"
 )
 
+var (
+	testPolicies = map[string]Policy{
+		"login": {
+			Length:      5,
+			Charset:     stringutil.DigitsCharset,
+			TTL:         5 * time.Minute,
+			MaxAttempts: 3,
+		},
+		"password_reset": {
+			Length:         5,
+			Charset:        stringutil.DigitsCharset,
+			TTL:            10 * time.Minute,
+			MaxAttempts:    3,
+			ResendCooldown: 30 * time.Second,
+			MaxResends:     2,
+		},
+		"transaction": {
+			Length:      6,
+			Charset:     stringutil.CrockfordBase32Charset,
+			TTL:         2 * time.Minute,
+			MaxAttempts: 3,
+		},
+	}
+
+	testOTP = entity.OTP{
+		UserID:      1,
+		Code:        "xxxxx",
+		Purpose:     "login",
+		RequestID:   "fake-request-id",
+		ChallengeID: "fake-challenge-id",
+		TTL:         5 * time.Minute,
+		MaxAttempts: 3,
+	}
+
+	testResendOTP = entity.OTP{
+		UserID:         1,
+		Code:           "xxxxx",
+		Purpose:        "password_reset",
+		TTL:            10 * time.Minute,
+		MaxAttempts:    3,
+		ResendCooldown: 30 * time.Second,
+		MaxResends:     2,
+	}
+
+	testClient = Client{Fingerprint: "fake-fingerprint", IP: "192.0.2.1"}
+	// testBinding is the binding of testClient with BindFingerprint and BindIP
+	testBinding = "769210ef5107c896f9390c2f57458b053f4d66ad27a1050eeb7223a1c9088ac8"
+)
+
+func fakeSender(t *testing.T, expMsg Message, ref string, err error) Sender {
+	return SenderFunc(func(_ context.Context, msg Message) (string, error) {
+		assert.Equal(t, expMsg, msg)
+
+		return ref, err
+	})
+}
+
+func testChallengeIDGenerator() (string, error) {
+	return "fake-challenge-id", nil
+}
+
 func TestUser_GenerateOTP(t *testing.T) {
 	t.Parallel()
 
 	type arg struct {
-		ctx               context.Context
-		userID, requestID string
+		ctx    context.Context
+		params GenerateOTPParams
+	}
+
+	type expectaion struct {
+		delivery Delivery
+		err      error
+	}
+
+	fakeUser := entity.User{
+		ID:    1,
+		UUID:  "fake-uuid",
+		Email: "user@example.com",
+		Phone: "+15550000001",
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectaion)
+	}{
+		{
+			desc: "ErrorUnsupportedPurpose",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+				})
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: GenerateOTPParams{
+							UserUUID:  "fake-uuid",
+							Purpose:   "unknown",
+							RequestID: "fake-request-id",
+						},
+					}, expectaion{
+						err: ErrUnsupportedPurpose,
+					}
+			},
+		},
+		{
+			desc: "ErrorPurposeRemovedBySetPolicies",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+				})
+				user.SetPolicies(map[string]Policy{"transaction": testPolicies["transaction"]}, "transaction")
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: GenerateOTPParams{
+							UserUUID:  "fake-uuid",
+							Purpose:   "login",
+							RequestID: "fake-request-id",
+						},
+					}, expectaion{
+						err: ErrUnsupportedPurpose,
+					}
+			},
+		},
+		{
+			desc: "ErrorGetUserByUUID",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(entity.User{}, errors.New("fake error"))
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: GenerateOTPParams{
+							UserUUID:  "fake-uuid",
+							RequestID: "fake-request-id",
+						},
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorUserNotFound",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(entity.User{}, repository.ErrNotFound)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: GenerateOTPParams{
+							UserUUID:  "fake-uuid",
+							RequestID: "fake-request-id",
+						},
+					}, expectaion{
+						err: ErrUserNotFound.wrap(repository.ErrNotFound),
+					}
+			},
+		},
+		{
+			desc: "ErrorUnsupportedChannel",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					Senders: map[string]Sender{
+						ChannelSMS: fakeSender(t, Message{}, "", nil),
+					},
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: GenerateOTPParams{
+							UserUUID:  "fake-uuid",
+							Channel:   "fax",
+							RequestID: "fake-request-id",
+						},
+					}, expectaion{
+						err: ErrUnsupportedChannel,
+					}
+			},
+		},
+		{
+			desc: "ErrorGeneratingOTP",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
+						return "", errors.New("fake error")
+					},
+					Senders: map[string]Sender{
+						ChannelSMS: fakeSender(t, Message{}, "", nil),
+					},
+					DefaultChannel: ChannelSMS,
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: GenerateOTPParams{
+							UserUUID:  "fake-uuid",
+							RequestID: "fake-request-id",
+						},
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorGeneratingChallengeID",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
+						return "xxxxx", nil
+					},
+					ChallengeIDGenerator: func() (string, error) {
+						return "", errors.New("fake error")
+					},
+					Senders: map[string]Sender{
+						ChannelSMS: fakeSender(t, Message{}, "", nil),
+					},
+					DefaultChannel: ChannelSMS,
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: GenerateOTPParams{
+							UserUUID:  "fake-uuid",
+							RequestID: "fake-request-id",
+						},
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorStoringOTP",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
+						return "xxxxx", nil
+					},
+					ChallengeIDGenerator: testChallengeIDGenerator,
+					NowFunc: testNowFunc,
+					Senders: map[string]Sender{
+						ChannelSMS: fakeSender(t, Message{}, "", nil),
+					},
+					DefaultChannel: ChannelSMS,
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				userRepo.On("StoreOTP", context.TODO(), testOTP).Return(errors.New("fake error"))
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: GenerateOTPParams{
+							UserUUID:  "fake-uuid",
+							RequestID: "fake-request-id",
+						},
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorOTPExist",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
+						return "xxxxx", nil
+					},
+					ChallengeIDGenerator: testChallengeIDGenerator,
+					NowFunc: testNowFunc,
+					Senders: map[string]Sender{
+						ChannelSMS: fakeSender(t, Message{}, "", nil),
+					},
+					DefaultChannel: ChannelSMS,
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				userRepo.On("StoreOTP", context.TODO(), testOTP).Return(repository.ErrOTPExist)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: GenerateOTPParams{
+							UserUUID:  "fake-uuid",
+							RequestID: "fake-request-id",
+						},
+					}, expectaion{
+						err: ErrOTPExist.wrap(repository.ErrOTPExist),
+					}
+			},
+		},
+		{
+			desc: "ErrorRecipientUnreachable",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
+						return "xxxxx", nil
+					},
+					ChallengeIDGenerator: testChallengeIDGenerator,
+					NowFunc: testNowFunc,
+					Senders: map[string]Sender{
+						ChannelEmail: fakeSender(t, Message{
+							Recipient: fakeUser,
+							OTP:       "xxxxx",
+							RequestID: "fake-request-id",
+						}, "", ErrRecipientUnreachable),
+					},
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				userRepo.On("StoreOTP", context.TODO(), testOTP).Return(nil)
+				userRepo.On("ExpireOTP", context.TODO(), fakeUser.ID, "login").Return(repository.ErrOTPNotFound)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: GenerateOTPParams{
+							UserUUID:  "fake-uuid",
+							Channel:   ChannelEmail,
+							RequestID: "fake-request-id",
+						},
+					}, expectaion{
+						err: ErrRecipientUnreachable,
+					}
+			},
+		},
+		{
+			desc: "ErrorDeliveryFailed",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
+						return "xxxxx", nil
+					},
+					ChallengeIDGenerator: testChallengeIDGenerator,
+					NowFunc: testNowFunc,
+					Senders: map[string]Sender{
+						ChannelEmail: fakeSender(t, Message{
+							Recipient: fakeUser,
+							OTP:       "xxxxx",
+							RequestID: "fake-request-id",
+						}, "", errors.New("fake error")),
+					},
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				userRepo.On("StoreOTP", context.TODO(), testOTP).Return(nil)
+				userRepo.On("ExpireOTP", context.TODO(), fakeUser.ID, "login").Return(nil)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: GenerateOTPParams{
+							UserUUID:  "fake-uuid",
+							Channel:   ChannelEmail,
+							RequestID: "fake-request-id",
+						},
+					}, expectaion{
+						err: ErrDeliveryFailed.wrap(errors.New("fake error")),
+					}
+			},
+		},
+		{
+			desc: "ErrorExpiringUndeliveredOTP",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
+						return "xxxxx", nil
+					},
+					ChallengeIDGenerator: testChallengeIDGenerator,
+					NowFunc: testNowFunc,
+					Senders: map[string]Sender{
+						ChannelEmail: fakeSender(t, Message{
+							Recipient: fakeUser,
+							OTP:       "xxxxx",
+							RequestID: "fake-request-id",
+						}, "", errors.New("fake error")),
+					},
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				userRepo.On("StoreOTP", context.TODO(), testOTP).Return(nil)
+				userRepo.On("ExpireOTP", context.TODO(), fakeUser.ID, "login").Return(errors.New("fake expire error"))
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: GenerateOTPParams{
+							UserUUID:  "fake-uuid",
+							Channel:   ChannelEmail,
+							RequestID: "fake-request-id",
+						},
+					}, expectaion{
+						err: errors.Join(ErrDeliveryFailed.wrap(errors.New("fake error")), errors.New("fake expire error")),
+					}
+			},
+		},
+		{
+			desc: "SuccessUsingRequestedChannel",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
+						return "xxxxx", nil
+					},
+					ChallengeIDGenerator: testChallengeIDGenerator,
+					NowFunc: testNowFunc,
+					Senders: map[string]Sender{
+						ChannelEmail: fakeSender(t, Message{
+							Recipient: fakeUser,
+							OTP:       "xxxxx",
+							RequestID: "fake-request-id",
+						}, "fake-ref", nil),
+					},
+					DefaultChannel: ChannelSMS,
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				userRepo.On("StoreOTP", context.TODO(), testOTP).Return(nil)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: GenerateOTPParams{
+							UserUUID:  "fake-uuid",
+							Channel:   ChannelEmail,
+							RequestID: "fake-request-id",
+						},
+					}, expectaion{
+						delivery: Delivery{
+							Channel:     ChannelEmail,
+							Reference:   "fake-ref",
+							ChallengeID: "fake-challenge-id",
+							ExpiresAt:   testNow.Add(5 * time.Minute),
+						},
+					}
+			},
+		},
+		{
+			desc: "SuccessUsingUserChannel",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				prefUser := fakeUser
+				prefUser.OTPChannel = ChannelWebhook
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
+						return "xxxxx", nil
+					},
+					ChallengeIDGenerator: testChallengeIDGenerator,
+					NowFunc: testNowFunc,
+					Senders: map[string]Sender{
+						ChannelWebhook: fakeSender(t, Message{
+							Recipient: prefUser,
+							OTP:       "xxxxx",
+							RequestID: "fake-request-id",
+						}, "fake-ref", nil),
+					},
+					DefaultChannel: ChannelSMS,
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(prefUser, nil)
+				userRepo.On("StoreOTP", context.TODO(), testOTP).Return(nil)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: GenerateOTPParams{
+							UserUUID:  "fake-uuid",
+							RequestID: "fake-request-id",
+						},
+					}, expectaion{
+						delivery: Delivery{
+							Channel:     ChannelWebhook,
+							Reference:   "fake-ref",
+							ChallengeID: "fake-challenge-id",
+							ExpiresAt:   testNow.Add(5 * time.Minute),
+						},
+					}
+			},
+		},
+		{
+			desc: "SuccessUsingDefaultChannel",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
+						return "xxxxx", nil
+					},
+					ChallengeIDGenerator: testChallengeIDGenerator,
+					NowFunc: testNowFunc,
+					Senders: map[string]Sender{
+						ChannelSMS: fakeSender(t, Message{
+							Recipient: fakeUser,
+							OTP:       "xxxxx",
+							RequestID: "fake-request-id",
+						}, "fake-ref", nil),
+					},
+					DefaultChannel: ChannelSMS,
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				userRepo.On("StoreOTP", context.TODO(), testOTP).Return(nil)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: GenerateOTPParams{
+							UserUUID:  "fake-uuid",
+							RequestID: "fake-request-id",
+						},
+					}, expectaion{
+						delivery: Delivery{
+							Channel:     ChannelSMS,
+							Reference:   "fake-ref",
+							ChallengeID: "fake-challenge-id",
+							ExpiresAt:   testNow.Add(5 * time.Minute),
+						},
+					}
+			},
+		},
+		{
+			desc: "SuccessBindingClient",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
+						return "xxxxx", nil
+					},
+					ChallengeIDGenerator: testChallengeIDGenerator,
+					NowFunc: testNowFunc,
+					Senders: map[string]Sender{
+						ChannelSMS: fakeSender(t, Message{
+							Recipient: fakeUser,
+							OTP:       "xxxxx",
+							RequestID: "fake-request-id",
+						}, "fake-ref", nil),
+					},
+					DefaultChannel:  ChannelSMS,
+					BindFingerprint: true,
+					BindIP:          true,
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				userRepo.On("StoreOTP", context.TODO(), entity.OTP{
+					UserID:      1,
+					Code:        "xxxxx",
+					Purpose:     "login",
+					RequestID:   "fake-request-id",
+					ChallengeID: "fake-challenge-id",
+					Binding:     testBinding,
+					TTL:         5 * time.Minute,
+					MaxAttempts: 3,
+				}).Return(nil)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: GenerateOTPParams{
+							UserUUID:  "fake-uuid",
+							RequestID: "fake-request-id",
+							Client:    testClient,
+						},
+					}, expectaion{
+						delivery: Delivery{
+							Channel:     ChannelSMS,
+							Reference:   "fake-ref",
+							ChallengeID: "fake-challenge-id",
+							ExpiresAt:   testNow.Add(5 * time.Minute),
+						},
+					}
+			},
+		},
+		{
+			desc: "SuccessUsingPurposePolicy",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(charset string, length uint8) (string, error) {
+						assert.Equal(t, stringutil.CrockfordBase32Charset, charset)
+						assert.Equal(t, uint8(6), length)
+
+						return "X1Y2Z3", nil
+					},
+					ChallengeIDGenerator: testChallengeIDGenerator,
+					NowFunc: testNowFunc,
+					Senders: map[string]Sender{
+						ChannelSMS: fakeSender(t, Message{
+							Recipient: fakeUser,
+							OTP:       "X1Y2Z3",
+							RequestID: "fake-request-id",
+						}, "fake-ref", nil),
+					},
+					DefaultChannel: ChannelSMS,
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				userRepo.On("StoreOTP", context.TODO(), entity.OTP{
+					UserID:      1,
+					Code:        "X1Y2Z3",
+					Purpose:     "transaction",
+					RequestID:   "fake-request-id",
+					ChallengeID: "fake-challenge-id",
+					TTL:         2 * time.Minute,
+					MaxAttempts: 3,
+				}).Return(nil)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: GenerateOTPParams{
+							UserUUID:  "fake-uuid",
+							Purpose:   "transaction",
+							RequestID: "fake-request-id",
+						},
+					}, expectaion{
+						delivery: Delivery{
+							Channel:     ChannelSMS,
+							Reference:   "fake-ref",
+							ChallengeID: "fake-challenge-id",
+							ExpiresAt:   testNow.Add(2 * time.Minute),
+						},
+					}
+			},
+		},
+		{
+			desc: "SuccessUsingResendPolicy",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
+						return "xxxxx", nil
+					},
+					ChallengeIDGenerator: testChallengeIDGenerator,
+					NowFunc: testNowFunc,
+					Senders: map[string]Sender{
+						ChannelSMS: fakeSender(t, Message{
+							Recipient: fakeUser,
+							OTP:       "xxxxx",
+							RequestID: "fake-request-id",
+						}, "fake-ref", nil),
+					},
+					DefaultChannel: ChannelSMS,
+				})
+
+				resendOTP := testResendOTP
+				resendOTP.RequestID = "fake-request-id"
+				resendOTP.ChallengeID = "fake-challenge-id"
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				userRepo.On("StoreOTP", context.TODO(), resendOTP).Return(nil)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: GenerateOTPParams{
+							UserUUID:  "fake-uuid",
+							Purpose:   "password_reset",
+							RequestID: "fake-request-id",
+						},
+					}, expectaion{
+						delivery: Delivery{
+							Channel:     ChannelSMS,
+							Reference:   "fake-ref",
+							ChallengeID: "fake-challenge-id",
+							ExpiresAt:   testNow.Add(10 * time.Minute),
+							ResendAfter: 30 * time.Second,
+							ResendsLeft: 2,
+						},
+					}
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			u, a, e := tC.mockFn(t)
+
+			got, err := u.GenerateOTP(a.ctx, a.params)
+			assert.Equal(t, e.delivery, got)
+			assert.Equal(t, e.err, err)
+		})
+	}
+}
+
+func TestUser_ResendOTP(t *testing.T) {
+	t.Parallel()
+
+	type arg struct {
+		ctx    context.Context
+		params ResendOTPParams
 	}
 
-	type expectaion struct {
-		otp string
-		err error
-	}
+	type expectaion struct {
+		delivery Delivery
+		err      error
+	}
+
+	fakeUser := entity.User{
+		ID:    1,
+		UUID:  "fake-uuid",
+		Email: "user@example.com",
+		Phone: "+15550000001",
+	}
+
+	testCases := []struct {
+		desc   string
+		mockFn func(*testing.T) (*User, arg, expectaion)
+	}{
+		{
+			desc: "ErrorUnsupportedPurpose",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+				})
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ResendOTPParams{
+							UserUUID: "fake-uuid",
+							Purpose:  "unknown",
+						},
+					}, expectaion{
+						err: ErrUnsupportedPurpose,
+					}
+			},
+		},
+		{
+			desc: "ErrorGetUserByUUID",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(entity.User{}, errors.New("fake error"))
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ResendOTPParams{
+							UserUUID: "fake-uuid",
+							Purpose:  "password_reset",
+						},
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorUnsupportedChannel",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					Senders: map[string]Sender{
+						ChannelSMS: fakeSender(t, Message{}, "", nil),
+					},
+					DefaultChannel: ChannelSMS,
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ResendOTPParams{
+							UserUUID: "fake-uuid",
+							Purpose:  "password_reset",
+							Channel:  "fax",
+						},
+					}, expectaion{
+						err: ErrUnsupportedChannel,
+					}
+			},
+		},
+		{
+			desc: "ErrorGeneratingOTP",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
+						return "", errors.New("fake error")
+					},
+					Senders: map[string]Sender{
+						ChannelSMS: fakeSender(t, Message{}, "", nil),
+					},
+					DefaultChannel: ChannelSMS,
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ResendOTPParams{
+							UserUUID: "fake-uuid",
+							Purpose:  "password_reset",
+						},
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorNoActiveOTP",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
+						return "xxxxx", nil
+					},
+					NowFunc: testNowFunc,
+					Senders: map[string]Sender{
+						ChannelSMS: fakeSender(t, Message{}, "", nil),
+					},
+					DefaultChannel: ChannelSMS,
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				userRepo.On("ResendOTP", context.TODO(), testResendOTP).Return(entity.OTP{}, repository.ErrOTPNotFound)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ResendOTPParams{
+							UserUUID: "fake-uuid",
+							Purpose:  "password_reset",
+						},
+					}, expectaion{
+						err: ErrNoActiveOTP.wrap(repository.ErrOTPNotFound),
+					}
+			},
+		},
+		{
+			desc: "ErrorResendLimit",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
+						return "xxxxx", nil
+					},
+					NowFunc: testNowFunc,
+					Senders: map[string]Sender{
+						ChannelSMS: fakeSender(t, Message{}, "", nil),
+					},
+					DefaultChannel: ChannelSMS,
+				})
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				userRepo.On("ResendOTP", context.TODO(), testResendOTP).Return(entity.OTP{}, repository.ErrResendLimit)
 
-	testCases := []struct {
-		desc   string
-		mockFn func(*testing.T) (*User, arg, expectaion)
-	}{
+				return user, arg{
+						ctx: context.TODO(),
+						params: ResendOTPParams{
+							UserUUID: "fake-uuid",
+							Purpose:  "password_reset",
+						},
+					}, expectaion{
+						err: ErrResendLimit.wrap(repository.ErrResendLimit),
+					}
+			},
+		},
 		{
-			desc: "ErrorGetUserIDByUUID",
+			desc: "ErrorResendCooldown",
 			mockFn: func(*testing.T) (*User, arg, expectaion) {
 				userRepo := mockrepo.NewUserRepository(t)
 				user := NewUser(Dependencies{
-					User: userRepo,
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
+						return "xxxxx", nil
+					},
+					NowFunc: testNowFunc,
+					Senders: map[string]Sender{
+						ChannelSMS: fakeSender(t, Message{}, "", nil),
+					},
+					DefaultChannel: ChannelSMS,
 				})
 
-				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(0), errors.New("fake error"))
+				cooldownErr := &repository.CooldownError{RetryAfter: 15 * time.Second}
+
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				userRepo.On("ResendOTP", context.TODO(), testResendOTP).Return(entity.OTP{}, cooldownErr)
+
+				expErr := ErrResendCooldown.wrap(cooldownErr)
+				expErr.RetryAfter = 15 * time.Second
 
 				return user, arg{
-						ctx:       context.TODO(),
-						userID:    "fake-uuid",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						params: ResendOTPParams{
+							UserUUID: "fake-uuid",
+							Purpose:  "password_reset",
+						},
 					}, expectaion{
-						otp: "",
-						err: errors.New("fake error"),
+						err: expErr,
 					}
 			},
 		},
 		{
-			desc: "ErrorGeneratingOTP",
+			desc: "ErrorDeliveryFailed",
 			mockFn: func(*testing.T) (*User, arg, expectaion) {
 				userRepo := mockrepo.NewUserRepository(t)
 				user := NewUser(Dependencies{
-					User: userRepo,
-					RandNumberGenerator: func(uint8) (string, error) {
-						return "", errors.New("fake error")
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
+						return "xxxxx", nil
 					},
+					NowFunc: testNowFunc,
+					Senders: map[string]Sender{
+						ChannelSMS: fakeSender(t, Message{
+							Recipient: fakeUser,
+							OTP:       "xxxxx",
+							RequestID: "fake-request-id",
+						}, "", errors.New("fake error")),
+					},
+					DefaultChannel: ChannelSMS,
 				})
 
-				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				userRepo.On("ResendOTP", context.TODO(), testResendOTP).Return(entity.OTP{
+					RequestID:   "fake-request-id",
+					ChallengeID: "fake-challenge-id",
+					ResendCount: 1,
+				}, nil)
 
 				return user, arg{
-						ctx:       context.TODO(),
-						userID:    "fake-uuid",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						params: ResendOTPParams{
+							UserUUID: "fake-uuid",
+							Purpose:  "password_reset",
+						},
 					}, expectaion{
-						otp: "",
-						err: errors.New("fake error"),
+						err: ErrDeliveryFailed.wrap(errors.New("fake error")),
 					}
 			},
 		},
 		{
-			desc: "ErrorStoringOTP",
+			desc: "SuccessResendingOTP",
 			mockFn: func(*testing.T) (*User, arg, expectaion) {
 				userRepo := mockrepo.NewUserRepository(t)
 				user := NewUser(Dependencies{
-					User: userRepo,
-					RandNumberGenerator: func(uint8) (string, error) {
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
 						return "xxxxx", nil
 					},
+					NowFunc: testNowFunc,
+					Senders: map[string]Sender{
+						ChannelSMS: fakeSender(t, Message{
+							Recipient: fakeUser,
+							OTP:       "xxxxx",
+							RequestID: "fake-request-id",
+						}, "fake-ref", nil),
+					},
+					DefaultChannel: ChannelSMS,
 				})
 
-				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
-				userRepo.On("StoreOTP", context.TODO(), uint64(1), "xxxxx", "fake-request-id").Return(errors.New("fake error"))
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				userRepo.On("ResendOTP", context.TODO(), testResendOTP).Return(entity.OTP{
+					RequestID:   "fake-request-id",
+					ChallengeID: "fake-challenge-id",
+					ResendCount: 1,
+				}, nil)
 
 				return user, arg{
-						ctx:       context.TODO(),
-						userID:    "fake-uuid",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						params: ResendOTPParams{
+							UserUUID: "fake-uuid",
+							Purpose:  "password_reset",
+						},
 					}, expectaion{
-						otp: "",
-						err: errors.New("fake error"),
+						delivery: Delivery{
+							Channel:     ChannelSMS,
+							Reference:   "fake-ref",
+							ChallengeID: "fake-challenge-id",
+							ExpiresAt:   testNow.Add(10 * time.Minute),
+							ResendAfter: 30 * time.Second,
+							ResendsLeft: 1,
+						},
 					}
 			},
 		},
 		{
-			desc: "SuccessGenerateOTP",
+			desc: "SuccessLastResend",
 			mockFn: func(*testing.T) (*User, arg, expectaion) {
 				userRepo := mockrepo.NewUserRepository(t)
 				user := NewUser(Dependencies{
-					User: userRepo,
-					RandNumberGenerator: func(uint8) (string, error) {
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+					RandStringGenerator: func(string, uint8) (string, error) {
 						return "xxxxx", nil
 					},
+					NowFunc: testNowFunc,
+					Senders: map[string]Sender{
+						ChannelSMS: fakeSender(t, Message{
+							Recipient: fakeUser,
+							OTP:       "xxxxx",
+							RequestID: "fake-request-id",
+						}, "fake-ref", nil),
+					},
+					DefaultChannel: ChannelSMS,
 				})
 
-				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
-				userRepo.On("StoreOTP", context.TODO(), uint64(1), "xxxxx", "fake-request-id").Return(nil)
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				userRepo.On("ResendOTP", context.TODO(), testResendOTP).Return(entity.OTP{
+					RequestID:   "fake-request-id",
+					ChallengeID: "fake-challenge-id",
+					ResendCount: 2,
+				}, nil)
 
 				return user, arg{
-						ctx:       context.TODO(),
-						userID:    "fake-uuid",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						params: ResendOTPParams{
+							UserUUID: "fake-uuid",
+							Purpose:  "password_reset",
+						},
 					}, expectaion{
-						otp: "xxxxx",
-						err: nil,
+						delivery: Delivery{
+							Channel:     ChannelSMS,
+							Reference:   "fake-ref",
+							ChallengeID: "fake-challenge-id",
+							ExpiresAt:   testNow.Add(10 * time.Minute),
+						},
 					}
 			},
 		},
@@ -126,8 +1129,8 @@ func TestUser_GenerateOTP(t *testing.T) {
 
 			u, a, e := tC.mockFn(t)
 
-			got, err := u.GenerateOTP(a.ctx, a.userID, a.requestID)
-			assert.Equal(t, e.otp, got)
+			got, err := u.ResendOTP(a.ctx, a.params)
+			assert.Equal(t, e.delivery, got)
 			assert.Equal(t, e.err, err)
 		})
 	}
@@ -137,35 +1140,61 @@ func TestUser_ValidateOTP(t *testing.T) {
 	t.Parallel()
 
 	type arg struct {
-		ctx       context.Context
-		userID    string
-		otp       string
-		requestID string
+		ctx    context.Context
+		params ValidateOTPParams
 	}
 
 	type expectaion struct {
-		err error
+		session Session
+		err     error
 	}
 
 	testCases := []struct {
 		desc   string
 		mockFn func(*testing.T) (*User, arg, expectaion)
 	}{
+		{
+			desc: "ErrorUnsupportedPurpose",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+				})
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID:    "fake-uuid",
+							OTP:         "xxxxx",
+							Purpose:     "unknown",
+							ChallengeID: "fake-challenge-id",
+						},
+					}, expectaion{
+						err: ErrUnsupportedPurpose,
+					}
+			},
+		},
 		{
 			desc: "ErrorGetUserIDByUUID",
 			mockFn: func(*testing.T) (*User, arg, expectaion) {
 				userRepo := mockrepo.NewUserRepository(t)
 				user := NewUser(Dependencies{
-					User: userRepo,
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
 				})
 
 				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(0), errors.New("fake error"))
 
 				return user, arg{
-						ctx:       context.TODO(),
-						userID:    "fake-uuid",
-						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID:    "fake-uuid",
+							OTP:         "xxxxx",
+							ChallengeID: "fake-challenge-id",
+						},
 					}, expectaion{
 						err: errors.New("fake error"),
 					}
@@ -176,7 +1205,9 @@ func TestUser_ValidateOTP(t *testing.T) {
 			mockFn: func(*testing.T) (*User, arg, expectaion) {
 				userRepo := mockrepo.NewUserRepository(t)
 				user := NewUser(Dependencies{
-					User: userRepo,
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
 				})
 
 				userRepo.
@@ -184,14 +1215,285 @@ func TestUser_ValidateOTP(t *testing.T) {
 					Return(uint64(1), nil)
 
 				userRepo.
-					On("UpdateOTPStatus", context.TODO(), uint64(1), "xxxxx", "fake-request-id").
+					On("UpdateOTPStatus", context.TODO(), uint64(1), "xxxxx", "login", "fake-challenge-id", "").
 					Return(errors.New("fake error"))
 
 				return user, arg{
-						ctx:       context.TODO(),
-						userID:    "fake-uuid",
-						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID:    "fake-uuid",
+							OTP:         "xxxxx",
+							ChallengeID: "fake-challenge-id",
+						},
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorInvalidOTP",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+				})
+
+				userRepo.
+					On("GetUserIDByUUID", context.TODO(), "fake-uuid").
+					Return(uint64(1), nil)
+
+				userRepo.
+					On("UpdateOTPStatus", context.TODO(), uint64(1), "xxxxx", "login", "fake-challenge-id", "").
+					Return(repository.ErrInvalidOTP)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID:    "fake-uuid",
+							OTP:         "xxxxx",
+							ChallengeID: "fake-challenge-id",
+						},
+					}, expectaion{
+						err: ErrInvalidOTP.wrap(repository.ErrInvalidOTP),
+					}
+			},
+		},
+		{
+			desc: "ErrorChallengeMismatch",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+				})
+
+				userRepo.
+					On("GetUserIDByUUID", context.TODO(), "fake-uuid").
+					Return(uint64(1), nil)
+
+				userRepo.
+					On("UpdateOTPStatus", context.TODO(), uint64(1), "xxxxx", "login", "other-challenge-id", "").
+					Return(repository.ErrChallengeMismatch)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID:    "fake-uuid",
+							OTP:         "xxxxx",
+							ChallengeID: "other-challenge-id",
+						},
+					}, expectaion{
+						err: ErrOTPChallengeMismatch.wrap(repository.ErrChallengeMismatch),
+					}
+			},
+		},
+		{
+			desc: "ErrorClientMismatch",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:            userRepo,
+					Policies:        testPolicies,
+					DefaultPurpose:  "login",
+					BindFingerprint: true,
+					BindIP:          true,
+				})
+
+				userRepo.
+					On("GetUserIDByUUID", context.TODO(), "fake-uuid").
+					Return(uint64(1), nil)
+
+				userRepo.
+					On("UpdateOTPStatus", context.TODO(), uint64(1), "xxxxx", "login", "fake-challenge-id",
+						testBinding).
+					Return(repository.ErrBindingMismatch)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID:    "fake-uuid",
+							OTP:         "xxxxx",
+							ChallengeID: "fake-challenge-id",
+							Client:      testClient,
+						},
+					}, expectaion{
+						err: ErrOTPClientMismatch.wrap(repository.ErrBindingMismatch),
+					}
+			},
+		},
+		{
+			desc: "ErrorOTPExpired",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+				})
+
+				userRepo.
+					On("GetUserIDByUUID", context.TODO(), "fake-uuid").
+					Return(uint64(1), nil)
+
+				userRepo.
+					On("UpdateOTPStatus", context.TODO(), uint64(1), "xxxxx", "login", "fake-challenge-id", "").
+					Return(repository.ErrOTPExpired)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID:    "fake-uuid",
+							OTP:         "xxxxx",
+							ChallengeID: "fake-challenge-id",
+						},
+					}, expectaion{
+						err: ErrOTPExpired.wrap(repository.ErrOTPExpired),
+					}
+			},
+		},
+		{
+			desc: "ErrorTooManyAttempts",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+				})
+
+				lockedErr := &repository.LockedError{Err: repository.ErrTooManyAttempts, RetryAfter: time.Minute}
+
+				userRepo.
+					On("GetUserIDByUUID", context.TODO(), "fake-uuid").
+					Return(uint64(1), nil)
+
+				userRepo.
+					On("UpdateOTPStatus", context.TODO(), uint64(1), "xxxxx", "login", "fake-challenge-id", "").
+					Return(lockedErr)
+
+				expErr := ErrTooManyAttempts.wrap(lockedErr)
+				expErr.RetryAfter = time.Minute
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID:    "fake-uuid",
+							OTP:         "xxxxx",
+							ChallengeID: "fake-challenge-id",
+						},
+					}, expectaion{
+						err: expErr,
+					}
+			},
+		},
+		{
+			desc: "ErrorUserLocked",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				user := NewUser(Dependencies{
+					User:           userRepo,
+					Policies:       testPolicies,
+					DefaultPurpose: "login",
+				})
+
+				lockedErr := &repository.LockedError{Err: repository.ErrUserLocked, RetryAfter: time.Minute}
+
+				userRepo.
+					On("GetUserIDByUUID", context.TODO(), "fake-uuid").
+					Return(uint64(1), nil)
+
+				userRepo.
+					On("UpdateOTPStatus", context.TODO(), uint64(1), "xxxxx", "login", "fake-challenge-id", "").
+					Return(lockedErr)
+
+				expErr := ErrUserLocked.wrap(lockedErr)
+				expErr.RetryAfter = time.Minute
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID:    "fake-uuid",
+							OTP:         "xxxxx",
+							ChallengeID: "fake-challenge-id",
+						},
+					}, expectaion{
+						err: expErr,
+					}
+			},
+		},
+		{
+			desc: "ErrorIssuingToken",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				tokens := mockrepo.NewTokenIssuer(t)
+				user := NewUser(Dependencies{
+					User:                  userRepo,
+					Policies:              testPolicies,
+					DefaultPurpose:        "login",
+					NowFunc:               testNowFunc,
+					Tokens:                tokens,
+					RefreshTokenGenerator: testRefreshTokenGenerator,
+					RefreshTokenTTL:       testRefreshTokenTTL,
+				})
+
+				userRepo.
+					On("GetUserIDByUUID", context.TODO(), "fake-uuid").
+					Return(uint64(1), nil)
+
+				userRepo.
+					On("UpdateOTPStatus", context.TODO(), uint64(1), "xxxxx", "login", "fake-challenge-id", "").
+					Return(nil)
+
+				tokens.On("Issue", "fake-uuid").Return("", time.Duration(0), errors.New("fake error"))
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID:    "fake-uuid",
+							OTP:         "xxxxx",
+							ChallengeID: "fake-challenge-id",
+						},
+					}, expectaion{
+						err: errors.New("fake error"),
+					}
+			},
+		},
+		{
+			desc: "ErrorStoringRefreshToken",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				tokens := mockrepo.NewTokenIssuer(t)
+				user := NewUser(Dependencies{
+					User:                  userRepo,
+					Policies:              testPolicies,
+					DefaultPurpose:        "login",
+					NowFunc:               testNowFunc,
+					Tokens:                tokens,
+					RefreshTokenGenerator: testRefreshTokenGenerator,
+					RefreshTokenTTL:       testRefreshTokenTTL,
+				})
+
+				userRepo.
+					On("GetUserIDByUUID", context.TODO(), "fake-uuid").
+					Return(uint64(1), nil)
+
+				userRepo.
+					On("UpdateOTPStatus", context.TODO(), uint64(1), "xxxxx", "login", "fake-challenge-id", "").
+					Return(nil)
+
+				tokens.On("Issue", "fake-uuid").Return("fake-access-token", 5*time.Minute, nil)
+				userRepo.On("StoreRefreshToken", context.TODO(), testRefreshToken).Return(errors.New("fake error"))
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID:    "fake-uuid",
+							OTP:         "xxxxx",
+							ChallengeID: "fake-challenge-id",
+						},
 					}, expectaion{
 						err: errors.New("fake error"),
 					}
@@ -201,8 +1503,53 @@ func TestUser_ValidateOTP(t *testing.T) {
 			desc: "SuccessGenerateOTP",
 			mockFn: func(*testing.T) (*User, arg, expectaion) {
 				userRepo := mockrepo.NewUserRepository(t)
+				tokens := mockrepo.NewTokenIssuer(t)
+				user := NewUser(Dependencies{
+					User:                  userRepo,
+					Policies:              testPolicies,
+					DefaultPurpose:        "login",
+					NowFunc:               testNowFunc,
+					Tokens:                tokens,
+					RefreshTokenGenerator: testRefreshTokenGenerator,
+					RefreshTokenTTL:       testRefreshTokenTTL,
+				})
+
+				userRepo.
+					On("GetUserIDByUUID", context.TODO(), "fake-uuid").
+					Return(uint64(1), nil)
+
+				userRepo.
+					On("UpdateOTPStatus", context.TODO(), uint64(1), "xxxxx", "login", "fake-challenge-id", "").
+					Return(nil)
+
+				tokens.On("Issue", "fake-uuid").Return("fake-access-token", 5*time.Minute, nil)
+				userRepo.On("StoreRefreshToken", context.TODO(), testRefreshToken).Return(nil)
+
+				return user, arg{
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID:    "fake-uuid",
+							OTP:         "xxxxx",
+							ChallengeID: "fake-challenge-id",
+						},
+					}, expectaion{
+						session: testSession,
+					}
+			},
+		},
+		{
+			desc: "SuccessNormalizeCrockfordOTP",
+			mockFn: func(*testing.T) (*User, arg, expectaion) {
+				userRepo := mockrepo.NewUserRepository(t)
+				tokens := mockrepo.NewTokenIssuer(t)
 				user := NewUser(Dependencies{
-					User: userRepo,
+					User:                  userRepo,
+					Policies:              testPolicies,
+					DefaultPurpose:        "login",
+					NowFunc:               testNowFunc,
+					Tokens:                tokens,
+					RefreshTokenGenerator: testRefreshTokenGenerator,
+					RefreshTokenTTL:       testRefreshTokenTTL,
 				})
 
 				userRepo.
@@ -210,16 +1557,22 @@ func TestUser_ValidateOTP(t *testing.T) {
 					Return(uint64(1), nil)
 
 				userRepo.
-					On("UpdateOTPStatus", context.TODO(), uint64(1), "xxxxx", "fake-request-id").
+					On("UpdateOTPStatus", context.TODO(), uint64(1), "X1Y2Z0", "transaction", "fake-challenge-id", "").
 					Return(nil)
 
+				tokens.On("Issue", "fake-uuid").Return("fake-access-token", 5*time.Minute, nil)
+				userRepo.On("StoreRefreshToken", context.TODO(), testRefreshToken).Return(nil)
+
 				return user, arg{
-						ctx:       context.TODO(),
-						userID:    "fake-uuid",
-						otp:       "xxxxx",
-						requestID: "fake-request-id",
+						ctx: context.TODO(),
+						params: ValidateOTPParams{
+							UserUUID:    "fake-uuid",
+							OTP:         "x-iy2-zo",
+							Purpose:     "transaction",
+							ChallengeID: "fake-challenge-id",
+						},
 					}, expectaion{
-						err: nil,
+						session: testSession,
 					}
 			},
 		},
@@ -232,8 +1585,122 @@ func TestUser_ValidateOTP(t *testing.T) {
 
 			u, a, e := tC.mockFn(t)
 
-			err := u.ValidateOTP(a.ctx, a.userID, a.otp, a.requestID)
+			got, err := u.ValidateOTP(a.ctx, a.params)
+			assert.Equal(t, e.session, got)
 			assert.Equal(t, e.err, err)
 		})
 	}
 }
+
+func TestUser_OTPEvents(t *testing.T) {
+	t.Parallel()
+
+	type event struct {
+		purpose string
+		outcome string
+	}
+
+	fakeUser := entity.User{
+		ID:    1,
+		UUID:  "fake-uuid",
+		Email: "user@example.com",
+	}
+
+	testCases := []struct {
+		desc   string
+		call   func(*User) error
+		mockFn func(*mockrepo.UserRepository)
+		exp    []event
+	}{
+		{
+			desc: "UnsupportedPurposeNotCounted",
+			call: func(user *User) error {
+				_, err := user.GenerateOTP(context.TODO(), GenerateOTPParams{UserUUID: "fake-uuid", Purpose: "unknown"})
+
+				return err
+			},
+			mockFn: func(*mockrepo.UserRepository) {},
+		},
+		{
+			desc: "Issued",
+			call: func(user *User) error {
+				_, err := user.GenerateOTP(context.TODO(), GenerateOTPParams{
+					UserUUID:  "fake-uuid",
+					Channel:   ChannelEmail,
+					RequestID: "fake-request-id",
+				})
+
+				return err
+			},
+			mockFn: func(userRepo *mockrepo.UserRepository) {
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(fakeUser, nil)
+				userRepo.On("StoreOTP", context.TODO(), testOTP).Return(nil)
+			},
+			exp: []event{{purpose: "login", outcome: OTPIssued}},
+		},
+		{
+			desc: "ResendFailed",
+			call: func(user *User) error {
+				_, err := user.ResendOTP(context.TODO(), ResendOTPParams{UserUUID: "fake-uuid", Purpose: "transaction"})
+
+				return err
+			},
+			mockFn: func(userRepo *mockrepo.UserRepository) {
+				userRepo.On("GetUserByUUID", context.TODO(), "fake-uuid").Return(entity.User{}, errors.New("fake error"))
+			},
+			exp: []event{{purpose: "transaction", outcome: OTPFailed}},
+		},
+		{
+			desc: "ValidateRefused",
+			call: func(user *User) error {
+				_, err := user.ValidateOTP(context.TODO(), ValidateOTPParams{
+					UserUUID:    "fake-uuid",
+					OTP:         "xxxxx",
+					ChallengeID: "fake-challenge-id",
+				})
+
+				return err
+			},
+			mockFn: func(userRepo *mockrepo.UserRepository) {
+				userRepo.On("GetUserIDByUUID", context.TODO(), "fake-uuid").Return(uint64(1), nil)
+				userRepo.
+					On("UpdateOTPStatus", context.TODO(), uint64(1), "xxxxx", "login", "fake-challenge-id", "").
+					Return(repository.ErrInvalidOTP)
+			},
+			exp: []event{{purpose: "login", outcome: ErrInvalidOTP.Code}},
+		},
+	}
+
+	for _, tC := range testCases {
+		tC := tC
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var events []event
+
+			userRepo := mockrepo.NewUserRepository(t)
+			tC.mockFn(userRepo)
+			user := NewUser(Dependencies{
+				User:           userRepo,
+				Policies:       testPolicies,
+				DefaultPurpose: "login",
+				RandStringGenerator: func(string, uint8) (string, error) {
+					return "xxxxx", nil
+				},
+				ChallengeIDGenerator: testChallengeIDGenerator,
+				NowFunc: testNowFunc,
+				Senders: map[string]Sender{
+					ChannelEmail: SenderFunc(func(context.Context, Message) (string, error) {
+						return "fake-ref", nil
+					}),
+				},
+				OTPEvents: func(purpose, outcome string) {
+					events = append(events, event{purpose: purpose, outcome: outcome})
+				},
+			})
+
+			_ = tC.call(user)
+			assert.Equal(t, tC.exp, events)
+		})
+	}
+}
@@ -0,0 +1,7 @@
+-- Development users, load them once the schema is migrated. The statement is
+-- plain SQL so it runs on every supported database.
+
+INSERT INTO users (uuid, name, email, phone, otp_channel) VALUES
+  ('ac304b86-1437-43bc-a7a9-239c262c2e17', 'Robert', 'robert@example.com', '+15550000001', NULL),
+  ('ead5f356-ad40-4b71-bc1f-f015cf2dbf26', 'Jhon', 'jhon@example.com', NULL, 'email'),
+  ('0ea89b50-828d-495f-a48a-3d1d97f8c3cd', 'George', NULL, '+15550000003', 'sms');